@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package app
+
+import (
+	"sync"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/io/event"
+)
+
+// Application runs the event loops of a set of windows, such as a main
+// window with its dialogs and tool palettes. Every window of the
+// Application receives its events in a goroutine of its own, so that
+// no window blocks the event loop the platform shares between windows,
+// and Run passes the events to the handlers of the windows one at a
+// time.
+type Application struct {
+	events chan appEvent
+
+	mu sync.Mutex
+	// active is the number of windows not yet destroyed.
+	active int
+}
+
+// appEvent is an event of a window of an Application, handled by Run.
+type appEvent struct {
+	w      *Window
+	e      event.Event
+	handle func(w *Window, e event.Event)
+	// done is signalled when the event is handled.
+	done chan struct{}
+}
+
+// NewApplication returns an Application without windows.
+func NewApplication() *Application {
+	return &Application{events: make(chan appEvent)}
+}
+
+// NewWindow creates a window like the package function NewWindow, whose
+// events are passed to handle by Run until the window is destroyed. The
+// handlers of the windows must not block, for the other windows wait for
+// them; in particular, a FrameEvent must be answered before the next
+// event of the window.
+func (a *Application) NewWindow(handle func(w *Window, e event.Event), options ...mado.Option) *Window {
+	w := NewWindow(new(Callbacks), options...)
+	a.run(w, handle)
+	return w
+}
+
+// run receives the events of w until its DestroyEvent.
+func (a *Application) run(w *Window, handle func(w *Window, e event.Event)) {
+	a.mu.Lock()
+	a.active++
+	a.mu.Unlock()
+	go func() {
+		done := make(chan struct{})
+		for {
+			e := w.NextEvent()
+			a.events <- appEvent{w: w, e: e, handle: handle, done: done}
+			<-done
+			if _, ok := e.(mado.DestroyEvent); ok {
+				return
+			}
+		}
+	}()
+}
+
+// Run handles the events of the windows until all of them are
+// destroyed. The handlers may create more windows.
+func (a *Application) Run() {
+	for {
+		a.mu.Lock()
+		active := a.active
+		a.mu.Unlock()
+		if active == 0 {
+			return
+		}
+		ev := <-a.events
+		ev.handle(ev.w, ev.e)
+		if _, ok := ev.e.(mado.DestroyEvent); ok {
+			a.mu.Lock()
+			a.active--
+			a.mu.Unlock()
+		}
+		ev.done <- struct{}{}
+	}
+}
@@ -42,6 +42,7 @@ func (c *Callbacks) Event(e event.Event) bool {
 	if c.d == nil {
 		panic("event while no driver active")
 	}
+	c.w.DeliverClipboard(e)
 	c.waitEvents = append(c.waitEvents, e)
 	if c.busy {
 		return true
@@ -160,3 +161,7 @@ func (c *Callbacks) ClickFocus() {
 func (c *Callbacks) ActionAt(p f32.Point) (system.Action, bool) {
 	return c.w.Queue.ActionAt(p)
 }
+
+func (c *Callbacks) DropTarget(pos f32.Point, types []string) (string, bool) {
+	return c.w.Queue.DropType(pos, types)
+}
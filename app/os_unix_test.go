@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package app
+
+import (
+	"image"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/system"
+	"github.com/kanryu/mado/op"
+)
+
+// TestMultipleWindows runs windows on the shared display connection of
+// the platform, for example under Xvfb or a headless Wayland compositor.
+func TestMultipleWindows(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("no X11 or Wayland display")
+	}
+	const n = 3
+	frames := make(chan error, n)
+	destroys := make(chan error, n)
+	var windows []*Window
+	for i := 0; i < n; i++ {
+		w := NewWindow(new(Callbacks), Size(200, 100))
+		windows = append(windows, w)
+		go func() {
+			var ops op.Ops
+			framed := false
+			for {
+				switch e := w.NextEvent().(type) {
+				case mado.FrameEvent:
+					e.Frame(&ops)
+					if !framed {
+						framed = true
+						frames <- nil
+					}
+				case mado.DestroyEvent:
+					if !framed {
+						frames <- e.Err
+					}
+					destroys <- e.Err
+					return
+				}
+			}
+		}()
+	}
+	timeout := time.After(10 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-frames:
+			if err != nil {
+				t.Fatalf("window destroyed before its first frame: %v", err)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for frames")
+		}
+	}
+	if got := len(Windows()); got != n {
+		t.Errorf("%d open windows, want %d", got, n)
+	}
+	for _, w := range windows {
+		w.Perform(system.ActionClose)
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-destroys:
+			if err != nil {
+				t.Errorf("window destroyed with error: %v", err)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for windows to close")
+		}
+	}
+	if got := len(Windows()); got != 0 {
+		t.Errorf("%d windows open after closing, want 0", got)
+	}
+}
+
+// TestPopupDismissedWithParent checks that closing a window dismisses
+// its popups.
+func TestPopupDismissedWithParent(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("no X11 or Wayland display")
+	}
+	// run delivers the events of w to events, until DestroyEvent.
+	run := func(w *Window, events chan<- event.Event) {
+		var ops op.Ops
+		for {
+			e := w.NextEvent()
+			switch e := e.(type) {
+			case mado.FrameEvent:
+				e.Frame(&ops)
+			case mado.DismissEvent, mado.DestroyEvent:
+			default:
+				continue
+			}
+			events <- e
+			if _, ok := e.(mado.DestroyEvent); ok {
+				return
+			}
+		}
+	}
+	// next returns the next event of events that isn't a FrameEvent.
+	next := func(events <-chan event.Event) event.Event {
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case e := <-events:
+				if _, ok := e.(mado.FrameEvent); !ok {
+					return e
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for event")
+			}
+		}
+	}
+	// frame waits for the first frame of a window.
+	frame := func(events <-chan event.Event) {
+		select {
+		case e := <-events:
+			if e, ok := e.(mado.DestroyEvent); ok {
+				t.Fatalf("window destroyed before its first frame: %v", e.Err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for frame")
+		}
+	}
+	parent := NewWindow(new(Callbacks), Size(200, 100))
+	parentEvents := make(chan event.Event, 10)
+	go run(parent, parentEvents)
+	frame(parentEvents)
+	popup := NewWindow(new(Callbacks), Size(50, 50), Popup(parent, mado.PopupTooltip, image.Rect(10, 10, 20, 20)))
+	popupEvents := make(chan event.Event, 10)
+	go run(popup, popupEvents)
+	frame(popupEvents)
+	parent.Perform(system.ActionClose)
+	if e := next(popupEvents); e != (mado.DismissEvent{}) {
+		t.Errorf("popup event %#v, want DismissEvent", e)
+	}
+	if _, ok := next(popupEvents).(mado.DestroyEvent); !ok {
+		t.Error("popup not destroyed")
+	}
+	if _, ok := next(parentEvents).(mado.DestroyEvent); !ok {
+		t.Error("parent not destroyed")
+	}
+}
@@ -7,10 +7,15 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"io"
 	"runtime"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/f32"
 	"github.com/kanryu/mado/font/gofont"
 	"github.com/kanryu/mado/gpu"
 	"github.com/kanryu/mado/internal/debug"
@@ -20,6 +25,7 @@ import (
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/io/system"
+	"github.com/kanryu/mado/io/transfer"
 	"github.com/kanryu/mado/layout"
 	"github.com/kanryu/mado/op"
 	"github.com/kanryu/mado/text"
@@ -108,6 +114,22 @@ type Window struct {
 
 	ImeState mado.EditorState
 
+	// clipReads tracks the ReadClipboard calls waiting for the
+	// clipboard content.
+	clipReads struct {
+		sync.Mutex
+		waiters []chan string
+	}
+	// clipFuncs are the clipboard requests waiting for the driver.
+	// Unlike driverFuncs, queueing them never blocks.
+	clipFuncs struct {
+		sync.Mutex
+		funcs []func(d mado.Driver)
+	}
+	// dragTypes are the MIME types last passed to the driver's
+	// SetDragSource.
+	dragTypes []string
+
 	// event stores the state required for processing and delivering events
 	// from NextEvent. If we had support for range over func, this would
 	// be the iterator state.
@@ -142,7 +164,11 @@ type Window struct {
 // platform.
 //
 // Calling NewWindow more than once is not supported on
-// iOS, Android, WebAssembly.
+// iOS, Android, WebAssembly. Elsewhere, every window must run
+// its NextEvent loop in a goroutine of its own, because a
+// window blocks the event loop shared with the other windows
+// until its events are received. The windows of an
+// [Application] do so.
 func NewWindow(callbacks mado.Callbacks, options ...mado.Option) *Window {
 	debug.Parse()
 	// Measure decoration height.
@@ -196,6 +222,59 @@ func NewWindow(callbacks mado.Callbacks, options ...mado.Option) *Window {
 	return w
 }
 
+// openWindows tracks the windows between their creation by the
+// platform and their DestroyEvent.
+var openWindows struct {
+	mu      sync.Mutex
+	windows []*Window
+	focused *Window
+}
+
+// Windows returns the open windows, in order of creation.
+func Windows() []*Window {
+	openWindows.mu.Lock()
+	defer openWindows.mu.Unlock()
+	return slices.Clone(openWindows.windows)
+}
+
+// Focused returns the window with the keyboard focus, or nil if the
+// focus is in no window of the program.
+func Focused() *Window {
+	openWindows.mu.Lock()
+	defer openWindows.mu.Unlock()
+	return openWindows.focused
+}
+
+func (w *Window) register() {
+	openWindows.mu.Lock()
+	defer openWindows.mu.Unlock()
+	openWindows.windows = append(openWindows.windows, w)
+}
+
+func (w *Window) unregister() {
+	openWindows.mu.Lock()
+	defer openWindows.mu.Unlock()
+	if i := slices.Index(openWindows.windows, w); i != -1 {
+		openWindows.windows = slices.Delete(openWindows.windows, i, i+1)
+	}
+	if openWindows.focused == w {
+		openWindows.focused = nil
+	}
+}
+
+// setFocus updates the focus of the window. Platforms may report the
+// focus of a window before the loss of focus of another.
+func (w *Window) setFocus(focus bool) {
+	openWindows.mu.Lock()
+	defer openWindows.mu.Unlock()
+	switch {
+	case focus:
+		openWindows.focused = w
+	case openWindows.focused == w:
+		openWindows.focused = nil
+	}
+}
+
 // func decoHeightOpt(h unit.Dp) mado.Option {
 // 	return func(m unit.Metric, c *mado.Config) {
 // 		c.DecoHeight = h
@@ -318,11 +397,22 @@ func (w *Window) ProcessFrame(d mado.Driver) {
 	if hint, ok := q.TextInputHint(); ok {
 		d.SetInputHint(hint)
 	}
-	if mime, txt, ok := q.WriteClipboard(); ok {
-		d.WriteClipboard(mime, txt)
+	if content, ok := q.WriteClipboard(); ok {
+		d.WriteClipboard(content)
+	}
+	for _, req := range q.ClipboardRequests() {
+		d.ReadClipboard(req.Type, req.Primary)
+	}
+	if types := q.TransferSource(); !slices.Equal(types, w.dragTypes) {
+		w.dragTypes = types
+		d.SetDragSource(types)
 	}
-	if q.ClipboardRequested() {
-		d.ReadClipboard()
+	for _, o := range q.TransferOffers() {
+		data, err := io.ReadAll(o.Data)
+		o.Data.Close()
+		if err == nil {
+			d.OfferDragData(o.Type, data)
+		}
 	}
 	oldState := w.ImeState
 	newState := oldState
@@ -394,6 +484,28 @@ func (w *Window) Run(f func()) {
 	}
 }
 
+// NewFramebuffer returns a Framebuffer for drawing the window on the CPU,
+// presented the fastest way the platform has. The window is expected to
+// be configured with [CustomRenderer]. Like Run, NewFramebuffer waits for
+// the native window event loop to create the framebuffer.
+func (w *Window) NewFramebuffer() (mado.Framebuffer, error) {
+	var (
+		fb  mado.Framebuffer
+		err error
+	)
+	done := make(chan struct{})
+	w.DriverDefer(func(d mado.Driver) {
+		defer close(done)
+		fb, err = d.NewFramebuffer()
+	})
+	select {
+	case <-done:
+		return fb, err
+	case <-w.Destroy:
+		return nil, errWindowDestroyed
+	}
+}
+
 // driverDefer is like Run but can be run from any context. It doesn't wait
 // for f to return.
 func (w *Window) DriverDefer(f func(d mado.Driver)) {
@@ -788,6 +900,13 @@ func (w *Window) CollectSemanticDiffs(diffs *[]input.SemanticID, n input.Semanti
 }
 
 func (w *Window) UpdateState(d mado.Driver) {
+	w.clipFuncs.Lock()
+	clipFuncs := w.clipFuncs.funcs
+	w.clipFuncs.funcs = nil
+	w.clipFuncs.Unlock()
+	for _, f := range clipFuncs {
+		f(d)
+	}
 	for {
 		select {
 		case f := <-w.driverFuncs:
@@ -869,6 +988,7 @@ func (w *Window) ProcessEvent(d mado.Driver, e event.Event) bool {
 		deco.Add(wrapper)
 		if err := w.ValidateAndProcess(d, viewSize, e2.Sync, wrapper, signal); err != nil {
 			w.DestroyGPU()
+			w.unregister()
 			w.out <- mado.DestroyEvent{Err: err}
 			close(w.Destroy)
 			break
@@ -877,6 +997,7 @@ func (w *Window) ProcessEvent(d mado.Driver, e event.Event) bool {
 		w.UpdateCursor(d)
 	case mado.DestroyEvent:
 		w.DestroyGPU()
+		w.unregister()
 		w.out <- e2
 		close(w.Destroy)
 	case mado.ViewEvent:
@@ -886,25 +1007,17 @@ func (w *Window) ProcessEvent(d mado.Driver, e event.Event) bool {
 		w.Decorations.Config = e2.Config
 		e2.Config = w.EffectiveConfig()
 		w.out <- e2
+	case mado.DismissEvent:
+		w.out <- e2
 	case mado.WakeupEvent:
 	case event.Event:
+		if e, ok := e2.(key.FocusEvent); ok {
+			w.setFocus(e.Focus)
+		}
 		focusDir := key.FocusDirection(-1)
 		if e, ok := e2.(key.Event); ok && e.State == key.Press {
 			isMobile := runtime.GOOS == "ios" || runtime.GOOS == "android"
-			switch {
-			case e.Name == key.NameTab && e.Modifiers == 0:
-				focusDir = key.FocusForward
-			case e.Name == key.NameTab && e.Modifiers == key.ModShift:
-				focusDir = key.FocusBackward
-			case e.Name == key.NameUpArrow && e.Modifiers == 0 && isMobile:
-				focusDir = key.FocusUp
-			case e.Name == key.NameDownArrow && e.Modifiers == 0 && isMobile:
-				focusDir = key.FocusDown
-			case e.Name == key.NameLeftArrow && e.Modifiers == 0 && isMobile:
-				focusDir = key.FocusLeft
-			case e.Name == key.NameRightArrow && e.Modifiers == 0 && isMobile:
-				focusDir = key.FocusRight
-			}
+			focusDir = focusDirection(e, isMobile)
 		}
 		e := e2
 		if focusDir != -1 {
@@ -933,7 +1046,9 @@ func (w *Window) NextEvent() event.Event {
 	state := &w.EventState
 	if !state.Created {
 		state.Created = true
+		w.register()
 		if err := mado.OsNewWindow(w.callbacks, state.InitialOpts); err != nil {
+			w.unregister()
 			close(w.Destroy)
 			return mado.DestroyEvent{Err: err}
 		}
@@ -975,6 +1090,165 @@ func (w *Window) NextEvent() event.Event {
 	}
 }
 
+// SetInputMode updates the pointer and keyboard input modes of the
+// window.
+func (w *Window) SetInputMode(mode mado.InputMode) {
+	w.DriverDefer(func(d mado.Driver) {
+		d.SetInputMode(mode)
+	})
+}
+
+// SetCursorPos moves the cursor to pos, in window coordinates. The
+// window must have focus.
+func (w *Window) SetCursorPos(pos f32.Point) {
+	w.DriverDefer(func(d mado.Driver) {
+		d.SetCursorPos(pos)
+	})
+}
+
+// SetCursor sets the cursor shape of the window. Cursor operations
+// replace it when the cursor they select changes.
+func (w *Window) SetCursor(c pointer.Cursor) {
+	w.DriverDefer(func(d mado.Driver) {
+		d.SetCursor(c)
+	})
+}
+
+// SetCustomCursor replaces the cursor shape of the window with an image
+// cursor. A nil c restores the standard cursor shapes.
+func (w *Window) SetCustomCursor(c *pointer.CustomCursor) {
+	w.DriverDefer(func(d mado.Driver) {
+		d.SetCustomCursor(c)
+	})
+}
+
+// ReleaseCustomCursor frees the resources of the window for c. Custom
+// cursors are converted to native cursors once per window, and kept
+// until they are released or the window is destroyed.
+func (w *Window) ReleaseCustomCursor(c *pointer.CustomCursor) {
+	w.DriverDefer(func(d mado.Driver) {
+		d.ReleaseCustomCursor(c)
+	})
+}
+
+// errWindowDestroyed is returned by window operations that need the
+// driver of a destroyed window.
+var errWindowDestroyed = errors.New("app: window destroyed")
+
+// ErrClipboardTimeout is returned by ReadClipboard when the platform
+// doesn't deliver the clipboard content in time.
+var ErrClipboardTimeout = errors.New("app: clipboard read timed out")
+
+// ReadClipboard reads the text content of the clipboard, waiting at most
+// timeout for the platform to deliver it. Unlike a clipboard.ReadCmd, it
+// doesn't need a frame and may be called from any goroutine but the one
+// running the window's native event loop, which delivers the content. Use
+// ReadClipboardOnLoop from there.
+func (w *Window) ReadClipboard(timeout time.Duration) (string, error) {
+	return w.readClipboard(nil, timeout, nil)
+}
+
+// ReadClipboardOnLoop is like ReadClipboard, for the goroutine running the
+// window's native event loop, whose driver is d. It requests the content
+// from d directly, and calls poll while waiting for it, to process the
+// events of the loop. poll may be nil if d delivers the content from
+// within its ReadClipboard.
+func (w *Window) ReadClipboardOnLoop(d mado.Driver, timeout time.Duration, poll func()) (string, error) {
+	return w.readClipboard(d, timeout, poll)
+}
+
+func (w *Window) readClipboard(d mado.Driver, timeout time.Duration, poll func()) (string, error) {
+	c := make(chan string, 1)
+	w.clipReads.Lock()
+	w.clipReads.waiters = append(w.clipReads.waiters, c)
+	w.clipReads.Unlock()
+	read := func(d mado.Driver) {
+		d.ReadClipboard("application/text", false)
+	}
+	if d != nil {
+		read(d)
+	} else {
+		w.deferClipboard(read)
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	// polling is ready while there is a poll function to call.
+	var polling chan struct{}
+	if poll != nil {
+		polling = make(chan struct{})
+		close(polling)
+	}
+wait:
+	for {
+		select {
+		case s := <-c:
+			return s, nil
+		case <-t.C:
+			break wait
+		case <-w.Destroy:
+			break wait
+		case <-polling:
+			poll()
+		}
+	}
+	w.clipReads.Lock()
+	for i, c2 := range w.clipReads.waiters {
+		if c2 == c {
+			w.clipReads.waiters = append(w.clipReads.waiters[:i], w.clipReads.waiters[i+1:]...)
+			break
+		}
+	}
+	w.clipReads.Unlock()
+	// The content may have arrived while giving up.
+	select {
+	case s := <-c:
+		return s, nil
+	default:
+		return "", ErrClipboardTimeout
+	}
+}
+
+// WriteClipboard replaces the content of the clipboard with text. Like
+// ReadClipboard, it doesn't need a frame, and it never blocks.
+func (w *Window) WriteClipboard(text string) {
+	w.deferClipboard(func(d mado.Driver) {
+		d.WriteClipboard([]input.ClipboardData{{Type: "application/text", Data: []byte(text)}})
+	})
+}
+
+// deferClipboard queues the clipboard request f for the driver. The
+// requests run in order, from UpdateState.
+func (w *Window) deferClipboard(f func(d mado.Driver)) {
+	w.clipFuncs.Lock()
+	w.clipFuncs.funcs = append(w.clipFuncs.funcs, f)
+	w.clipFuncs.Unlock()
+	w.Wakeup()
+}
+
+// DeliverClipboard completes the pending ReadClipboard calls with the
+// content of e, if e is clipboard text. Callbacks call it as soon as the
+// driver sends e, even while busy with another event, for the readers
+// waiting on the event loop.
+func (w *Window) DeliverClipboard(ev event.Event) {
+	e, ok := ev.(transfer.DataEvent)
+	if !ok || e.Type != "application/text" || e.Primary {
+		return
+	}
+	w.clipReads.Lock()
+	waiters := w.clipReads.waiters
+	w.clipReads.waiters = nil
+	w.clipReads.Unlock()
+	if len(waiters) == 0 {
+		return
+	}
+	r := e.Open()
+	data, _ := io.ReadAll(r)
+	r.Close()
+	for _, c := range waiters {
+		c <- string(data)
+	}
+}
+
 func (w *Window) UpdateCursor(d mado.Driver) {
 	if c := w.Queue.Cursor(); c != w.cursor {
 		w.cursor = c
@@ -1123,6 +1397,56 @@ func MinSize(w, h unit.Dp) mado.Option {
 	}
 }
 
+// Pos moves the window content area to the screen position (x, y), in
+// pixels. Wayland doesn't let clients position their windows and ignores it.
+func Pos(x, y int) mado.Option {
+	return func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Pos = image.Pt(x, y)
+	}
+}
+
+// AspectRatio constrains the window content area to the numer:denom width
+// to height ratio. AspectRatio(0, 0) removes the constraint.
+func AspectRatio(numer, denom int) mado.Option {
+	if numer < 0 || denom < 0 || (numer == 0) != (denom == 0) {
+		panic("aspect ratio must be positive")
+	}
+	return func(_ unit.Metric, cnf *mado.Config) {
+		cnf.AspectRatio = image.Pt(numer, denom)
+	}
+}
+
+// Opacity sets the opacity of the whole window, including its decorations.
+// The opacity must be in the range (0, 1], where 1 is fully opaque.
+func Opacity(opacity float32) mado.Option {
+	if !(opacity > 0 && opacity <= 1) {
+		panic("opacity must be in the range (0, 1]")
+	}
+	return func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Opacity = opacity
+	}
+}
+
+// Icon sets the window icon from a list of candidate images of different
+// sizes, such as 16x16, 32x32 and 48x48. The platform picks the sizes it
+// needs and rescales them if necessary. An empty list reverts to the default
+// icon. Icon copies the images; they may be modified afterwards.
+//
+// The window icon is supported on X11 and, if the compositor implements
+// xdg-toplevel-icon-v1, on Wayland.
+func Icon(images []image.Image) mado.Option {
+	icon := make([]*image.NRGBA, len(images))
+	for i, img := range images {
+		b := img.Bounds()
+		nrgba := image.NewNRGBA(image.Rectangle{Max: b.Size()})
+		draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+		icon[i] = nrgba
+	}
+	return func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Icon = icon
+	}
+}
+
 // StatusColor sets the color of the Android status bar.
 func StatusColor(color color.NRGBA) mado.Option {
 	return func(_ unit.Metric, cnf *mado.Config) {
@@ -1142,7 +1466,9 @@ func NavigationColor(color color.NRGBA) mado.Option {
 //
 // Caller must assume responsibility for rendering which includes
 // initializing the render backend, swapping the framebuffer and
-// handling frame pacing.
+// handling frame pacing. Programs that draw on the CPU may instead
+// present an image with the [mado.Framebuffer] of
+// [Window.NewFramebuffer].
 func CustomRenderer(custom bool) mado.Option {
 	return func(_ unit.Metric, cnf *mado.Config) {
 		cnf.CustomRenderer = custom
@@ -1158,6 +1484,43 @@ func Decorated(enabled bool) mado.Option {
 	}
 }
 
+// Transient makes the window a transient window of parent, such as a
+// dialog, that the platform keeps above parent. The parent must be open
+// when NextEvent is first called for the window.
+func Transient(parent *Window) mado.Option {
+	if parent == nil {
+		panic("nil parent window")
+	}
+	return func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Parent = parent
+		cnf.Popup = mado.NoPopup
+	}
+}
+
+// Popup makes the window an undecorated popup of parent, placed against
+// anchor. The anchor is in the pixel coordinates of the parent's pointer
+// events, and the window size is set by the Size option. The window
+// receives a [mado.DismissEvent] before it is destroyed by a click
+// outside it or by the closing of parent.
+//
+// Like for Transient, the parent must be open when NextEvent is first
+// called for the window. Popups are supported on X11 and Wayland.
+func Popup(parent *Window, kind mado.PopupKind, anchor image.Rectangle) mado.Option {
+	if parent == nil {
+		panic("nil parent window")
+	}
+	if kind == mado.NoPopup {
+		panic("invalid popup kind")
+	}
+	return func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Parent = parent
+		cnf.Popup = kind
+		cnf.Anchor = anchor
+		cnf.Decorated = false
+		cnf.DecoHeight = 0
+	}
+}
+
 // flushEvent is sent to detect when the user program
 // has completed processing of all prior events. Its an
 // [io/event.Event] but only for internal use.
@@ -1168,3 +1531,25 @@ func (t flushEvent) ImplementsEvent() {}
 // theFlushEvent avoids allocating garbage when sending
 // flushEvents.
 var theFlushEvent flushEvent
+
+// focusDirection returns the focus move triggered by a key press, or -1
+// if there is none. Arrow keys move the focus on mobile platforms only.
+func focusDirection(e key.Event, mobile bool) key.FocusDirection {
+	// The lock keys don't change the meaning of the keys.
+	mods := e.Modifiers.Held()
+	switch {
+	case e.Name == key.NameTab && mods == 0:
+		return key.FocusForward
+	case e.Name == key.NameTab && mods == key.ModShift:
+		return key.FocusBackward
+	case e.Name == key.NameUpArrow && mods == 0 && mobile:
+		return key.FocusUp
+	case e.Name == key.NameDownArrow && mods == 0 && mobile:
+		return key.FocusDown
+	case e.Name == key.NameLeftArrow && mods == 0 && mobile:
+		return key.FocusLeft
+	case e.Name == key.NameRightArrow && mods == 0 && mobile:
+		return key.FocusRight
+	}
+	return -1
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package app
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/key"
+)
+
+func TestFocusDirection(t *testing.T) {
+	locks := key.ModCapsLock | key.ModNumLock
+	tests := []struct {
+		name   key.Name
+		mods   key.Modifiers
+		mobile bool
+		want   key.FocusDirection
+	}{
+		{key.NameTab, 0, false, key.FocusForward},
+		{key.NameTab, key.ModShift, false, key.FocusBackward},
+		{key.NameTab, key.ModCtrl, false, -1},
+		// Lock keys don't prevent focus moves.
+		{key.NameTab, locks, false, key.FocusForward},
+		{key.NameTab, key.ModShift | key.ModCapsLock, false, key.FocusBackward},
+		{key.NameUpArrow, 0, false, -1},
+		{key.NameUpArrow, 0, true, key.FocusUp},
+		{key.NameLeftArrow, key.ModNumLock, true, key.FocusLeft},
+		{key.NameRightArrow, key.ModShift, true, -1},
+	}
+	for _, test := range tests {
+		e := key.Event{Name: test.name, Modifiers: test.mods, State: key.Press}
+		if got := focusDirection(e, test.mobile); got != test.want {
+			t.Errorf("focusDirection(%v %v, mobile %v) = %v, want %v", test.mods, test.name, test.mobile, got, test.want)
+		}
+	}
+}
+
+func TestWindowRegistry(t *testing.T) {
+	w1 := NewWindow(new(Callbacks))
+	w2 := NewWindow(new(Callbacks))
+	w1.register()
+	w2.register()
+	defer w1.unregister()
+	defer w2.unregister()
+	if got := Windows(); len(got) != 2 || got[0] != w1 || got[1] != w2 {
+		t.Fatalf("Windows() = %v, want [w1 w2]", got)
+	}
+	w1.setFocus(true)
+	// Gaining focus may be reported before the loss of focus.
+	w2.setFocus(true)
+	w1.setFocus(false)
+	if got := Focused(); got != w2 {
+		t.Errorf("Focused() = %p, want w2 %p", got, w2)
+	}
+	w2.unregister()
+	if got := Focused(); got != nil {
+		t.Errorf("Focused() = %p after destroy, want nil", got)
+	}
+	if got := Windows(); len(got) != 1 || got[0] != w1 {
+		t.Errorf("Windows() = %v after destroy, want [w1]", got)
+	}
+}
+
+// framebufferDriver is a driver whose framebuffers are images of size.
+type framebufferDriver struct {
+	mado.Driver
+	size image.Point
+}
+
+type fakeFramebuffer struct {
+	img *image.RGBA
+}
+
+func (d *framebufferDriver) NewFramebuffer() (mado.Framebuffer, error) {
+	return &fakeFramebuffer{img: image.NewRGBA(image.Rectangle{Max: d.size})}, nil
+}
+
+func (f *fakeFramebuffer) Image() *image.RGBA                     { return f.img }
+func (f *fakeFramebuffer) Present(dirty ...image.Rectangle) error { return nil }
+func (f *fakeFramebuffer) Release()                               {}
+
+func TestWindowFramebuffer(t *testing.T) {
+	c := new(Callbacks)
+	w := NewWindow(c)
+	c.d = &framebufferDriver{size: image.Pt(64, 48)}
+	type result struct {
+		fb  mado.Framebuffer
+		err error
+	}
+	res := make(chan result)
+	go func() {
+		fb, err := w.NewFramebuffer()
+		res <- result{fb, err}
+	}()
+	// Run the event loop until the framebuffer is created.
+	for {
+		select {
+		case r := <-res:
+			if r.err != nil {
+				t.Fatal(r.err)
+			}
+			if got, want := r.fb.Image().Rect.Size(), image.Pt(64, 48); got != want {
+				t.Errorf("framebuffer size %v, want %v", got, want)
+			}
+			return
+		default:
+		}
+		c.Event(mado.WakeupEvent{})
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestApplication(t *testing.T) {
+	a := NewApplication()
+	var (
+		windows []*Window
+		cbs     []*Callbacks
+	)
+	configs := make(map[*Window]int)
+	handle := func(w *Window, e event.Event) {
+		if _, ok := e.(mado.ConfigEvent); ok {
+			configs[w]++
+		}
+	}
+	for i := 0; i < 2; i++ {
+		c := new(Callbacks)
+		w := NewWindow(c)
+		// The test delivers the events of the platform.
+		w.EventState.Created = true
+		c.d = new(framebufferDriver)
+		a.run(w, handle)
+		windows = append(windows, w)
+		cbs = append(cbs, c)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Run()
+	}()
+	// Events of the second window go through while the first window
+	// has none.
+	for i := 0; i < 3; i++ {
+		cbs[1].Event(mado.ConfigEvent{})
+	}
+	cbs[0].Event(mado.ConfigEvent{})
+	for _, c := range cbs {
+		c.Event(mado.DestroyEvent{})
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after the windows were destroyed")
+	}
+	if got := []int{configs[windows[0]], configs[windows[1]]}; got[0] != 1 || got[1] != 3 {
+		t.Errorf("handled %v ConfigEvents, want [1 3]", got)
+	}
+}
@@ -7,6 +7,7 @@ package darwin
 import (
 	"errors"
 	"runtime"
+	"strings"
 
 	"unsafe"
 
@@ -35,6 +36,7 @@ __attribute__ ((visibility ("hidden"))) void gio_flushContextBuffer(CFTypeRef ct
 __attribute__ ((visibility ("hidden"))) void gio_clearCurrentContext(void);
 __attribute__ ((visibility ("hidden"))) void gio_lockContext(CFTypeRef ctxRef);
 __attribute__ ((visibility ("hidden"))) void gio_unlockContext(CFTypeRef ctxRef);
+__attribute__ ((visibility ("hidden"))) void *gio_getProcAddress(const char *procname);
 
 typedef void (*PFN_glFlush)(void);
 
@@ -312,6 +314,21 @@ func (c *glContext) SwapInterval(interval int) {
 	C.gio_swapInterval(c.ctx, C.int(interval))
 }
 
+func (c *glContext) GetProcAddress(procname string) unsafe.Pointer {
+	cname := C.CString(procname)
+	defer C.free(unsafe.Pointer(cname))
+	return C.gio_getProcAddress(cname)
+}
+
+func (c *glContext) ExtensionSupported(extension string) bool {
+	for _, ext := range strings.Fields(c.context.GetString(gl.EXTENSIONS)) {
+		if ext == extension {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *window) NewContext() (mado.Context, error) {
 	return newContext(w)
 }
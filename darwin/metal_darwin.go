@@ -7,6 +7,7 @@ package darwin
 import (
 	"errors"
 	"fmt"
+	"unsafe"
 
 	"github.com/kanryu/mado"
 	"github.com/kanryu/mado/gpu"
@@ -181,6 +182,12 @@ func (c *mtlContext) SwapBuffers() error {
 func (c *mtlContext) SwapInterval(interval int) {
 	fmt.Println("not implemented")
 }
+func (c *mtlContext) GetProcAddress(procname string) unsafe.Pointer {
+	return nil
+}
+func (c *mtlContext) ExtensionSupported(extension string) bool {
+	return false
+}
 func (w *window) NewContext() (mado.Context, error) {
 	return newMtlContext(w)
 }
@@ -18,6 +18,7 @@ import (
 
 	"github.com/kanryu/mado"
 	"github.com/kanryu/mado/internal/f32"
+	"github.com/kanryu/mado/io/input"
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/io/system"
@@ -47,6 +48,8 @@ __attribute__ ((visibility ("hidden"))) uint64_t mach_absolute_time(void);
 __attribute__ ((visibility ("hidden"))) void gio_main(void);
 __attribute__ ((visibility ("hidden"))) void gio_enablePollEvents(void);
 __attribute__ ((visibility ("hidden"))) void gio_PollEvents(void);
+__attribute__ ((visibility ("hidden"))) void gio_waitEvents(double timeout);
+__attribute__ ((visibility ("hidden"))) void gio_postEmptyEvent(void);
 __attribute__ ((visibility ("hidden"))) CFTypeRef gio_createView(void);
 __attribute__ ((visibility ("hidden"))) CFTypeRef gio_createWindow(CFTypeRef viewRef, CGFloat width, CGFloat height, CGFloat minWidth, CGFloat minHeight, CGFloat maxWidth, CGFloat maxHeight);
 
@@ -173,6 +176,16 @@ static void unhideWindow(CFTypeRef windowRef) {
 	[window deminiaturize:window];
 }
 
+static void getFrameExtents(CFTypeRef windowRef, CGFloat *left, CGFloat *top, CGFloat *right, CGFloat *bottom) {
+	NSWindow* window = (__bridge NSWindow *)windowRef;
+	NSRect frame = [window frame];
+	NSRect content = [window contentRectForFrameRect:frame];
+	*left = NSMinX(content) - NSMinX(frame);
+	*top = NSMaxY(frame) - NSMaxY(content);
+	*right = NSMaxX(frame) - NSMaxX(content);
+	*bottom = NSMinY(content) - NSMinY(frame);
+}
+
 static NSRect getScreenFrame(CFTypeRef windowRef) {
 	NSWindow* window = (__bridge NSWindow *)windowRef;
 	return [[window screen] frame];
@@ -193,6 +206,56 @@ static void zoomWindow(CFTypeRef windowRef) {
 	[window zoom:nil];
 }
 
+// warpCursor moves the cursor to (x, y) in view coordinates with the
+// origin in the upper left corner.
+static void warpCursor(CFTypeRef viewRef, CGFloat x, CGFloat y, bool associate) {
+	NSView *view = (__bridge NSView *)viewRef;
+	NSRect local = NSMakeRect(x, view.bounds.size.height - y - 1, 0, 0);
+	NSRect global = [view.window convertRectToScreen:[view convertRect:local toView:nil]];
+	// Quartz has its origin in the upper left corner of the main display.
+	CGFloat mainHeight = CGDisplayBounds(CGMainDisplayID()).size.height;
+	CGWarpMouseCursorPosition(CGPointMake(global.origin.x, mainHeight - global.origin.y - 1));
+	// Warping suppresses mouse events for a while, unless the cursor is
+	// associated again.
+	CGAssociateMouseAndMouseCursorPosition(associate);
+}
+
+static void associateCursor(bool associate) {
+	CGAssociateMouseAndMouseCursorPosition(associate);
+}
+
+// createCursor creates an NSCursor from non-premultiplied RGBA pixels.
+static CFTypeRef createCursor(const uint8_t *pix, int width, int height, int xhot, int yhot) {
+	@autoreleasepool {
+		NSBitmapImageRep *rep = [[NSBitmapImageRep alloc] initWithBitmapDataPlanes:NULL
+			pixelsWide:width
+			pixelsHigh:height
+			bitsPerSample:8
+			samplesPerPixel:4
+			hasAlpha:YES
+			isPlanar:NO
+			colorSpaceName:NSCalibratedRGBColorSpace
+			bitmapFormat:NSBitmapFormatAlphaNonpremultiplied
+			bytesPerRow:width*4
+			bitsPerPixel:32];
+		if (rep == nil) {
+			return NULL;
+		}
+		memcpy(rep.bitmapData, pix, width*height*4);
+		NSImage *img = [[NSImage alloc] initWithSize:NSMakeSize(width, height)];
+		[img addRepresentation:rep];
+		NSCursor *cursor = [[NSCursor alloc] initWithImage:img hotSpot:NSMakePoint(xhot, yhot)];
+		return CFBridgingRetain(cursor);
+	}
+}
+
+static void setCustomCursor(CFTypeRef cursorRef) {
+	@autoreleasepool {
+		NSCursor *cursor = (__bridge NSCursor *)cursorRef;
+		[cursor set];
+	}
+}
+
 static CFTypeRef layerForView(CFTypeRef viewRef) {
 	NSView *view = (__bridge NSView *)viewRef;
 	return (__bridge CFTypeRef)view.layer;
@@ -256,6 +319,8 @@ func InitDarwin() {
 	mado.OsNewWindow = newWindow
 	mado.EnablePollEvents = EnablePollEvents
 	mado.PollEvents = PollEvents
+	mado.WaitEvents = WaitEvents
+	mado.PostEmptyEvent = PostEmptyEvent
 	mado.GetTimerValue = GetTimerValue
 	mado.GetTimerFrequency = GetTimerFrequency
 }
@@ -283,6 +348,25 @@ type window struct {
 	redraw      chan struct{}
 	cursor      pointer.Cursor
 	pointerBtns pointer.Buttons
+	// shownCursor is the cursor currently displayed, which is hidden
+	// in the hidden and disabled input modes.
+	shownCursor pointer.Cursor
+	// customCursor is the NSCursor set by SetCustomCursor, or 0.
+	customCursor C.CFTypeRef
+	// customCursors caches the cursors created by SetCustomCursor.
+	customCursors map[*pointer.CustomCursor]C.CFTypeRef
+	// customShown tracks whether customCursor is displayed.
+	customShown bool
+
+	// inputMode is the cursor mode set by SetInputMode.
+	inputMode mado.InputMode
+	// focused tracks whether the window has keyboard focus.
+	focused bool
+	// lastPos is the most recent cursor position.
+	lastPos f32.Point
+	// virtualPos is the cursor position reported while the cursor is
+	// disabled.
+	virtualPos f32.Point
 
 	scale  float32
 	config mado.Config
@@ -328,7 +412,19 @@ func (w *window) contextView() C.CFTypeRef {
 	return w.view
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(mime string, primary bool) {
+	// Only text is supported, and there is no primary selection. Send
+	// empty responses on unsupported types.
+	if mime != "application/text" || primary {
+		w.w.Event(transfer.DataEvent{
+			Type: mime,
+			Open: func() io.ReadCloser {
+				return io.NopCloser(strings.NewReader(""))
+			},
+			Primary: primary,
+		})
+		return
+	}
 	cstr := C.readClipboard()
 	if cstr != 0 {
 		defer C.CFRelease(cstr)
@@ -342,12 +438,23 @@ func (w *window) ReadClipboard() {
 	})
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
-	cstr := stringToNSString(string(s))
-	defer C.CFRelease(cstr)
-	C.writeClipboard(cstr)
+func (w *window) WriteClipboard(content []input.ClipboardData) {
+	for _, c := range content {
+		if c.Type == "application/text" {
+			cstr := stringToNSString(string(c.Data))
+			defer C.CFRelease(cstr)
+			C.writeClipboard(cstr)
+			return
+		}
+	}
 }
 
+func (w *window) SetDragSource(types []string) {
+	// Dragging to other applications is not supported.
+}
+
+func (w *window) OfferDragData(mime string, data []byte) {}
+
 func (w *window) updateWindowMode() {
 	style := int(C.getWindowStyleMask(C.windowForView(w.view)))
 	if style&C.NSWindowStyleMaskFullScreen != 0 {
@@ -480,7 +587,138 @@ func (w *window) Perform(acts system.Action) {
 }
 
 func (w *window) SetCursor(cursor pointer.Cursor) {
-	w.cursor = windowSetCursor(w.cursor, cursor)
+	w.cursor = cursor
+	w.updateCursor()
+}
+
+func (w *window) updateCursor() {
+	c := w.cursor
+	hidden := false
+	switch w.inputMode.Cursor {
+	case mado.CursorModeHidden, mado.CursorModeDisabled:
+		c = pointer.CursorNone
+		hidden = true
+	}
+	if w.customCursor != 0 && !hidden {
+		if w.shownCursor == pointer.CursorNone {
+			C.gio_showCursor()
+		}
+		C.setCustomCursor(w.customCursor)
+		w.shownCursor = pointer.CursorDefault
+		w.customShown = true
+		return
+	}
+	if w.customShown {
+		w.customShown = false
+		if c != pointer.CursorNone {
+			// Replace the custom cursor, even if the cursor shape
+			// didn't change.
+			C.gio_setCursor(C.NSUInteger(macosCursorID[c]))
+			w.shownCursor = c
+			return
+		}
+	}
+	w.shownCursor = windowSetCursor(w.shownCursor, c)
+}
+
+func (w *window) SetCustomCursor(c *pointer.CustomCursor) {
+	w.customCursor = 0
+	if c != nil {
+		w.customCursor = w.loadCustomCursor(c)
+	}
+	w.updateCursor()
+}
+
+func (w *window) ReleaseCustomCursor(c *pointer.CustomCursor) {
+	ref, ok := w.customCursors[c]
+	if !ok {
+		return
+	}
+	delete(w.customCursors, c)
+	if ref == 0 {
+		return
+	}
+	if ref == w.customCursor {
+		w.SetCustomCursor(nil)
+	}
+	C.CFRelease(ref)
+}
+
+// loadCustomCursor returns the NSCursor for the image of c, creating it
+// on first use.
+func (w *window) loadCustomCursor(c *pointer.CustomCursor) C.CFTypeRef {
+	if ref, ok := w.customCursors[c]; ok {
+		return ref
+	}
+	var ref C.CFTypeRef
+	if size := c.Size(); size.X > 0 && size.Y > 0 {
+		// Pack the rows for NSBitmapImageRep.
+		img := c.Image
+		pix := make([]byte, 0, size.X*size.Y*4)
+		for y := 0; y < size.Y; y++ {
+			pix = append(pix, img.Pix[y*img.Stride:y*img.Stride+size.X*4]...)
+		}
+		ref = C.createCursor((*C.uint8_t)(&pix[0]), C.int(size.X), C.int(size.Y), C.int(c.Hotspot.X), C.int(c.Hotspot.Y))
+	}
+	if w.customCursors == nil {
+		w.customCursors = make(map[*pointer.CustomCursor]C.CFTypeRef)
+	}
+	w.customCursors[c] = ref
+	return ref
+}
+
+// SetInputMode implements the cursor modes. macOS can't confine the
+// cursor to a window, so the captured mode behaves like the normal mode.
+// Raw mouse motion is not supported.
+func (w *window) SetInputMode(mode mado.InputMode) {
+	disable := mode.Cursor == mado.CursorModeDisabled && w.inputMode.Cursor != mado.CursorModeDisabled
+	enable := mode.Cursor != mado.CursorModeDisabled && w.inputMode.Cursor == mado.CursorModeDisabled
+	w.inputMode = mode
+	switch {
+	case disable:
+		w.virtualPos = w.lastPos
+		if w.focused {
+			w.centerCursor()
+		}
+	case enable:
+		if w.focused {
+			w.warpCursor(w.virtualPos)
+		}
+	}
+	w.updateCursor()
+}
+
+func (w *window) SetCursorPos(pos f32.Point) {
+	if w.inputMode.Cursor == mado.CursorModeDisabled {
+		w.virtualPos = pos
+		return
+	}
+	if w.focused {
+		w.warpCursor(pos)
+	}
+}
+
+// warpCursor moves the cursor to pos, in pixels.
+func (w *window) warpCursor(pos f32.Point) {
+	associate := w.inputMode.Cursor != mado.CursorModeDisabled
+	C.warpCursor(w.view, C.CGFloat(pos.X/w.scale), C.CGFloat(pos.Y/w.scale), C.bool(associate))
+}
+
+func (w *window) centerCursor() {
+	w.warpCursor(f32.Point{
+		X: float32(C.viewWidth(w.view)) * w.scale / 2,
+		Y: float32(C.viewHeight(w.view)) * w.scale / 2,
+	})
+}
+
+// modifiers is like convertMods but includes the lock modifiers if
+// requested by the input mode. macOS has no num lock.
+func (w *window) modifiers(mods C.NSUInteger) key.Modifiers {
+	kmods := convertMods(mods)
+	if w.inputMode.LockKeyMods && mods&C.NSAlphaShiftKeyMask != 0 {
+		kmods |= key.ModCapsLock
+	}
+	return kmods
 }
 
 func (w *window) EditorStateChanged(old, new mado.EditorState) {
@@ -526,12 +764,12 @@ func (w *window) setStage(stage mado.Stage) {
 //export gio_onKeys
 func gio_onKeys(view, cstr C.CFTypeRef, keyCode C.UInt16, ti C.double, mods C.NSUInteger, keyDown C.bool) {
 	str := nsstringToString(cstr)
-	kmods := convertMods(mods)
+	w := mustView(view)
+	kmods := w.modifiers(mods)
 	ks := key.Release
 	if keyDown {
 		ks = key.Press
 	}
-	w := mustView(view)
 	for _, k := range str {
 		if n, ok := convertKey(k); ok {
 			w.w.Event(key.Event{
@@ -558,6 +796,17 @@ func gio_onMouse(view, evt C.CFTypeRef, cdir C.int, cbtn C.NSInteger, x, y, dx,
 	xf, yf := float32(x)*w.scale, float32(y)*w.scale
 	dxf, dyf := float32(dx)*w.scale, float32(dy)*w.scale
 	pos := f32.Point{X: xf, Y: yf}
+	disabled := w.inputMode.Cursor == mado.CursorModeDisabled
+	if disabled {
+		// The cursor is dissociated from the mouse; report the
+		// accumulated motion.
+		if cdir == C.MOUSE_MOVE {
+			w.virtualPos = w.virtualPos.Add(f32.Point{X: dxf, Y: dyf})
+		}
+		pos = w.virtualPos
+	} else {
+		w.lastPos = pos
+	}
 	var btn pointer.Buttons
 	switch cbtn {
 	case 0:
@@ -571,6 +820,9 @@ func gio_onMouse(view, evt C.CFTypeRef, cdir C.int, cbtn C.NSInteger, x, y, dx,
 	switch cdir {
 	case C.MOUSE_MOVE:
 		typ = pointer.Move
+		if disabled && dxf == 0 && dyf == 0 {
+			return
+		}
 	case C.MOUSE_UP:
 		typ = pointer.Release
 		w.pointerBtns &^= btn
@@ -578,7 +830,7 @@ func gio_onMouse(view, evt C.CFTypeRef, cdir C.int, cbtn C.NSInteger, x, y, dx,
 		typ = pointer.Press
 		w.pointerBtns |= btn
 		act, ok := w.w.ActionAt(pos)
-		if ok && w.config.Mode != mado.Fullscreen {
+		if ok && w.config.Mode != mado.Fullscreen && !disabled {
 			switch act {
 			case system.ActionMove:
 				C.performWindowDragWithEvent(C.windowForView(w.view), evt)
@@ -590,14 +842,18 @@ func gio_onMouse(view, evt C.CFTypeRef, cdir C.int, cbtn C.NSInteger, x, y, dx,
 	default:
 		panic("invalid direction")
 	}
+	var scroll f32.Point
+	if typ == pointer.Scroll {
+		scroll = f32.Point{X: dxf, Y: dyf}
+	}
 	w.w.Event(pointer.Event{
 		Kind:      typ,
 		Source:    pointer.Mouse,
 		Time:      t,
 		Buttons:   w.pointerBtns,
 		Position:  pos,
-		Scroll:    f32.Point{X: dxf, Y: dyf},
-		Modifiers: convertMods(mods),
+		Scroll:    scroll,
+		Modifiers: w.modifiers(mods),
 	})
 }
 
@@ -616,6 +872,14 @@ func gio_onDraw(view C.CFTypeRef) {
 //export gio_onFocus
 func gio_onFocus(view C.CFTypeRef, focus C.int) {
 	w := mustView(view)
+	w.focused = focus == 1
+	if w.inputMode.Cursor == mado.CursorModeDisabled {
+		// Give the cursor back while the window is inactive.
+		C.associateCursor(C.bool(!w.focused))
+		if w.focused {
+			w.centerCursor()
+		}
+	}
 	w.w.Event(key.FocusEvent{Focus: focus == 1})
 	if w.stage >= mado.StageInactive {
 		if focus == 0 {
@@ -886,6 +1150,20 @@ func (w *window) GetFrameBufferSize() image.Point {
 	return image.Pt(int(width), int(height))
 }
 
+func (w *window) NewFramebuffer() (mado.Framebuffer, error) {
+	return mado.NewContextFramebuffer(w)
+}
+
+func (w *window) GetFrameExtents() (left, top, right, bottom int) {
+	var l, t, r, b C.CGFloat
+	C.getFrameExtents(C.windowForView(w.view), &l, &t, &r, &b)
+	scale := float32(C.getViewBackingScale(w.view))
+	px := func(v C.CGFloat) int {
+		return int(float32(v)*scale + .5)
+	}
+	return px(l), px(t), px(r), px(b)
+}
+
 func configFor(scale float32) unit.Metric {
 	return unit.Metric{
 		PxPerDp: scale,
@@ -903,6 +1181,13 @@ func gio_onClose(view C.CFTypeRef) {
 	deleteView(view)
 	C.CFRelease(w.view)
 	w.view = 0
+	for _, ref := range w.customCursors {
+		if ref != 0 {
+			C.CFRelease(ref)
+		}
+	}
+	w.customCursors = nil
+	w.customCursor = 0
 }
 
 //export gio_onHide
@@ -1022,6 +1307,23 @@ func PollEvents() {
 	C.gio_PollEvents()
 }
 
+// WaitEvents blocks until an AppKit event arrives or the timeout
+// expires, and dispatches the event. A negative timeout waits
+// indefinitely.
+func WaitEvents(timeout time.Duration) {
+	secs := -1.0
+	if timeout >= 0 {
+		secs = timeout.Seconds()
+	}
+	C.gio_waitEvents(C.double(secs))
+}
+
+// PostEmptyEvent wakes up WaitEvents. It may be called from any
+// goroutine.
+func PostEmptyEvent() {
+	C.gio_postEmptyEvent()
+}
+
 func IsEnablePollEvents() bool {
 	return bool(C.gio_isEnablePollEvents())
 }
@@ -182,7 +182,7 @@ bool withPollEvents = false;
 			     dx:(CGFloat) dx
 				 dy:(CGFloat) dy {
 	NSPoint p = [self convertPoint:[event locationInWindow] fromView:nil];
-	if (!event.hasPreciseScrollingDeltas) {
+	if (typ == MOUSE_SCROLL && !event.hasPreciseScrollingDeltas) {
 		// dx and dy are in rows and columns.
 		dx *= 10;
 		dy *= 10;
@@ -220,17 +220,18 @@ bool withPollEvents = false;
 - (void)otherMouseUp:(NSEvent *)event {
 	[self handleMouse:event type:MOUSE_UP dx:0 dy:0];
 }
+// The motion deltas of move events are used while the cursor is disabled.
 - (void)mouseMoved:(NSEvent *)event {
-	[self handleMouse:event type:MOUSE_MOVE dx:0 dy:0];
+	[self handleMouse:event type:MOUSE_MOVE dx:event.deltaX dy:event.deltaY];
 }
 - (void)mouseDragged:(NSEvent *)event {
-	[self handleMouse:event type:MOUSE_MOVE dx:0 dy:0];
+	[self handleMouse:event type:MOUSE_MOVE dx:event.deltaX dy:event.deltaY];
 }
 - (void)rightMouseDragged:(NSEvent *)event {
-	[self handleMouse:event type:MOUSE_MOVE dx:0 dy:0];
+	[self handleMouse:event type:MOUSE_MOVE dx:event.deltaX dy:event.deltaY];
 }
 - (void)otherMouseDragged:(NSEvent *)event {
-	[self handleMouse:event type:MOUSE_MOVE dx:0 dy:0];
+	[self handleMouse:event type:MOUSE_MOVE dx:event.deltaX dy:event.deltaY];
 }
 - (void)scrollWheel:(NSEvent *)event {
 	CGFloat dx = -event.scrollingDeltaX;
@@ -599,3 +600,36 @@ void gio_PollEvents(void)
 
     } // autoreleasepool
 }
+
+void gio_waitEvents(double timeout)
+{
+    @autoreleasepool {
+
+    NSDate* date = timeout < 0 ? [NSDate distantFuture] : [NSDate dateWithTimeIntervalSinceNow:timeout];
+    NSEvent* event = [NSApp nextEventMatchingMask:NSEventMaskAny
+                                        untilDate:date
+                                           inMode:NSDefaultRunLoopMode
+                                          dequeue:YES];
+    if (event != nil)
+        [NSApp sendEvent:event];
+
+    } // autoreleasepool
+}
+
+void gio_postEmptyEvent(void)
+{
+    @autoreleasepool {
+
+    NSEvent* event = [NSEvent otherEventWithType:NSEventTypeApplicationDefined
+                                        location:NSMakePoint(0, 0)
+                                   modifierFlags:0
+                                       timestamp:0
+                                    windowNumber:0
+                                         context:nil
+                                         subtype:0
+                                           data1:0
+                                           data2:0];
+    [NSApp postEvent:event atStart:YES];
+
+    } // autoreleasepool
+}
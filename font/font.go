@@ -29,6 +29,10 @@ type Font struct {
 	Style Style
 	// Weight is the text weight.
 	Weight Weight
+	// Features enables or disables optional OpenType features of the font.
+	Features Features
+	// Variations sets the axes of variable fonts.
+	Variations Variations
 }
 
 // Face is an opaque handle to a typeface. The concrete implementation depends
@@ -72,6 +76,28 @@ type Face interface {
 //   - monospace
 type Typeface string
 
+// Features is a comma-separated list of OpenType feature settings, in the
+// syntax of the CSS font-feature-settings property. A setting is a feature
+// tag of four characters, optionally quoted and followed by a value. No
+// value or "on" enables the feature, 0 or "off" disables it, and greater
+// values select among alternates of the feature:
+//
+//	tnum, liga off, "ss01", salt 2
+//
+// Tabular numbers (tnum), slashed zero (zero) and proportional widths for
+// CJK text (palt) are common examples.
+type Features string
+
+// Variations is a comma-separated list of settings of variation axes, in
+// the syntax of the CSS font-variation-settings property. A setting is an
+// axis tag of four characters, optionally quoted, followed by a value in
+// the design units of the axis:
+//
+//	wght 650, wdth 80
+//
+// Axes not listed, and fonts without variations, keep their defaults.
+type Variations string
+
 const (
 	Regular Style = iota
 	Italic
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package mado
+
+import (
+	"image"
+
+	"github.com/kanryu/mado/gpu"
+)
+
+// Framebuffer is a CPU-side pixel buffer shown in a window. It lets
+// windows with CustomRenderer set draw their content in software, for
+// example in emulators, without a GPU context of their own.
+//
+// A Framebuffer must only be used from one goroutine at a time.
+type Framebuffer interface {
+	// Image returns the pixels of the next frame, sized to the
+	// GetFrameBufferSize of the window. The pixels are kept between
+	// frames until the window changes size, which clears them.
+	Image() *image.RGBA
+	// Present shows the image in the window. The dirty rectangles
+	// limit the upload to the regions of the image that changed since
+	// the last Present; without any, all of the image is uploaded.
+	Present(dirty ...image.Rectangle) error
+	// Release frees the resources of the framebuffer.
+	Release()
+}
+
+// contextFramebuffer is a Framebuffer that draws its image with a GPU
+// context, uploading the dirty regions to a texture.
+type contextFramebuffer struct {
+	d    Driver
+	ctx  Context
+	blit gpu.Blitter
+	img  *image.RGBA
+	// size is the size of the context surface.
+	size image.Point
+}
+
+// NewContextFramebuffer returns a Framebuffer for the window of d that
+// presents its image through a context from d.NewContext. It is the
+// fallback of drivers without a faster way to show CPU-side pixels.
+func NewContextFramebuffer(d Driver) (Framebuffer, error) {
+	ctx, err := d.NewContext()
+	if err != nil {
+		return nil, err
+	}
+	return &contextFramebuffer{d: d, ctx: ctx}, nil
+}
+
+func (f *contextFramebuffer) Image() *image.RGBA {
+	f.img = resizeFramebuffer(f.img, f.d.GetFrameBufferSize())
+	return f.img
+}
+
+func (f *contextFramebuffer) Present(dirty ...image.Rectangle) error {
+	img := f.Image()
+	if sz := img.Rect.Size(); sz != f.size {
+		if err := f.ctx.Refresh(); err != nil {
+			return err
+		}
+		f.size = sz
+		dirty = nil
+	}
+	if err := f.ctx.Lock(); err != nil {
+		return err
+	}
+	defer f.ctx.Unlock()
+	if f.blit == nil {
+		b, err := gpu.NewBlitter(f.ctx.API())
+		if err != nil {
+			return err
+		}
+		f.blit = b
+	}
+	target, err := f.ctx.RenderTarget()
+	if err != nil {
+		return err
+	}
+	if err := f.blit.Blit(img, dirty, target); err != nil {
+		return err
+	}
+	return f.ctx.Present()
+}
+
+func (f *contextFramebuffer) Release() {
+	if f.blit != nil {
+		if f.ctx.Lock() == nil {
+			f.blit.Release()
+			f.ctx.Unlock()
+		}
+		f.blit = nil
+	}
+	f.ctx.Release()
+	f.img = nil
+}
+
+// resizeFramebuffer returns img, or a new image if img is nil or not of
+// the given size.
+func resizeFramebuffer(img *image.RGBA, size image.Point) *image.RGBA {
+	if img != nil && img.Rect.Size() == size {
+		return img
+	}
+	return image.NewRGBA(image.Rectangle{Max: size})
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package mado
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"unsafe"
+
+	"github.com/kanryu/mado/gpu"
+)
+
+// cpuDriver is a Driver with a window of size whose context renders
+// into target.
+type cpuDriver struct {
+	Driver
+	size   image.Point
+	target *image.RGBA
+}
+
+type cpuContext struct {
+	d *cpuDriver
+}
+
+func (d *cpuDriver) GetFrameBufferSize() image.Point {
+	return d.size
+}
+
+func (d *cpuDriver) NewContext() (Context, error) {
+	return &cpuContext{d: d}, nil
+}
+
+func (c *cpuContext) API() gpu.API { return gpu.CPU{} }
+func (c *cpuContext) RenderTarget() (gpu.RenderTarget, error) {
+	return gpu.CPURenderTarget{Image: c.d.target}, nil
+}
+func (c *cpuContext) Present() error {
+	return nil
+}
+func (c *cpuContext) Refresh() error {
+	c.d.target = image.NewRGBA(image.Rectangle{Max: c.d.size})
+	return nil
+}
+func (c *cpuContext) Release()                                      {}
+func (c *cpuContext) Lock() error                                   { return nil }
+func (c *cpuContext) Unlock()                                       {}
+func (c *cpuContext) SwapBuffers() error                            { return nil }
+func (c *cpuContext) SwapInterval(interval int)                     {}
+func (c *cpuContext) GetProcAddress(procname string) unsafe.Pointer { return nil }
+func (c *cpuContext) ExtensionSupported(extension string) bool      { return false }
+
+func TestContextFramebuffer(t *testing.T) {
+	d := &cpuDriver{size: image.Pt(40, 30)}
+	fb, err := NewContextFramebuffer(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fb.Release()
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	img := fb.Image()
+	if got, want := img.Rect.Size(), d.size; got != want {
+		t.Fatalf("image size: got %v, want %v", got, want)
+	}
+	img.SetRGBA(1, 1, red)
+	img.SetRGBA(20, 20, red)
+	// The first present uploads all of the image.
+	if err := fb.Present(image.Rect(0, 0, 2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.target.RGBAAt(20, 20); got != red {
+		t.Errorf("pixel outside dirty rectangle of first present: got %v, want %v", got, red)
+	}
+	img.SetRGBA(1, 1, blue)
+	img.SetRGBA(20, 20, blue)
+	if err := fb.Present(image.Rect(0, 0, 2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.target.RGBAAt(1, 1); got != blue {
+		t.Errorf("dirty pixel: got %v, want %v", got, blue)
+	}
+	if got := d.target.RGBAAt(20, 20); got != red {
+		t.Errorf("clean pixel: got %v, want %v", got, red)
+	}
+	// A resize clears the image and presents all of it.
+	d.size = image.Pt(50, 30)
+	img = fb.Image()
+	if got, want := img.Rect.Size(), d.size; got != want {
+		t.Fatalf("resized image size: got %v, want %v", got, want)
+	}
+	img.SetRGBA(45, 5, red)
+	if err := fb.Present(image.Rect(0, 0, 1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.target.RGBAAt(45, 5); got != red {
+		t.Errorf("pixel after resize: got %v, want %v", got, red)
+	}
+}
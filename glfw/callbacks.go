@@ -2,7 +2,10 @@ package glfw
 
 import (
 	"image"
+	"io"
+	"net/url"
 	"runtime"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -30,6 +33,7 @@ type Callbacks struct {
 	PrevWindowSize      image.Point
 	PrevFramebufferSize image.Point
 	PrevCursorPos       f32.Point
+	PrevButtons         pointer.Buttons
 	PrevModifiers       key.Modifiers
 	Preedit             string
 	WaitEvents          []event.Event
@@ -61,6 +65,7 @@ func (c *Callbacks) Event(e event.Event) bool {
 	if c.D == nil {
 		panic("event while no driver active")
 	}
+	c.W.DeliverClipboard(e)
 	c.WaitEvents = append(c.WaitEvents, e)
 	if c.Busy {
 		return true
@@ -76,6 +81,7 @@ func (c *Callbacks) Event(e event.Event) bool {
 		// POST events to glfw callbacks
 		switch e2 := e.(type) {
 		case mado.ViewEvent:
+			c.Gw.view = e2
 			if c.WindowInitialized != nil {
 				close(c.WindowInitialized)
 			}
@@ -137,14 +143,28 @@ func (c *Callbacks) Event(e event.Event) bool {
 		case pointer.Event:
 			if e2.Kind == pointer.Scroll {
 				c.Gw.fScrollHolder(c.Gw, float64(e2.Scroll.X), float64(e2.Scroll.Y))
-			} else if c.PrevCursorPos != e2.Position {
+				break
+			}
+			if c.PrevCursorPos != e2.Position {
 				c.Gw.fCursorPosHolder(c.Gw, float64(e2.Position.X), float64(e2.Position.Y))
 				c.PrevCursorPos = e2.Position
-			} else {
-				c.Gw.fMouseButtonHolder(c.Gw, MouseButton(e2.Buttons), Action(e2.Kind), ModifierKey(e2.Modifiers))
+			}
+			if e2.Kind == pointer.Press || e2.Kind == pointer.Release {
+				c.mouseButtons(e2)
 			}
 		case pointer.CursorEnterEvent:
+			c.Gw.hovered = e2.Entered
 			c.Gw.fCursorEnterHolder(c.Gw, e2.Entered)
+		case input.DropEvent:
+			if e2.Type != "text/uri-list" {
+				break
+			}
+			r := e2.Open()
+			data, err := io.ReadAll(r)
+			r.Close()
+			if names := dropPaths(data); err == nil && len(names) > 0 {
+				c.Gw.fDropHolder(c.Gw, names)
+			}
 		case key.Event:
 			// IME with Preedit is confirmed by pressing Enter and POSTed as input token
 			if c.Preedit != "" && (e2.Name == key.NameReturn || e2.Name == key.NameEnter) {
@@ -152,7 +172,12 @@ func (c *Callbacks) Event(e event.Event) bool {
 				break
 			}
 			c.PrevModifiers = e2.Modifiers
-			c.Gw.fKeyHolder(c.Gw, Key(e2.KeyCode), 0, Action(e2.State), ModifierKey(e2.Modifiers))
+			action := Press
+			if e2.State == key.Release {
+				action = Release
+			}
+			c.Gw.inputKey(Key(e2.KeyCode), action)
+			c.Gw.fKeyHolder(c.Gw, Key(e2.KeyCode), 0, action, modifierKeys(e2.Modifiers))
 		case key.EditEvent:
 			if e2.Preedit {
 				c.Preedit = e2.Text
@@ -162,7 +187,7 @@ func (c *Callbacks) Event(e event.Event) bool {
 				c.W.ImeState = mado.EditorState{}
 			}
 			for _, r := range e2.Text {
-				c.Gw.fCharModsHolder(c.Gw, r, ModifierKey(c.PrevModifiers))
+				c.Gw.fCharModsHolder(c.Gw, r, modifierKeys(c.PrevModifiers))
 				if !e2.Preedit {
 					// [1] chars with IME off
 					// [2] Token whose input is confirmed (not preedit)
@@ -172,6 +197,7 @@ func (c *Callbacks) Event(e event.Event) bool {
 		}
 	}
 	c.Busy = false
+	pendingEvents.post()
 	select {
 	case <-c.W.Destroy:
 		return handled
@@ -209,6 +235,71 @@ func (c *Callbacks) SemanticRoot() input.SemanticID {
 	return c.W.Semantic.Root
 }
 
+// mouseButtons reports the buttons that changed state in e.
+func (c *Callbacks) mouseButtons(e pointer.Event) {
+	changed := e.Buttons ^ c.PrevButtons
+	c.PrevButtons = e.Buttons
+	for _, b := range [...]struct {
+		btn    pointer.Buttons
+		button MouseButton
+	}{
+		{pointer.ButtonPrimary, MouseButtonLeft},
+		{pointer.ButtonSecondary, MouseButtonRight},
+		{pointer.ButtonTertiary, MouseButtonMiddle},
+	} {
+		if changed&b.btn == 0 {
+			continue
+		}
+		action := Release
+		if e.Buttons&b.btn != 0 {
+			action = Press
+		}
+		c.Gw.inputMouseButton(b.button, action)
+		c.Gw.fMouseButtonHolder(c.Gw, b.button, action, modifierKeys(e.Modifiers))
+	}
+}
+
+// dropPaths returns the local file paths of a text/uri-list.
+func dropPaths(uriList []byte) []string {
+	var paths []string
+	for _, line := range strings.Split(string(uriList), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+		paths = append(paths, u.Path)
+	}
+	return paths
+}
+
+// modifierKeys converts key modifiers to their GLFW equivalents.
+func modifierKeys(m key.Modifiers) ModifierKey {
+	var mods ModifierKey
+	if m.Contain(key.ModShift) {
+		mods |= ModShift
+	}
+	if m.Contain(key.ModCtrl) {
+		mods |= ModControl
+	}
+	if m.Contain(key.ModAlt) {
+		mods |= ModAlt
+	}
+	if m.Contain(key.ModSuper) || m.Contain(key.ModCommand) {
+		mods |= ModSuper
+	}
+	if m.Contain(key.ModCapsLock) {
+		mods |= ModCapsLock
+	}
+	if m.Contain(key.ModNumLock) {
+		mods |= ModNumLock
+	}
+	return mods
+}
+
 // LookupSemantic looks up a semantic node from an ID. The zero ID denotes the root.
 func (c *Callbacks) LookupSemantic(semID input.SemanticID) (input.SemanticNode, bool) {
 	c.W.UpdateSemantics()
@@ -278,6 +369,20 @@ func (c *Callbacks) ActionAt(p f32.Point) (system.Action, bool) {
 	return c.W.Queue.ActionAt(p)
 }
 
+// DropTarget accepts the drops of the window targets, and file lists
+// anywhere for the drop callback.
+func (c *Callbacks) DropTarget(pos f32.Point, types []string) (string, bool) {
+	if t, ok := c.W.Queue.DropType(pos, types); ok {
+		return t, true
+	}
+	for _, t := range types {
+		if t == "text/uri-list" {
+			return t, true
+		}
+	}
+	return "", false
+}
+
 // waitForInitialized waits until mado.Driver is initialized.
 // It needs to poll some OS Events.
 func (c *Callbacks) waitForInitialized() {
@@ -1,7 +1,6 @@
 package glfw
 
 import (
-	"fmt"
 	"unsafe"
 )
 
@@ -17,6 +16,10 @@ func (w *Window) initContext() {
 }
 
 func (w *Window) MakeContextCurrent() {
+	if w.ctx == nil {
+		reportError(noWindowContext, "the window has no context")
+		panicError()
+	}
 	if err := w.ctx.Lock(); err != nil {
 		panic(err)
 	}
@@ -25,6 +28,9 @@ func (w *Window) MakeContextCurrent() {
 
 // DetachCurrentContext detaches the current context.
 func DetachCurrentContext() {
+	if theApp.Ctx == nil {
+		return
+	}
 	theApp.Ctx.Unlock()
 	panicError()
 }
@@ -38,6 +44,10 @@ func GetCurrentContext() *Window {
 // swap interval is greater than zero, the GPU driver waits the specified number
 // of screen updates before swapping the buffers.
 func (w *Window) SwapBuffers() {
+	if w.ctx == nil {
+		reportError(noWindowContext, "the window has no context")
+		panicError()
+	}
 	if err := w.ctx.SwapBuffers(); err != nil {
 		panic(err)
 	}
@@ -59,6 +69,10 @@ func (w *Window) SwapBuffers() {
 // Some GPU drivers do not honor the requested swap interval, either because of
 // user settings that override the request or due to bugs in the driver.
 func SwapInterval(interval int) {
+	if theApp.Ctx == nil {
+		reportError(noCurrentContext, "no context is current")
+		panicError()
+	}
 	theApp.Ctx.SwapInterval(interval)
 	panicError()
 }
@@ -72,9 +86,15 @@ func SwapInterval(interval int) {
 // The extension strings will not change during the lifetime of a context, so
 // there is no danger in doing this.
 func ExtensionSupported(extension string) bool {
-	fmt.Println("not implemented")
-	panicError()
-	return false
+	if theApp.Ctx == nil {
+		reportError(noCurrentContext, "cannot query extension without a current OpenGL or OpenGL ES context")
+		panicError()
+	}
+	if extension == "" {
+		reportError(invalidValue, "extension name cannot be an empty string")
+		panicError()
+	}
+	return theApp.Ctx.ExtensionSupported(extension)
 }
 
 // GetProcAddress returns the address of the specified OpenGL or OpenGL ES core
@@ -86,7 +106,9 @@ func ExtensionSupported(extension string) bool {
 // This function is used to provide GL proc resolving capabilities to an
 // external C library.
 func GetProcAddress(procname string) unsafe.Pointer {
-	fmt.Println("not implemented")
-	panicError()
-	return nil
+	if theApp.Ctx == nil {
+		reportError(noCurrentContext, "cannot query entry point without a current OpenGL or OpenGL ES context")
+		panicError()
+	}
+	return theApp.Ctx.GetProcAddress(procname)
 }
@@ -137,6 +137,19 @@ var lastError = make(chan *Error, 1)
 // 	}
 // }
 
+// reportError records an error for the next acceptError, like the GLFW
+// error callback does.
+func reportError(code ErrorCode, desc string) {
+	flushErrors()
+	err := &Error{code, desc}
+	select {
+	case lastError <- err:
+	default:
+		fmt.Fprintln(os.Stderr, "go-gl/glfw: internal error: an uncaught error has occurred:", err)
+		fmt.Fprintln(os.Stderr, "go-gl/glfw: Please report this in the Go package issue tracker.")
+	}
+}
+
 // Set the glfw callback internally
 func init() {
 	// C.glfwSetErrorCallbackCB()
@@ -2,11 +2,14 @@ package glfw
 
 import (
 	"fmt"
+	"math"
 	"sync"
+	"time"
 
 	"github.com/kanryu/mado"
 	"github.com/kanryu/mado/app"
 	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/gamepad"
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/op"
@@ -44,7 +47,6 @@ func NewApplication() *Application {
 		// Context:         ctx,
 		// Stop:            stop,
 		// Shutdown:        cancel,
-		fJoystickHolder: func(joy Joystick, event PeripheralEvent) {},
 	}
 }
 
@@ -150,6 +152,9 @@ func Init() error {
 // This function may only be called from the main thread.
 func Terminate() {
 	//theApp.Stop()
+	destroyCursors()
+	joysticks.Close()
+	joysticks = gamepad.NewSource()
 	flushErrors()
 }
 
@@ -164,6 +169,8 @@ func Terminate() {
 // This function may only be called from the main thread.
 func PollEvents() {
 	mado.PollEvents()
+	dispatchMonitorEvents()
+	dispatchJoystickEvents()
 	panicError()
 }
 
@@ -181,8 +188,8 @@ func PollEvents() {
 //
 // This function may only be called from the main thread.
 func WaitEvents() {
-	fmt.Println("not implemented")
-	panicError()
+	waitEvents(-1)
+	PollEvents()
 }
 
 // WaitEventsTimeout puts the calling thread to sleep until at least one event is available in the
@@ -208,8 +215,12 @@ func WaitEvents() {
 //
 // Event processing is not required for joystick input to work.
 func WaitEventsTimeout(timeout float64) {
-	fmt.Println("not implemented")
-	panicError()
+	if !(timeout >= 0 && timeout <= math.MaxInt64/float64(time.Second)) {
+		reportError(invalidValue, fmt.Sprintf("invalid time %f", timeout))
+		panicError()
+	}
+	waitEvents(time.Duration(timeout * float64(time.Second)))
+	PollEvents()
 }
 
 // PostEmptyEvent posts an empty event from the current thread to the main
@@ -220,10 +231,88 @@ func WaitEventsTimeout(timeout float64) {
 //
 // This function may be called from secondary threads.
 func PostEmptyEvent() {
-	fmt.Println("not implemented")
+	wakeEvents()
 	panicError()
 }
 
+// pendingEvents is posted when events are delivered to the glfw callbacks
+// or recorded for PollEvents, waking up WaitEvents.
+var pendingEvents = make(eventSignal, 1)
+
+// joystickPollInterval is how often WaitEvents checks for joystick
+// connection changes while a joystick callback is set. Joysticks are
+// polled, so they can't wake it up.
+const joystickPollInterval = 100 * time.Millisecond
+
+// eventSignal is a wakeup flag. Posting it never blocks.
+type eventSignal chan struct{}
+
+func (s eventSignal) post() {
+	select {
+	case s <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until s is posted or the timeout expires, and reports
+// whether s was posted. A negative timeout waits indefinitely.
+func (s eventSignal) wait(timeout time.Duration) bool {
+	if timeout < 0 {
+		<-s
+		return true
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-s:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// wakeEvents wakes up WaitEvents from any goroutine.
+func wakeEvents() {
+	pendingEvents.post()
+	if f := mado.PostEmptyEvent; f != nil {
+		f()
+	}
+}
+
+// waitEvents blocks until events are available or the timeout expires.
+// A negative timeout waits indefinitely.
+//
+// Where the platform pumps its events from PollEvents, it waits on the
+// platform queue. Elsewhere the windows deliver their events as they
+// arrive, from event loops that sleep on the display connection, and
+// waitEvents sleeps until they post pendingEvents.
+func waitEvents(timeout time.Duration) {
+	if f := mado.WaitEvents; f != nil {
+		f(timeout)
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		d := timeout
+		if timeout >= 0 {
+			d = max(time.Until(deadline), 0)
+		}
+		watchJoysticks := theApp != nil && theApp.fJoystickHolder != nil
+		if watchJoysticks && (d < 0 || d > joystickPollInterval) {
+			d = joystickPollInterval
+		}
+		if pendingEvents.wait(d) {
+			return
+		}
+		if watchJoysticks && pollJoysticks() {
+			return
+		}
+		if timeout >= 0 && !time.Now().Before(deadline) {
+			return
+		}
+	}
+}
+
 // InitHint function sets hints for the next initialization of GLFW.
 //
 // The values you set hints to are never reset by GLFW, but they only take
@@ -236,7 +325,14 @@ func PostEmptyEvent() {
 //
 // This function must only be called from the main thread.
 func InitHint(hint Hint, value int) {
-	fmt.Println("not implemented")
+	switch hint {
+	case JoystickHatButtons:
+		joystickState.mu.Lock()
+		joystickState.hatButtons = value == True
+		joystickState.mu.Unlock()
+	default:
+		fmt.Println("not implemented")
+	}
 }
 
 // GetVersion retrieves the major, minor and revision numbers of the GLFW
@@ -263,8 +359,12 @@ func GetVersionString() string {
 //
 // This function may only be called from the main thread.
 func GetClipboardString() string {
-	fmt.Println("not implemented")
-	return ""
+	if theApp.MainWindow == nil {
+		reportError(platformError, "no window to read the clipboard with")
+		panicError()
+		return ""
+	}
+	return theApp.MainWindow.GetClipboardString()
 }
 
 // SetClipboardString sets the system clipboard to the specified UTF-8 encoded
@@ -272,8 +372,27 @@ func GetClipboardString() string {
 //
 // This function may only be called from the main thread.
 func SetClipboardString(str string) {
-	fmt.Println("not implemented")
-	panicError()
+	if theApp.MainWindow == nil {
+		reportError(platformError, "no window to own the clipboard")
+		panicError()
+		return
+	}
+	theApp.MainWindow.SetClipboardString(str)
+}
+
+// clipboardTimeout is how long GetClipboardString waits for the
+// clipboard owner to deliver the content.
+var clipboardTimeout = time.Second
+
+// clipboardPollInterval is how long GetClipboardString waits for events
+// at a time, while it processes the events of the main thread.
+const clipboardPollInterval = 10 * time.Millisecond
+
+// onEventLoop reports whether the main thread runs the native event loop
+// of the windows, pumped by PollEvents. Drivers are then called directly
+// from the main thread, as nothing else would run their requests.
+func onEventLoop() bool {
+	return mado.WaitEvents != nil
 }
 
 // GetTime returns the value of the GLFW timer. Unless the timer has been set
@@ -0,0 +1,287 @@
+package glfw
+
+import (
+	"image"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/app"
+	"github.com/kanryu/mado/io/input"
+	"github.com/kanryu/mado/io/key"
+	"github.com/kanryu/mado/io/pointer"
+	"github.com/kanryu/mado/io/transfer"
+)
+
+// fakeDriver is a window driver without a window.
+type fakeDriver struct {
+	mado.Driver
+}
+
+func (fakeDriver) SetAnimating(anim bool)          {}
+func (fakeDriver) SetCursor(cursor pointer.Cursor) {}
+
+// newFakeWindow returns a window whose events are delivered by the test.
+func newFakeWindow() *Window {
+	c := new(Callbacks)
+	w := &Window{
+		data:      app.NewWindow(c),
+		callbacks: c,
+	}
+	c.SetGlfwWindow(w)
+	c.D = fakeDriver{}
+	return w
+}
+
+// clipboardDriver is a fake driver with a system clipboard. A nil
+// content is an unavailable clipboard.
+type clipboardDriver struct {
+	fakeDriver
+	c       *Callbacks
+	content *string
+}
+
+func (d *clipboardDriver) ReadClipboard(mime string, primary bool) {
+	if d.content == nil || primary {
+		return
+	}
+	s := *d.content
+	d.c.Event(transfer.DataEvent{
+		Type: "application/text",
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader(s))
+		},
+	})
+}
+
+func (d *clipboardDriver) WriteClipboard(content []input.ClipboardData) {
+	str := string(content[0].Data)
+	d.content = &str
+}
+
+// pumpEvents runs the event loop of w until done is closed.
+func pumpEvents(w *Window, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		w.callbacks.Event(mado.WakeupEvent{})
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// drainEvents discards the wakeups of earlier tests.
+func drainEvents() {
+	select {
+	case <-pendingEvents:
+	default:
+	}
+}
+
+func TestWaitEventsTimeout(t *testing.T) {
+	drainEvents()
+	const timeout = 10 * time.Millisecond
+	start := time.Now()
+	WaitEventsTimeout(timeout.Seconds())
+	if d := time.Since(start); d < timeout {
+		t.Errorf("WaitEventsTimeout returned after %v, want at least %v", d, timeout)
+	}
+}
+
+func TestPostEmptyEvent(t *testing.T) {
+	drainEvents()
+	go PostEmptyEvent()
+	// WaitEvents blocks the test if PostEmptyEvent doesn't wake it up.
+	WaitEvents()
+}
+
+func TestWaitEventsWindowEvent(t *testing.T) {
+	drainEvents()
+	w := newFakeWindow()
+	entered := false
+	w.SetCursorEnterCallback(func(w *Window, e bool) {
+		entered = e
+	})
+	go w.callbacks.Event(pointer.CursorEnterEvent{Entered: true})
+	WaitEvents()
+	if !entered {
+		t.Error("WaitEvents returned before the window event was delivered")
+	}
+}
+
+func TestWaitEventsMonitorEvent(t *testing.T) {
+	drainEvents()
+	var events []PeripheralEvent
+	// Set the callback without watching the monitors of the system.
+	fMonitorHolder = func(m *Monitor, e PeripheralEvent) {
+		events = append(events, e)
+	}
+	defer func() { fMonitorHolder = nil }()
+	go goMonitorCB(mado.MonitorEvent{Monitor: mado.Monitor{ID: 1}, Connected: true})
+	WaitEvents()
+	if len(events) != 1 || events[0] != Connected {
+		t.Errorf("monitor events = %v, want [Connected]", events)
+	}
+}
+
+func TestClipboardString(t *testing.T) {
+	w := newFakeWindow()
+	w.callbacks.D = &clipboardDriver{c: w.callbacks}
+	done := make(chan struct{})
+	var got string
+	go func() {
+		defer close(done)
+		w.SetClipboardString("clipboard text")
+		got = w.GetClipboardString()
+	}()
+	pumpEvents(w, done)
+	if want := "clipboard text"; got != want {
+		t.Errorf("GetClipboardString() = %q, want %q", got, want)
+	}
+}
+
+func TestClipboardStringUnavailable(t *testing.T) {
+	defer func(d time.Duration) { clipboardTimeout = d }(clipboardTimeout)
+	clipboardTimeout = 10 * time.Millisecond
+	w := newFakeWindow()
+	w.callbacks.D = &clipboardDriver{c: w.callbacks}
+	done := make(chan struct{})
+	got := "unchanged"
+	go func() {
+		defer close(done)
+		got = w.GetClipboardString()
+	}()
+	pumpEvents(w, done)
+	if got != "" {
+		t.Errorf("GetClipboardString() = %q for an unavailable clipboard, want \"\"", got)
+	}
+}
+
+// loopOnMainThread makes w behave as on platforms whose main thread runs
+// the event loop of the windows, and returns a function restoring the
+// platform.
+func loopOnMainThread(w *Window) func() {
+	wait := mado.WaitEvents
+	mado.WaitEvents = func(timeout time.Duration) {
+		w.callbacks.Event(mado.WakeupEvent{})
+	}
+	return func() { mado.WaitEvents = wait }
+}
+
+func TestClipboardStringOnEventLoop(t *testing.T) {
+	w := newFakeWindow()
+	w.callbacks.D = &clipboardDriver{c: w.callbacks}
+	defer loopOnMainThread(w)()
+	// Nothing runs the event loop but the calls below, so they block
+	// forever or time out if they wait for it.
+	w.SetClipboardString("first")
+	w.SetClipboardString("clipboard text")
+	if got, want := w.GetClipboardString(), "clipboard text"; got != want {
+		t.Errorf("GetClipboardString() = %q, want %q", got, want)
+	}
+	var got string
+	w.SetCharModsCallback(func(w *Window, char rune, mods ModifierKey) {})
+	w.SetCharCallback(func(w *Window, char rune) {
+		got = w.GetClipboardString()
+	})
+	w.callbacks.Event(key.EditEvent{Text: "v"})
+	if want := "clipboard text"; got != want {
+		t.Errorf("GetClipboardString() = %q from a callback, want %q", got, want)
+	}
+}
+
+// framebufferDriver is a fake driver whose framebuffers record their
+// presents.
+type framebufferDriver struct {
+	fakeDriver
+}
+
+type fakeFramebuffer struct {
+	presents int
+}
+
+func (framebufferDriver) NewFramebuffer() (mado.Framebuffer, error) {
+	return new(fakeFramebuffer), nil
+}
+
+func (f *fakeFramebuffer) Image() *image.RGBA                     { return image.NewRGBA(image.Rect(0, 0, 1, 1)) }
+func (f *fakeFramebuffer) Present(dirty ...image.Rectangle) error { f.presents++; return nil }
+func (f *fakeFramebuffer) Release()                               {}
+
+func TestNewFramebuffer(t *testing.T) {
+	w := newFakeWindow()
+	w.callbacks.D = framebufferDriver{}
+	done := make(chan struct{})
+	var (
+		fb  mado.Framebuffer
+		err error
+	)
+	go func() {
+		defer close(done)
+		fb, err = w.NewFramebuffer()
+	}()
+	pumpEvents(w, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fb.Present(); err != nil || fb.(*fakeFramebuffer).presents != 1 {
+		t.Errorf("Present() = %v, want the framebuffer of the driver presented", err)
+	}
+}
+
+func TestGetAttrib(t *testing.T) {
+	w := newFakeWindow()
+	w.ctxconfig = mado.CtxConfig{Client: OpenGLESAPI, Major: 3, Minor: 1}
+	w.SetCursorEnterCallback(func(w *Window, entered bool) {})
+	w.callbacks.Event(pointer.CursorEnterEvent{Entered: true})
+	tests := []struct {
+		attrib Hint
+		want   int
+	}{
+		{Hovered, True},
+		{Visible, True},
+		{Iconified, False},
+		{Floating, False},
+		{ClientAPI, OpenGLESAPI},
+		{ContextVersionMajor, 3},
+		{ContextVersionMinor, 1},
+	}
+	for _, test := range tests {
+		if got := w.GetAttrib(test.attrib); got != test.want {
+			t.Errorf("GetAttrib(0x%08X) = %d, want %d", int(test.attrib), got, test.want)
+		}
+	}
+}
+
+func TestSetSizeLimitsInvalid(t *testing.T) {
+	w := newFakeWindow()
+	defer func() {
+		if recover() == nil {
+			t.Error("SetSizeLimits accepted a maximum below the minimum")
+		}
+	}()
+	w.SetSizeLimits(200, 200, 100, 100)
+}
+
+func TestDropCallback(t *testing.T) {
+	w := newFakeWindow()
+	var got []string
+	w.SetDropCallback(func(w *Window, names []string) {
+		got = names
+	})
+	list := "# comment\r\nfile:///tmp/a%20b.txt\r\nhttps://example.com/c\r\nfile://localhost/tmp/d\r\n"
+	w.callbacks.Event(input.DropEvent{
+		Type: "text/uri-list",
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader(list))
+		},
+	})
+	if want := []string{"/tmp/a b.txt", "/tmp/d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dropped %q, want %q", got, want)
+	}
+}
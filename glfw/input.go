@@ -1,6 +1,14 @@
 package glfw
 
-import "image"
+import (
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/pointer"
+)
 
 // Joystick corresponds to a joystick.
 type Joystick int
@@ -263,8 +271,31 @@ const (
 	HandCursor      StandardCursor = 0x00036004
 	HResizeCursor   StandardCursor = 0x00036005
 	VResizeCursor   StandardCursor = 0x00036006
+	// The cursors added by GLFW 3.4.
+	ResizeNWSECursor StandardCursor = 0x00036007
+	ResizeNESWCursor StandardCursor = 0x00036008
+	ResizeAllCursor  StandardCursor = 0x00036009
+	NotAllowedCursor StandardCursor = 0x0003600A
+	// Aliases for the GLFW 3.4 names.
+	PointingHandCursor = HandCursor
+	ResizeEWCursor     = HResizeCursor
+	ResizeNSCursor     = VResizeCursor
 )
 
+// standardCursors maps the standard cursors to cursor shapes.
+var standardCursors = map[StandardCursor]pointer.Cursor{
+	ArrowCursor:      pointer.CursorDefault,
+	IBeamCursor:      pointer.CursorText,
+	CrosshairCursor:  pointer.CursorCrosshair,
+	HandCursor:       pointer.CursorPointer,
+	HResizeCursor:    pointer.CursorEastWestResize,
+	VResizeCursor:    pointer.CursorNorthSouthResize,
+	ResizeNWSECursor: pointer.CursorNorthWestSouthEastResize,
+	ResizeNESWCursor: pointer.CursorNorthEastSouthWestResize,
+	ResizeAllCursor:  pointer.CursorAllScroll,
+	NotAllowedCursor: pointer.CursorNotAllowed,
+}
+
 // Action corresponds to a key or button action.
 type Action int
 
@@ -297,15 +328,159 @@ const (
 	CursorCaptured int = 0x00034004
 )
 
-// TODO: implement these input functions
+// stick is the internal state of a released key or mouse button whose
+// press has not yet been observed by GetKey or GetMouseButton.
+const stick Action = 3
 
 // GetInputMode returns the value of an input option of the window.
 func (w *Window) GetInputMode(mode InputMode) int {
+	switch mode {
+	case CursorMode:
+		switch w.inputMode.Cursor {
+		case mado.CursorModeHidden:
+			return CursorHidden
+		case mado.CursorModeDisabled:
+			return CursorDisabled
+		case mado.CursorModeCaptured:
+			return CursorCaptured
+		default:
+			return CursorNormal
+		}
+	case StickyKeysMode:
+		return boolToInt(w.stickyKeys)
+	case StickyMouseButtonsMode:
+		return boolToInt(w.stickyMouseButtons)
+	case LockKeyMods:
+		return boolToInt(w.inputMode.LockKeyMods)
+	case RawMouseMotion:
+		return boolToInt(w.inputMode.RawMouseMotion)
+	}
+	reportError(invalidEnum, fmt.Sprintf("invalid input mode 0x%08X", int(mode)))
+	panicError()
 	return 0
 }
 
 // SetInputMode sets an input option for the window.
-func (w *Window) SetInputMode(mode InputMode, value int) {}
+func (w *Window) SetInputMode(mode InputMode, value int) {
+	m := w.inputMode
+	switch mode {
+	case CursorMode:
+		switch value {
+		case CursorNormal:
+			m.Cursor = mado.CursorModeNormal
+		case CursorHidden:
+			m.Cursor = mado.CursorModeHidden
+		case CursorDisabled:
+			m.Cursor = mado.CursorModeDisabled
+		case CursorCaptured:
+			m.Cursor = mado.CursorModeCaptured
+		default:
+			reportError(invalidEnum, fmt.Sprintf("invalid cursor mode 0x%08X", value))
+			panicError()
+			return
+		}
+	case StickyKeysMode:
+		w.stickyKeys = value != 0
+		if !w.stickyKeys {
+			// Release keys that were stuck.
+			for k, a := range w.keys {
+				if a == stick {
+					w.keys[k] = Release
+				}
+			}
+		}
+		return
+	case StickyMouseButtonsMode:
+		w.stickyMouseButtons = value != 0
+		if !w.stickyMouseButtons {
+			for b, a := range w.mouseButtons {
+				if a == stick {
+					w.mouseButtons[b] = Release
+				}
+			}
+		}
+		return
+	case LockKeyMods:
+		m.LockKeyMods = value != 0
+	case RawMouseMotion:
+		if !RawMouseMotionSupported() {
+			reportError(platformError, "raw mouse motion is not supported on this system")
+			panicError()
+			return
+		}
+		m.RawMouseMotion = value != 0
+	default:
+		reportError(invalidEnum, fmt.Sprintf("invalid input mode 0x%08X", int(mode)))
+		panicError()
+		return
+	}
+	if m == w.inputMode {
+		return
+	}
+	w.inputMode = m
+	w.data.SetInputMode(m)
+}
+
+// GetKey returns the last reported state of a keyboard key. The returned
+// state is one of Press or Release. The higher-level state Repeat is only
+// reported to the key callback.
+//
+// If the StickyKeysMode input mode is enabled, this function returns Press
+// the first time you call it for a key that was pressed, even if that key
+// has already been released.
+//
+// The key functions deal with physical keys, with key tokens named after
+// their use on the standard US keyboard layout. If you want to input text,
+// use the Unicode character callback instead.
+func (w *Window) GetKey(key Key) Action {
+	a, ok := w.keys[key]
+	if !ok {
+		return Release
+	}
+	if a == stick {
+		w.keys[key] = Release
+		return Press
+	}
+	return a
+}
+
+// GetMouseButton returns the last state reported for the specified mouse
+// button.
+//
+// If the StickyMouseButtonsMode input mode is enabled, this function returns
+// Press the first time you call it for a mouse button that has been pressed,
+// even if the mouse button has already been released.
+func (w *Window) GetMouseButton(button MouseButton) Action {
+	if button < MouseButton1 || button > MouseButtonLast {
+		reportError(invalidEnum, fmt.Sprintf("invalid mouse button %d", button))
+		panicError()
+		return Release
+	}
+	a := w.mouseButtons[button]
+	if a == stick {
+		w.mouseButtons[button] = Release
+		return Press
+	}
+	return a
+}
+
+// inputKey records the state of key for GetKey.
+func (w *Window) inputKey(key Key, action Action) {
+	if action == Release && w.stickyKeys && w.keys[key] == Press {
+		action = stick
+	} else if action == Repeat {
+		action = Press
+	}
+	w.keys[key] = action
+}
+
+// inputMouseButton records the state of button for GetMouseButton.
+func (w *Window) inputMouseButton(button MouseButton, action Action) {
+	if action == Release && w.stickyMouseButtons && w.mouseButtons[button] == Press {
+		action = stick
+	}
+	w.mouseButtons[button] = action
+}
 
 // RawMouseMotionSupported returns whether raw mouse motion is supported on the
 // current system. This status does not change after GLFW has been initialized
@@ -320,11 +495,16 @@ func (w *Window) SetInputMode(mode InputMode, value int) {}
 //
 // This function must only be called from the main thread.
 func RawMouseMotionSupported() bool {
-	return true
+	return mado.RawMouseMotionSupported != nil && mado.RawMouseMotionSupported()
 }
 
 // Cursor represents a cursor.
-type Cursor struct{}
+type Cursor struct {
+	// shape is the shape of a standard cursor.
+	shape pointer.Cursor
+	// custom is the image of a custom cursor, or nil.
+	custom *pointer.CustomCursor
+}
 
 // GetCursorPos returns the last reported position of the cursor.
 //
@@ -335,7 +515,8 @@ type Cursor struct{}
 // function. Casting directly to an integer type works for positive coordinates,
 // but fails for negative ones.
 func (w *Window) GetCursorPos() (x, y float64) {
-	return
+	pos := w.callbacks.PrevCursorPos
+	return float64(pos.X), float64(pos.Y)
 }
 
 // SetCursorPos sets the position of the cursor. The specified window must
@@ -344,7 +525,11 @@ func (w *Window) GetCursorPos() (x, y float64) {
 //
 // If the cursor is disabled (with CursorDisabled) then the cursor position is
 // unbounded and limited only by the minimum and maximum values of a double.
-func (w *Window) SetCursorPos(xpos, ypos float64) {}
+func (w *Window) SetCursorPos(xpos, ypos float64) {
+	pos := f32.Pt(float32(xpos), float32(ypos))
+	w.callbacks.PrevCursorPos = pos
+	w.data.SetCursorPos(pos)
+}
 
 // CreateCursor creates a new custom cursor image that can be set for a window with SetCursor.
 // The cursor can be destroyed with Destroy. Any remaining cursors are destroyed by Terminate.
@@ -358,22 +543,89 @@ func (w *Window) SetCursorPos(xpos, ypos float64) {}
 // The cursor hotspot is specified in pixels, relative to the upper-left corner of the cursor image.
 // Like all other coordinate systems in GLFW, the X-axis points to the right and the Y-axis points down.
 func CreateCursor(img image.Image, xhot, yhot int) *Cursor {
-	return &Cursor{}
+	if b := img.Bounds(); b.Empty() {
+		reportError(invalidValue, fmt.Sprintf("invalid image dimensions for cursor: %dx%d", b.Dx(), b.Dy()))
+		return nil
+	}
+	c := &Cursor{custom: pointer.NewCustomCursor(img, image.Pt(xhot, yhot))}
+	cursors.Lock()
+	defer cursors.Unlock()
+	if cursors.m == nil {
+		cursors.m = make(map[*Cursor]struct{})
+	}
+	cursors.m[c] = struct{}{}
+	return c
+}
+
+// cursors tracks the custom cursors for Terminate.
+var cursors struct {
+	sync.Mutex
+	m map[*Cursor]struct{}
 }
 
 // CreateStandardCursor returns a cursor with a standard shape,
 // that can be set for a window with SetCursor.
 func CreateStandardCursor(shape StandardCursor) *Cursor {
-	return &Cursor{}
+	c, ok := standardCursors[shape]
+	if !ok {
+		reportError(invalidEnum, fmt.Sprintf("invalid standard cursor 0x%08X", int(shape)))
+		return nil
+	}
+	return &Cursor{shape: c}
 }
 
 // Destroy destroys a cursor previously created with CreateCursor.
 // Any remaining cursors will be destroyed by Terminate.
-func (c *Cursor) Destroy() {}
+//
+// If the cursor is current for any window, that window will be reverted
+// to the default cursor.
+func (c *Cursor) Destroy() {
+	windows.l.Lock()
+	var all []*Window
+	for _, w := range windows.m {
+		all = append(all, w)
+	}
+	windows.l.Unlock()
+	for _, w := range all {
+		if w.cursor == c {
+			w.SetCursor(nil)
+		}
+		if c.custom != nil {
+			w.data.ReleaseCustomCursor(c.custom)
+		}
+	}
+	cursors.Lock()
+	delete(cursors.m, c)
+	cursors.Unlock()
+}
+
+// destroyCursors destroys the remaining custom cursors.
+func destroyCursors() {
+	cursors.Lock()
+	var all []*Cursor
+	for c := range cursors.m {
+		all = append(all, c)
+	}
+	cursors.Unlock()
+	for _, c := range all {
+		c.Destroy()
+	}
+}
 
 // SetCursor sets the cursor image to be used when the cursor is over the client area
 // of the specified window. The set cursor will only be visible when the cursor mode of the
 // window is CursorNormal.
 //
 // On some platforms, the set cursor may not be visible unless the window also has input focus.
-func (w *Window) SetCursor(c *Cursor) {}
+//
+// A nil cursor reverts the window to the default cursor.
+func (w *Window) SetCursor(c *Cursor) {
+	w.cursor = c
+	if c == nil {
+		c = &Cursor{shape: pointer.CursorDefault}
+	}
+	w.data.SetCustomCursor(c.custom)
+	if c.custom == nil {
+		w.data.SetCursor(c.shape)
+	}
+}
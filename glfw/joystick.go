@@ -1,14 +1,318 @@
-package glfw
-
-// JoystickCallback is the joystick configuration callback.
-type JoystickCallback func(joy Joystick, event PeripheralEvent)
-
-// SetJoystickCallback sets the joystick configuration callback, or removes the
-// currently set callback. This is called when a joystick is connected to or
-// disconnected from the system.
-func SetJoystickCallback(cbfun JoystickCallback) (previous JoystickCallback) {
-	previous = theApp.fJoystickHolder
-	theApp.fJoystickHolder = cbfun
-	panicError()
-	return previous
-}
+package glfw
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/kanryu/mado/io/gamepad"
+)
+
+// joysticks reads the joysticks of the system.
+var joysticks = gamepad.NewSource()
+
+// joystickState holds the state of the joystick functions.
+var joystickState struct {
+	mu sync.Mutex
+	// events are the connection changes not yet reported to the
+	// joystick callback.
+	events []gamepad.ConnectEvent
+	// users are the user pointers of the joysticks.
+	users [gamepad.MaxJoysticks]unsafe.Pointer
+	// hatButtons reports hats as buttons in GetButtons.
+	hatButtons bool
+}
+
+func init() {
+	joystickState.hatButtons = true
+}
+
+// JoystickCallback is the joystick configuration callback.
+type JoystickCallback func(joy Joystick, event PeripheralEvent)
+
+// SetJoystickCallback sets the joystick configuration callback, or removes the
+// currently set callback. This is called when a joystick is connected to or
+// disconnected from the system.
+func SetJoystickCallback(cbfun JoystickCallback) (previous JoystickCallback) {
+	previous = theApp.fJoystickHolder
+	theApp.fJoystickHolder = cbfun
+	panicError()
+	return previous
+}
+
+// pollJoysticks reads the pending joystick input and records the
+// connection changes for the joystick callback. It reports whether
+// any changes were recorded.
+func pollJoysticks() bool {
+	events := joysticks.Poll()
+	joystickState.mu.Lock()
+	defer joystickState.mu.Unlock()
+	changed := false
+	for _, e := range events {
+		e, ok := e.(gamepad.ConnectEvent)
+		if !ok {
+			continue
+		}
+		if !e.Connected {
+			joystickState.users[e.ID] = nil
+		}
+		joystickState.events = append(joystickState.events, e)
+		changed = true
+	}
+	return changed
+}
+
+// dispatchJoystickEvents calls the joystick callback for the recorded
+// connection changes.
+func dispatchJoystickEvents() {
+	pollJoysticks()
+	joystickState.mu.Lock()
+	events := joystickState.events
+	joystickState.events = nil
+	joystickState.mu.Unlock()
+	if theApp == nil {
+		return
+	}
+	for _, e := range events {
+		event := Connected
+		if !e.Connected {
+			event = Disconnected
+		}
+		if f := theApp.fJoystickHolder; f != nil {
+			f(Joystick(e.ID), event)
+		}
+	}
+}
+
+// joystick polls the joysticks and returns the state of joy.
+func (joy Joystick) joystick() (gamepad.Joystick, bool) {
+	if joy < 0 || joy > JoystickLast {
+		reportError(invalidEnum, "invalid joystick ID")
+		return gamepad.Joystick{}, false
+	}
+	pollJoysticks()
+	return joysticks.Joystick(gamepad.ID(joy))
+}
+
+// Present reports whether the specified joystick is present.
+//
+// There is no need to call this function before other functions that accept
+// a joystick ID, as they all check for presence before performing any other
+// work.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) Present() bool {
+	_, ok := joy.joystick()
+	panicError()
+	return ok
+}
+
+// GetAxes returns the values of all axes of the specified joystick. Each
+// element in the array is a value between -1.0 and 1.0.
+//
+// If the specified joystick is not present this function will return nil but
+// will not generate an error. This can be used instead of first calling
+// Present.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetAxes() []float32 {
+	j, ok := joy.joystick()
+	panicError()
+	if !ok {
+		return nil
+	}
+	return j.State.Axes
+}
+
+// GetButtons returns the state of all buttons of the specified joystick.
+//
+// For backward compatibility with earlier versions that did not have
+// GetHats, the button array also includes all hats, each represented as four
+// buttons. The hats are in the same order as returned by GetHats and are in
+// the order up, right, down and left. To disable these extra buttons, set the
+// JoystickHatButtons init hint before initialization.
+//
+// If the specified joystick is not present this function will return nil but
+// will not generate an error. This can be used instead of first calling
+// Present.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetButtons() []Action {
+	j, ok := joy.joystick()
+	panicError()
+	if !ok {
+		return nil
+	}
+	buttons := make([]Action, 0, len(j.State.Buttons)+4*len(j.State.Hats))
+	for _, b := range j.State.Buttons {
+		buttons = append(buttons, pressAction(b))
+	}
+	joystickState.mu.Lock()
+	hatButtons := joystickState.hatButtons
+	joystickState.mu.Unlock()
+	if hatButtons {
+		for _, h := range j.State.Hats {
+			for _, dir := range []gamepad.Hat{gamepad.HatUp, gamepad.HatRight, gamepad.HatDown, gamepad.HatLeft} {
+				buttons = append(buttons, pressAction(h&dir != 0))
+			}
+		}
+	}
+	return buttons
+}
+
+// GetHats returns the state of all hats of the specified joystick.
+//
+// If the specified joystick is not present this function will return nil but
+// will not generate an error. This can be used instead of first calling
+// Present.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetHats() []JoystickHatState {
+	j, ok := joy.joystick()
+	panicError()
+	if !ok {
+		return nil
+	}
+	hats := make([]JoystickHatState, len(j.State.Hats))
+	for i, h := range j.State.Hats {
+		hats[i] = JoystickHatState(h)
+	}
+	return hats
+}
+
+// GetName returns the name, encoded as UTF-8, of the specified joystick.
+//
+// If the specified joystick is not present this function will return the
+// empty string but will not generate an error.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetName() string {
+	j, _ := joy.joystick()
+	panicError()
+	return j.Name
+}
+
+// GetGUID returns the SDL compatible GUID, as a UTF-8 encoded
+// hexadecimal string, of the specified joystick.
+//
+// The GUID is what connects a joystick to a gamepad mapping. A connected
+// joystick will always have a GUID even if there is no gamepad mapping
+// assigned to it.
+//
+// If the specified joystick is not present this function will return the
+// empty string but will not generate an error.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetGUID() string {
+	j, _ := joy.joystick()
+	panicError()
+	return j.GUID
+}
+
+// SetUserPointer sets the user-defined pointer of the joystick. The current
+// value is retained until the joystick is disconnected. The initial value is
+// nil.
+//
+// This function may be called from the joystick callback, even for a
+// joystick that is being disconnected.
+func (joy Joystick) SetUserPointer(pointer unsafe.Pointer) {
+	if joy < 0 || joy > JoystickLast {
+		return
+	}
+	joystickState.mu.Lock()
+	defer joystickState.mu.Unlock()
+	joystickState.users[joy] = pointer
+}
+
+// GetUserPointer returns the current value of the user-defined pointer of the
+// joystick. The initial value is nil.
+//
+// This function may be called from the joystick callback, even for a
+// joystick that is being disconnected.
+func (joy Joystick) GetUserPointer() unsafe.Pointer {
+	if joy < 0 || joy > JoystickLast {
+		return nil
+	}
+	joystickState.mu.Lock()
+	defer joystickState.mu.Unlock()
+	return joystickState.users[joy]
+}
+
+// IsGamepad reports whether the specified joystick is both present and
+// has a gamepad mapping.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) IsGamepad() bool {
+	j, ok := joy.joystick()
+	panicError()
+	return ok && j.IsGamepad()
+}
+
+// GetGamepadName returns the human-readable name of the gamepad from the
+// gamepad mapping assigned to the specified joystick.
+//
+// If the specified joystick is not present or does not have a gamepad mapping
+// this function will return the empty string but will not generate an error.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetGamepadName() string {
+	j, _ := joy.joystick()
+	panicError()
+	return j.GamepadName()
+}
+
+// GetGamepadState retrieves the state of the specified joystick remapped
+// to an Xbox-like gamepad.
+//
+// If the specified joystick is not present or does not have a gamepad mapping
+// this function will return nil.
+//
+// The Guide button may not be available for input as it is often hooked by
+// the system or the Steam client.
+//
+// Not all devices have all the buttons or axes provided by GamepadState.
+// Unavailable buttons and axes will always report Release and 0.0
+// respectively.
+//
+// This function must only be called from the main thread.
+func (joy Joystick) GetGamepadState() *GamepadState {
+	j, _ := joy.joystick()
+	panicError()
+	g, ok := j.Gamepad()
+	if !ok {
+		return nil
+	}
+	state := new(GamepadState)
+	for i, b := range g.Buttons {
+		state.Buttons[i] = pressAction(b)
+	}
+	state.Axes = g.Axes
+	return state
+}
+
+// UpdateGamepadMappings parses the specified ASCII encoded string and
+// updates the internal list with any gamepad mappings it finds. This
+// string may contain either a single gamepad mapping or many mappings
+// separated by newlines. The parser supports the full format of the
+// gamecontrollerdb.txt source file including empty lines and comments.
+//
+// If there is already a gamepad mapping for a given GUID in the internal
+// list, it will be replaced by the one passed to this function. If the
+// library is terminated and re-initialized the internal list will revert to
+// the built-in default.
+//
+// This function must only be called from the main thread.
+func UpdateGamepadMappings(mapping string) bool {
+	err := joysticks.UpdateMappings(mapping)
+	if err != nil {
+		reportError(invalidValue, err.Error())
+	}
+	panicError()
+	return err == nil
+}
+
+func pressAction(pressed bool) Action {
+	if pressed {
+		return Press
+	}
+	return Release
+}
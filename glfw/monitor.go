@@ -1,9 +1,17 @@
 package glfw
 
-import "unsafe"
+import (
+	"image"
+	"sync"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+)
 
 // Monitor represents a monitor.
 type Monitor struct {
+	data mado.Monitor
+	user unsafe.Pointer
 }
 
 // PeripheralEvent corresponds to a peripheral(Monitor or Joystick)
@@ -35,49 +43,120 @@ type VidMode struct {
 
 var fMonitorHolder func(monitor *Monitor, event PeripheralEvent)
 
-//export goMonitorCB
-func goMonitorCB(monitor unsafe.Pointer, event int) {
-	//fMonitorHolder(&Monitor{(*C.GLFWmonitor)(monitor)}, PeripheralEvent(event))
+// monitors keeps a stable *Monitor for every connected monitor, so that
+// handles compare equal and user pointers survive between calls.
+var monitors struct {
+	mu   sync.Mutex
+	byID map[uint64]*Monitor
+	// events are the monitor changes not yet reported to the monitor
+	// callback.
+	events []mado.MonitorEvent
 }
 
-// GetMonitors returns a slice of handles for all currently connected monitors.
-func GetMonitors() []*Monitor {
-	var length int
+// lookupMonitor returns the handle of m, updated to the latest state.
+func lookupMonitor(m mado.Monitor) *Monitor {
+	monitors.mu.Lock()
+	defer monitors.mu.Unlock()
+	if monitors.byID == nil {
+		monitors.byID = make(map[uint64]*Monitor)
+	}
+	mon, ok := monitors.byID[m.ID]
+	if !ok {
+		mon = new(Monitor)
+		monitors.byID[m.ID] = mon
+	}
+	mon.data = m
+	return mon
+}
 
-	// mC := C.glfwGetMonitors((*C.int)(unsafe.Pointer(&length)))
-	// panicError()
-	// if mC == nil {
-	// 	return nil
-	// }
+func forgetMonitor(id uint64) {
+	monitors.mu.Lock()
+	defer monitors.mu.Unlock()
+	delete(monitors.byID, id)
+}
 
-	m := make([]*Monitor, length)
+// goMonitorCB records a monitor change. It is called from the
+// goroutines of the platform, so the monitor callback is called later,
+// by PollEvents or WaitEvents.
+func goMonitorCB(e mado.MonitorEvent) {
+	monitors.mu.Lock()
+	monitors.events = append(monitors.events, e)
+	monitors.mu.Unlock()
+	wakeEvents()
+}
 
-	// for i := 0; i < length; i++ {
-	// 	m[i] = &Monitor{C.GetMonitorAtIndex(mC, C.int(i))}
-	// }
+// dispatchMonitorEvents calls the monitor callback for the recorded
+// monitor changes.
+func dispatchMonitorEvents() {
+	monitors.mu.Lock()
+	events := monitors.events
+	monitors.events = nil
+	monitors.mu.Unlock()
+	for _, e := range events {
+		m := lookupMonitor(e.Monitor)
+		event := Connected
+		if !e.Connected {
+			event = Disconnected
+		}
+		if f := fMonitorHolder; f != nil {
+			f(m, event)
+		}
+		if !e.Connected {
+			forgetMonitor(e.Monitor.ID)
+		}
+	}
+}
 
+// GetMonitors returns a slice of handles for all currently connected monitors.
+func GetMonitors() []*Monitor {
+	if mado.GetMonitors == nil {
+		return nil
+	}
+	list := mado.GetMonitors()
+	m := make([]*Monitor, len(list))
+	for i, mon := range list {
+		m[i] = lookupMonitor(mon)
+	}
 	return m
 }
 
 // GetPrimaryMonitor returns the primary monitor. This is usually the monitor
 // where elements like the Windows task bar or the OS X menu bar is located.
 func GetPrimaryMonitor() *Monitor {
-	// m := C.glfwGetPrimaryMonitor()
-	// panicError()
-	// if m == nil {
-	// 	return nil
-	// }
-	// return &Monitor{m}
+	if mado.GetMonitors == nil {
+		return nil
+	}
+	list := mado.GetMonitors()
+	for _, mon := range list {
+		if mon.Primary {
+			return lookupMonitor(mon)
+		}
+	}
+	if len(list) > 0 {
+		return lookupMonitor(list[0])
+	}
 	return nil
 }
 
+// monitorAt returns the monitor showing the screen position p, or the
+// primary monitor if p is off screen.
+func monitorAt(p image.Point) *Monitor {
+	if mado.GetMonitors == nil {
+		return nil
+	}
+	for _, mon := range mado.GetMonitors() {
+		if p.In(mon.Bounds) {
+			return lookupMonitor(mon)
+		}
+	}
+	return GetPrimaryMonitor()
+}
+
 // GetPos returns the position, in screen coordinates, of the upper-left
 // corner of the monitor.
 func (m *Monitor) GetPos() (x, y int) {
-	var xpos, ypos int
-	// C.glfwGetMonitorPos(m.data, &xpos, &ypos)
-	panicError()
-	return int(xpos), int(ypos)
+	pos := m.data.Bounds.Min
+	return pos.X, pos.Y
 }
 
 // GetWorkarea returns the position, in screen coordinates, of the upper-left
@@ -89,10 +168,8 @@ func (m *Monitor) GetPos() (x, y int) {
 //
 // This function must only be called from the main thread.
 func (m *Monitor) GetWorkarea() (x, y, width, height int) {
-	var cX, cY, cWidth, cHeight int
-	// C.glfwGetMonitorWorkarea(m.data, &cX, &cY, &cWidth, &cHeight)
-	x, y, width, height = int(cX), int(cY), int(cWidth), int(cHeight)
-	return
+	r := m.data.WorkArea
+	return r.Min.X, r.Min.Y, r.Dx(), r.Dy()
 }
 
 // GetContentScale function retrieves the content scale for the specified monitor.
@@ -103,9 +180,7 @@ func (m *Monitor) GetWorkarea() (x, y, width, height int) {
 //
 // This function must only be called from the main thread.
 func (m *Monitor) GetContentScale() (float32, float32) {
-	var x, y float32
-	// C.glfwGetMonitorContentScale(m.data, &x, &y)
-	return float32(x), float32(y)
+	return m.data.Scale, m.data.Scale
 }
 
 // SetUserPointer sets the user-defined pointer of the monitor. The current value
@@ -116,7 +191,7 @@ func (m *Monitor) GetContentScale() (float32, float32) {
 //
 // This function may be called from any thread. Access is not synchronized.
 func (m *Monitor) SetUserPointer(pointer unsafe.Pointer) {
-	// C.glfwSetMonitorUserPointer(m.data, pointer)
+	m.user = pointer
 }
 
 // GetUserPointer returns the current value of the user-defined pointer of the
@@ -127,8 +202,7 @@ func (m *Monitor) SetUserPointer(pointer unsafe.Pointer) {
 //
 // This function may be called from any thread. Access is not synchronized.
 func (m *Monitor) GetUserPointer() unsafe.Pointer {
-	// return C.glfwGetMonitorUserPointer(m.data)
-	return nil
+	return m.user
 }
 
 // GetPhysicalSize returns the size, in millimetres, of the display area of the
@@ -138,21 +212,12 @@ func (m *Monitor) GetUserPointer() unsafe.Pointer {
 // because the monitor's EDID data is incorrect, or because the driver does not
 // report it accurately.
 func (m *Monitor) GetPhysicalSize() (width, height int) {
-	var wi, h int
-	// C.glfwGetMonitorPhysicalSize(m.data, &wi, &h)
-	panicError()
-	return int(wi), int(h)
+	return m.data.PhysicalSize.X, m.data.PhysicalSize.Y
 }
 
 // GetName returns a human-readable name of the monitor, encoded as UTF-8.
 func (m *Monitor) GetName() string {
-	// mn := C.glfwGetMonitorName(m.data)
-	// panicError()
-	// if mn == nil {
-	// 	return ""
-	// }
-	// return C.GoString(mn)
-	return ""
+	return m.data.Name
 }
 
 // MonitorCallback is the signature for monitor configuration callback
@@ -166,12 +231,14 @@ type MonitorCallback func(monitor *Monitor, event PeripheralEvent)
 // This function must only be called from the main thread.
 func SetMonitorCallback(cbfun MonitorCallback) MonitorCallback {
 	previous := fMonitorHolder
-	// fMonitorHolder = cbfun
-	// if cbfun == nil {
-	// 	C.glfwSetMonitorCallback(nil)
-	// } else {
-	// 	C.glfwSetMonitorCallbackCB()
-	// }
+	fMonitorHolder = cbfun
+	if mado.SetMonitorCallback != nil {
+		if cbfun == nil {
+			mado.SetMonitorCallback(nil)
+		} else {
+			mado.SetMonitorCallback(goMonitorCB)
+		}
+	}
 	return previous
 }
 
@@ -180,21 +247,10 @@ func SetMonitorCallback(cbfun MonitorCallback) MonitorCallback {
 // (the sum of all channel depths) and then by resolution area (the product of
 // width and height).
 func (m *Monitor) GetVideoModes() []*VidMode {
-	var length int
-
-	// vC := C.glfwGetVideoModes(m.data, (*C.int)(unsafe.Pointer(&length)))
-	// panicError()
-	// if vC == nil {
-	// 	return nil
-	// }
-
-	v := make([]*VidMode, length)
-
-	// for i := 0; i < length; i++ {
-	// 	t := C.GetVidmodeAtIndex(vC, C.int(i))
-	// 	v[i] = &VidMode{int(t.width), int(t.height), int(t.redBits), int(t.greenBits), int(t.blueBits), int(t.refreshRate)}
-	// }
-
+	v := make([]*VidMode, len(m.data.Modes))
+	for i, mode := range m.data.Modes {
+		v[i] = newVidMode(mode)
+	}
 	return v
 }
 
@@ -202,13 +258,14 @@ func (m *Monitor) GetVideoModes() []*VidMode {
 // are using a full screen window, the return value will therefore depend on
 // whether it is focused.
 func (m *Monitor) GetVideoMode() *VidMode {
-	// t := C.glfwGetVideoMode(m.data)
-	// if t == nil {
-	// 	return nil
-	// }
-	// panicError()
-	// return &VidMode{int(t.width), int(t.height), int(t.redBits), int(t.greenBits), int(t.blueBits), int(t.refreshRate)}
-	return nil
+	if m.data.Mode == (mado.VideoMode{}) {
+		return nil
+	}
+	return newVidMode(m.data.Mode)
+}
+
+func newVidMode(m mado.VideoMode) *VidMode {
+	return &VidMode{m.Size.X, m.Size.Y, m.RedBits, m.GreenBits, m.BlueBits, m.RefreshRate}
 }
 
 // SetGamma generates a 256-element gamma ramp from the specified exponent and then calls
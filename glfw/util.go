@@ -34,3 +34,10 @@ package glfw
 // 	r.pixels = (*C.uchar)(pix)
 // 	return r, free
 // }
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,6 +1,9 @@
 package glfw
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
 	"unsafe"
 )
 
@@ -11,7 +14,7 @@ import (
 // extensions necessary for Vulkan surface creation are available and GetPhysicalDevicePresentationSupport
 // to check whether a queue family of a physical device supports image presentation.
 func VulkanSupported() bool {
-	return true
+	return vulkanGetInstanceProcAddr() != nil
 }
 
 // GetVulkanGetInstanceProcAddress returns the function pointer used to find Vulkan core or
@@ -19,7 +22,7 @@ func VulkanSupported() bool {
 //
 // Note that this function does not work the same way as the glfwGetInstanceProcAddress.
 func GetVulkanGetInstanceProcAddress() unsafe.Pointer {
-	return nil
+	return vulkanGetInstanceProcAddr()
 }
 
 // GetRequiredInstanceExtensions returns a slice of Vulkan instance extension names required
@@ -33,10 +36,23 @@ func GetVulkanGetInstanceProcAddress() unsafe.Pointer {
 // If Vulkan is available but no set of extensions allowing window surface creation was found, this
 // function returns nil. You may still use Vulkan for off-screen rendering and compute work.
 func (window *Window) GetRequiredInstanceExtensions() []string {
-	return nil
+	return vulkanInstanceExtensions(window.view)
 }
 
-// CreateWindowSurface creates a Vulkan surface for this window.
+// CreateWindowSurface creates a Vulkan surface for this window. The instance
+// is a VkInstance handle, such as a vulkan-go vk.Instance, created with the
+// extensions returned by GetRequiredInstanceExtensions. The optional
+// allocCallbacks points to the VkAllocationCallbacks for the surface. The
+// returned surface is a VkSurfaceKHR handle, which must be destroyed with
+// vkDestroySurfaceKHR before the window is destroyed.
 func (window *Window) CreateWindowSurface(instance interface{}, allocCallbacks unsafe.Pointer) (surface uintptr, err error) {
-	return
+	if instance == nil {
+		return 0, errors.New("vulkan: instance is nil")
+	}
+	val := reflect.ValueOf(instance)
+	if k := val.Kind(); k != reflect.Ptr && k != reflect.UnsafePointer {
+		return 0, fmt.Errorf("vulkan: instance is not a VkInstance (expected kind Ptr, got %s)", k)
+	}
+	surf, err := createVulkanSurface(window.view, val.UnsafePointer(), allocCallbacks)
+	return uintptr(surf), err
 }
@@ -0,0 +1,24 @@
+//go:build (!linux || android || novulkan) && (!freebsd || novulkan)
+// +build !linux android novulkan
+// +build !freebsd novulkan
+
+package glfw
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+)
+
+func vulkanGetInstanceProcAddr() unsafe.Pointer {
+	return nil
+}
+
+func vulkanInstanceExtensions(view mado.ViewEvent) []string {
+	return nil
+}
+
+func createVulkanSurface(view mado.ViewEvent, inst, alloc unsafe.Pointer) (uint64, error) {
+	return 0, errors.New("vulkan: not supported")
+}
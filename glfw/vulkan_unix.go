@@ -0,0 +1,24 @@
+//go:build ((linux && !android) || freebsd) && !novulkan
+// +build linux,!android freebsd
+// +build !novulkan
+
+package glfw
+
+import (
+	"unsafe"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/unix"
+)
+
+func vulkanGetInstanceProcAddr() unsafe.Pointer {
+	return unix.VulkanGetInstanceProcAddr()
+}
+
+func vulkanInstanceExtensions(view mado.ViewEvent) []string {
+	return unix.VulkanInstanceExtensions(view)
+}
+
+func createVulkanSurface(view mado.ViewEvent, inst, alloc unsafe.Pointer) (uint64, error) {
+	return unix.CreateVulkanSurface(view, inst, alloc)
+}
@@ -3,15 +3,13 @@ package glfw
 import (
 	"fmt"
 	"image"
-	"io"
-	"strings"
+	"math"
 	"sync"
 	"unsafe"
 
 	"github.com/kanryu/mado"
 	"github.com/kanryu/mado/app"
-	"github.com/kanryu/mado/io/clipboard"
-	"github.com/kanryu/mado/io/key"
+	"github.com/kanryu/mado/io/input"
 	"github.com/kanryu/mado/io/system"
 	"github.com/kanryu/mado/unit"
 )
@@ -332,9 +330,25 @@ type Window struct {
 	callbacks *Callbacks
 	pointer   unsafe.Pointer
 	ctx       mado.Context
+	// view holds the native window handles of the last ViewEvent.
+	view mado.ViewEvent
+	// ctxconfig are the context hints the window was created with.
+	ctxconfig mado.CtxConfig
+	// hovered tracks whether the cursor is over the window.
+	hovered bool
 
 	shouldClose bool
 
+	// Input modes and the key and mouse button states for GetKey and
+	// GetMouseButton.
+	inputMode          mado.InputMode
+	stickyKeys         bool
+	stickyMouseButtons bool
+	keys               map[Key]Action
+	mouseButtons       [MouseButtonLast + 1]Action
+	// cursor is the cursor set by SetCursor, or nil.
+	cursor *Cursor
+
 	// Window.
 	fPosHolder             func(w *Window, xpos int, ypos int)
 	fSizeHolder            func(w *Window, width int, height int)
@@ -421,6 +435,8 @@ func CreateWindow(width, height int, title string, monitor *Monitor, share *Wind
 		App:                    theApp,
 		data:                   w,
 		callbacks:              c,
+		ctxconfig:              mado.GlfwConfig.Hints.Context,
+		keys:                   make(map[Key]Action),
 		fPosHolder:             func(w *Window, xpos int, ypos int) {},
 		fSizeHolder:            func(w *Window, width int, height int) {},
 		fFramebufferSizeHolder: func(w *Window, width int, height int) {},
@@ -461,7 +477,11 @@ func CreateWindow(width, height int, title string, monitor *Monitor, share *Wind
 	c.SetGlfwWindow(wnd)
 	theApp.appendWindow(wnd)
 	c.waitForInitialized()
-	wnd.initContext()
+	// Vulkan and other external renderers ask for windows without a
+	// context, because it would compete with them for the window.
+	if mado.GlfwConfig.Hints.Context.Client != NoAPI {
+		wnd.initContext()
+	}
 	return wnd, nil
 }
 
@@ -511,17 +531,16 @@ func (w *Window) SetTitle(title string) {
 // The desired image sizes varies depending on platform and system settings. The selected
 // images will be rescaled as needed. Good sizes include 16x16, 32x32 and 48x48.
 func (w *Window) SetIcon(images []image.Image) {
-	fmt.Println("not implemented")
-
+	w.data.Option(app.Icon(images))
 	panicError()
 }
 
 // GetPos returns the position, in screen coordinates, of the upper-left
 // corner of the client area of the window.
 func (w *Window) GetPos() (x, y int) {
-	fmt.Println("not implemented")
+	pos := w.data.EffectiveConfig().Pos
 	panicError()
-	return int(0), int(0)
+	return pos.X, pos.Y
 }
 
 // SetPos sets the position, in screen coordinates, of the upper-left corner
@@ -539,16 +558,16 @@ func (w *Window) GetPos() (x, y int) {
 //
 // This function may only be called from the main thread.
 func (w *Window) SetPos(xpos, ypos int) {
-	fmt.Println("not implemented")
-	w.data.Perform(system.ActionCenter)
+	w.data.Option(app.Pos(xpos, ypos))
 	panicError()
 }
 
 // GetSize returns the size, in screen coordinates, of the client area of the
 // specified window.
 func (w *Window) GetSize() (width, height int) {
-	fmt.Println("not implemented")
-	return 800, 600
+	size := w.data.EffectiveConfig().Size
+	panicError()
+	return size.X, size.Y
 }
 
 // SetSize sets the size, in screen coordinates, of the client area of the
@@ -562,8 +581,14 @@ func (w *Window) GetSize() (width, height int) {
 //
 // This function may only be called from the main thread.
 func (w *Window) SetSize(width, height int) {
-	fmt.Println("not implemented")
-	w.data.Perform(system.ActionCenter)
+	if width <= 0 || height <= 0 {
+		reportError(invalidValue, fmt.Sprintf("invalid window size %dx%d", width, height))
+		panicError()
+		return
+	}
+	w.data.Option(func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Size = image.Pt(width, height)
+	})
 	panicError()
 }
 
@@ -571,8 +596,27 @@ func (w *Window) SetSize(width, height int) {
 // If the window is full screen or not resizable, this function does nothing.
 //
 // The size limits are applied immediately and may cause the window to be resized.
+//
+// The minimum or the maximum size is disabled by setting its width or height
+// to glfw.DontCare.
 func (w *Window) SetSizeLimits(minw, minh, maxw, maxh int) {
-	fmt.Println("not implemented")
+	var min, max image.Point
+	if minw != DontCare && minh != DontCare {
+		min = image.Pt(minw, minh)
+	}
+	if maxw != DontCare && maxh != DontCare {
+		max = image.Pt(maxw, maxh)
+	}
+	if min.X < 0 || min.Y < 0 || max.X < 0 || max.Y < 0 ||
+		max != (image.Point{}) && (max.X < min.X || max.Y < min.Y) {
+		reportError(invalidValue, fmt.Sprintf("invalid window size limits %dx%d to %dx%d", minw, minh, maxw, maxh))
+		panicError()
+		return
+	}
+	w.data.Option(func(_ unit.Metric, cnf *mado.Config) {
+		cnf.MinSize = min
+		cnf.MaxSize = max
+	})
 	panicError()
 }
 
@@ -586,7 +630,14 @@ func (w *Window) SetSizeLimits(minw, minh, maxw, maxh int) {
 //
 // The aspect ratio is applied immediately and may cause the window to be resized.
 func (w *Window) SetAspectRatio(numer, denom int) {
-	fmt.Println("not implemented")
+	if numer == DontCare || denom == DontCare {
+		numer, denom = 0, 0
+	} else if numer <= 0 || denom <= 0 {
+		reportError(invalidValue, fmt.Sprintf("invalid window aspect ratio %d:%d", numer, denom))
+		panicError()
+		return
+	}
+	w.data.Option(app.AspectRatio(numer, denom))
 	panicError()
 }
 
@@ -598,6 +649,18 @@ func (w *Window) GetFramebufferSize() (width, height int) {
 	return size.X, size.Y
 }
 
+// NewFramebuffer returns a pixel buffer the size of the framebuffer of the
+// window, for drawing the window on the CPU instead of with a context. The
+// window is expected to be created with the ClientAPI hint set to NoAPI.
+//
+// This function may only be called from the main thread.
+func (w *Window) NewFramebuffer() (mado.Framebuffer, error) {
+	if onEventLoop() {
+		return w.callbacks.D.NewFramebuffer()
+	}
+	return w.data.NewFramebuffer()
+}
+
 // GetFrameSize retrieves the size, in screen coordinates, of each edge of the frame
 // of the specified window. This size includes the title bar, if the window has one.
 // The size of the frame may vary depending on the window-related hints used to create it.
@@ -605,9 +668,9 @@ func (w *Window) GetFramebufferSize() (width, height int) {
 // Because this function retrieves the size of each window frame edge and not the offset
 // along a particular coordinate axis, the retrieved values will always be zero or positive.
 func (w *Window) GetFrameSize() (left, top, right, bottom int) {
-	fmt.Println("not implemented")
+	left, top, right, bottom = w.callbacks.D.GetFrameExtents()
 	panicError()
-	return 0, 0, 800, 600
+	return left, top, right, bottom
 }
 
 // GetContentScale function retrieves the content scale for the specified
@@ -618,8 +681,14 @@ func (w *Window) GetFrameSize() (left, top, right, bottom int) {
 //
 // This function may only be called from the main thread.
 func (w *Window) GetContentScale() (float32, float32) {
-	fmt.Println("not implemented")
-	return 1.0, 1.0
+	if s := w.callbacks.PrevScaling; s.X > 0 && s.Y > 0 {
+		return s.X, s.Y
+	}
+	// The window has no scale of its own until it is shown on a monitor.
+	if s := w.data.Metric.PxPerDp; s > 0 {
+		return s, s
+	}
+	return 1, 1
 }
 
 // GetOpacity function returns the opacity of the window, including any
@@ -633,8 +702,12 @@ func (w *Window) GetContentScale() (float32, float32) {
 //
 // This function may only be called from the main thread.
 func (w *Window) GetOpacity() float32 {
-	fmt.Println("not implemented")
-	return 1.0
+	opacity := w.data.EffectiveConfig().Opacity
+	if opacity == 0 {
+		// The zero value means opaque.
+		opacity = 1
+	}
+	return opacity
 }
 
 // SetOpacity function sets the opacity of the window, including any
@@ -648,7 +721,14 @@ func (w *Window) GetOpacity() float32 {
 //
 // This function may only be called from the main thread.
 func (w *Window) SetOpacity(opacity float32) {
-	fmt.Println("not implemented")
+	if !(opacity >= 0 && opacity <= 1) {
+		reportError(invalidValue, fmt.Sprintf("invalid window opacity %f", opacity))
+		panicError()
+		return
+	}
+	// Fully transparent is the smallest opacity the option accepts.
+	w.data.Option(app.Opacity(max(opacity, math.SmallestNonzeroFloat32)))
+	panicError()
 }
 
 // RequestWindowAttention funciton requests user attention to the specified
@@ -660,7 +740,7 @@ func (w *Window) SetOpacity(opacity float32) {
 //
 // This function must only be called from the main thread.
 func (w *Window) RequestAttention() {
-	fmt.Println("not implemented")
+	w.data.Perform(system.ActionRequestAttention)
 }
 
 // Focus brings the specified window to front and sets input focus.
@@ -672,7 +752,7 @@ func (w *Window) RequestAttention() {
 // Do not use this function to steal focus from other applications unless you are certain that
 // is what the user wants. Focus stealing can be extremely disruptive.
 func (w *Window) Focus() {
-	w.callbacks.Event(key.FocusEvent{Focus: true})
+	w.data.Perform(system.ActionFocus)
 }
 
 // Iconify iconifies/minimizes the window, if it was previously restored. If it
@@ -715,9 +795,12 @@ func (w *Window) Show() {
 // Hide hides the window, if it was previously visible. If the window is already
 // hidden or is in full screen mode, this function does nothing.
 //
+// Windows can't be hidden on any of the supported platforms, and Hide
+// reports a PlatformError instead.
+//
 // This function may only be called from the main thread.
 func (w *Window) Hide() {
-	fmt.Println("not implemented")
+	reportError(platformError, "hiding windows is not supported")
 	panicError()
 }
 
@@ -726,8 +809,11 @@ func (w *Window) Hide() {
 //
 // Returns nil if the window is in windowed mode.
 func (w *Window) GetMonitor() *Monitor {
-	fmt.Println("not implemented")
-	return nil
+	cnf := w.data.EffectiveConfig()
+	if cnf.Mode != mado.Fullscreen {
+		return nil
+	}
+	return monitorAt(cnf.Pos)
 }
 
 // SetMonitor sets the monitor that the window uses for full screen mode or,
@@ -745,17 +831,86 @@ func (w *Window) GetMonitor() *Monitor {
 // When a window transitions from full screen to windowed mode, this function
 // restores any previous window settings such as whether it is decorated, floating,
 // resizable, has size or aspect ratio limits, etc..
+//
+// Video modes are not switched: a full screen window covers the monitor at
+// its current video mode, and width, height and refreshRate are only used
+// for windowed mode.
 func (w *Window) SetMonitor(monitor *Monitor, xpos, ypos, width, height, refreshRate int) {
-	fmt.Println("not implemented")
+	if monitor != nil {
+		pos := monitor.data.Bounds.Min
+		w.data.Option(func(_ unit.Metric, cnf *mado.Config) {
+			cnf.Pos = pos
+			cnf.Mode = mado.Fullscreen
+		})
+		panicError()
+		return
+	}
+	if width <= 0 || height <= 0 {
+		reportError(invalidValue, fmt.Sprintf("invalid window size %dx%d", width, height))
+		panicError()
+		return
+	}
+	w.data.Option(func(_ unit.Metric, cnf *mado.Config) {
+		cnf.Mode = mado.Windowed
+		cnf.Pos = image.Pt(xpos, ypos)
+		cnf.Size = image.Pt(width, height)
+	})
 	panicError()
 }
 
 // GetAttrib returns an attribute of the window. There are many attributes,
 // some related to the window and others to its context.
+//
+// Windows are always visible and resizable, and never floating. The context
+// attributes are the hints the window was created with, and AutoIconify,
+// FocusOnShow and TransparentFramebuffer, which are not supported, are
+// False.
 func (w *Window) GetAttrib(attrib Hint) int {
-	fmt.Println("not implemented")
+	cnf := w.data.EffectiveConfig()
+	ctx := w.ctxconfig
+	var v int
+	switch attrib {
+	case Focused:
+		v = boolToInt(app.Focused() == w.data)
+	case Iconified:
+		v = boolToInt(cnf.Mode == mado.Minimized)
+	case Maximized:
+		v = boolToInt(cnf.Mode == mado.Maximized)
+	case Hovered:
+		v = boolToInt(w.hovered)
+	case Visible, Resizable:
+		v = True
+	case Decorated:
+		v = boolToInt(cnf.Decorated)
+	case Floating, AutoIconify, FocusOnShow, TransparentFramebuffer:
+		v = False
+	case ClientAPI:
+		v = ctx.Client
+	case ContextCreationAPI:
+		v = ctx.Source
+	case ContextVersionMajor:
+		v = ctx.Major
+	case ContextVersionMinor:
+		v = ctx.Minor
+	case ContextRevision:
+		v = 0
+	case ContextRobustness:
+		v = ctx.Robustness
+	case ContextReleaseBehavior:
+		v = ctx.Release
+	case ContextNoError:
+		v = boolToInt(ctx.Noerror)
+	case OpenGLForwardCompatible:
+		v = boolToInt(ctx.Forward)
+	case OpenGLDebugContext:
+		v = boolToInt(ctx.Debug)
+	case OpenGLProfile:
+		v = ctx.Profile
+	default:
+		reportError(invalidEnum, fmt.Sprintf("invalid window attribute 0x%08X", int(attrib)))
+	}
 	panicError()
-	return 0
+	return v
 }
 
 // SetAttrib function sets the value of an attribute of the specified window.
@@ -770,7 +925,15 @@ func (w *Window) GetAttrib(attrib Hint) int {
 //
 // This function may only be called from the main thread.
 func (w *Window) SetAttrib(attrib Hint, value int) {
-	fmt.Println("not implemented")
+	switch attrib {
+	case Decorated:
+		w.data.Option(app.Decorated(value != False))
+	case Resizable, Floating, AutoIconify, FocusOnShow:
+		reportError(platformError, fmt.Sprintf("window attribute 0x%08X is not supported", int(attrib)))
+	default:
+		reportError(invalidEnum, fmt.Sprintf("invalid window attribute 0x%08X", int(attrib)))
+	}
+	panicError()
 }
 
 // SetUserPointer sets the user-defined pointer of the window. The current value
@@ -1123,8 +1286,11 @@ func (w *Window) SetPreeditCandidateCallback(cbfun PreeditCandidateCallback) (pr
 //
 // This function may only be called from the main thread.
 func (w *Window) SetClipboardString(str string) {
-	gtx := w.data.Queue.Source()
-	gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(str))})
+	if onEventLoop() {
+		w.callbacks.D.WriteClipboard([]input.ClipboardData{{Type: "application/text", Data: []byte(str)}})
+	} else {
+		w.data.WriteClipboard(str)
+	}
 	panicError()
 }
 
@@ -1136,9 +1302,19 @@ func (w *Window) SetClipboardString(str string) {
 //
 // This function may only be called from the main thread.
 func (w *Window) GetClipboardString() string {
-	gtx := w.data.Queue.Source()
-	gtx.Execute(clipboard.ReadCmd{Tag: w})
-	// There is probably no way to retrieve the clipboard synchronously due to the current geoui design.
-	fmt.Println("not implemented")
-	return ""
+	var str string
+	var err error
+	if onEventLoop() {
+		str, err = w.data.ReadClipboardOnLoop(w.callbacks.D, clipboardTimeout, func() {
+			waitEvents(clipboardPollInterval)
+		})
+	} else {
+		str, err = w.data.ReadClipboard(clipboardTimeout)
+	}
+	if err != nil {
+		reportError(formatUnavailable, "failed to retrieve clipboard as string: "+err.Error())
+		acceptError(FormatUnavailable)
+		return ""
+	}
+	return str
 }
@@ -190,12 +190,12 @@ func (c *GlfwContext) RefreshContextAttribs(ctxconfig *CtxConfig) error {
 		// {GLX|WGL}_ARB_create_context extension and fail here
 
 		if c.Client == GLFW_OPENGL_API {
-			return fmt.Errorf("Requested OpenGL version %i.%i, got version %i.%i",
+			return fmt.Errorf("Requested OpenGL version %d.%d, got version %d.%d",
 				ctxconfig.Major, ctxconfig.Minor,
 				c.Major, c.Minor,
 			)
 		} else {
-			return fmt.Errorf("Requested OpenGL ES version %i.%i, got version %i.%i",
+			return fmt.Errorf("Requested OpenGL ES version %d.%d, got version %d.%d",
 				ctxconfig.Major, ctxconfig.Minor,
 				c.Major, c.Minor,
 			)
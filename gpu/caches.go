@@ -98,6 +98,9 @@ func newOpCache() *opCache {
 }
 
 func (r *opCache) get(key opKey) (o opCacheValue, exist bool) {
+	if r == nil {
+		return
+	}
 	v := r.index[key]
 	if v == 0 {
 		return
@@ -107,6 +110,9 @@ func (r *opCache) get(key opKey) (o opCacheValue, exist bool) {
 }
 
 func (r *opCache) put(key opKey, val opCacheValue) {
+	if r == nil {
+		return
+	}
 	v := r.index[key]
 	val.keep = true
 	val.key = key
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"unsafe"
+
+	"gioui.org/shader"
+	"gioui.org/shader/gio"
+	"github.com/kanryu/mado/gpu/internal/driver"
+	"github.com/kanryu/mado/internal/byteslice"
+	"github.com/kanryu/mado/internal/f32"
+)
+
+// A Blitter draws the pixels of an image to a render target, for programs
+// that render their frames on the CPU.
+type Blitter interface {
+	// Release non-Go resources. The Blitter is no longer valid after
+	// Release.
+	Release()
+	// Blit draws img to target. Only the dirty rectangles of img are
+	// uploaded to the GPU, or all of img if dirty is empty or img
+	// changed size since the last Blit.
+	Blit(img *image.RGBA, dirty []image.Rectangle, target RenderTarget) error
+}
+
+// imageBlitter uploads images to a texture and draws it with the copy
+// shader of the compute renderer.
+type imageBlitter struct {
+	ctx      driver.Device
+	pipeline driver.Pipeline
+	vertices driver.Buffer
+	uniforms *copyUniforms
+	uniBuf   driver.Buffer
+	tex      driver.Texture
+	size     image.Point
+}
+
+// rasterBlitter copies images to the image of a CPURenderTarget.
+type rasterBlitter struct {
+	// last is the image of the target of the last Blit.
+	last *image.RGBA
+}
+
+// NewBlitter creates a Blitter for the given API.
+func NewBlitter(api API) (Blitter, error) {
+	if _, ok := api.(CPU); ok {
+		return new(rasterBlitter), nil
+	}
+	d, err := driver.NewDevice(api)
+	if err != nil {
+		return nil, err
+	}
+	d.BeginFrame(nil, false, image.Point{})
+	defer d.EndFrame()
+	b := &imageBlitter{ctx: d}
+	if err := b.init(); err != nil {
+		b.Release()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *imageBlitter) init() error {
+	vert, frag, err := newShaders(b.ctx, gio.Shader_copy_vert, gio.Shader_copy_frag)
+	if err != nil {
+		return err
+	}
+	defer vert.Release()
+	defer frag.Release()
+	pipe, err := b.ctx.NewPipeline(driver.PipelineDesc{
+		VertexShader:   vert,
+		FragmentShader: frag,
+		VertexLayout: driver.VertexLayout{
+			Inputs: []driver.InputDesc{
+				{Type: shader.DataTypeFloat, Size: 2, Offset: 0},
+				{Type: shader.DataTypeFloat, Size: 2, Offset: 4 * 2},
+			},
+			Stride: int(unsafe.Sizeof(layerVertex{})),
+		},
+		PixelFormat: driver.TextureFormatOutput,
+		Topology:    driver.TopologyTriangles,
+	})
+	if err != nil {
+		return err
+	}
+	b.pipeline = pipe
+	b.uniforms = new(copyUniforms)
+	b.uniBuf, err = b.ctx.NewBuffer(driver.BufferBindingUniforms, int(unsafe.Sizeof(*b.uniforms)))
+	if err != nil {
+		return err
+	}
+	b.vertices, err = b.ctx.NewBuffer(driver.BufferBindingVertices, 6*int(unsafe.Sizeof(layerVertex{})))
+	return err
+}
+
+func (b *imageBlitter) Release() {
+	type resource interface {
+		Release()
+	}
+	res := []resource{b.pipeline, b.uniBuf, b.vertices, b.tex}
+	for _, r := range res {
+		if r != nil {
+			r.Release()
+		}
+	}
+	b.ctx.Release()
+	*b = imageBlitter{}
+}
+
+func (b *imageBlitter) Blit(img *image.RGBA, dirty []image.Rectangle, target RenderTarget) error {
+	size := img.Rect.Size()
+	if size.X == 0 || size.Y == 0 {
+		return nil
+	}
+	fbo := b.ctx.BeginFrame(target, false, size)
+	defer b.ctx.EndFrame()
+	if b.tex == nil || size != b.size {
+		if err := b.resize(size); err != nil {
+			return err
+		}
+		dirty = nil
+	}
+	if len(dirty) == 0 {
+		dirty = []image.Rectangle{img.Rect}
+	}
+	for _, r := range dirty {
+		r = r.Intersect(img.Rect)
+		if r.Empty() {
+			continue
+		}
+		driver.UploadImage(b.tex, r.Min.Sub(img.Rect.Min), img.SubImage(r).(*image.RGBA))
+	}
+	b.ctx.PrepareTexture(b.tex)
+	// The content of the target is not kept between frames, so draw all of
+	// the texture.
+	b.ctx.BeginRenderPass(fbo, driver.LoadDesc{Action: driver.LoadActionInvalidate})
+	defer b.ctx.EndRenderPass()
+	b.ctx.Viewport(0, 0, size.X, size.Y)
+	b.ctx.BindPipeline(b.pipeline)
+	b.ctx.BindVertexBuffer(b.vertices, 0)
+	b.ctx.BindUniforms(b.uniBuf)
+	b.ctx.BindTexture(0, b.tex)
+	b.ctx.DrawArrays(0, 6)
+	return nil
+}
+
+// resize replaces the texture with one of the given size.
+func (b *imageBlitter) resize(size image.Point) error {
+	if b.tex != nil {
+		b.tex.Release()
+		b.tex = nil
+	}
+	tex, err := b.ctx.NewTexture(driver.TextureFormatRGBA8, size.X, size.Y, driver.FilterNearest, driver.FilterNearest, driver.BufferBindingTexture)
+	if err != nil {
+		return err
+	}
+	b.tex = tex
+	b.size = size
+	sizef := f32.Pt(float32(size.X), float32(size.Y))
+	quad := [4]layerVertex{
+		{posX: 0, posY: 0, u: 0, v: 0},
+		{posX: sizef.X, posY: 0, u: sizef.X, v: 0},
+		{posX: sizef.X, posY: sizef.Y, u: sizef.X, v: sizef.Y},
+		{posX: 0, posY: sizef.Y, u: 0, v: sizef.Y},
+	}
+	vertices := [6]layerVertex{quad[0], quad[1], quad[3], quad[3], quad[2], quad[1]}
+	b.vertices.Upload(byteslice.Slice(vertices[:]))
+	// Transform positions to clip space: [-1, -1] - [1, 1], and texture
+	// coordinates to texture space: [0, 0] - [1, 1].
+	clip := f32.Affine2D{}.Scale(f32.Pt(0, 0), f32.Pt(2/sizef.X, 2/sizef.Y)).Offset(f32.Pt(-1, -1))
+	sx, _, ox, _, sy, oy := clip.Elems()
+	b.uniforms.scale = [2]float32{sx, sy}
+	b.uniforms.pos = [2]float32{ox, oy}
+	b.uniforms.uvScale = [2]float32{1 / sizef.X, 1 / sizef.Y}
+	b.uniBuf.Upload(byteslice.Struct(b.uniforms))
+	return nil
+}
+
+func (b *rasterBlitter) Release() {
+	b.last = nil
+}
+
+func (b *rasterBlitter) Blit(img *image.RGBA, dirty []image.Rectangle, target RenderTarget) error {
+	t, ok := target.(CPURenderTarget)
+	if !ok {
+		return errors.New("gpu: CPU blitter needs a CPURenderTarget")
+	}
+	dst := t.Image
+	if dst == nil {
+		return errors.New("gpu: CPURenderTarget has no image")
+	}
+	if dst != b.last || dst.Rect.Size() != img.Rect.Size() {
+		b.last = dst
+		dirty = nil
+	}
+	if len(dirty) == 0 {
+		dirty = []image.Rectangle{img.Rect}
+	}
+	off := dst.Rect.Min.Sub(img.Rect.Min)
+	for _, r := range dirty {
+		r = r.Intersect(img.Rect)
+		draw.Draw(dst, r.Add(off), img, r.Min, draw.Src)
+	}
+	return nil
+}
@@ -330,6 +330,9 @@ const (
 
 // New creates a GPU for the given API.
 func New(api API) (GPU, error) {
+	if _, ok := api.(CPU); ok {
+		return newRaster(), nil
+	}
 	d, err := driver.NewDevice(api)
 	if err != nil {
 		return nil, err
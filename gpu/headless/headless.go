@@ -8,6 +8,8 @@ import (
 	"errors"
 	"image"
 	"image/color"
+	"image/draw"
+	"os"
 
 	"github.com/kanryu/mado/gpu"
 	"github.com/kanryu/mado/gpu/internal/driver"
@@ -21,6 +23,9 @@ type Window struct {
 	dev    driver.Device
 	gpu    gpu.GPU
 	fboTex driver.Texture
+	// img is the framebuffer of a window rendered by the CPU renderer,
+	// used when no GPU context is available.
+	img *image.RGBA
 }
 
 type context interface {
@@ -57,11 +62,16 @@ func newContext() (context, error) {
 	return nil, errors.New("headless: no available GPU backends")
 }
 
-// NewWindow creates a new headless window.
+// NewWindow creates a new headless window. The window is rendered on the
+// CPU if no GPU is available, or if the GIORENDERER environment variable is
+// set to "cpu".
 func NewWindow(width, height int) (*Window, error) {
+	if os.Getenv("GIORENDERER") == "cpu" {
+		return newCPUWindow(width, height)
+	}
 	ctx, err := newContext()
 	if err != nil {
-		return nil, err
+		return newCPUWindow(width, height)
 	}
 	w := &Window{
 		size: image.Point{X: width, Y: height},
@@ -95,13 +105,32 @@ func NewWindow(width, height int) (*Window, error) {
 	})
 	if err != nil {
 		ctx.Release()
+		return newCPUWindow(width, height)
+	}
+	return w, nil
+}
+
+func newCPUWindow(width, height int) (*Window, error) {
+	gp, err := gpu.New(gpu.CPU{})
+	if err != nil {
 		return nil, err
 	}
+	w := &Window{
+		size: image.Point{X: width, Y: height},
+		gpu:  gp,
+		img:  image.NewRGBA(image.Rectangle{Max: image.Point{X: width, Y: height}}),
+	}
 	return w, nil
 }
 
 // Release resources associated with the window.
 func (w *Window) Release() {
+	if w.img != nil {
+		w.gpu.Release()
+		w.gpu = nil
+		w.img = nil
+		return
+	}
 	contextDo(w.ctx, func() error {
 		if w.fboTex != nil {
 			w.fboTex.Release()
@@ -129,6 +158,10 @@ func (w *Window) Size() image.Point {
 // Frame replaces the window content and state with the
 // operation list.
 func (w *Window) Frame(frame *op.Ops) error {
+	if w.img != nil {
+		w.gpu.Clear(color.NRGBA{})
+		return w.gpu.Frame(frame, gpu.CPURenderTarget{Image: w.img}, w.size)
+	}
 	return contextDo(w.ctx, func() error {
 		w.gpu.Clear(color.NRGBA{})
 		return w.gpu.Frame(frame, w.fboTex, w.size)
@@ -137,6 +170,10 @@ func (w *Window) Frame(frame *op.Ops) error {
 
 // Screenshot transfers the Window content at origin img.Rect.Min to img.
 func (w *Window) Screenshot(img *image.RGBA) error {
+	if w.img != nil {
+		draw.Draw(img, img.Rect, w.img, img.Rect.Min, draw.Src)
+		return nil
+	}
 	return contextDo(w.ctx, func() error {
 		return driver.DownloadImage(w.dev, w.fboTex, img)
 	})
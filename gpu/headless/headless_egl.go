@@ -1,7 +1,8 @@
 // SPDX-License-Identifier: Unlicense OR MIT
 
-//go:build linux || freebsd || openbsd
-// +build linux freebsd openbsd
+//go:build ((linux && !android) || freebsd || openbsd) && !noopengl
+// +build linux,!android freebsd openbsd
+// +build !noopengl
 
 package headless
 
@@ -11,6 +12,6 @@ import (
 
 func init() {
 	newContextPrimary = func() (context, error) {
-		return egl.NewContext(egl.EGL_DEFAULT_DISPLAY)
+		return egl.NewHeadlessContext()
 	}
 }
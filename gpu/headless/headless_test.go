@@ -128,6 +128,34 @@ func TestDepth(t *testing.T) {
 	}
 }
 
+func TestCPU(t *testing.T) {
+	t.Setenv("GIORENDERER", "cpu")
+	w, release := newTestWindow(t)
+	defer release()
+	if w.img == nil {
+		t.Fatal("GIORENDERER=cpu didn't select the CPU renderer")
+	}
+
+	col := color.NRGBA{A: 0xff, R: 0xca, G: 0xfe}
+	var ops op.Ops
+	paint.FillShape(&ops, col, clip.Ellipse(image.Rect(100, 100, 300, 300)).Op(&ops))
+	if err := w.Frame(&ops); err != nil {
+		t.Fatal(err)
+	}
+
+	// Screenshot from an offset.
+	img := image.NewRGBA(image.Rect(100, 100, 300, 300))
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(200, 200); got != f32color.NRGBAToRGBA(col) {
+		t.Errorf("got color %v inside the ellipse, expected %v", got, f32color.NRGBAToRGBA(col))
+	}
+	if got := img.RGBAAt(101, 101); got != (color.RGBA{}) {
+		t.Errorf("got color %v outside the ellipse, expected transparent", got)
+	}
+}
+
 func TestNoOps(t *testing.T) {
 	w, release := newTestWindow(t)
 	defer release()
@@ -4,6 +4,7 @@ package driver
 
 import (
 	"fmt"
+	"image"
 	"unsafe"
 
 	"github.com/kanryu/mado/internal/gl"
@@ -44,6 +45,12 @@ type VulkanRenderTarget struct {
 	Framebuffer uint64
 }
 
+// CPURenderTarget is a render target suitable for the CPU renderer.
+type CPURenderTarget struct {
+	// Image receives the frame at origin Image.Rect.Min.
+	Image *image.RGBA
+}
+
 type OpenGL struct {
 	// ES forces the use of ANGLE OpenGL ES libraries on macOS. It is
 	// ignored on all other platforms.
@@ -84,6 +91,9 @@ type Vulkan struct {
 	Format int
 }
 
+// CPU renders frames on the CPU, without a Device.
+type CPU struct{}
+
 // API specific device constructors.
 var (
 	NewOpenGLDevice     func(api OpenGL) (Device, error)
@@ -123,7 +133,9 @@ func (OpenGL) implementsAPI()                          {}
 func (Direct3D11) implementsAPI()                      {}
 func (Metal) implementsAPI()                           {}
 func (Vulkan) implementsAPI()                          {}
+func (CPU) implementsAPI()                             {}
 func (OpenGLRenderTarget) ImplementsRenderTarget()     {}
 func (Direct3D11RenderTarget) ImplementsRenderTarget() {}
 func (MetalRenderTarget) ImplementsRenderTarget()      {}
 func (VulkanRenderTarget) ImplementsRenderTarget()     {}
+func (CPURenderTarget) ImplementsRenderTarget()        {}
@@ -0,0 +1,522 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/kanryu/mado/internal/f32"
+	"github.com/kanryu/mado/internal/f32color"
+	"github.com/kanryu/mado/op"
+)
+
+// This file contains a renderer that rasterizes operation lists on the CPU,
+// for machines and windows without a GPU.
+
+// raster is a GPU that renders into the *image.RGBA of a CPURenderTarget.
+type raster struct {
+	drawOps drawOps
+	cache   *textureCache
+	// masks caches the coverage masks of the clip paths of a frame.
+	masks map[*pathOp]*rasterMask
+	// fb holds the frame in premultiplied linear colors.
+	fb rasterBuffer
+}
+
+// rasterMask is the coverage of a clip path.
+type rasterMask struct {
+	rect  image.Rectangle
+	cover []float32
+}
+
+// rasterBuffer is an image of premultiplied linear colors.
+type rasterBuffer struct {
+	rect image.Rectangle
+	pix  []f32color.RGBA
+}
+
+var (
+	// srgb8ToLinear maps sRGB channel values to linear values.
+	srgb8ToLinear [256]float32
+	// linearToSRGB8 maps linear channel values, quantized to
+	// len(linearToSRGB8) steps, to sRGB channel values.
+	linearToSRGB8 [1 << 14]uint8
+)
+
+func init() {
+	for i := range srgb8ToLinear {
+		srgb8ToLinear[i] = f32color.LinearFromSRGB(color.NRGBA{R: uint8(i), A: 0xff}).R
+	}
+	for i := range linearToSRGB8 {
+		c := f32color.RGBA{R: float32(i) / float32(len(linearToSRGB8)-1), A: 1}
+		linearToSRGB8[i] = c.SRGB().R
+	}
+}
+
+func newRaster() *raster {
+	return &raster{
+		cache: newTextureCache(),
+		masks: make(map[*pathOp]*rasterMask),
+	}
+}
+
+func (r *raster) Release() {
+	r.cache.release()
+	r.masks = nil
+	r.fb = rasterBuffer{}
+}
+
+func (r *raster) Clear(col color.NRGBA) {
+	r.drawOps.clear = true
+	r.drawOps.clearColor = f32color.LinearFromSRGB(col)
+}
+
+func (r *raster) Frame(frameOps *op.Ops, target RenderTarget, viewport image.Point) error {
+	t, ok := target.(CPURenderTarget)
+	if !ok {
+		return fmt.Errorf("gpu: render target %T is not supported by the CPU renderer", target)
+	}
+	if t.Image == nil {
+		return errors.New("gpu: CPURenderTarget has no image")
+	}
+	d := &r.drawOps
+	d.reset(viewport)
+	d.collect(frameOps, viewport)
+	for _, img := range d.imageOps {
+		expandPathOp(img.path, img.clip)
+	}
+	r.fb.reset(image.Rectangle{Max: viewport})
+	if d.clear {
+		d.clear = false
+		r.fb.fill(d.clearColor)
+	} else {
+		r.fb.load(t.Image)
+	}
+	r.drawLayer(&r.fb, 0, len(d.imageOps), -1)
+	r.fb.store(t.Image)
+	r.cache.frame()
+	for p := range r.masks {
+		delete(r.masks, p)
+	}
+	return nil
+}
+
+// drawLayer draws the operations in the range [start;end) that belong to
+// the opacity layer with index parent, or the frame if parent is -1.
+func (r *raster) drawLayer(dst *rasterBuffer, start, end, parent int) {
+	imgs := r.drawOps.imageOps
+	for i, l := range r.drawOps.layers {
+		if l.parent != parent {
+			continue
+		}
+		// A full screen clear may have dropped the operations of the layer.
+		lstart, lend := l.opStart, l.opEnd
+		if lstart < start || lstart > end {
+			continue
+		}
+		if lend < lstart || lend > end {
+			lend = end
+		}
+		r.drawImages(dst, imgs[start:lstart])
+		var bounds image.Rectangle
+		for _, img := range imgs[lstart:lend] {
+			bounds = bounds.Union(img.clip)
+		}
+		if !bounds.Empty() {
+			var buf rasterBuffer
+			buf.reset(bounds)
+			r.drawLayer(&buf, lstart, lend, i)
+			dst.composite(&buf, l.opacity)
+		}
+		start = lend
+	}
+	r.drawImages(dst, imgs[start:end])
+}
+
+func (r *raster) drawImages(dst *rasterBuffer, imgs []imageOp) {
+	var masks []*rasterMask
+	for _, img := range imgs {
+		rect := img.clip.Intersect(dst.rect)
+		if rect.Empty() {
+			continue
+		}
+		masks = masks[:0]
+		for p := img.path; p != nil; p = p.parent {
+			if p.path {
+				masks = append(masks, r.mask(p))
+			}
+		}
+		m := &img.material
+		var (
+			tex *rasterTexture
+			lod float32
+		)
+		if m.material == materialTexture {
+			tex = r.texture(m.data)
+			lod = tex.lod(m.uvTrans, img.clip)
+		}
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				cov := m.opacity
+				for _, mask := range masks {
+					cov *= mask.cover[mask.offset(x, y)]
+				}
+				if cov == 0 {
+					continue
+				}
+				src := shade(m, tex, lod, img.clip, x, y)
+				dst.blend(x, y, src, cov)
+			}
+		}
+	}
+}
+
+// texture returns the texture for the image data.
+func (r *raster) texture(data imageOpData) *rasterTexture {
+	key := textureCacheKey{
+		filter: data.filter,
+		handle: data.handle,
+	}
+	if t, ok := r.cache.get(key); ok {
+		return t.(*rasterTexture)
+	}
+	t := newRasterTexture(data.src, data.filter == filterLinear)
+	r.cache.put(key, t)
+	return t
+}
+
+// mask returns the coverage of the clip path p inside p.clip.
+func (r *raster) mask(p *pathOp) *rasterMask {
+	if m, ok := r.masks[p]; ok {
+		return m
+	}
+	b := p.clip
+	m := &rasterMask{
+		rect:  b,
+		cover: make([]float32, b.Dx()*b.Dy()),
+	}
+	r.masks[p] = m
+	bo := binary.LittleEndian
+	coord := func(v []byte) float32 {
+		return math.Float32frombits(bo.Uint32(v))
+	}
+	// Every quadratic curve is encoded in the 4 vertices of its quad.
+	for v := p.pathVerts; len(v) >= vertStride*4; v = v[vertStride*4:] {
+		maxy := coord(v[4:]) + p.off.Y
+		from := f32.Pt(coord(v[8:]), coord(v[12:])).Add(p.off)
+		ctrl := f32.Pt(coord(v[16:]), coord(v[20:])).Add(p.off)
+		to := f32.Pt(coord(v[24:]), coord(v[28:])).Add(p.off)
+		m.stencil(from, ctrl, to, maxy)
+	}
+	for i, c := range m.cover {
+		if c < 0 {
+			c = -c
+		}
+		if c > 1 {
+			c = 1
+		}
+		m.cover[i] = c
+	}
+	return m
+}
+
+// stencil accumulates the signed area covered by the quadratic curve in the
+// pixels between the curve and maxy, the maximum y of its contour. It
+// mirrors the stencil shader of the GPU renderer, so both renderers
+// anti-alias paths alike.
+func (m *rasterMask) stencil(from, ctrl, to f32.Point, maxy float32) {
+	lo := min(min(from, ctrl), to)
+	hi := max(max(from, ctrl), to)
+	// Shade the pixels whose centers are inside the quad of the curve.
+	x0 := clampInt(int(math.Ceil(float64(lo.X-1.5))), m.rect.Min.X, m.rect.Max.X)
+	x1 := clampInt(int(math.Ceil(float64(hi.X+.5))), m.rect.Min.X, m.rect.Max.X)
+	y0 := clampInt(int(math.Ceil(float64(lo.Y-1.5))), m.rect.Min.Y, m.rect.Max.Y)
+	y1 := clampInt(int(math.Ceil(float64(maxy+.5))), m.rect.Min.Y, m.rect.Max.Y)
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			c := f32.Pt(float32(x)+.5, float32(y)+.5)
+			m.cover[m.offset(x, y)] += curveArea(from.Sub(c), ctrl.Sub(c), to.Sub(c))
+		}
+	}
+}
+
+// curveArea returns the signed area of the pixel centered at the origin that
+// lies below the curve.
+func curveArea(from, ctrl, to f32.Point) float32 {
+	left, right := from, to
+	if to.X < from.X {
+		left, right = to, from
+	}
+	// The signed horizontal extent of the pixel.
+	ext0, ext1 := clamp1(from.X, -.5, .5), clamp1(to.X, -.5, .5)
+	width := ext1 - ext0
+	if width == 0 {
+		return 0
+	}
+	// Find the t where the curve crosses the middle of the extent,
+	// and approximate the curve with its tangent there.
+	x0 := (ext0+ext1)*.5 - left.X
+	p1 := ctrl.Sub(left)
+	v := right.Sub(ctrl)
+	var t float32
+	if d := p1.X + float32(math.Sqrt(float64(p1.X*p1.X+(v.X-p1.X)*x0))); d != 0 {
+		t = x0 / d
+	}
+	y := lerp(lerp(left.Y, ctrl.Y, t), lerp(ctrl.Y, right.Y, t), t)
+	dhalf := p1.Mul(1 - t).Add(v.Mul(t))
+	dy := float32(math.Abs(float64(dhalf.Y / dhalf.X * width)))
+	// Compute the pixel area below the line.
+	sx := clamp1(dy*+.5+y+.5, 0, 1)
+	sy := clamp1(dy*-.5+y+.5, 0, 1)
+	sz := clamp1((+.5-y)/dy+.5, 0, 1)
+	sw := clamp1((-.5-y)/dy+.5, 0, 1)
+	return .5 * (sz - sz*sy + 1 - sx + sx*sw) * width
+}
+
+func lerp(a, b, t float32) float32 {
+	return a + (b-a)*t
+}
+
+// clamp1 clamps v to [min;max], mapping NaN to min.
+func clamp1(v, min, max float32) float32 {
+	if !(v > min) {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// shade returns the color of the material at the pixel (x, y) of an
+// operation clipped to clip.
+func shade(m *material, tex *rasterTexture, lod float32, clip image.Rectangle, x, y int) f32color.RGBA {
+	switch m.material {
+	case materialLinearGradient:
+		uv := m.uvTrans.Transform(pixelUV(clip, x, y))
+		t := uv.X
+		if t < 0 {
+			t = 0
+		} else if t > 1 {
+			t = 1
+		}
+		return mixRGBA(m.color1, m.color2, t)
+	case materialTexture:
+		uv := m.uvTrans.Transform(pixelUV(clip, x, y))
+		return tex.sample(uv, lod, m.data.filter)
+	default:
+		return m.color
+	}
+}
+
+// pixelUV returns the position of the center of pixel (x, y) relative to
+// clip, where (0, 0) is the top left and (1, 1) the bottom right corner.
+func pixelUV(clip image.Rectangle, x, y int) f32.Point {
+	return f32.Point{
+		X: (float32(x-clip.Min.X) + .5) / float32(clip.Dx()),
+		Y: (float32(y-clip.Min.Y) + .5) / float32(clip.Dy()),
+	}
+}
+
+// rasterTexture is an image in linear colors, along with its mipmaps if
+// it is filtered linearly.
+type rasterTexture struct {
+	levels []rasterBuffer
+}
+
+func newRasterTexture(src *image.RGBA, mipmaps bool) *rasterTexture {
+	t := new(rasterTexture)
+	level := rasterBuffer{}
+	level.reset(image.Rectangle{Max: src.Rect.Size()})
+	level.load(src)
+	t.levels = append(t.levels, level)
+	for mipmaps {
+		prev := &t.levels[len(t.levels)-1]
+		sz := prev.rect.Size()
+		if sz.X <= 1 && sz.Y <= 1 {
+			break
+		}
+		var next rasterBuffer
+		next.reset(image.Rectangle{Max: image.Pt(clampInt(sz.X/2, 1, sz.X), clampInt(sz.Y/2, 1, sz.Y))})
+		for y := 0; y < next.rect.Max.Y; y++ {
+			for x := 0; x < next.rect.Max.X; x++ {
+				top := mixRGBA(prev.at(2*x, 2*y), prev.at(2*x+1, 2*y), .5)
+				bottom := mixRGBA(prev.at(2*x, 2*y+1), prev.at(2*x+1, 2*y+1), .5)
+				next.pix[next.offset(x, y)] = mixRGBA(top, bottom, .5)
+			}
+		}
+		t.levels = append(t.levels, next)
+	}
+	return t
+}
+
+func (t *rasterTexture) release() {}
+
+// lod returns the mipmap level of detail for sampling t with the texture
+// transform uvTrans over clip.
+func (t *rasterTexture) lod(uvTrans f32.Affine2D, clip image.Rectangle) float32 {
+	sz := t.levels[0].rect.Size()
+	sx, hx, _, hy, sy, _ := uvTrans.Elems()
+	// The texel distances between adjacent pixels.
+	w, h := float32(sz.X)/float32(clip.Dx()), float32(sz.Y)/float32(clip.Dy())
+	rhox := math.Hypot(float64(sx*w), float64(hy*w))
+	rhoy := math.Hypot(float64(hx*h), float64(sy*h))
+	return float32(math.Log2(math.Max(rhox, rhoy)))
+}
+
+// sample samples t at the normalized coordinates uv, clamping to the
+// edges like a GPU sampler.
+func (t *rasterTexture) sample(uv f32.Point, lod float32, filter byte) f32color.RGBA {
+	if filter == filterNearest {
+		l := &t.levels[0]
+		sz := l.rect.Size()
+		x := math.Floor(float64(uv.X * float32(sz.X)))
+		y := math.Floor(float64(uv.Y * float32(sz.Y)))
+		return l.at(int(x), int(y))
+	}
+	n := len(t.levels) - 1
+	switch {
+	case lod <= 0:
+		return t.levels[0].bilinear(uv)
+	case lod >= float32(n):
+		return t.levels[n].bilinear(uv)
+	}
+	l := int(lod)
+	return mixRGBA(t.levels[l].bilinear(uv), t.levels[l+1].bilinear(uv), lod-float32(l))
+}
+
+// bilinear samples b at the normalized coordinates uv.
+func (b *rasterBuffer) bilinear(uv f32.Point) f32color.RGBA {
+	sz := b.rect.Size()
+	fx, fy := uv.X*float32(sz.X)-.5, uv.Y*float32(sz.Y)-.5
+	x0, y0 := math.Floor(float64(fx)), math.Floor(float64(fy))
+	tx, ty := fx-float32(x0), fy-float32(y0)
+	x, y := int(x0), int(y0)
+	top := mixRGBA(b.at(x, y), b.at(x+1, y), tx)
+	bottom := mixRGBA(b.at(x, y+1), b.at(x+1, y+1), tx)
+	return mixRGBA(top, bottom, ty)
+}
+
+// at returns the pixel (x, y) of b, clamped to its bounds.
+func (b *rasterBuffer) at(x, y int) f32color.RGBA {
+	x = clampInt(x, b.rect.Min.X, b.rect.Max.X-1)
+	y = clampInt(y, b.rect.Min.Y, b.rect.Max.Y-1)
+	return b.pix[b.offset(x, y)]
+}
+
+// texel returns the linear color of the pixel (x, y) of src, relative to
+// src.Rect.Min and clamped to its bounds.
+func texel(src *image.RGBA, x, y int) f32color.RGBA {
+	sz := src.Rect.Size()
+	x = clampInt(x, 0, sz.X-1)
+	y = clampInt(y, 0, sz.Y-1)
+	i := src.PixOffset(src.Rect.Min.X+x, src.Rect.Min.Y+y)
+	p := src.Pix[i : i+4 : i+4]
+	return f32color.RGBA{
+		R: srgb8ToLinear[p[0]],
+		G: srgb8ToLinear[p[1]],
+		B: srgb8ToLinear[p[2]],
+		A: float32(p[3]) / 0xff,
+	}
+}
+
+func mixRGBA(c1, c2 f32color.RGBA, t float32) f32color.RGBA {
+	return f32color.RGBA{
+		R: c1.R + (c2.R-c1.R)*t,
+		G: c1.G + (c2.G-c1.G)*t,
+		B: c1.B + (c2.B-c1.B)*t,
+		A: c1.A + (c2.A-c1.A)*t,
+	}
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func (b *rasterBuffer) reset(rect image.Rectangle) {
+	n := rect.Dx() * rect.Dy()
+	if cap(b.pix) < n {
+		b.pix = make([]f32color.RGBA, n)
+	}
+	b.pix = b.pix[:n]
+	b.rect = rect
+	b.fill(f32color.RGBA{})
+}
+
+func (b *rasterBuffer) fill(c f32color.RGBA) {
+	for i := range b.pix {
+		b.pix[i] = c
+	}
+}
+
+func (m *rasterMask) offset(x, y int) int {
+	return (y-m.rect.Min.Y)*m.rect.Dx() + x - m.rect.Min.X
+}
+
+func (b *rasterBuffer) offset(x, y int) int {
+	return (y-b.rect.Min.Y)*b.rect.Dx() + x - b.rect.Min.X
+}
+
+// blend composites src with coverage cov over the pixel (x, y).
+func (b *rasterBuffer) blend(x, y int, src f32color.RGBA, cov float32) {
+	d := &b.pix[b.offset(x, y)]
+	inv := 1 - src.A*cov
+	d.R = src.R*cov + d.R*inv
+	d.G = src.G*cov + d.G*inv
+	d.B = src.B*cov + d.B*inv
+	d.A = src.A*cov + d.A*inv
+}
+
+// composite blends src with the given opacity over b.
+func (b *rasterBuffer) composite(src *rasterBuffer, opacity float32) {
+	rect := src.rect.Intersect(b.rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			b.blend(x, y, src.pix[src.offset(x, y)], opacity)
+		}
+	}
+}
+
+// load converts the pixels of img, at origin img.Rect.Min, to b.
+func (b *rasterBuffer) load(img *image.RGBA) {
+	for y := b.rect.Min.Y; y < b.rect.Max.Y; y++ {
+		for x := b.rect.Min.X; x < b.rect.Max.X; x++ {
+			p := image.Pt(x, y).Add(img.Rect.Min)
+			if !p.In(img.Rect) {
+				continue
+			}
+			b.pix[b.offset(x, y)] = texel(img, x, y)
+		}
+	}
+}
+
+// store converts b to sRGB and writes it to img at origin img.Rect.Min.
+func (b *rasterBuffer) store(img *image.RGBA) {
+	rect := b.rect.Intersect(img.Rect.Sub(img.Rect.Min))
+	quant := float32(len(linearToSRGB8) - 1)
+	toSRGB := func(c float32) uint8 {
+		return linearToSRGB8[clampInt(int(c*quant+.5), 0, len(linearToSRGB8)-1)]
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			c := b.pix[b.offset(x, y)]
+			i := img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			p := img.Pix[i : i+4 : i+4]
+			p[0] = toSRGB(c.R)
+			p[1] = toSRGB(c.G)
+			p[2] = toSRGB(c.B)
+			p[3] = uint8(clampInt(int(c.A*0xff+.5), 0, 0xff))
+		}
+	}
+}
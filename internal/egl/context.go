@@ -0,0 +1,667 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build linux || freebsd || openbsd
+// +build linux freebsd openbsd
+
+package egl
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/gpu"
+	"github.com/kanryu/mado/internal/gl"
+)
+
+// Context is an EGL context and its window surface, shared by the
+// window backends and headless rendering.
+type Context struct {
+	disp          EGLDisplay
+	eglCtx        *eglContext
+	eglSurf       EGLSurface
+	attribs       []EGLint
+	width, height int
+	// glFuncs queries the client API, loaded on first use.
+	glFuncs *gl.Functions
+}
+
+type eglContext struct {
+	config      EGLConfig
+	ctx         EGLContext
+	visualID    int
+	srgb        bool
+	surfaceless bool
+}
+
+// eglDisplays tracks the live contexts of every initialized display.
+// Windows sharing a native display share their EGL display, which must
+// not be terminated before the last of their contexts is released. New
+// contexts join the share group of the live contexts, so that textures
+// and buffers may be used from all windows of a display.
+var eglDisplays struct {
+	mu   sync.Mutex
+	ctxs map[EGLDisplay][]EGLContext
+}
+
+// shareContext returns a live context of disp, or NilEGLContext.
+func shareContext(disp EGLDisplay) EGLContext {
+	eglDisplays.mu.Lock()
+	defer eglDisplays.mu.Unlock()
+	if ctxs := eglDisplays.ctxs[disp]; len(ctxs) > 0 {
+		return ctxs[0]
+	}
+	return NilEGLContext
+}
+
+// createSharedContext is like eglCreateContext, except that it shares
+// the objects of the live contexts of disp when possible.
+func createSharedContext(disp EGLDisplay, cfg EGLConfig, attribs []EGLint) EGLContext {
+	share := shareContext(disp)
+	ctx := EglCreateContext(disp, cfg, share, attribs)
+	if ctx == NilEGLContext && share != NilEGLContext {
+		// The contexts may differ in client API or version.
+		ctx = EglCreateContext(disp, cfg, NilEGLContext, attribs)
+	}
+	return ctx
+}
+
+func addDisplayContext(disp EGLDisplay, ctx EGLContext) {
+	eglDisplays.mu.Lock()
+	defer eglDisplays.mu.Unlock()
+	if eglDisplays.ctxs == nil {
+		eglDisplays.ctxs = make(map[EGLDisplay][]EGLContext)
+	}
+	eglDisplays.ctxs[disp] = append(eglDisplays.ctxs[disp], ctx)
+}
+
+// removeDisplayContext forgets ctx and reports whether it was the last
+// context of disp.
+func removeDisplayContext(disp EGLDisplay, ctx EGLContext) bool {
+	eglDisplays.mu.Lock()
+	defer eglDisplays.mu.Unlock()
+	ctxs := eglDisplays.ctxs[disp]
+	for i, c := range ctxs {
+		if c == ctx {
+			ctxs = append(ctxs[:i:i], ctxs[i+1:]...)
+			break
+		}
+	}
+	if len(ctxs) == 0 {
+		delete(eglDisplays.ctxs, disp)
+		return true
+	}
+	eglDisplays.ctxs[disp] = ctxs
+	return false
+}
+
+func (c *Context) Release() {
+	c.ReleaseSurface()
+	last := true
+	if c.eglCtx != nil {
+		EglDestroyContext(c.disp, c.eglCtx.ctx)
+		last = removeDisplayContext(c.disp, c.eglCtx.ctx)
+		c.eglCtx = nil
+	}
+	if last {
+		EglTerminate(c.disp)
+	}
+	c.disp = NilEGLDisplay
+}
+
+func (c *Context) Present() error {
+	if !EglSwapBuffers(c.disp, c.eglSurf) {
+		return fmt.Errorf("eglSwapBuffers failed (%x)", EglGetError())
+	}
+	return nil
+}
+
+func (c *Context) HasSurface() bool {
+	return c.eglSurf != NilEGLSurface
+}
+
+func NewContext(disp NativeDisplayType, eglApi uint) (*Context, error) {
+	if err := LoadEGL(); err != nil {
+		return nil, err
+	}
+	eglDisp := EglGetDisplay(disp)
+	// eglGetDisplay can return EGL_NO_DISPLAY yet no error
+	// (EGL_SUCCESS), in which case a default EGL display might be
+	// available.
+	if eglDisp == NilEGLDisplay {
+		eglDisp = EglGetDisplay(EGL_DEFAULT_DISPLAY)
+	}
+	if eglDisp == NilEGLDisplay {
+		return nil, fmt.Errorf("eglGetDisplay failed: 0x%x", EglGetError())
+	}
+	var eglCtx *eglContext
+	var attribs []EGLint
+	var err error
+	if mado.GlfwConfig.Enable {
+		eglCtx, attribs, err = glfwCreateContext(eglDisp, eglApi)
+
+	} else {
+		eglCtx, err = createContext(eglDisp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	addDisplayContext(eglDisp, eglCtx.ctx)
+	c := &Context{
+		disp:    eglDisp,
+		eglCtx:  eglCtx,
+		attribs: attribs,
+	}
+	return c, nil
+}
+
+// NewHeadlessContext creates an OpenGL ES context on the default EGL
+// display for rendering without a window surface.
+func NewHeadlessContext() (*Context, error) {
+	return NewContext(EGL_DEFAULT_DISPLAY, EGL_OPENGL_ES_API)
+}
+
+func (c *Context) RenderTarget() (gpu.RenderTarget, error) {
+	return gpu.OpenGLRenderTarget{}, nil
+}
+
+func (c *Context) API() gpu.API {
+	return gpu.OpenGL{}
+}
+
+func (c *Context) ReleaseSurface() {
+	if c.eglSurf == NilEGLSurface {
+		return
+	}
+	// Make sure any in-flight GL commands are complete.
+	EglWaitClient()
+	c.ReleaseCurrent()
+	EglDestroySurface(c.disp, c.eglSurf)
+	c.eglSurf = NilEGLSurface
+}
+
+func (c *Context) VisualID() int {
+	return c.eglCtx.visualID
+}
+
+func (c *Context) CreateSurface(win NativeWindowType, width, height int) error {
+	eglSurf, err := createSurface(c.disp, c.eglCtx, c.attribs, win)
+	c.eglSurf = eglSurf
+	c.width = width
+	c.height = height
+	return err
+}
+
+func (c *Context) ReleaseCurrent() {
+	if c.disp != NilEGLDisplay {
+		EglMakeCurrent(c.disp, NilEGLSurface, NilEGLSurface, NilEGLContext)
+	}
+}
+
+func (c *Context) MakeCurrent() error {
+	// OpenGL contexts are implicit and thread-local. Lock the OS thread.
+	runtime.LockOSThread()
+
+	if c.eglSurf == NilEGLSurface && !c.eglCtx.surfaceless {
+		return errors.New("no surface created yet EGL_KHR_surfaceless_context is not supported")
+	}
+	if !EglMakeCurrent(c.disp, c.eglSurf, c.eglSurf, c.eglCtx.ctx) {
+		return fmt.Errorf("eglMakeCurrent error 0x%x", EglGetError())
+	}
+	return nil
+}
+
+func (c *Context) EnableVSync(enable bool) {
+	if enable {
+		EglSwapInterval(c.disp, 1)
+	} else {
+		EglSwapInterval(c.disp, 0)
+	}
+}
+
+func (c *Context) SwapBuffers() error {
+	ok := EglSwapBuffers(c.disp, c.eglSurf)
+	if ok {
+		return nil
+	}
+	fmt.Println("eglSwapBuffers", EglGetError())
+	return fmt.Errorf("eglSwapBuffers returned false")
+}
+
+func (c *Context) SwapInterval(interval int) error {
+	ok := EglSwapInterval(c.disp, EGLint(interval))
+	if ok {
+		return nil
+	}
+	return fmt.Errorf("eglSwapInterval returned false")
+}
+
+// GetProcAddress returns the address of a client API or EGL function.
+func (c *Context) GetProcAddress(procname string) unsafe.Pointer {
+	return EglGetProcAddress(procname)
+}
+
+// ExtensionSupported reports whether the current context supports a
+// client API extension, or whether the display supports an EGL extension.
+func (c *Context) ExtensionSupported(extension string) bool {
+	if c.glFuncs == nil {
+		// Core functions such as glGetString are only available from
+		// eglGetProcAddress since EGL 1.5, so load them from the library.
+		f, err := gl.NewFunctions(nil, true)
+		if err != nil {
+			return false
+		}
+		c.glFuncs = f
+	}
+	if hasExtension(c.glFuncs.Extensions(), extension) {
+		return true
+	}
+	exts := strings.Split(EglQueryString(c.disp, EGL_EXTENSIONS), " ")
+	return hasExtension(exts, extension)
+}
+
+func hasExtension(exts []string, ext string) bool {
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func createContext(disp EGLDisplay) (*eglContext, error) {
+	major, minor, ret := EglInitialize(disp)
+	if !ret {
+		return nil, fmt.Errorf("eglInitialize failed: 0x%x", EglGetError())
+	}
+	// sRGB framebuffer support on EGL 1.5 or if EGL_KHR_gl_colorspace is supported.
+	exts := strings.Split(EglQueryString(disp, EGL_EXTENSIONS), " ")
+	srgb := major > 1 || minor >= 5 || hasExtension(exts, "EGL_KHR_gl_colorspace")
+	attribs := []EGLint{
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_ES2_BIT,
+		EGL_SURFACE_TYPE, EGL_WINDOW_BIT,
+		EGL_BLUE_SIZE, 8,
+		EGL_GREEN_SIZE, 8,
+		EGL_RED_SIZE, 8,
+		EGL_CONFIG_CAVEAT, EGL_NONE,
+	}
+	if srgb {
+		if runtime.GOOS == "linux" || runtime.GOOS == "android" {
+			// Some Mesa drivers crash if an sRGB framebuffer is requested without alpha.
+			// https://bugs.freedesktop.org/show_bug.cgi?id=107782.
+			//
+			// Also, some Android devices (Samsung S9) need alpha for sRGB to work.
+			attribs = append(attribs, EGL_ALPHA_SIZE, 8)
+		}
+	}
+	attribs = append(attribs, EGL_NONE)
+
+	eglCfg, ret := EglChooseConfig(disp, attribs)
+	if !ret {
+		return nil, fmt.Errorf("eglChooseConfig failed: 0x%x", EglGetError())
+	}
+	if eglCfg == NilEGLConfig {
+		supportsNoCfg := hasExtension(exts, "EGL_KHR_no_config_context")
+		if !supportsNoCfg {
+			return nil, errors.New("eglChooseConfig returned no configs")
+		}
+	}
+	var visID EGLint
+	if eglCfg != NilEGLConfig {
+		var ok bool
+		visID, ok = EglGetConfigAttrib(disp, eglCfg, EGL_NATIVE_VISUAL_ID)
+		if !ok {
+			return nil, errors.New("newContext: eglGetConfigAttrib for _EGL_NATIVE_VISUAL_ID failed")
+		}
+	}
+	ctxAttribs := []EGLint{
+		EGL_CONTEXT_CLIENT_VERSION, 3,
+		EGL_NONE,
+	}
+	eglCtx := createSharedContext(disp, eglCfg, ctxAttribs)
+	if eglCtx == NilEGLContext {
+		// Fall back to OpenGL ES 2 and rely on extensions.
+		ctxAttribs := []EGLint{
+			EGL_CONTEXT_CLIENT_VERSION, 2,
+			EGL_NONE,
+		}
+		eglCtx = createSharedContext(disp, eglCfg, ctxAttribs)
+		if eglCtx == NilEGLContext {
+			return nil, fmt.Errorf("eglCreateContext failed: 0x%x", EglGetError())
+		}
+	}
+	return &eglContext{
+		config:      EGLConfig(eglCfg),
+		ctx:         EGLContext(eglCtx),
+		visualID:    int(visID),
+		srgb:        srgb,
+		surfaceless: hasExtension(exts, "EGL_KHR_surfaceless_context"),
+	}, nil
+}
+
+func glfwCreateContext(disp EGLDisplay, eglApi uint) (*eglContext, []EGLint, error) {
+	major, minor, ret := EglInitialize(disp)
+	if !ret {
+		return nil, nil, fmt.Errorf("eglInitialize failed: 0x%x", EglGetError())
+	}
+	// sRGB framebuffer support on EGL 1.5 or if EGL_KHR_gl_colorspace is supported.
+	exts := strings.Split(EglQueryString(disp, EGL_EXTENSIONS), " ")
+	srgb := major > 1 || minor >= 5 || hasExtension(exts, "EGL_KHR_gl_colorspace")
+	mado.GlfwConfig.PlatformContext.Major = int(major)
+	mado.GlfwConfig.PlatformContext.Minor = int(minor)
+	mado.GlfwConfig.PlatformContext.EGL_KHR_create_context = hasExtension(exts, "EGL_KHR_create_context")
+	mado.GlfwConfig.PlatformContext.KHR_create_context_no_error = hasExtension(exts, "KHR_create_context_no_error")
+	mado.GlfwConfig.PlatformContext.KHR_gl_colorspace = hasExtension(exts, "KHR_gl_colorspace")
+	mado.GlfwConfig.PlatformContext.KHR_get_all_proc_addresses = hasExtension(exts, "KHR_get_all_proc_addresses")
+	mado.GlfwConfig.PlatformContext.KHR_context_flush_control = hasExtension(exts, "KHR_context_flush_control")
+	mado.GlfwConfig.PlatformContext.EXT_present_opaque = hasExtension(exts, "EXT_present_opaque")
+	cfg := mado.GlfwConfig.PlatformContext
+	ctxconfig := &mado.GlfwConfig.Hints.Context
+	fbconfig := &mado.GlfwConfig.Hints.Framebuffer
+
+	var eglCfg EGLConfig
+
+	if _config, err := chooseEGLConfig(disp, ctxconfig, fbconfig); err != nil {
+		return nil, nil, fmt.Errorf("EGL: Failed to find a suitable EGLConfig")
+	} else {
+		eglCfg = _config
+	}
+
+	if ok := EglBindAPI(eglApi); !ok {
+		return nil, nil, errors.New("eglBindAPI: bind EGL Api failed")
+	}
+
+	var attribs []EGLint
+	if cfg.EGL_KHR_create_context {
+		mask := 0
+		flags := 0
+
+		if ctxconfig.Client == mado.GLFW_OPENGL_API {
+			if ctxconfig.Forward {
+				flags |= EGL_CONTEXT_OPENGL_FORWARD_COMPATIBLE_BIT_KHR
+			}
+			if ctxconfig.Profile == mado.GLFW_OPENGL_CORE_PROFILE {
+				mask |= EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT_KHR
+			} else if ctxconfig.Profile == mado.GLFW_OPENGL_COMPAT_PROFILE {
+				mask |= EGL_CONTEXT_OPENGL_COMPATIBILITY_PROFILE_BIT_KHR
+			}
+		}
+
+		if ctxconfig.Debug {
+			flags |= EGL_CONTEXT_OPENGL_DEBUG_BIT_KHR
+		}
+
+		if ctxconfig.Robustness != 0 {
+			if ctxconfig.Robustness == mado.GLFW_NO_RESET_NOTIFICATION {
+				attribs = append(attribs, EGL_CONTEXT_OPENGL_RESET_NOTIFICATION_STRATEGY_KHR,
+					EGL_NO_RESET_NOTIFICATION_KHR)
+			} else if ctxconfig.Robustness == mado.GLFW_LOSE_CONTEXT_ON_RESET {
+				attribs = append(attribs, EGL_CONTEXT_OPENGL_RESET_NOTIFICATION_STRATEGY_KHR,
+					EGL_LOSE_CONTEXT_ON_RESET_KHR)
+			}
+
+			flags |= EGL_CONTEXT_OPENGL_ROBUST_ACCESS_BIT_KHR
+		}
+
+		if ctxconfig.Major != 1 || ctxconfig.Minor != 0 {
+			attribs = append(attribs, EGL_CONTEXT_MAJOR_VERSION_KHR, EGLint(ctxconfig.Major))
+			attribs = append(attribs, EGL_CONTEXT_MINOR_VERSION_KHR, EGLint(ctxconfig.Minor))
+		}
+
+		if ctxconfig.Noerror {
+			if cfg.KHR_create_context_no_error {
+				flags |= EGL_CONTEXT_OPENGL_NO_ERROR_KHR
+			}
+		}
+
+		if mask != 0 {
+			attribs = append(attribs, EGL_CONTEXT_OPENGL_PROFILE_MASK_KHR, EGLint(mask))
+
+		}
+
+		if flags != 0 {
+			attribs = append(attribs, EGL_CONTEXT_FLAGS_KHR, EGLint(flags))
+		}
+	} else {
+		if ctxconfig.Client == mado.GLFW_OPENGL_ES_API {
+			attribs = append(attribs, EGL_CONTEXT_CLIENT_VERSION, EGLint(ctxconfig.Major))
+		}
+	}
+
+	if cfg.KHR_context_flush_control {
+		if ctxconfig.Release == mado.GLFW_RELEASE_BEHAVIOR_NONE {
+			attribs = append(attribs, EGL_CONTEXT_RELEASE_BEHAVIOR_KHR,
+				EGL_CONTEXT_RELEASE_BEHAVIOR_NONE_KHR)
+		} else if ctxconfig.Release == mado.GLFW_RELEASE_BEHAVIOR_FLUSH {
+			attribs = append(attribs, EGL_CONTEXT_RELEASE_BEHAVIOR_KHR,
+				EGL_CONTEXT_RELEASE_BEHAVIOR_FLUSH_KHR)
+		}
+	}
+
+	attribs = append(attribs, EGL_NONE, EGL_NONE)
+
+	var visID EGLint
+	if eglCfg != NilEGLConfig {
+		var ok bool
+		visID, ok = EglGetConfigAttrib(disp, eglCfg, EGL_NATIVE_VISUAL_ID)
+		if !ok {
+			return nil, nil, errors.New("newContext: eglGetConfigAttrib for _EGL_NATIVE_VISUAL_ID failed")
+		}
+	}
+	eglCtx := createSharedContext(disp, eglCfg, attribs)
+
+	if eglCtx == NilEGLContext {
+		return nil, nil, fmt.Errorf("EGL: Failed to create context: %d", EglGetError())
+	}
+
+	// // Set up attributes for surface creation
+	attribs = []EGLint{}
+
+	if fbconfig.SRGB {
+		if cfg.KHR_gl_colorspace {
+			attribs = append(attribs, EGL_GL_COLORSPACE_KHR, EGL_GL_COLORSPACE_SRGB_KHR)
+		}
+	}
+
+	if !fbconfig.Doublebuffer {
+		attribs = append(attribs, EGL_RENDER_BUFFER, EGL_SINGLE_BUFFER)
+	}
+
+	// _GLFW_WAYLAND
+	if mado.GlfwConfig.WindowType == mado.WindowTypeWayland {
+		if cfg.EXT_present_opaque {
+			var transparentInt EGLint
+			if !fbconfig.Transparent {
+				transparentInt = 1
+			}
+			attribs = append(attribs, EGL_PRESENT_OPAQUE_EXT, transparentInt)
+		}
+	}
+
+	attribs = append(attribs, EGL_NONE, EGL_NONE)
+
+	// // Load the appropriate client library
+	// if !cfg.EGL_KKHR_get_all_proc_addresses {
+	// }
+
+	return &eglContext{
+		config:      EGLConfig(eglCfg),
+		ctx:         EGLContext(eglCtx),
+		visualID:    int(visID),
+		srgb:        srgb,
+		surfaceless: hasExtension(exts, "EGL_KHR_surfaceless_context"),
+	}, attribs, nil
+}
+
+func chooseEGLConfig(disp EGLDisplay, ctxconfig *mado.CtxConfig, fbconfig *mado.FbConfig) (EGLConfig, error) {
+	var wrongApiAvailable bool
+	apiBit := EGL_OPENGL_BIT
+	if ctxconfig.Client == mado.GLFW_OPENGL_ES_API {
+		if ctxconfig.Major == 1 {
+			apiBit = EGL_OPENGL_ES_BIT
+
+		} else {
+			apiBit = EGL_OPENGL_ES2_BIT
+		}
+	}
+
+	if fbconfig.Stereo {
+		return EGLConfig(0), fmt.Errorf("EGL: Stereo rendering not supported")
+	}
+
+	var nativeCount int
+	EglGetConfigs(disp, nil, 0, &nativeCount)
+	if nativeCount == 0 {
+		return EGLConfig(0), fmt.Errorf("EGL: No EGLConfigs returned")
+
+	}
+
+	nativeConfigs := make([]EGLConfig, nativeCount)
+	EglGetConfigs(disp, nativeConfigs, nativeCount, &nativeCount)
+
+	usableConfigs := []*mado.FbConfig{}
+
+	for i := 0; i < nativeCount; i++ {
+		n := nativeConfigs[i]
+		u := &mado.FbConfig{}
+
+		// Only consider RGB(A) EGLConfigs
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_COLOR_BUFFER_TYPE); ok {
+			if val != EGL_RGB_BUFFER {
+				continue
+			}
+		}
+
+		// Only consider window EGLConfigs
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_SURFACE_TYPE); ok {
+			if val&EGL_WINDOW_BIT == 0 {
+				continue
+			}
+		}
+
+		// _GLFW_X11
+		if mado.GlfwConfig.WindowType == mado.WindowTypeX11 {
+			// Only consider EGLConfigs with associated Visuals
+			if val, ok := EglGetConfigAttrib(disp, n, EGL_NATIVE_VISUAL_ID); ok {
+				if val == 0 {
+					continue
+				}
+			}
+		}
+
+		if ctxconfig.Client == mado.GLFW_OPENGL_ES_API {
+			if ctxconfig.Major == 1 {
+				if val, ok := EglGetConfigAttrib(disp, n, EGL_RENDERABLE_TYPE); ok {
+					if val&EGL_OPENGL_ES_BIT == 0 {
+						continue
+					}
+				}
+			} else {
+				if val, ok := EglGetConfigAttrib(disp, n, EGL_RENDERABLE_TYPE); ok {
+					if val&EGL_OPENGL_ES2_BIT == 0 {
+						continue
+					}
+				}
+			}
+		} else if ctxconfig.Client == mado.GLFW_OPENGL_API {
+			if val, ok := EglGetConfigAttrib(disp, n, EGL_RENDERABLE_TYPE); ok {
+				if val&EGL_OPENGL_BIT == 0 {
+					continue
+				}
+			}
+		}
+
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_RENDERABLE_TYPE); ok {
+			if int(val)&apiBit == 0 {
+				wrongApiAvailable = true
+				continue
+			}
+		}
+
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_RED_SIZE); ok {
+			u.RedBits = int(val)
+		}
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_GREEN_SIZE); ok {
+			u.GreenBits = int(val)
+		}
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_BLUE_SIZE); ok {
+			u.BlueBits = int(val)
+		}
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_ALPHA_SIZE); ok {
+			u.AlphaBits = int(val)
+		}
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_DEPTH_SIZE); ok {
+			u.DepthBits = int(val)
+		}
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_STENCIL_SIZE); ok {
+			u.StencilBits = int(val)
+		}
+
+		// GLFW_WAYLAND
+		if mado.GlfwConfig.WindowType == mado.WindowTypeWayland {
+			// NOTE: The wl_surface opaque region is no guarantee that its buffer
+			//       is presented as opaque, if it also has an alpha channel
+			// HACK: If EGL_EXT_present_opaque is unavailable, ignore any config
+			//       with an alpha channel to ensure the buffer is opaque
+			if !mado.GlfwConfig.PlatformContext.EXT_present_opaque {
+				if !fbconfig.Transparent && u.AlphaBits > 0 {
+					continue
+				}
+			}
+		}
+		if val, ok := EglGetConfigAttrib(disp, n, EGL_SAMPLES); ok {
+			u.Samples = int(val)
+		}
+
+		u.Doublebuffer = fbconfig.Doublebuffer
+
+		u.Handle = uintptr(n)
+		usableConfigs = append(usableConfigs, u)
+	}
+
+	closest := mado.ChooseFBConfig(fbconfig, usableConfigs)
+	if closest == nil {
+		if wrongApiAvailable {
+			if ctxconfig.Client == mado.GLFW_OPENGL_ES_API {
+				if ctxconfig.Major == 1 {
+					return EGLConfig(0), fmt.Errorf("EGL: Failed to find support for OpenGL ES 1.x")
+				} else {
+					return EGLConfig(0), fmt.Errorf("EGL: Failed to find support for OpenGL ES 2 or later")
+				}
+			} else {
+				return EGLConfig(0), fmt.Errorf("EGL: Failed to find support for OpenGL")
+			}
+		} else {
+			return EGLConfig(0), fmt.Errorf("EGL: Failed to find a suitable EGLConfig")
+		}
+	}
+
+	return EGLConfig(closest.Handle), nil
+}
+
+func createSurface(disp EGLDisplay, eglCtx *eglContext, attribs []EGLint, win NativeWindowType) (EGLSurface, error) {
+	var surfAttribs []EGLint
+	if attribs == nil {
+		if eglCtx.srgb {
+			surfAttribs = append(surfAttribs, EGL_GL_COLORSPACE_KHR, EGL_GL_COLORSPACE_SRGB_KHR)
+		}
+		surfAttribs = append(surfAttribs, EGL_NONE)
+	} else {
+		surfAttribs = attribs
+	}
+	eglSurf := EglCreateWindowSurface(disp, eglCtx.config, win, surfAttribs)
+	if eglSurf == NilEGLSurface && eglCtx.srgb {
+		// Try again without sRGB.
+		eglCtx.srgb = false
+		surfAttribs = []EGLint{EGL_NONE}
+		eglSurf = EglCreateWindowSurface(disp, eglCtx.config, win, surfAttribs)
+	}
+	if eglSurf == NilEGLSurface {
+		return NilEGLSurface, fmt.Errorf("newContext: eglCreateWindowSurface failed 0x%x (sRGB=%v)", EglGetError(), eglCtx.srgb)
+	}
+	return eglSurf, nil
+}
@@ -14,11 +14,14 @@ package egl
 #cgo openbsd LDFLAGS: -L/usr/X11R6/lib
 #cgo CFLAGS: -DEGL_NO_X11
 
+#include <stdlib.h>
 #include <EGL/egl.h>
 #include <EGL/eglext.h>
 */
 import "C"
 
+import "unsafe"
+
 type (
 	EGLenum           = C.EGLenum
 	EGLint            = C.EGLint
@@ -124,6 +127,12 @@ func EglQueryString(disp EGLDisplay, name EGLint) string {
 	return C.GoString(C.eglQueryString(disp, name))
 }
 
+func EglGetProcAddress(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return unsafe.Pointer(C.eglGetProcAddress(cname))
+}
+
 func EglGetDisplay(disp NativeDisplayType) EGLDisplay {
 	return C.eglGetDisplay(disp)
 }
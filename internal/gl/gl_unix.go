@@ -1162,6 +1162,27 @@ func (f *Functions) getStringi(pname Enum, index int) string {
 	return C.GoString((*C.char)(unsafe.Pointer(str)))
 }
 
+// Extensions returns the extensions of the current OpenGL or OpenGL ES
+// context.
+func (f *Functions) Extensions() []string {
+	// GL_NUM_EXTENSIONS is only valid from OpenGL (ES) 3.0, and the
+	// core profile no longer supports glGetString(GL_EXTENSIONS).
+	if f.glGetStringi != nil {
+		if n := f.GetInteger(NUM_EXTENSIONS); n > 0 {
+			exts := make([]string, 0, n)
+			for i := 0; i < n; i++ {
+				exts = append(exts, f.getStringi(EXTENSIONS, i))
+			}
+			return exts
+		}
+	}
+	str := C.glGetString(f.glGetString, C.GLenum(EXTENSIONS))
+	if str == nil {
+		return nil
+	}
+	return strings.Fields(C.GoString((*C.char)(unsafe.Pointer(str))))
+}
+
 func (f *Functions) GetString(pname Enum) string {
 	switch {
 	case runtime.GOOS == "darwin" && pname == EXTENSIONS:
@@ -137,5 +137,11 @@ func GetProcAddressWGL(procname string) uintptr {
 	if proc != 0 {
 		return proc
 	}
-	return OpenGL32.NewProc(procname).Addr()
+	// Core functions are exported by opengl32.dll instead. Find, unlike
+	// Addr, doesn't panic on missing functions.
+	p := OpenGL32.NewProc(procname)
+	if err := p.Find(); err != nil {
+		return 0
+	}
+	return p.Addr()
 }
@@ -25,6 +25,10 @@ static void vkDestroyInstance(PFN_vkDestroyInstance f, VkInstance instance, cons
 	f(instance, pAllocator);
 }
 
+static VkResult vkEnumerateInstanceExtensionProperties(PFN_vkEnumerateInstanceExtensionProperties f, const char *pLayerName, uint32_t *pPropertyCount, VkExtensionProperties *pProperties) {
+	return f(pLayerName, pPropertyCount, pProperties);
+}
+
 static VkResult vkEnumeratePhysicalDevices(PFN_vkEnumeratePhysicalDevices f, VkInstance instance, uint32_t *pPhysicalDeviceCount, VkPhysicalDevice *pPhysicalDevices) {
 	return f(instance, pPhysicalDeviceCount, pPhysicalDevices);
 }
@@ -598,6 +602,8 @@ var funcs struct {
 	vkCreateInstance                         C.PFN_vkCreateInstance
 	vkDestroyInstance                        C.PFN_vkDestroyInstance
 	vkEnumeratePhysicalDevices               C.PFN_vkEnumeratePhysicalDevices
+	vkGetInstanceProcAddr                    C.PFN_vkGetInstanceProcAddr
+	vkEnumerateInstanceExtensionProperties   C.PFN_vkEnumerateInstanceExtensionProperties
 	vkGetPhysicalDeviceQueueFamilyProperties C.PFN_vkGetPhysicalDeviceQueueFamilyProperties
 	vkGetPhysicalDeviceFormatProperties      C.PFN_vkGetPhysicalDeviceFormatProperties
 	vkCreateDevice                           C.PFN_vkCreateDevice
@@ -739,6 +745,8 @@ func vkInit() error {
 		funcs.vkCreateInstance = must("vkCreateInstance")
 		funcs.vkDestroyInstance = must("vkDestroyInstance")
 		funcs.vkEnumeratePhysicalDevices = must("vkEnumeratePhysicalDevices")
+		funcs.vkGetInstanceProcAddr = must("vkGetInstanceProcAddr")
+		funcs.vkEnumerateInstanceExtensionProperties = must("vkEnumerateInstanceExtensionProperties")
 		funcs.vkGetPhysicalDeviceQueueFamilyProperties = must("vkGetPhysicalDeviceQueueFamilyProperties")
 		funcs.vkGetPhysicalDeviceFormatProperties = must("vkGetPhysicalDeviceFormatProperties")
 		funcs.vkCreateDevice = must("vkCreateDevice")
@@ -865,6 +873,39 @@ func CreateInstance(exts ...string) (Instance, error) {
 	return inst, nil
 }
 
+// GetInstanceProcAddr returns the vkGetInstanceProcAddr function of the
+// Vulkan loader.
+func GetInstanceProcAddr() (unsafe.Pointer, error) {
+	if err := vkInit(); err != nil {
+		return nil, err
+	}
+	return unsafe.Pointer(funcs.vkGetInstanceProcAddr), nil
+}
+
+// EnumerateInstanceExtensions returns the names of the instance extensions
+// supported by the Vulkan loader.
+func EnumerateInstanceExtensions() ([]string, error) {
+	if err := vkInit(); err != nil {
+		return nil, err
+	}
+	var count C.uint32_t
+	if err := vkErr(C.vkEnumerateInstanceExtensionProperties(funcs.vkEnumerateInstanceExtensionProperties, nil, &count, nil)); err != nil {
+		return nil, fmt.Errorf("vulkan: vkEnumerateInstanceExtensionProperties: %w", err)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	props := make([]C.VkExtensionProperties, count)
+	if err := vkErr(C.vkEnumerateInstanceExtensionProperties(funcs.vkEnumerateInstanceExtensionProperties, nil, &count, &props[0])); err != nil {
+		return nil, fmt.Errorf("vulkan: vkEnumerateInstanceExtensionProperties: %w", err)
+	}
+	exts := make([]string, count)
+	for i := range exts {
+		exts[i] = C.GoString(&props[i].extensionName[0])
+	}
+	return exts, nil
+}
+
 func mallocCStringArr(s []string) []*C.char {
 	carr := make([]*C.char, len(s))
 	for i, ext := range s {
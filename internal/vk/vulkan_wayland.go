@@ -34,14 +34,16 @@ func init() {
 	})
 }
 
-func CreateWaylandSurface(inst Instance, disp unsafe.Pointer, wlSurf unsafe.Pointer) (Surface, error) {
+// CreateWaylandSurface creates a surface for a Wayland surface. The optional
+// alloc points to the VkAllocationCallbacks for the surface.
+func CreateWaylandSurface(inst Instance, disp unsafe.Pointer, wlSurf unsafe.Pointer, alloc unsafe.Pointer) (Surface, error) {
 	inf := C.VkWaylandSurfaceCreateInfoKHR{
 		sType:   C.VK_STRUCTURE_TYPE_WAYLAND_SURFACE_CREATE_INFO_KHR,
 		display: (*C.struct_wl_display)(disp),
 		surface: (*C.struct_wl_surface)(wlSurf),
 	}
 	var surf Surface
-	if err := vkErr(C.vkCreateWaylandSurfaceKHR(wlFuncs.vkCreateWaylandSurfaceKHR, inst, &inf, nil, &surf)); err != nil {
+	if err := vkErr(C.vkCreateWaylandSurfaceKHR(wlFuncs.vkCreateWaylandSurfaceKHR, inst, &inf, (*C.VkAllocationCallbacks)(alloc), &surf)); err != nil {
 		return 0, fmt.Errorf("vulkan: vkCreateWaylandSurfaceKHR: %w", err)
 	}
 	return surf, nil
@@ -32,14 +32,16 @@ func init() {
 	})
 }
 
-func CreateXlibSurface(inst Instance, dpy unsafe.Pointer, window uintptr) (Surface, error) {
+// CreateXlibSurface creates a surface for an X11 window. The optional alloc
+// points to the VkAllocationCallbacks for the surface.
+func CreateXlibSurface(inst Instance, dpy unsafe.Pointer, window uintptr, alloc unsafe.Pointer) (Surface, error) {
 	inf := C.VkXlibSurfaceCreateInfoKHR{
 		sType:  C.VK_STRUCTURE_TYPE_XLIB_SURFACE_CREATE_INFO_KHR,
 		dpy:    (*C.Display)(dpy),
 		window: (C.Window)(window),
 	}
 	var surf Surface
-	if err := vkErr(C.vkCreateXlibSurfaceKHR(x11Funcs.vkCreateXlibSurfaceKHR, inst, &inf, nil, &surf)); err != nil {
+	if err := vkErr(C.vkCreateXlibSurfaceKHR(x11Funcs.vkCreateXlibSurfaceKHR, inst, &inf, (*C.VkAllocationCallbacks)(alloc), &surf)); err != nil {
 		return 0, fmt.Errorf("vulkan: vkCreateXlibSurfaceKHR: %w", err)
 	}
 	return surf, nil
@@ -8,16 +8,27 @@ import (
 	"github.com/kanryu/mado/io/event"
 )
 
-// WriteCmd copies Text to the clipboard.
+// WriteCmd copies Data to the clipboard. The WriteCmds executed
+// during a frame are offered together, one for each MIME Type, in
+// order of preference.
 type WriteCmd struct {
+	// Type is the MIME type of Data. Text is "application/text".
 	Type string
 	Data io.ReadCloser
 }
 
-// ReadCmd requests the text of the clipboard, delivered to
+// ReadCmd requests the content of the clipboard, delivered to
 // the handler through an [io/transfer.DataEvent].
 type ReadCmd struct {
 	Tag event.Tag
+	// Type is the requested MIME type. The empty Type requests
+	// text, "application/text".
+	Type string
+	// Primary requests the primary selection instead of the
+	// clipboard, on platforms that have one. The primary selection
+	// holds the most recently selected text, as pasted by a middle
+	// click.
+	Primary bool
 }
 
 func (WriteCmd) ImplementsCommand() {}
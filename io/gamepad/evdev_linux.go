@@ -0,0 +1,564 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build linux && !android
+// +build linux,!android
+
+package gamepad
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// Event types and codes from linux/input-event-codes.h.
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evAbs = 0x03
+	evCnt = 0x20
+
+	synReport  = 0
+	synDropped = 3
+
+	btnMisc         = 0x100
+	btnJoystick     = 0x120
+	btnSouth        = 0x130
+	btnEast         = 0x131
+	btnNorth        = 0x133
+	btnWest         = 0x134
+	btnTL           = 0x136
+	btnTR           = 0x137
+	btnTL2          = 0x138
+	btnTR2          = 0x139
+	btnSelect       = 0x13a
+	btnStart        = 0x13b
+	btnMode         = 0x13c
+	btnThumbL       = 0x13d
+	btnThumbR       = 0x13e
+	btnDigi         = 0x140
+	btnToolFinger   = 0x145
+	btnDpadUp       = 0x220
+	btnDpadDown     = 0x221
+	btnDpadLeft     = 0x222
+	btnDpadRight    = 0x223
+	btnTriggerHappy = 0x2c0
+	keyCnt          = 0x300
+
+	absX     = 0x00
+	absY     = 0x01
+	absZ     = 0x02
+	absRX    = 0x03
+	absRY    = 0x04
+	absRZ    = 0x05
+	absHat0X = 0x10
+	absHat0Y = 0x11
+	absHat3Y = 0x17
+	absCnt   = 0x40
+)
+
+// Request numbers of the evdev ioctls.
+const (
+	eviocgid      = 0x02
+	eviocgname    = 0x06
+	eviocgkey     = 0x18
+	eviocgbitBase = 0x20
+	eviocgabs     = 0x40
+)
+
+const devInput = "/dev/input"
+
+// inputEvent is struct input_event.
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// inputID is struct input_id.
+type inputID struct {
+	Bustype uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+// absInfo is struct input_absinfo.
+type absInfo struct {
+	Value      int32
+	Minimum    int32
+	Maximum    int32
+	Fuzz       int32
+	Flat       int32
+	Resolution int32
+}
+
+// evdevBackend reads joysticks from the evdev devices in /dev/input.
+type evdevBackend struct {
+	// inotify watches /dev/input for hotplug, or is -1.
+	inotify int
+	devices map[string]*evdevDevice
+	scanned bool
+}
+
+// evdevDevice is an open joystick device.
+type evdevDevice struct {
+	path string
+	fd   int
+	joy  *Joystick
+	// keys maps key codes from btnMisc to buttons, or -1.
+	keys [keyCnt - btnMisc]int
+	// abs maps absolute axis codes to axes or hats, or -1.
+	abs     [absCnt]int
+	absInfo [absCnt]absInfo
+	// hats are the hat positions, indexed by hat code.
+	hats [(absHat3Y - absHat0X + 1) / 2][2]int32
+	// dropped is set while the kernel drops events.
+	dropped bool
+}
+
+// hatStates maps hat positions to hat states.
+var hatStates = [3][3]Hat{
+	{HatLeft | HatUp, HatLeft, HatLeft | HatDown},
+	{HatUp, HatCentered, HatDown},
+	{HatRight | HatUp, HatRight, HatRight | HatDown},
+}
+
+func init() {
+	newBackend = newEvdevBackend
+}
+
+func newEvdevBackend() (backend, error) {
+	b := &evdevBackend{
+		inotify: -1,
+		devices: make(map[string]*evdevDevice),
+	}
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err == nil {
+		// Devices are readable after udev has set their permissions, so
+		// watch for attribute changes as well.
+		_, err = syscall.InotifyAddWatch(fd, devInput, syscall.IN_CREATE|syscall.IN_ATTRIB|syscall.IN_DELETE)
+		if err != nil {
+			syscall.Close(fd)
+		} else {
+			b.inotify = fd
+		}
+	}
+	// Joysticks still work without hotplug.
+	return b, nil
+}
+
+func (b *evdevBackend) close() {
+	for _, d := range b.devices {
+		d.close()
+	}
+	b.devices = nil
+	if b.inotify != -1 {
+		syscall.Close(b.inotify)
+		b.inotify = -1
+	}
+}
+
+func (b *evdevBackend) poll(s *Source) {
+	if !b.scanned {
+		b.scanned = true
+		b.scan(s)
+	}
+	b.readHotplug(s)
+	for path, d := range b.devices {
+		if err := d.read(s); err != nil {
+			d.close()
+			s.disconnect(d.joy)
+			delete(b.devices, path)
+		}
+	}
+}
+
+// scan opens the joysticks present at startup.
+func (b *evdevBackend) scan(s *Source) {
+	entries, err := os.ReadDir(devInput)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if isEventDevice(e.Name()) {
+			b.open(s, filepath.Join(devInput, e.Name()))
+		}
+	}
+}
+
+// readHotplug handles the pending inotify events.
+func (b *evdevBackend) readHotplug(s *Source) {
+	if b.inotify == -1 {
+		return
+	}
+	var buf [4096]byte
+	for {
+		n, err := syscall.Read(b.inotify, buf[:])
+		if err != nil || n <= 0 {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + syscall.SizeofInotifyEvent
+			off = nameStart + int(ev.Len)
+			if off > n {
+				break
+			}
+			name := string(bytes.TrimRight(buf[nameStart:off], "\x00"))
+			if !isEventDevice(name) {
+				continue
+			}
+			path := filepath.Join(devInput, name)
+			switch {
+			case ev.Mask&(syscall.IN_CREATE|syscall.IN_ATTRIB) != 0:
+				b.open(s, path)
+			case ev.Mask&syscall.IN_DELETE != 0:
+				if d, ok := b.devices[path]; ok {
+					d.close()
+					s.disconnect(d.joy)
+					delete(b.devices, path)
+				}
+			}
+		}
+	}
+}
+
+// isEventDevice reports whether name is an evdev device node.
+func isEventDevice(name string) bool {
+	n, ok := strings.CutPrefix(name, "event")
+	if !ok || n == "" {
+		return false
+	}
+	_, err := strconv.Atoi(n)
+	return err == nil
+}
+
+// open connects the device at path, if it is a joystick.
+func (b *evdevBackend) open(s *Source, path string) {
+	if _, exists := b.devices[path]; exists {
+		return
+	}
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		// Not readable (yet).
+		return
+	}
+	d, err := newEvdevDevice(s, path, fd)
+	if err != nil {
+		syscall.Close(fd)
+		return
+	}
+	b.devices[path] = d
+}
+
+var errNotJoystick = errors.New("gamepad: not a joystick")
+
+func newEvdevDevice(s *Source, path string, fd int) (*evdevDevice, error) {
+	var evBits [(evCnt + 7) / 8]byte
+	var keyBits [(keyCnt + 7) / 8]byte
+	var absBits [(absCnt + 7) / 8]byte
+	var id inputID
+	if err := ioctl(fd, eviocgbit(0, len(evBits)), unsafe.Pointer(&evBits)); err != nil {
+		return nil, err
+	}
+	if err := ioctl(fd, eviocgbit(evKey, len(keyBits)), unsafe.Pointer(&keyBits)); err != nil {
+		return nil, err
+	}
+	if err := ioctl(fd, eviocgbit(evAbs, len(absBits)), unsafe.Pointer(&absBits)); err != nil {
+		return nil, err
+	}
+	if err := ioctl(fd, ioc(eviocgid, unsafe.Sizeof(id)), unsafe.Pointer(&id)); err != nil {
+		return nil, err
+	}
+	if !isJoystick(evBits[:], keyBits[:]) {
+		return nil, errNotJoystick
+	}
+	var nameBuf [256]byte
+	name := "Unknown"
+	if ioctl(fd, ioc(eviocgname, uintptr(len(nameBuf))), unsafe.Pointer(&nameBuf)) == nil {
+		if n := string(bytes.TrimRight(nameBuf[:], "\x00")); n != "" {
+			name = n
+		}
+	}
+	d := &evdevDevice{path: path, fd: fd}
+	axes, buttons, hats := d.layout(keyBits[:], absBits[:])
+	guid := deviceGUID(id, name)
+	d.joy = s.connect(name, guid, axes, buttons, hats, d.defaultMapping(name, guid))
+	if d.joy == nil {
+		return nil, errors.New("gamepad: too many joysticks")
+	}
+	d.pollState(s)
+	return d, nil
+}
+
+// isJoystick reports whether a device with the given event and key
+// capabilities is a joystick rather than, say, a touchpad.
+func isJoystick(evBits, keyBits []byte) bool {
+	if !isBitSet(evKey, evBits) || !isBitSet(evAbs, evBits) {
+		return false
+	}
+	if isBitSet(btnToolFinger, keyBits) {
+		return false
+	}
+	for code := btnJoystick; code < btnDigi; code++ {
+		if isBitSet(code, keyBits) {
+			return true
+		}
+	}
+	for code := btnTriggerHappy; code < keyCnt; code++ {
+		if isBitSet(code, keyBits) {
+			return true
+		}
+	}
+	return false
+}
+
+// layout assigns joystick buttons, axes and hats to the key and
+// absolute axis codes of the device, in code order.
+func (d *evdevDevice) layout(keyBits, absBits []byte) (axes, buttons, hats int) {
+	for code := btnMisc; code < keyCnt; code++ {
+		d.keys[code-btnMisc] = -1
+		if isBitSet(code, keyBits) {
+			d.keys[code-btnMisc] = buttons
+			buttons++
+		}
+	}
+	for code := 0; code < absCnt; code++ {
+		d.abs[code] = -1
+		if !isBitSet(code, absBits) {
+			continue
+		}
+		if isHatCode(code) {
+			// A hat is a pair of X and Y axes, with X at the even
+			// code.
+			y := code | 1
+			d.abs[code], d.abs[y] = hats, hats
+			hats++
+			code = y
+			continue
+		}
+		d.abs[code] = axes
+		axes++
+	}
+	return axes, buttons, hats
+}
+
+func isHatCode(code int) bool {
+	return code >= absHat0X && code <= absHat3Y
+}
+
+// defaultMapping derives a gamepad mapping from the codes of the Linux
+// gamepad specification, or returns nil if the device doesn't follow
+// it.
+func (d *evdevDevice) defaultMapping(name, guid string) *Mapping {
+	button := func(code int) element {
+		if i := d.keys[code-btnMisc]; i != -1 {
+			return element{kind: elementButton, index: i}
+		}
+		return element{}
+	}
+	axis := func(code int) element {
+		if i := d.abs[code]; i != -1 && !isHatCode(code) {
+			return element{kind: elementAxis, index: i, scale: 1}
+		}
+		return element{}
+	}
+	if d.keys[btnSouth-btnMisc] == -1 || d.abs[absX] == -1 || d.abs[absY] == -1 {
+		return nil
+	}
+	m := &Mapping{GUID: guid, Name: name}
+	m.buttons[ButtonA] = button(btnSouth)
+	m.buttons[ButtonB] = button(btnEast)
+	m.buttons[ButtonX] = button(btnWest)
+	m.buttons[ButtonY] = button(btnNorth)
+	m.buttons[ButtonLeftBumper] = button(btnTL)
+	m.buttons[ButtonRightBumper] = button(btnTR)
+	m.buttons[ButtonBack] = button(btnSelect)
+	m.buttons[ButtonStart] = button(btnStart)
+	m.buttons[ButtonGuide] = button(btnMode)
+	m.buttons[ButtonLeftThumb] = button(btnThumbL)
+	m.buttons[ButtonRightThumb] = button(btnThumbR)
+	if hat := d.abs[absHat0X]; hat != -1 {
+		m.buttons[ButtonDpadUp] = element{kind: elementHat, index: hat, bit: HatUp}
+		m.buttons[ButtonDpadRight] = element{kind: elementHat, index: hat, bit: HatRight}
+		m.buttons[ButtonDpadDown] = element{kind: elementHat, index: hat, bit: HatDown}
+		m.buttons[ButtonDpadLeft] = element{kind: elementHat, index: hat, bit: HatLeft}
+	} else {
+		m.buttons[ButtonDpadUp] = button(btnDpadUp)
+		m.buttons[ButtonDpadRight] = button(btnDpadRight)
+		m.buttons[ButtonDpadDown] = button(btnDpadDown)
+		m.buttons[ButtonDpadLeft] = button(btnDpadLeft)
+	}
+	m.axes[AxisLeftX] = axis(absX)
+	m.axes[AxisLeftY] = axis(absY)
+	m.axes[AxisRightX] = axis(absRX)
+	m.axes[AxisRightY] = axis(absRY)
+	// Analog triggers, or digital ones.
+	m.axes[AxisLeftTrigger] = axis(absZ)
+	if m.axes[AxisLeftTrigger].kind == elementNone {
+		m.axes[AxisLeftTrigger] = button(btnTL2)
+	}
+	m.axes[AxisRightTrigger] = axis(absRZ)
+	if m.axes[AxisRightTrigger].kind == elementNone {
+		m.axes[AxisRightTrigger] = button(btnTR2)
+	}
+	return m
+}
+
+// deviceGUID formats the SDL GUID of a device.
+func deviceGUID(id inputID, name string) string {
+	if id.Vendor != 0 && id.Product != 0 && id.Version != 0 {
+		return fmt.Sprintf("%02x%02x0000%02x%02x0000%02x%02x0000%02x%02x0000",
+			id.Bustype&0xff, id.Bustype>>8,
+			id.Vendor&0xff, id.Vendor>>8,
+			id.Product&0xff, id.Product>>8,
+			id.Version&0xff, id.Version>>8)
+	}
+	var n [12]byte
+	copy(n[:], name)
+	return fmt.Sprintf("%02x%02x0000%x", id.Bustype&0xff, id.Bustype>>8, n[:])
+}
+
+func (d *evdevDevice) close() {
+	syscall.Close(d.fd)
+}
+
+// read handles the pending events of the device. It returns an error
+// if the device is gone.
+func (d *evdevDevice) read(s *Source) error {
+	var buf [64]inputEvent
+	size := int(unsafe.Sizeof(buf[0]))
+	raw := unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), len(buf)*size)
+	for {
+		n, err := syscall.Read(d.fd, raw)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return nil
+		}
+		for _, ev := range buf[:n/size] {
+			d.handle(s, ev)
+		}
+	}
+}
+
+// handle updates the joystick state from an event.
+func (d *evdevDevice) handle(s *Source, ev inputEvent) {
+	if ev.Type == evSyn {
+		switch ev.Code {
+		case synDropped:
+			d.dropped = true
+		case synReport:
+			if d.dropped {
+				d.dropped = false
+				d.pollState(s)
+			}
+		}
+		return
+	}
+	if d.dropped {
+		// The state is re-read at the next report.
+		return
+	}
+	switch ev.Type {
+	case evKey:
+		d.handleKey(s, int(ev.Code), ev.Value != 0)
+	case evAbs:
+		d.handleAbs(s, int(ev.Code), ev.Value)
+	}
+}
+
+func (d *evdevDevice) handleKey(s *Source, code int, pressed bool) {
+	if code < btnMisc || code >= keyCnt {
+		return
+	}
+	if i := d.keys[code-btnMisc]; i != -1 {
+		s.setButton(d.joy, i, pressed)
+	}
+}
+
+func (d *evdevDevice) handleAbs(s *Source, code int, value int32) {
+	if code < 0 || code >= absCnt || d.abs[code] == -1 {
+		return
+	}
+	index := d.abs[code]
+	if isHatCode(code) {
+		hat := (code - absHat0X) / 2
+		axis := (code - absHat0X) % 2
+		if value < 0 {
+			value = -1
+		} else if value > 0 {
+			value = 1
+		}
+		d.hats[hat][axis] = value
+		s.setHat(d.joy, index, hatStates[d.hats[hat][0]+1][d.hats[hat][1]+1])
+		return
+	}
+	info := d.absInfo[code]
+	v := float32(value)
+	if r := info.Maximum - info.Minimum; r != 0 {
+		// Normalize to [-1, 1].
+		v = (v-float32(info.Minimum))*2/float32(r) - 1
+	}
+	s.setAxis(d.joy, index, v)
+}
+
+// pollState reads the current key and axis state of the device.
+func (d *evdevDevice) pollState(s *Source) {
+	var keyState [(keyCnt + 7) / 8]byte
+	if ioctl(d.fd, ioc(eviocgkey, uintptr(len(keyState))), unsafe.Pointer(&keyState)) == nil {
+		for code := btnMisc; code < keyCnt; code++ {
+			d.handleKey(s, code, isBitSet(code, keyState[:]))
+		}
+	}
+	for code := 0; code < absCnt; code++ {
+		if d.abs[code] == -1 {
+			continue
+		}
+		info := &d.absInfo[code]
+		if ioctl(d.fd, ioc(eviocgabs+uintptr(code), unsafe.Sizeof(*info)), unsafe.Pointer(info)) != nil {
+			continue
+		}
+		d.handleAbs(s, code, info.Value)
+	}
+}
+
+func isBitSet(bit int, bits []byte) bool {
+	return bits[bit/8]&(1<<(bit%8)) != 0
+}
+
+// ioc encodes a read ioctl request for the evdev interface with the
+// generic Linux encoding.
+func ioc(nr, size uintptr) uintptr {
+	const (
+		iocRead = 2
+		evdev   = 'E'
+	)
+	return iocRead<<30 | size<<16 | evdev<<8 | nr
+}
+
+// eviocgbit returns the EVIOCGBIT request for the event type ev.
+func eviocgbit(ev, size int) uintptr {
+	return ioc(eviocgbitBase+uintptr(ev), uintptr(size))
+}
+
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build linux && !android
+// +build linux,!android
+
+package gamepad
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+
+	"github.com/kanryu/mado/io/event"
+)
+
+func TestDeviceGUID(t *testing.T) {
+	id := inputID{Bustype: 0x03, Vendor: 0x045e, Product: 0x028e, Version: 0x0110}
+	if got, want := deviceGUID(id, "Xbox 360 Controller"), "030000005e0400008e02000010010000"; got != want {
+		t.Errorf("got GUID %q, want %q", got, want)
+	}
+	if got, want := deviceGUID(inputID{Bustype: 0x05}, "Pad"), "05000000506164000000000000000000"; got != want {
+		t.Errorf("got GUID %q, want %q", got, want)
+	}
+}
+
+func TestIsJoystick(t *testing.T) {
+	var evBits [(evCnt + 7) / 8]byte
+	var keyBits [(keyCnt + 7) / 8]byte
+	setBit(evKey, evBits[:])
+	setBit(evAbs, evBits[:])
+	setBit(btnSouth, keyBits[:])
+	if !isJoystick(evBits[:], keyBits[:]) {
+		t.Error("gamepad not detected")
+	}
+	setBit(btnToolFinger, keyBits[:])
+	if isJoystick(evBits[:], keyBits[:]) {
+		t.Error("touchpad detected as a joystick")
+	}
+}
+
+// TestEvdevStream feeds a recorded event stream through a pipe.
+func TestEvdevStream(t *testing.T) {
+	var keyBits [(keyCnt + 7) / 8]byte
+	var absBits [(absCnt + 7) / 8]byte
+	for _, code := range []int{btnSouth, btnEast, btnStart} {
+		setBit(code, keyBits[:])
+	}
+	for _, code := range []int{absX, absY, absHat0X, absHat0Y} {
+		setBit(code, absBits[:])
+	}
+	var fds [2]int
+	if err := syscall.Pipe2(fds[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	s := new(Source)
+	d := &evdevDevice{fd: fds[0]}
+	axes, buttons, hats := d.layout(keyBits[:], absBits[:])
+	if axes != 2 || buttons != 3 || hats != 1 {
+		t.Fatalf("got %d axes, %d buttons, %d hats; want 2, 3, 1", axes, buttons, hats)
+	}
+	d.absInfo[absX] = absInfo{Minimum: -32768, Maximum: 32767}
+	d.absInfo[absY] = absInfo{Minimum: 0, Maximum: 255}
+	d.joy = s.connect("Test Pad", "guid", axes, buttons, hats, d.defaultMapping("Test Pad", "guid"))
+
+	stream := []inputEvent{
+		{Type: evKey, Code: btnSouth, Value: 1},
+		{Type: evAbs, Code: absX, Value: 32767},
+		{Type: evAbs, Code: absY, Value: 0},
+		{Type: evAbs, Code: absHat0X, Value: -1},
+		{Type: evAbs, Code: absHat0Y, Value: -1},
+		{Type: evSyn, Code: synReport},
+		// Events are ignored while the kernel drops them.
+		{Type: evSyn, Code: synDropped},
+		{Type: evKey, Code: btnEast, Value: 1},
+		{Type: evSyn, Code: synReport},
+		{Type: evKey, Code: btnSouth, Value: 0},
+		{Type: evSyn, Code: synReport},
+	}
+	raw := unsafe.Slice((*byte)(unsafe.Pointer(&stream[0])), len(stream)*int(unsafe.Sizeof(stream[0])))
+	if _, err := syscall.Write(fds[1], raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.read(s); err != nil {
+		t.Fatal(err)
+	}
+	want := []event.Event{
+		ConnectEvent{ID: 0, Connected: true},
+		ButtonEvent{ID: 0, Button: 0, Pressed: true},
+		AxisEvent{ID: 0, Axis: 0, Value: 1},
+		AxisEvent{ID: 0, Axis: 1, Value: -1},
+		HatEvent{ID: 0, Hat: 0, Value: HatLeft},
+		HatEvent{ID: 0, Hat: 0, Value: HatLeft | HatUp},
+		ButtonEvent{ID: 0, Button: 0, Pressed: false},
+	}
+	if got := s.events; !reflect.DeepEqual(got, want) {
+		t.Errorf("got events\n%v\nwant\n%v", got, want)
+	}
+	j, ok := s.Joystick(0)
+	if !ok {
+		t.Fatal("joystick not connected")
+	}
+	g, ok := j.Gamepad()
+	if !ok {
+		t.Fatal("joystick is not a gamepad")
+	}
+	if g.Axes[AxisLeftX] != 1 || !g.Buttons[ButtonDpadUp] || !g.Buttons[ButtonDpadLeft] || g.Buttons[ButtonA] {
+		t.Errorf("unexpected gamepad state %+v", g)
+	}
+}
+
+func setBit(bit int, bits []byte) {
+	bits[bit/8] |= 1 << (bit % 8)
+}
@@ -0,0 +1,358 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package gamepad reads joysticks and gamepads.
+
+A Source tracks the joysticks connected to the system. A joystick is a
+set of axes, buttons and hats, in the order the device reports them.
+Joysticks with a gamepad mapping are also available as gamepads with the
+standard Xbox-like layout. Mappings use the SDL_GameControllerDB format
+and are added with UpdateMappings. On Linux, devices following the
+kernel gamepad specification are mapped without a database entry.
+
+Joysticks are polled: Poll reads the pending device input and returns
+the resulting events.
+*/
+package gamepad
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/kanryu/mado/io/event"
+)
+
+// ID identifies a joystick slot. IDs are reused after a joystick is
+// disconnected.
+type ID int
+
+// MaxJoysticks is the number of joystick slots.
+const MaxJoysticks = 16
+
+// Hat is the state of a hat switch, as a set of directions.
+type Hat uint8
+
+// Button is a gamepad button.
+type Button uint8
+
+// Axis is a gamepad axis.
+type Axis uint8
+
+// State is the input state of a joystick.
+type State struct {
+	// Axes are in the range [-1, 1].
+	Axes    []float32
+	Buttons []bool
+	Hats    []Hat
+}
+
+// GamepadState is the input state of a joystick with a gamepad mapping.
+type GamepadState struct {
+	Buttons [ButtonCount]bool
+	// Axes are in the range [-1, 1]. Released triggers are at -1.
+	Axes [AxisCount]float32
+}
+
+// Joystick describes a connected joystick.
+type Joystick struct {
+	ID ID
+	// Name is the name reported by the device.
+	Name string
+	// GUID identifies the device model in SDL_GameControllerDB
+	// format.
+	GUID  string
+	State State
+
+	mapping *Mapping
+}
+
+// ConnectEvent is generated when a joystick is connected or
+// disconnected.
+type ConnectEvent struct {
+	ID        ID
+	Connected bool
+}
+
+// ButtonEvent is generated when a joystick button is pressed or
+// released.
+type ButtonEvent struct {
+	ID      ID
+	Button  int
+	Pressed bool
+}
+
+// AxisEvent is generated when a joystick axis moves.
+type AxisEvent struct {
+	ID    ID
+	Axis  int
+	Value float32
+}
+
+// HatEvent is generated when a joystick hat changes direction.
+type HatEvent struct {
+	ID    ID
+	Hat   int
+	Value Hat
+}
+
+// Source reads joystick input from the devices of the system. Its
+// methods may be called from any goroutine.
+type Source struct {
+	mu        sync.Mutex
+	joysticks [MaxJoysticks]*Joystick
+	// mappings is keyed by GUID.
+	mappings map[string]*Mapping
+	backend  backend
+	// err is the backend initialization error, if any.
+	err error
+	// events are generated by the backend during poll.
+	events []event.Event
+}
+
+// backend is a platform joystick driver.
+type backend interface {
+	// poll detects connected and disconnected joysticks and reads
+	// their pending input.
+	poll(s *Source)
+	close()
+}
+
+// newBackend creates the platform backend, if the platform has one.
+var newBackend func() (backend, error)
+
+var (
+	errNoBackend    = errors.New("gamepad: joysticks are not supported on this platform")
+	errSourceClosed = errors.New("gamepad: source is closed")
+)
+
+const (
+	HatCentered Hat = 0
+	HatUp       Hat = 1
+	HatRight    Hat = 2
+	HatDown     Hat = 4
+	HatLeft     Hat = 8
+)
+
+const (
+	ButtonA Button = iota
+	ButtonB
+	ButtonX
+	ButtonY
+	ButtonLeftBumper
+	ButtonRightBumper
+	ButtonBack
+	ButtonStart
+	ButtonGuide
+	ButtonLeftThumb
+	ButtonRightThumb
+	ButtonDpadUp
+	ButtonDpadRight
+	ButtonDpadDown
+	ButtonDpadLeft
+
+	// ButtonCount is the number of gamepad buttons.
+	ButtonCount = iota
+)
+
+const (
+	AxisLeftX Axis = iota
+	AxisLeftY
+	AxisRightX
+	AxisRightY
+	AxisLeftTrigger
+	AxisRightTrigger
+
+	// AxisCount is the number of gamepad axes.
+	AxisCount = iota
+)
+
+// NewSource creates a Source. The platform devices are opened by the
+// first call to a method of the source.
+func NewSource() *Source {
+	return &Source{}
+}
+
+// Poll reads the pending joystick input and connection changes, and
+// returns the resulting events.
+func (s *Source) Poll() []event.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.poll()
+	events := s.events
+	s.events = nil
+	return events
+}
+
+// Err returns the error from opening the platform joystick backend, if
+// any.
+func (s *Source) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	return s.err
+}
+
+// Joystick returns a copy of the joystick connected to slot id, without
+// polling the devices.
+func (s *Source) Joystick(id ID) (Joystick, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 0 || id >= MaxJoysticks || s.joysticks[id] == nil {
+		return Joystick{}, false
+	}
+	j := *s.joysticks[id]
+	j.State = State{
+		Axes:    append([]float32(nil), j.State.Axes...),
+		Buttons: append([]bool(nil), j.State.Buttons...),
+		Hats:    append([]Hat(nil), j.State.Hats...),
+	}
+	return j, true
+}
+
+// UpdateMappings parses the gamepad mappings of db, one per line in
+// SDL_GameControllerDB format, and adds them to the source. Mappings
+// replace earlier mappings of the same GUID. Empty lines, comments and
+// mappings for other platforms are skipped. Valid mappings are added
+// even if other lines contain errors.
+func (s *Source) UpdateMappings(db string) error {
+	mappings, err := ParseMappings(db)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mappings == nil {
+		s.mappings = make(map[string]*Mapping)
+	}
+	for _, m := range mappings {
+		s.mappings[m.GUID] = m
+	}
+	for _, j := range s.joysticks {
+		if j == nil {
+			continue
+		}
+		if m, ok := s.mappings[j.GUID]; ok {
+			j.mapping = m
+		}
+	}
+	return err
+}
+
+// Close closes the platform devices. A closed source reports no
+// joysticks.
+func (s *Source) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.backend != nil {
+		s.backend.close()
+		s.backend = nil
+	}
+	s.joysticks = [MaxJoysticks]*Joystick{}
+	s.events = nil
+	s.err = errSourceClosed
+}
+
+func (s *Source) init() {
+	if s.backend != nil || s.err != nil {
+		return
+	}
+	if newBackend == nil {
+		s.err = errNoBackend
+		return
+	}
+	s.backend, s.err = newBackend()
+}
+
+func (s *Source) poll() {
+	s.init()
+	if s.backend != nil {
+		s.backend.poll(s)
+	}
+}
+
+// connect assigns a slot to a new joystick and returns it, or nil if
+// all slots are taken. The mapping is used if the database has none
+// for the GUID.
+func (s *Source) connect(name, guid string, axes, buttons, hats int, mapping *Mapping) *Joystick {
+	for i, j := range s.joysticks {
+		if j != nil {
+			continue
+		}
+		if m, ok := s.mappings[guid]; ok {
+			mapping = m
+		}
+		j = &Joystick{
+			ID:   ID(i),
+			Name: name,
+			GUID: guid,
+			State: State{
+				Axes:    make([]float32, axes),
+				Buttons: make([]bool, buttons),
+				Hats:    make([]Hat, hats),
+			},
+			mapping: mapping,
+		}
+		s.joysticks[i] = j
+		s.events = append(s.events, ConnectEvent{ID: j.ID, Connected: true})
+		return j
+	}
+	return nil
+}
+
+func (s *Source) disconnect(j *Joystick) {
+	if s.joysticks[j.ID] != j {
+		return
+	}
+	s.joysticks[j.ID] = nil
+	s.events = append(s.events, ConnectEvent{ID: j.ID, Connected: false})
+}
+
+func (s *Source) setAxis(j *Joystick, axis int, v float32) {
+	if j.State.Axes[axis] == v {
+		return
+	}
+	j.State.Axes[axis] = v
+	s.events = append(s.events, AxisEvent{ID: j.ID, Axis: axis, Value: v})
+}
+
+func (s *Source) setButton(j *Joystick, button int, pressed bool) {
+	if j.State.Buttons[button] == pressed {
+		return
+	}
+	j.State.Buttons[button] = pressed
+	s.events = append(s.events, ButtonEvent{ID: j.ID, Button: button, Pressed: pressed})
+}
+
+func (s *Source) setHat(j *Joystick, hat int, v Hat) {
+	if j.State.Hats[hat] == v {
+		return
+	}
+	j.State.Hats[hat] = v
+	s.events = append(s.events, HatEvent{ID: j.ID, Hat: hat, Value: v})
+}
+
+// IsGamepad reports whether the joystick has a gamepad mapping that
+// fits its axes, buttons and hats.
+func (j Joystick) IsGamepad() bool {
+	return j.mapping != nil && j.mapping.fits(j.State)
+}
+
+// GamepadName returns the name of the gamepad mapping of the joystick,
+// or the empty string if the joystick is not a gamepad.
+func (j Joystick) GamepadName() string {
+	if !j.IsGamepad() {
+		return ""
+	}
+	return j.mapping.Name
+}
+
+// Gamepad returns the joystick state as a gamepad state. It returns
+// false if the joystick is not a gamepad.
+func (j Joystick) Gamepad() (GamepadState, bool) {
+	if !j.IsGamepad() {
+		return GamepadState{}, false
+	}
+	return j.mapping.apply(j.State), true
+}
+
+func (ConnectEvent) ImplementsEvent() {}
+func (ButtonEvent) ImplementsEvent()  {}
+func (AxisEvent) ImplementsEvent()    {}
+func (HatEvent) ImplementsEvent()     {}
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gamepad
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Mapping maps the axes, buttons and hats of a joystick model to the
+// gamepad layout.
+type Mapping struct {
+	// GUID is the joystick model of the mapping.
+	GUID string
+	// Name is the gamepad name.
+	Name string
+	// Platform is the platform of the mapping, or the empty string if
+	// the mapping applies to all platforms.
+	Platform string
+
+	buttons [ButtonCount]element
+	axes    [AxisCount]element
+}
+
+// element is the source of a gamepad button or axis.
+type element struct {
+	kind elementKind
+	// index is the joystick axis, button or hat.
+	index int
+	// bit is the hat direction of a hat element.
+	bit Hat
+	// scale and offset transform the range of an axis element.
+	scale, offset float32
+}
+
+type elementKind uint8
+
+const (
+	elementNone elementKind = iota
+	elementAxis
+	elementButton
+	elementHat
+)
+
+// buttonNames are the SDL names of the gamepad buttons.
+var buttonNames = [ButtonCount]string{
+	ButtonA:           "a",
+	ButtonB:           "b",
+	ButtonX:           "x",
+	ButtonY:           "y",
+	ButtonLeftBumper:  "leftshoulder",
+	ButtonRightBumper: "rightshoulder",
+	ButtonBack:        "back",
+	ButtonStart:       "start",
+	ButtonGuide:       "guide",
+	ButtonLeftThumb:   "leftstick",
+	ButtonRightThumb:  "rightstick",
+	ButtonDpadUp:      "dpup",
+	ButtonDpadRight:   "dpright",
+	ButtonDpadDown:    "dpdown",
+	ButtonDpadLeft:    "dpleft",
+}
+
+// axisNames are the SDL names of the gamepad axes.
+var axisNames = [AxisCount]string{
+	AxisLeftX:        "leftx",
+	AxisLeftY:        "lefty",
+	AxisRightX:       "rightx",
+	AxisRightY:       "righty",
+	AxisLeftTrigger:  "lefttrigger",
+	AxisRightTrigger: "righttrigger",
+}
+
+// platformName is the SDL name of the current platform.
+var platformName = map[string]string{
+	"windows": "Windows",
+	"darwin":  "Mac OS X",
+	"linux":   "Linux",
+	"android": "Android",
+	"ios":     "iOS",
+}[runtime.GOOS]
+
+// ParseMappings parses a database of mappings in SDL_GameControllerDB
+// format, one mapping per line. Empty lines, comments and mappings for
+// other platforms are skipped. ParseMappings returns the valid mappings
+// along with an error for the invalid lines, if any.
+func ParseMappings(db string) ([]*Mapping, error) {
+	var mappings []*Mapping
+	var errs []error
+	for _, line := range strings.Split(db, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		m, err := ParseMapping(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if m.Platform != "" && m.Platform != platformName {
+			continue
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, errors.Join(errs...)
+}
+
+// ParseMapping parses a single mapping in SDL_GameControllerDB format:
+// a GUID, a name and a list of gamepad elements, separated by commas.
+// Elements refer to joystick axes (a0), buttons (b0) or hat directions
+// (h0.1). Axes may be limited to their positive (+a0) or negative
+// (-a0) half, and inverted (a0~). Unknown elements are ignored.
+func ParseMapping(s string) (*Mapping, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("gamepad: invalid mapping %q", s)
+	}
+	guid := fields[0]
+	if len(guid) != 32 {
+		return nil, fmt.Errorf("gamepad: invalid mapping GUID %q", guid)
+	}
+	if _, err := strconv.ParseUint(guid[:16], 16, 64); err != nil {
+		return nil, fmt.Errorf("gamepad: invalid mapping GUID %q", guid)
+	}
+	if _, err := strconv.ParseUint(guid[16:], 16, 64); err != nil {
+		return nil, fmt.Errorf("gamepad: invalid mapping GUID %q", guid)
+	}
+	m := &Mapping{
+		GUID: normalizeGUID(guid),
+		Name: fields[1],
+	}
+	for _, f := range fields[2:] {
+		key, value, ok := strings.Cut(f, ":")
+		if !ok {
+			continue
+		}
+		if key == "platform" {
+			m.Platform = value
+			continue
+		}
+		var e *element
+		for i, n := range buttonNames {
+			if n == key {
+				e = &m.buttons[i]
+			}
+		}
+		for i, n := range axisNames {
+			if n == key {
+				e = &m.axes[i]
+			}
+		}
+		if e == nil {
+			continue
+		}
+		var err error
+		*e, err = parseElement(value)
+		if err != nil {
+			return nil, fmt.Errorf("gamepad: invalid element %q in mapping %q: %w", f, m.Name, err)
+		}
+	}
+	return m, nil
+}
+
+func parseElement(v string) (element, error) {
+	min, max := float32(-1), float32(1)
+	switch {
+	case strings.HasPrefix(v, "+"):
+		min = 0
+		v = v[1:]
+	case strings.HasPrefix(v, "-"):
+		max = 0
+		v = v[1:]
+	}
+	invert := strings.HasSuffix(v, "~")
+	v = strings.TrimSuffix(v, "~")
+	if len(v) < 2 {
+		return element{}, errors.New("missing index")
+	}
+	var e element
+	switch v[0] {
+	case 'a':
+		e.kind = elementAxis
+	case 'b':
+		e.kind = elementButton
+	case 'h':
+		e.kind = elementHat
+		hat, bit, ok := strings.Cut(v[1:], ".")
+		if !ok {
+			return element{}, errors.New("missing hat direction")
+		}
+		i, err := strconv.ParseUint(hat, 10, 8)
+		if err != nil {
+			return element{}, err
+		}
+		b, err := strconv.ParseUint(bit, 10, 4)
+		if err != nil {
+			return element{}, err
+		}
+		e.index, e.bit = int(i), Hat(b)
+		return e, nil
+	default:
+		return element{}, fmt.Errorf("unknown element type %q", v[0])
+	}
+	i, err := strconv.ParseUint(v[1:], 10, 8)
+	if err != nil {
+		return element{}, err
+	}
+	e.index = int(i)
+	if e.kind == elementAxis {
+		// Map [min, max] to [-1, 1].
+		e.scale = 2 / (max - min)
+		e.offset = -(max + min) / (max - min)
+		if invert {
+			e.scale, e.offset = -e.scale, -e.offset
+		}
+	}
+	return e, nil
+}
+
+// normalizeGUID clears the CRC of the device name that newer SDL
+// versions store in bytes 2 and 3 of the GUID.
+func normalizeGUID(guid string) string {
+	return strings.ToLower(guid[:4] + "0000" + guid[8:])
+}
+
+// fits reports whether the elements of the mapping exist in st.
+func (m *Mapping) fits(st State) bool {
+	check := func(e element) bool {
+		switch e.kind {
+		case elementAxis:
+			return e.index < len(st.Axes)
+		case elementButton:
+			return e.index < len(st.Buttons)
+		case elementHat:
+			return e.index < len(st.Hats)
+		}
+		return true
+	}
+	for _, e := range m.buttons {
+		if !check(e) {
+			return false
+		}
+	}
+	for _, e := range m.axes {
+		if !check(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply maps a joystick state to a gamepad state. The mapping must fit
+// the state.
+func (m *Mapping) apply(st State) GamepadState {
+	var g GamepadState
+	for i, e := range m.buttons {
+		switch e.kind {
+		case elementAxis:
+			// Half axes are pressed in the half of their range away
+			// from the rest position.
+			v := st.Axes[e.index]*e.scale + e.offset
+			if e.offset < 0 || (e.offset == 0 && e.scale > 0) {
+				g.Buttons[i] = v >= 0
+			} else {
+				g.Buttons[i] = v <= 0
+			}
+		case elementButton:
+			g.Buttons[i] = st.Buttons[e.index]
+		case elementHat:
+			g.Buttons[i] = st.Hats[e.index]&e.bit != 0
+		}
+	}
+	for i, e := range m.axes {
+		var v float32
+		switch e.kind {
+		case elementNone:
+			v = 0
+		case elementAxis:
+			v = st.Axes[e.index]*e.scale + e.offset
+			if v < -1 {
+				v = -1
+			} else if v > 1 {
+				v = 1
+			}
+		case elementButton:
+			v = -1
+			if st.Buttons[e.index] {
+				v = 1
+			}
+		case elementHat:
+			v = -1
+			if st.Hats[e.index]&e.bit != 0 {
+				v = 1
+			}
+		}
+		g.Axes[i] = v
+	}
+	return g
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gamepad
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMapping = "030000005e0400008e02000010010000,Xbox 360 Controller,a:b0,b:b1,back:b6,dpdown:h0.4,dpleft:h0.8,dpright:h0.2,dpup:h0.1,guide:b8,leftshoulder:b4,leftstick:b9,lefttrigger:a2,leftx:a0,lefty:a1,rightshoulder:b5,rightstick:b10,righttrigger:a5,rightx:a3,righty:a4,start:b7,x:b2,y:b3,"
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping(testMapping + "platform:Linux,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GUID != "030000005e0400008e02000010010000" || m.Name != "Xbox 360 Controller" || m.Platform != "Linux" {
+		t.Errorf("got GUID %q, name %q, platform %q", m.GUID, m.Name, m.Platform)
+	}
+	if e := m.buttons[ButtonStart]; e.kind != elementButton || e.index != 7 {
+		t.Errorf("start: got %+v", e)
+	}
+	if e := m.buttons[ButtonDpadLeft]; e.kind != elementHat || e.index != 0 || e.bit != HatLeft {
+		t.Errorf("dpleft: got %+v", e)
+	}
+	if e := m.axes[AxisRightTrigger]; e.kind != elementAxis || e.index != 5 || e.scale != 1 || e.offset != 0 {
+		t.Errorf("righttrigger: got %+v", e)
+	}
+}
+
+func TestParseMappingErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"030000005e0400008e02000010010000",
+		"030000005e0400008e020000100100,Short GUID,a:b0",
+		"030000005e0400008e0200001001zzzz,Bad GUID,a:b0",
+		"030000005e0400008e02000010010000,Bad Element,a:c0",
+		"030000005e0400008e02000010010000,Bad Hat,dpup:h0",
+		"030000005e0400008e02000010010000,Bad Index,leftx:a",
+	} {
+		if _, err := ParseMapping(s); err == nil {
+			t.Errorf("ParseMapping(%q) succeeded", s)
+		}
+	}
+}
+
+func TestParseMappings(t *testing.T) {
+	db := strings.Join([]string{
+		"# Comment",
+		"",
+		testMapping + "platform:Plan 9,",
+		"03000000de2800000112000001000000,Steam Controller,a:b0,b:b1,x:b2,y:b3,",
+		"invalid",
+		// The CRC of newer SDL versions is ignored.
+		"0300abcd4c050000c405000011810000,PS4 Controller,a:b0,",
+	}, "\n")
+	mappings, err := ParseMappings(db)
+	if err == nil {
+		t.Error("ParseMappings didn't report the invalid line")
+	}
+	var guids []string
+	for _, m := range mappings {
+		guids = append(guids, m.GUID)
+	}
+	want := []string{"03000000de2800000112000001000000", "030000004c050000c405000011810000"}
+	if strings.Join(guids, " ") != strings.Join(want, " ") {
+		t.Errorf("got mappings %v, want %v", guids, want)
+	}
+}
+
+func TestMappingApply(t *testing.T) {
+	m, err := ParseMapping("03000000000000000000000000000000,Test,a:b1,b:+a0,x:-a0,y:a1~,dpup:h0.1,leftx:a0,lefty:a1~,rightx:+a1,lefttrigger:b0,righttrigger:h0.4,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := State{
+		Axes:    []float32{0.75, 0.5},
+		Buttons: []bool{true, false},
+		Hats:    []Hat{HatUp | HatRight},
+	}
+	if !m.fits(st) {
+		t.Fatal("mapping doesn't fit")
+	}
+	g := m.apply(st)
+	wantButtons := map[Button]bool{
+		ButtonA:      false,
+		ButtonB:      true,
+		ButtonX:      false,
+		ButtonY:      true,
+		ButtonDpadUp: true,
+	}
+	for b, want := range wantButtons {
+		if got := g.Buttons[b]; got != want {
+			t.Errorf("button %d: got %v, want %v", b, got, want)
+		}
+	}
+	wantAxes := map[Axis]float32{
+		AxisLeftX:        0.75,
+		AxisLeftY:        -0.5,
+		AxisRightX:       0,
+		AxisRightY:       0,
+		AxisLeftTrigger:  1,
+		AxisRightTrigger: -1,
+	}
+	for a, want := range wantAxes {
+		if got := g.Axes[a]; got != want {
+			t.Errorf("axis %d: got %v, want %v", a, got, want)
+		}
+	}
+	if m.fits(State{Axes: []float32{0}}) {
+		t.Error("mapping fits a joystick without the mapped elements")
+	}
+}
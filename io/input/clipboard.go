@@ -7,45 +7,80 @@ import (
 
 	"github.com/kanryu/mado/io/clipboard"
 	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/transfer"
 )
 
+// textMIME is the MIME type of clipboard text.
+const textMIME = "application/text"
+
+// ClipboardData is clipboard content in a MIME type.
+type ClipboardData struct {
+	Type string
+	Data []byte
+}
+
+// ClipboardRequest is a request for the content of the clipboard, or
+// of the primary selection, in a MIME type.
+type ClipboardRequest struct {
+	Type    string
+	Primary bool
+}
+
 // clipboardState contains the state for clipboard event routing.
 type clipboardState struct {
-	receivers []event.Tag
+	receivers []clipboardReceiver
+}
+
+type clipboardReceiver struct {
+	tag event.Tag
+	req ClipboardRequest
 }
 
 type clipboardQueue struct {
-	// request avoid read clipboard every frame while waiting.
-	requested bool
-	mime      string
-	text      []byte
+	// requests are the reads not yet requested from the platform. They
+	// avoid reading the clipboard every frame while waiting.
+	requests []ClipboardRequest
+	content  []ClipboardData
 }
 
-// WriteClipboard returns the most recent data to be copied
+// WriteClipboard returns the most recent content to be copied
 // to the clipboard, if any.
-func (q *clipboardQueue) WriteClipboard() (mime string, content []byte, ok bool) {
-	if q.text == nil {
-		return "", nil, false
+func (q *clipboardQueue) WriteClipboard() (content []ClipboardData, ok bool) {
+	if q.content == nil {
+		return nil, false
 	}
-	content = q.text
-	q.text = nil
-	return q.mime, content, true
+	content = q.content
+	q.content = nil
+	return content, true
 }
 
-// ClipboardRequested reports if any new handler is waiting
-// to read the clipboard.
-func (q *clipboardQueue) ClipboardRequested(state clipboardState) bool {
-	req := len(state.receivers) > 0 && q.requested
-	q.requested = false
-	return req
+// ClipboardRequests returns the reads that new handlers are
+// waiting for.
+func (q *clipboardQueue) ClipboardRequests(state clipboardState) []ClipboardRequest {
+	var reqs []ClipboardRequest
+	for _, req := range q.requests {
+		for _, r := range state.receivers {
+			if r.req == req {
+				reqs = append(reqs, req)
+				break
+			}
+		}
+	}
+	q.requests = nil
+	return reqs
 }
 
-func (q *clipboardQueue) Push(state clipboardState, e event.Event) (clipboardState, []taggedEvent) {
+func (q *clipboardQueue) Push(state clipboardState, e transfer.DataEvent) (clipboardState, []taggedEvent) {
 	var evts []taggedEvent
+	var waiting []clipboardReceiver
 	for _, r := range state.receivers {
-		evts = append(evts, taggedEvent{tag: r, event: e})
+		if r.req.Type != e.Type || r.req.Primary != e.Primary {
+			waiting = append(waiting, r)
+			continue
+		}
+		evts = append(evts, taggedEvent{tag: r.tag, event: e})
 	}
-	state.receivers = nil
+	state.receivers = waiting
 	return state, evts
 }
 
@@ -55,18 +90,35 @@ func (q *clipboardQueue) ProcessWriteClipboard(req clipboard.WriteCmd) {
 	if err != nil {
 		return
 	}
-	q.mime = req.Type
-	q.text = content
+	data := ClipboardData{Type: req.Type, Data: content}
+	for i, d := range q.content {
+		if d.Type == data.Type {
+			q.content[i] = data
+			return
+		}
+	}
+	q.content = append(q.content, data)
 }
 
-func (q *clipboardQueue) ProcessReadClipboard(state clipboardState, tag event.Tag) clipboardState {
-	for _, k := range state.receivers {
-		if k == tag {
+func (q *clipboardQueue) ProcessReadClipboard(state clipboardState, cmd clipboard.ReadCmd) clipboardState {
+	req := ClipboardRequest{Type: cmd.Type, Primary: cmd.Primary}
+	if req.Type == "" {
+		req.Type = textMIME
+	}
+	pending := false
+	for _, r := range state.receivers {
+		if r.req != req {
+			continue
+		}
+		if r.tag == cmd.Tag {
 			return state
 		}
+		pending = true
 	}
 	n := len(state.receivers)
-	state.receivers = append(state.receivers[:n:n], tag)
-	q.requested = true
+	state.receivers = append(state.receivers[:n:n], clipboardReceiver{tag: cmd.Tag, req: req})
+	if !pending {
+		q.requests = append(q.requests, req)
+	}
 	return state
 }
@@ -4,6 +4,7 @@ package input
 
 import (
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -72,6 +73,24 @@ func TestQueueProcessReadClipboard(t *testing.T) {
 	assertClipboardReadCmd(t, r, 0)
 }
 
+func TestClipboardEmptyResponse(t *testing.T) {
+	r, handler := new(Router), new(int)
+
+	r.Source().Execute(clipboard.ReadCmd{Tag: handler, Type: "image/png"})
+	assertClipboardReadCmd(t, r, 1)
+	// The platform responds with empty content to a failed read.
+	r.Queue(transfer.DataEvent{
+		Type: "image/png",
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader(""))
+		},
+	})
+	assertEventTypeSequence(t, events(r, -1, transfer.TargetFilter{Target: handler, Type: "image/png"}), transfer.DataEvent{})
+	// The handler may read again.
+	r.Source().Execute(clipboard.ReadCmd{Tag: handler, Type: "image/png"})
+	assertClipboardReadCmd(t, r, 1)
+}
+
 func TestQueueProcessWriteClipboard(t *testing.T) {
 	r := new(Router)
 
@@ -87,12 +106,62 @@ func TestQueueProcessWriteClipboard(t *testing.T) {
 	assertClipboardWriteCmd(t, r, mime, "Write 2")
 }
 
+func TestClipboardReadTypes(t *testing.T) {
+	r, handlers := new(Router), make([]int, 3)
+
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[0]})
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[1], Type: "image/png"})
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[2], Primary: true})
+	want := []ClipboardRequest{
+		{Type: "application/text"},
+		{Type: "image/png"},
+		{Type: "application/text", Primary: true},
+	}
+	if got := r.ClipboardRequests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got requests %v, expected %v", got, want)
+	}
+
+	// Only the primary selection reader receives its event.
+	r.Queue(transfer.DataEvent{
+		Type: "application/text",
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader("Primary"))
+		},
+		Primary: true,
+	})
+	for i, n := range []int{0, 0, 1} {
+		f := transfer.TargetFilter{Target: &handlers[i], Type: want[i].Type}
+		if got := len(events(r, -1, f)); got != n {
+			t.Errorf("handler %d got %d events, expected %d", i, got, n)
+		}
+	}
+	if got := len(r.state().receivers); got != 2 {
+		t.Errorf("got %d receivers, expected 2", got)
+	}
+}
+
+func TestClipboardWriteTypes(t *testing.T) {
+	r := new(Router)
+
+	r.Source().Execute(clipboard.WriteCmd{Type: "text/html", Data: io.NopCloser(strings.NewReader("<b>1</b>"))})
+	r.Source().Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader("1"))})
+	r.Source().Execute(clipboard.WriteCmd{Type: "text/html", Data: io.NopCloser(strings.NewReader("<b>2</b>"))})
+	content, ok := r.WriteClipboard()
+	want := []ClipboardData{
+		{Type: "text/html", Data: []byte("<b>2</b>")},
+		{Type: "application/text", Data: []byte("1")},
+	}
+	if !ok || !reflect.DeepEqual(content, want) {
+		t.Errorf("got content %q, expected %q", content, want)
+	}
+}
+
 func assertClipboardReadCmd(t *testing.T, router *Router, expected int) {
 	t.Helper()
 	if got := len(router.state().receivers); got != expected {
 		t.Errorf("unexpected %d receivers, got %d", expected, got)
 	}
-	if router.ClipboardRequested() != (expected > 0) {
+	if (len(router.ClipboardRequests()) > 0) != (expected > 0) {
 		t.Error("missing requests")
 	}
 }
@@ -102,24 +171,28 @@ func assertClipboardReadDuplicated(t *testing.T, router *Router, expected int) {
 	if len(router.state().receivers) != expected {
 		t.Error("receivers removed")
 	}
-	if router.ClipboardRequested() != false {
+	if len(router.ClipboardRequests()) != 0 {
 		t.Error("duplicated requests")
 	}
 }
 
 func assertClipboardWriteCmd(t *testing.T, router *Router, mimeExp, expected string) {
 	t.Helper()
-	if (router.cqueue.text != nil) != (expected != "") {
+	if (router.cqueue.content != nil) != (expected != "") {
 		t.Error("text not defined")
 	}
-	mime, text, ok := router.cqueue.WriteClipboard()
+	content, ok := router.cqueue.WriteClipboard()
 	if ok != (expected != "") {
 		t.Error("duplicated requests")
 	}
-	if string(mime) != mimeExp {
+	var mime, text string
+	if len(content) > 0 {
+		mime, text = content[0].Type, string(content[0].Data)
+	}
+	if mime != mimeExp {
 		t.Errorf("got MIME type %s, expected %s", mime, mimeExp)
 	}
-	if string(text) != expected {
+	if text != expected {
 		t.Errorf("got text %s, expected %s", text, expected)
 	}
 }
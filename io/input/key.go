@@ -269,7 +269,8 @@ func keyFilterMatch(focus event.Tag, f key.Filter, e key.Event, system bool) boo
 	if e.Modifiers&f.Required != f.Required {
 		return false
 	}
-	if e.Modifiers&^(f.Required|f.Optional) != 0 {
+	// Lock modifiers are state, not part of the shortcut.
+	if e.Modifiers.Held()&^(f.Required|f.Optional) != 0 {
 		return false
 	}
 	return true
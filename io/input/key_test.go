@@ -316,6 +316,18 @@ func TestKeyRouting(t *testing.T) {
 	assertEventSequence(t, events(r, -1, key.Filter{Focus: h, Name: "A"}, key.Filter{Name: "B"}), A)
 }
 
+func TestKeyLockModifiers(t *testing.T) {
+	r := new(Router)
+	f := key.Filter{Name: "A", Required: key.ModShortcut}
+	events(r, -1, f)
+	r.Frame(new(op.Ops))
+	locked := key.Event{Name: "A", Modifiers: key.ModShortcut | key.ModCapsLock | key.ModNumLock}
+	shifted := key.Event{Name: "A", Modifiers: key.ModShortcut | key.ModShift | key.ModCapsLock}
+	r.Queue(locked, shifted)
+	// Lock modifiers don't prevent a match, other modifiers do.
+	assertEventSequence(t, events(r, -1, f), locked)
+}
+
 func assertFocus(t *testing.T, router *Router, expected event.Tag) {
 	t.Helper()
 	if !router.Source().Focused(expected) {
@@ -28,6 +28,8 @@ type pointerQueue struct {
 		// frames.
 		contentIDs map[semanticContent][]semanticID
 	}
+	// offers are the data offered to other applications.
+	offers []TransferOffer
 }
 
 type hitNode struct {
@@ -43,6 +45,9 @@ type hitNode struct {
 type pointerState struct {
 	cursor   pointer.Cursor
 	pointers []pointerInfo
+	// extSource is the source of a transfer dropped on another
+	// application.
+	extSource event.Tag
 }
 
 type pointerInfo struct {
@@ -398,6 +403,10 @@ func (c *pointerCollector) cursor(cursor pointer.Cursor) {
 }
 
 func (q *pointerQueue) offerData(handlers map[event.Tag]*handler, state pointerState, req transfer.OfferCmd) (pointerState, []taggedEvent) {
+	if state.extSource != nil && state.extSource == req.Tag {
+		q.offers = append(q.offers, TransferOffer{Type: req.Type, Data: req.Data})
+		return state, nil
+	}
 	var evts []taggedEvent
 	for i, p := range state.pointers {
 		if p.dataSource != req.Tag {
@@ -932,17 +941,27 @@ func (q *pointerQueue) deliverDropEvent(handlers map[event.Tag]*handler, p point
 }
 
 func (q *pointerQueue) deliverTransferCancelEvent(handlers map[event.Tag]*handler, p pointerInfo, evts []taggedEvent) (pointerInfo, []taggedEvent) {
-	evts = append(evts, taggedEvent{tag: p.dataSource, event: transfer.CancelEvent{}})
+	evts = transferCancelEvents(handlers, p.dataSource, evts)
+	p.dataSource = nil
+	p.dataTarget = nil
+	return p, evts
+}
+
+// transferCancelEvents appends the CancelEvents of the transfer from
+// source to evts.
+func transferCancelEvents(handlers map[event.Tag]*handler, source event.Tag, evts []taggedEvent) []taggedEvent {
+	evts = append(evts, taggedEvent{tag: source, event: transfer.CancelEvent{}})
 	// Cancel all potential targets.
-	src := &handlers[p.dataSource].filter.pointer
+	src, ok := handlers[source]
+	if !ok {
+		return evts
+	}
 	for k, h := range handlers {
-		if _, ok := firstMimeMatch(src, &h.filter.pointer); ok {
+		if _, ok := firstMimeMatch(&src.filter.pointer, &h.filter.pointer); ok {
 			evts = append(evts, taggedEvent{tag: k, event: transfer.CancelEvent{}})
 		}
 	}
-	p.dataSource = nil
-	p.dataTarget = nil
-	return p, evts
+	return evts
 }
 
 // ClipFor clips r to the parents of area.
@@ -5,6 +5,7 @@ package input
 import (
 	"fmt"
 	"image"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -1043,6 +1044,94 @@ func TestTransfer(t *testing.T) {
 			t.Error("offer was not closed")
 		}
 	})
+
+	t.Run("drop from another application", func(t *testing.T) {
+		ops := new(op.Ops)
+		var r Router
+		_, tgt := setup(&r, ops, "file", "file")
+		r.Frame(ops)
+		ofr := &offer{data: "hello"}
+		open := func() io.ReadCloser { return ofr }
+		r.Queue(
+			DropEvent{Position: f32.Pt(10, 10), Type: "file", Open: open},
+			DropEvent{Position: f32.Pt(40, 10), Type: "nofile", Open: open},
+		)
+		assertEventSequence(t, events(&r, -1, transfer.TargetFilter{Target: tgt, Type: "file"}))
+		r.Queue(DropEvent{Position: f32.Pt(40, 10), Type: "file", Open: open})
+		evs := events(&r, -1, transfer.TargetFilter{Target: tgt, Type: "file"})
+		if len(evs) != 1 {
+			t.Fatalf("unexpected number of events: %d, want 1", len(evs))
+		}
+		dataEvent, ok := evs[0].(transfer.DataEvent)
+		if !ok {
+			t.Fatalf("unexpected event type: %T, want %T", evs[0], transfer.DataEvent{})
+		}
+		if got, want := dataEvent.Open(), ofr; got != want {
+			t.Fatalf("got %v; want %v", got, want)
+		}
+	})
+
+	t.Run("drop on another application", func(t *testing.T) {
+		ops := new(op.Ops)
+		var r Router
+		src, tgt := setup(&r, ops, "file", "file")
+		r.Frame(ops)
+		if types := r.TransferSource(); types != nil {
+			t.Fatalf("transfer source %v before drag", types)
+		}
+		// Drag out of the window.
+		r.Queue(
+			pointer.Event{
+				Position: f32.Pt(10, 10),
+				Kind:     pointer.Press,
+			},
+			pointer.Event{
+				Position: f32.Pt(10, 10),
+				Kind:     pointer.Move,
+			},
+			pointer.Event{
+				Position: f32.Pt(-10, 10),
+				Kind:     pointer.Move,
+			},
+		)
+		if got, want := r.TransferSource(), []string{"file"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got transfer source %v, want %v", got, want)
+		}
+		events(&r, -1, transfer.SourceFilter{Target: src, Type: "file"})
+		events(&r, -1, transfer.TargetFilter{Target: tgt, Type: "file"})
+
+		// Drop on the other application.
+		r.Queue(
+			ExternalDropEvent{},
+			pointer.Event{
+				Position: f32.Pt(-10, 10),
+				Kind:     pointer.Release,
+			},
+		)
+		if types := r.TransferSource(); types != nil {
+			t.Fatalf("transfer source %v after drop", types)
+		}
+		assertEventSequence(t, events(&r, -1, transfer.SourceFilter{Target: src, Type: "file"}))
+		r.Queue(
+			ExternalRequestEvent{Type: "nofile"},
+			ExternalRequestEvent{Type: "file"},
+		)
+		assertEventSequence(t, events(&r, -1, transfer.SourceFilter{Target: src, Type: "file"}), transfer.RequestEvent{Type: "file"})
+
+		ofr := &offer{data: "hello"}
+		r.Source().Execute(transfer.OfferCmd{Tag: src, Type: "file", Data: ofr})
+		offers := r.TransferOffers()
+		if len(offers) != 1 || offers[0].Type != "file" || offers[0].Data != ofr {
+			t.Fatalf("got offers %v, want the offered data", offers)
+		}
+		if offers := r.TransferOffers(); len(offers) != 0 {
+			t.Fatalf("got offers %v twice", offers)
+		}
+
+		r.Queue(ExternalDoneEvent{})
+		assertEventSequence(t, events(&r, -1, transfer.SourceFilter{Target: src, Type: "file"}), transfer.CancelEvent{})
+		assertEventSequence(t, events(&r, -1, transfer.TargetFilter{Target: tgt, Type: "file"}), transfer.CancelEvent{})
+	})
 }
 
 func TestDeferredInputOp(t *testing.T) {
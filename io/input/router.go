@@ -473,6 +473,18 @@ func (q *Router) processEvent(e event.Event, system bool) {
 		cstate, evts := q.cqueue.Push(state.clipboardState, e)
 		state.clipboardState = cstate
 		q.changeState(e, state, evts)
+	case DropEvent:
+		q.changeState(e, state, q.pointer.queue.drop(q.handlers, e))
+	case ExternalDropEvent:
+		pstate, evts := q.pointer.queue.externalDrop(q.handlers, state.pointerState)
+		state.pointerState = pstate
+		q.changeState(e, state, evts)
+	case ExternalRequestEvent:
+		q.changeState(e, state, q.pointer.queue.externalRequest(q.handlers, state.pointerState, e))
+	case ExternalDoneEvent:
+		pstate, evts := q.pointer.queue.externalDone(q.handlers, state.pointerState)
+		state.pointerState = pstate
+		q.changeState(e, state, evts)
 	default:
 		panic("unknown event type")
 	}
@@ -549,7 +561,7 @@ func (q *Router) executeCommand(c Command) stateChange {
 	case clipboard.WriteCmd:
 		q.cqueue.ProcessWriteClipboard(req)
 	case clipboard.ReadCmd:
-		state.clipboardState = q.cqueue.ProcessReadClipboard(state.clipboardState, req.Tag)
+		state.clipboardState = q.cqueue.ProcessReadClipboard(state.clipboardState, req)
 	case pointer.GrabCmd:
 		state.pointerState, evts = q.pointer.queue.grab(state.pointerState, req)
 	case op.InvalidateCmd:
@@ -721,15 +733,45 @@ func (q *Router) TextInputHint() (key.InputHint, bool) {
 }
 
 // WriteClipboard returns the most recent content to be copied
-// to the clipboard, if any.
-func (q *Router) WriteClipboard() (mime string, content []byte, ok bool) {
+// to the clipboard, if any, in order of preference.
+func (q *Router) WriteClipboard() (content []ClipboardData, ok bool) {
 	return q.cqueue.WriteClipboard()
 }
 
-// ClipboardRequested reports if any new handler is waiting
-// to read the clipboard.
-func (q *Router) ClipboardRequested() bool {
-	return q.cqueue.ClipboardRequested(q.lastState().clipboardState)
+// ClipboardRequests returns the clipboard reads that new handlers
+// are waiting for.
+func (q *Router) ClipboardRequests() []ClipboardRequest {
+	return q.cqueue.ClipboardRequests(q.lastState().clipboardState)
+}
+
+// TransferSource returns the MIME types offered by the source of the
+// pointer-guided transfer in progress, or nil if there is none.
+func (q *Router) TransferSource() []string {
+	for _, p := range q.lastState().pointers {
+		if p.dataSource == nil {
+			continue
+		}
+		if h, ok := q.handlers[p.dataSource]; ok {
+			return append([]string(nil), h.filter.pointer.sourceMimes...)
+		}
+	}
+	return nil
+}
+
+// TransferOffers returns the data offered to other applications since
+// the last call.
+func (q *Router) TransferOffers() []TransferOffer {
+	offers := q.pointer.queue.offers
+	q.pointer.queue.offers = nil
+	return offers
+}
+
+// DropType returns the first of types accepted by the foremost
+// transfer target at pos, for platforms that report whether a drop from
+// another application would be accepted.
+func (q *Router) DropType(pos f32.Point, types []string) (string, bool) {
+	_, t, ok := q.pointer.queue.dropTarget(q.handlers, pos, types)
+	return t, ok
 }
 
 // Cursor returns the last cursor set.
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"io"
+
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/transfer"
+)
+
+// DropEvent is sent by the platform when data from another application
+// is dropped on the window. It is delivered as a [transfer.DataEvent] to
+// the foremost target under Position that accepts Type.
+type DropEvent struct {
+	// Position is the drop location, in window coordinates.
+	Position f32.Point
+	// Type is the MIME type of the data.
+	Type string
+	// Open returns a reader of the data. It may be called more than
+	// once, each call returning a new reader.
+	Open func() io.ReadCloser
+}
+
+// ExternalDropEvent is sent by the platform when the pointer-guided
+// transfer started in the window is dropped on another application,
+// before the pointer release. The source of the transfer then receives
+// a [transfer.RequestEvent] for every [ExternalRequestEvent], until an
+// [ExternalDoneEvent] ends the transfer.
+type ExternalDropEvent struct{}
+
+// ExternalRequestEvent is sent by the platform when the application a
+// transfer was dropped on requests the data in a MIME type.
+type ExternalRequestEvent struct {
+	Type string
+}
+
+// ExternalDoneEvent is sent by the platform when the application a
+// transfer was dropped on completes or abandons the transfer.
+type ExternalDoneEvent struct{}
+
+// TransferOffer is data offered by a source in response to an
+// [ExternalRequestEvent].
+type TransferOffer struct {
+	Type string
+	// Data must be closed after use.
+	Data io.ReadCloser
+}
+
+func (DropEvent) ImplementsEvent()            {}
+func (ExternalDropEvent) ImplementsEvent()    {}
+func (ExternalRequestEvent) ImplementsEvent() {}
+func (ExternalDoneEvent) ImplementsEvent()    {}
+
+// drop delivers the data of e to the foremost target that accepts it.
+func (q *pointerQueue) drop(handlers map[event.Tag]*handler, e DropEvent) []taggedEvent {
+	tag, _, ok := q.dropTarget(handlers, e.Position, []string{e.Type})
+	if !ok {
+		return nil
+	}
+	return []taggedEvent{{tag: tag, event: transfer.DataEvent{
+		Type: e.Type,
+		Open: e.Open,
+	}}}
+}
+
+// dropTarget returns the foremost target at pos that accepts one of
+// types, and the first such type.
+func (q *pointerQueue) dropTarget(handlers map[event.Tag]*handler, pos f32.Point, types []string) (tag event.Tag, typ string, ok bool) {
+	q.hitTest(pos, func(n *hitNode) bool {
+		h, found := handlers[n.tag]
+		if !found {
+			return true
+		}
+		for _, t := range types {
+			for _, m := range h.filter.pointer.targetMimes {
+				if m == t {
+					tag, typ, ok = n.tag, t, true
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return tag, typ, ok
+}
+
+// externalDrop moves the source of the transfer in progress to
+// state.extSource, where it stays until externalDone.
+func (q *pointerQueue) externalDrop(handlers map[event.Tag]*handler, state pointerState) (pointerState, []taggedEvent) {
+	state, evts := q.externalDone(handlers, state)
+	for i, p := range state.pointers {
+		if p.dataSource == nil {
+			continue
+		}
+		state.extSource = p.dataSource
+		p.dataSource = nil
+		p.dataTarget = nil
+		state.pointers = append([]pointerInfo{}, state.pointers...)
+		state.pointers[i] = p
+		break
+	}
+	return state, evts
+}
+
+// externalRequest requests the data of the transfer dropped on another
+// application.
+func (q *pointerQueue) externalRequest(handlers map[event.Tag]*handler, state pointerState, e ExternalRequestEvent) []taggedEvent {
+	h, ok := handlers[state.extSource]
+	if !ok {
+		return nil
+	}
+	for _, m := range h.filter.pointer.sourceMimes {
+		if m == e.Type {
+			return []taggedEvent{{tag: state.extSource, event: transfer.RequestEvent{Type: m}}}
+		}
+	}
+	return nil
+}
+
+// externalDone ends the transfer dropped on another application.
+func (q *pointerQueue) externalDone(handlers map[event.Tag]*handler, state pointerState) (pointerState, []taggedEvent) {
+	if state.extSource == nil {
+		return state, nil
+	}
+	evts := transferCancelEvents(handlers, state.extSource, nil)
+	state.extSource = nil
+	return state, evts
+}
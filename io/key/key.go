@@ -169,6 +169,12 @@ const (
 	// ModSuper is the "logo" modifier key, often
 	// represented by a Windows logo.
 	ModSuper
+	// ModCapsLock is set when caps lock is on. Like ModNumLock, it is
+	// only reported to windows that enable lock key modifiers, and it
+	// never prevents a key filter from matching.
+	ModCapsLock
+	// ModNumLock is set when num lock is on.
+	ModNumLock
 )
 
 // Name is the identifier for a keyboard key.
@@ -233,6 +239,12 @@ func (m Modifiers) Contain(m2 Modifiers) bool {
 	return m&m2 == m2
 }
 
+// Held returns the modifiers without the caps lock and num lock states,
+// for comparing with a combination of modifier keys.
+func (m Modifiers) Held() Modifiers {
+	return m &^ (ModCapsLock | ModNumLock)
+}
+
 // FocusCmd requests to set or clear the keyboard focus.
 type FocusCmd struct {
 	// Tag is the new focus. The focus is cleared if Tag is nil, or if Tag
@@ -280,6 +292,12 @@ func (m Modifiers) String() string {
 	if m.Contain(ModSuper) {
 		strs = append(strs, string(NameSuper))
 	}
+	if m.Contain(ModCapsLock) {
+		strs = append(strs, "CapsLock")
+	}
+	if m.Contain(ModNumLock) {
+		strs = append(strs, "NumLock")
+	}
 	return strings.Join(strs, "-")
 }
 
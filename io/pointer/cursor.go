@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package pointer
+
+import (
+	"image"
+	"image/draw"
+)
+
+// CustomCursor is a cursor shape defined by an image. Unlike Cursor, it
+// is not an operation; it is installed for a whole window with the
+// window's SetCustomCursor method.
+type CustomCursor struct {
+	// Image is the cursor image, in pixels. Its bounds start at the
+	// origin.
+	Image *image.NRGBA
+	// Hotspot is the point of Image that is the cursor position.
+	Hotspot image.Point
+}
+
+// NewCustomCursor creates a custom cursor from a copy of img. The
+// hotspot is relative to the upper left corner of img and is clamped
+// to its bounds.
+func NewCustomCursor(img image.Image, hotspot image.Point) *CustomCursor {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rectangle{Max: b.Size()})
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	if hotspot.X >= b.Dx() {
+		hotspot.X = b.Dx() - 1
+	}
+	if hotspot.Y >= b.Dy() {
+		hotspot.Y = b.Dy() - 1
+	}
+	if hotspot.X < 0 {
+		hotspot.X = 0
+	}
+	if hotspot.Y < 0 {
+		hotspot.Y = 0
+	}
+	return &CustomCursor{Image: dst, Hotspot: hotspot}
+}
+
+// Size returns the size of the cursor image.
+func (c *CustomCursor) Size() image.Point {
+	return c.Image.Bounds().Size()
+}
@@ -3,6 +3,8 @@
 package pointer
 
 import (
+	"image"
+	"image/color"
 	"testing"
 )
 
@@ -31,3 +33,27 @@ func TestTypeString(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCustomCursor(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 20, 14, 23))
+	src.Set(11, 21, color.RGBA{R: 0x80, A: 0x80})
+	for _, tc := range []struct {
+		hotspot, want image.Point
+	}{
+		{image.Pt(1, 2), image.Pt(1, 2)},
+		{image.Pt(-1, 5), image.Pt(0, 2)},
+		{image.Pt(7, -3), image.Pt(3, 0)},
+	} {
+		c := NewCustomCursor(src, tc.hotspot)
+		if got := c.Hotspot; got != tc.want {
+			t.Errorf("NewCustomCursor(%v) hotspot: got %v, want %v", tc.hotspot, got, tc.want)
+		}
+		if got, want := c.Image.Bounds(), image.Rect(0, 0, 4, 3); got != want {
+			t.Errorf("NewCustomCursor image bounds: got %v, want %v", got, want)
+		}
+		// The premultiplied source pixel is stored non-premultiplied.
+		if got, want := c.Image.NRGBAAt(1, 1), (color.NRGBA{R: 0xff, A: 0x80}); got != want {
+			t.Errorf("NewCustomCursor pixel: got %v, want %v", got, want)
+		}
+	}
+}
@@ -38,6 +38,14 @@ const (
 	ActionClose
 	// ActionMove moves a window directed by the user.
 	ActionMove
+	// ActionFocus brings the window to the front and gives it input focus.
+	// Like ActionRaise, platforms may refuse to steal the focus from other
+	// applications.
+	ActionFocus
+	// ActionRequestAttention asks the platform to draw the user's attention
+	// to the window, for example by flashing its taskbar entry. The request
+	// ends when the window gains focus.
+	ActionRequestAttention
 )
 
 func (op ActionInputOp) Add(o *op.Ops) {
@@ -72,6 +80,10 @@ func (a Action) string() string {
 		return "ActionClose"
 	case ActionMove:
 		return "ActionMove"
+	case ActionFocus:
+		return "ActionFocus"
+	case ActionRequestAttention:
+		return "ActionRequestAttention"
 	}
 	return ""
 }
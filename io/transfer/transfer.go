@@ -15,6 +15,12 @@
 // to the source and all potential targets.
 //
 // Note that the RequestEvent is sent to the source upon drop.
+//
+// Where the platform supports it, transfers also cross application
+// boundaries: data dropped from another application is delivered as a
+// DataEvent to the foremost target accepting its type, and a source
+// dropped on another application receives a RequestEvent for every type
+// the application requests, followed by a CancelEvent.
 package transfer
 
 import (
@@ -87,6 +93,9 @@ type DataEvent struct {
 	// Open returns the transfer data. It is only valid to call Open in the frame
 	// the DataEvent is received. The caller must close the return value after use.
 	Open func() io.ReadCloser
+	// Primary is set for data read from the primary selection by a
+	// clipboard.ReadCmd.
+	Primary bool
 }
 
 func (DataEvent) ImplementsEvent() {}
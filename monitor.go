@@ -0,0 +1,119 @@
+package mado
+
+import (
+	"image"
+	"sort"
+)
+
+// GetMonitors returns the connected monitors, with the primary monitor
+// first. It is nil on platforms without monitor support.
+var GetMonitors func() []Monitor
+
+// SetMonitorCallback sets the function called whenever a monitor is
+// connected or disconnected. It is nil on platforms without monitor
+// support.
+var SetMonitorCallback func(f func(MonitorEvent))
+
+// Monitor describes a display connected to the system.
+type Monitor struct {
+	// ID identifies the monitor for as long as it is connected.
+	ID uint64
+	// Name is a human-readable name of the monitor.
+	Name string
+	// Primary reports whether the monitor is the primary monitor.
+	Primary bool
+	// Bounds is the area of the monitor in the virtual desktop, in
+	// screen coordinates.
+	Bounds image.Rectangle
+	// WorkArea is the part of Bounds not occluded by task bars and
+	// panels. It equals Bounds if the platform doesn't report it.
+	WorkArea image.Rectangle
+	// PhysicalSize is the size of the display area in millimetres.
+	PhysicalSize image.Point
+	// Scale is the ratio between the monitor DPI and the platform
+	// default DPI.
+	Scale float32
+	// Mode is the current video mode.
+	Mode VideoMode
+	// Modes is the list of supported video modes, sorted by SortVideoModes.
+	Modes []VideoMode
+}
+
+// VideoMode describes a single video mode of a monitor.
+type VideoMode struct {
+	// Size is the resolution in pixels.
+	Size image.Point
+	// RedBits, GreenBits and BlueBits are the bit depths of the color
+	// channels.
+	RedBits, GreenBits, BlueBits int
+	// RefreshRate is the refresh rate in Hz.
+	RefreshRate int
+}
+
+// MonitorEvent is sent when a monitor is connected or disconnected.
+type MonitorEvent struct {
+	Monitor Monitor
+	// Connected is false for disconnected monitors.
+	Connected bool
+}
+
+// SortVideoModes sorts modes in ascending order, first by color bit depth
+// and then by resolution area, width and refresh rate.
+func SortVideoModes(modes []VideoMode) {
+	sort.SliceStable(modes, func(i, j int) bool {
+		a, b := modes[i], modes[j]
+		if da, db := a.RedBits+a.GreenBits+a.BlueBits, b.RedBits+b.GreenBits+b.BlueBits; da != db {
+			return da < db
+		}
+		if aa, ab := a.Size.X*a.Size.Y, b.Size.X*b.Size.Y; aa != ab {
+			return aa < ab
+		}
+		if a.Size.X != b.Size.X {
+			return a.Size.X < b.Size.X
+		}
+		return a.RefreshRate < b.RefreshRate
+	})
+}
+
+// SplitBPP splits a color depth into red, green and blue bit depths.
+func SplitBPP(bpp int) (red, green, blue int) {
+	// We assume that by 32 the user really meant 24.
+	if bpp == 32 {
+		bpp = 24
+	}
+	red, green, blue = bpp/3, bpp/3, bpp/3
+	delta := bpp - red*3
+	if delta >= 1 {
+		green++
+	}
+	if delta == 2 {
+		red++
+	}
+	return
+}
+
+// DiffMonitors returns the events that describe the transition from the
+// monitors in old to the monitors in new. Monitors are matched by ID.
+func DiffMonitors(old, new []Monitor) []MonitorEvent {
+	var events []MonitorEvent
+	for _, o := range old {
+		if !containsMonitor(new, o.ID) {
+			events = append(events, MonitorEvent{Monitor: o})
+		}
+	}
+	for _, n := range new {
+		if !containsMonitor(old, n.ID) {
+			events = append(events, MonitorEvent{Monitor: n, Connected: true})
+		}
+	}
+	return events
+}
+
+func containsMonitor(monitors []Monitor, id uint64) bool {
+	for _, m := range monitors {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
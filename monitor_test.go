@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package mado
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestSortVideoModes(t *testing.T) {
+	mode := func(w, h, bpp, hz int) VideoMode {
+		r, g, b := SplitBPP(bpp)
+		return VideoMode{Size: image.Pt(w, h), RedBits: r, GreenBits: g, BlueBits: b, RefreshRate: hz}
+	}
+	modes := []VideoMode{
+		mode(1920, 1080, 24, 60),
+		mode(1280, 1024, 24, 60),
+		mode(1920, 1080, 16, 60),
+		mode(1920, 1080, 24, 30),
+		mode(1024, 1280, 24, 60),
+		mode(800, 600, 24, 75),
+	}
+	SortVideoModes(modes)
+	want := []VideoMode{
+		mode(1920, 1080, 16, 60),
+		mode(800, 600, 24, 75),
+		mode(1024, 1280, 24, 60),
+		mode(1280, 1024, 24, 60),
+		mode(1920, 1080, 24, 30),
+		mode(1920, 1080, 24, 60),
+	}
+	if !reflect.DeepEqual(modes, want) {
+		t.Errorf("got modes\n%v\nwant\n%v", modes, want)
+	}
+}
+
+func TestSplitBPP(t *testing.T) {
+	tests := []struct {
+		bpp, r, g, b int
+	}{
+		{32, 8, 8, 8},
+		{24, 8, 8, 8},
+		{16, 5, 6, 5},
+		{15, 5, 5, 5},
+		{8, 3, 3, 2},
+		{0, 0, 0, 0},
+	}
+	for _, test := range tests {
+		r, g, b := SplitBPP(test.bpp)
+		if r != test.r || g != test.g || b != test.b {
+			t.Errorf("SplitBPP(%d) = %d, %d, %d; want %d, %d, %d", test.bpp, r, g, b, test.r, test.g, test.b)
+		}
+	}
+}
+
+func TestDiffMonitors(t *testing.T) {
+	a := Monitor{ID: 1, Name: "A"}
+	b := Monitor{ID: 2, Name: "B"}
+	c := Monitor{ID: 3, Name: "C"}
+	// Monitors are matched by ID, not by their configuration.
+	a2 := Monitor{ID: 1, Name: "A", Scale: 2}
+	tests := []struct {
+		old, new []Monitor
+		want     []MonitorEvent
+	}{
+		{nil, nil, nil},
+		{nil, []Monitor{a, b}, []MonitorEvent{{Monitor: a, Connected: true}, {Monitor: b, Connected: true}}},
+		{[]Monitor{a, b}, nil, []MonitorEvent{{Monitor: a}, {Monitor: b}}},
+		{[]Monitor{a, b}, []Monitor{a2, c}, []MonitorEvent{{Monitor: b}, {Monitor: c, Connected: true}}},
+		{[]Monitor{a, b}, []Monitor{b, a}, nil},
+	}
+	for i, test := range tests {
+		if got := DiffMonitors(test.old, test.new); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got events %v, want %v", i, got, test.want)
+		}
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	"unsafe"
 
+	"github.com/kanryu/mado/f32"
 	"github.com/kanryu/mado/gpu"
+	"github.com/kanryu/mado/io/input"
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/io/system"
@@ -23,9 +27,24 @@ var OsMain func()
 var OsNewWindow func(window Callbacks, options []Option) error
 var EnablePollEvents func()
 var PollEvents func()
+
+// WaitEvents blocks until the platform has events for PollEvents or the
+// timeout expires. A negative timeout waits indefinitely. It is nil on
+// platforms whose windows run their own event loops and deliver events
+// as they arrive.
+var WaitEvents func(timeout time.Duration)
+
+// PostEmptyEvent wakes up WaitEvents. It may be called from any
+// goroutine, and is nil where WaitEvents is nil.
+var PostEmptyEvent func()
+
 var GetTimerValue func() uint64
 var GetTimerFrequency func() uint64
 
+// RawMouseMotionSupported reports whether the platform can deliver
+// unaccelerated pointer motion. It is nil on platforms without support.
+var RawMouseMotionSupported func() bool
+
 // extraArgs contains extra arguments to append to
 // os.Args. The arguments are separated with |.
 // Useful for running programs on mobiles where the
@@ -56,8 +75,22 @@ type Config struct {
 	MaxSize image.Point
 	// MinSize is the window minimum allowed dimensions.
 	MinSize image.Point
+	// Pos is the screen position of the window content area, in pixels.
+	// Wayland doesn't let clients position their windows and ignores it.
+	Pos image.Point
+	// AspectRatio constrains the content area to the AspectRatio.X to
+	// AspectRatio.Y width to height ratio. The zero value means no
+	// constraint.
+	AspectRatio image.Point
+	// Opacity is the opacity of the whole window, including its
+	// decorations, in the range (0, 1]. The zero value means opaque.
+	Opacity float32
 	// Title is the window title displayed in its decoration bar.
 	Title string
+	// Icon is the list of candidate window icon images, from which the
+	// platform picks the sizes it needs. An empty list means the default
+	// icon.
+	Icon []*image.NRGBA
 	// WindowMode is the window mode.
 	Mode WindowMode
 	// StatusColor is the color of the Android status bar.
@@ -75,8 +108,52 @@ type Config struct {
 	// decoHeight is the height of the fallback decoration for platforms such
 	// as Wayland that may need fallback client-side decorations.
 	DecoHeight unit.Dp
+	// Parent is the window the window belongs to, or nil. A window with
+	// a parent is either a transient window, such as a dialog, that the
+	// platform keeps above its parent, or a popup placed against Anchor.
+	// Parent is set at window creation only.
+	Parent Window
+	// Popup is the kind of popup of a window with a parent.
+	Popup PopupKind
+	// Anchor is the rectangle of the parent a popup is placed against, in
+	// the pixel coordinates of the parent's pointer events. The popup
+	// is placed below the anchor, or above it if there is no room below,
+	// and slid horizontally to stay on screen.
+	Anchor image.Rectangle
 }
 
+// PopupKind is the kind of a popup window.
+type PopupKind uint8
+
+const (
+	// NoPopup is the kind of windows that are not popups.
+	NoPopup PopupKind = iota
+	// PopupMenu is for menus and drop-down lists. A menu takes the
+	// pointer and keyboard input while it is open.
+	PopupMenu
+	// PopupTooltip is for tooltips and other popups that don't take
+	// input.
+	PopupTooltip
+)
+
+// String returns the kind name.
+func (k PopupKind) String() string {
+	switch k {
+	case NoPopup:
+		return "none"
+	case PopupMenu:
+		return "menu"
+	case PopupTooltip:
+		return "tooltip"
+	}
+	return ""
+}
+
+// DismissEvent is sent to a popup dismissed by the platform, when the
+// user clicks outside it or when its parent closes. The popup is
+// destroyed after the event.
+type DismissEvent struct{}
+
 // ConfigEvent is sent whenever the configuration of a Window changes.
 type ConfigEvent struct {
 	Config Config
@@ -197,6 +274,51 @@ func (o Orientation) String() string {
 	return ""
 }
 
+// CursorMode controls the visibility and movement of the pointer cursor
+// over a window.
+type CursorMode uint8
+
+const (
+	// CursorModeNormal is the regular visible and free cursor.
+	CursorModeNormal CursorMode = iota
+	// CursorModeHidden hides the cursor while it is over the window.
+	CursorModeHidden
+	// CursorModeDisabled hides and locks the cursor to the window while it
+	// has focus. Pointer events then carry a virtual position that
+	// accumulates relative motion without bounds.
+	CursorModeDisabled
+	// CursorModeCaptured confines the visible cursor to the window while it
+	// has focus.
+	CursorModeCaptured
+)
+
+// InputMode describes how a window delivers pointer and keyboard input.
+type InputMode struct {
+	Cursor CursorMode
+	// RawMouseMotion requests unaccelerated motion while the cursor
+	// is disabled. It has no effect if RawMouseMotionSupported reports
+	// false.
+	RawMouseMotion bool
+	// LockKeyMods includes key.ModCapsLock and key.ModNumLock in the
+	// modifiers of key and pointer events.
+	LockKeyMods bool
+}
+
+// String returns the mode name.
+func (m CursorMode) String() string {
+	switch m {
+	case CursorModeNormal:
+		return "normal"
+	case CursorModeHidden:
+		return "hidden"
+	case CursorModeDisabled:
+		return "disabled"
+	case CursorModeCaptured:
+		return "captured"
+	}
+	return ""
+}
+
 // type frameEvent struct {
 // 	mado.FrameEvent
 
@@ -213,6 +335,12 @@ type Context interface {
 	Unlock()
 	SwapBuffers() error
 	SwapInterval(interval int)
+	// GetProcAddress returns the address of an OpenGL or OpenGL ES
+	// function, or nil if the context has no such function.
+	GetProcAddress(procname string) unsafe.Pointer
+	// ExtensionSupported reports whether the context supports an OpenGL
+	// extension or an extension of the context creation API.
+	ExtensionSupported(extension string) bool
 }
 
 // Driver is the interface for the platform implementation
@@ -225,14 +353,38 @@ type Driver interface {
 	ShowTextInput(show bool)
 	SetInputHint(mode key.InputHint)
 	NewContext() (Context, error)
-	// ReadClipboard requests the clipboard content.
-	ReadClipboard()
-	// WriteClipboard requests a clipboard write.
-	WriteClipboard(mime string, s []byte)
+	// NewFramebuffer returns a Framebuffer for the window, presented
+	// the fastest way the platform has for CPU-side pixels.
+	NewFramebuffer() (Framebuffer, error)
+	// ReadClipboard requests the clipboard content in a MIME type, or
+	// the content of the primary selection if primary is set.
+	ReadClipboard(mime string, primary bool)
+	// WriteClipboard replaces the clipboard content, offering each of
+	// the MIME types in content.
+	WriteClipboard(content []input.ClipboardData)
+	// SetDragSource announces the MIME types offered by the source of
+	// the pointer-guided transfer in progress, or nil when there is
+	// none. Platforms that support it offer the types to the other
+	// applications the pointer is dragged over.
+	SetDragSource(types []string)
+	// OfferDragData answers the requests of another application for
+	// the data of a transfer dropped on it.
+	OfferDragData(mime string, data []byte)
 	// Configure the window.
 	Configure([]Option)
 	// SetCursor updates the current cursor to name.
 	SetCursor(cursor pointer.Cursor)
+	// SetCustomCursor replaces the cursor shape with an image cursor, or
+	// restores the cursor set by SetCursor if c is nil.
+	SetCustomCursor(c *pointer.CustomCursor)
+	// ReleaseCustomCursor frees the native cursor created for c, if
+	// any. A window using c reverts to the cursor set by SetCursor.
+	ReleaseCustomCursor(c *pointer.CustomCursor)
+	// SetInputMode updates the pointer and keyboard input modes.
+	SetInputMode(mode InputMode)
+	// SetCursorPos moves the cursor to pos, in window coordinates. With
+	// CursorModeDisabled it sets the virtual cursor position instead.
+	SetCursorPos(pos f32.Point)
 	// Wakeup wakes up the event loop and sends a WakeupEvent.
 	Wakeup()
 	// Perform actions on the window.
@@ -240,6 +392,9 @@ type Driver interface {
 	// EditorStateChanged notifies the driver that the editor state changed.
 	EditorStateChanged(old, new EditorState)
 	GetFrameBufferSize() image.Point
+	// GetFrameExtents returns the size of each edge of the window frame,
+	// in pixels. A window without platform decorations has no frame.
+	GetFrameExtents() (left, top, right, bottom int)
 }
 
 // Make it possible to update the options into Callbacks
@@ -290,9 +445,10 @@ func WalkActions(actions system.Action, do func(system.Action)) {
 	}
 }
 
-func (ConfigEvent) ImplementsEvent() {}
-func (WakeupEvent) ImplementsEvent() {}
-func (StageEvent) ImplementsEvent()  {}
+func (ConfigEvent) ImplementsEvent()  {}
+func (WakeupEvent) ImplementsEvent()  {}
+func (StageEvent) ImplementsEvent()   {}
+func (DismissEvent) ImplementsEvent() {}
 
 func init() {
 	if extraArgs != "" {
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-text/typesetting/font"
+	apifont "github.com/go-text/typesetting/opentype/api/font"
+	"github.com/go-text/typesetting/opentype/loader"
+	"github.com/go-text/typesetting/shaping"
+
+	giofont "github.com/kanryu/mado/font"
+)
+
+// faceVariation identifies a face with variation settings applied.
+type faceVariation struct {
+	font       font.Font
+	variations giofont.Variations
+}
+
+// parseFeatures parses OpenType feature settings.
+func parseFeatures(s giofont.Features) ([]shaping.FontFeature, error) {
+	var features []shaping.FontFeature
+	err := parseSettings(string(s), func(tag loader.Tag, value string) error {
+		v := uint32(1)
+		switch value {
+		case "", "on":
+		case "off":
+			v = 0
+		default:
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", value)
+			}
+			v = uint32(n)
+		}
+		features = append(features, shaping.FontFeature{Tag: tag, Value: v})
+		return nil
+	})
+	return features, err
+}
+
+// parseVariations parses the settings of variation axes.
+func parseVariations(s giofont.Variations) ([]apifont.Variation, error) {
+	var variations []apifont.Variation
+	err := parseSettings(string(s), func(tag loader.Tag, value string) error {
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("invalid value %q", value)
+		}
+		variations = append(variations, apifont.Variation{Tag: tag, Value: float32(v)})
+		return nil
+	})
+	return variations, err
+}
+
+// parseSettings calls f for each comma-separated setting of a tag and an
+// optional value in s.
+func parseSettings(s string, f func(tag loader.Tag, value string) error) error {
+	for _, setting := range strings.Split(s, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		tag, value := setting, ""
+		if q := setting[0]; q == '"' || q == '\'' {
+			end := strings.IndexByte(setting[1:], q)
+			if end == -1 {
+				return fmt.Errorf("unterminated tag in %q", setting)
+			}
+			tag, value = setting[1:end+1], setting[end+2:]
+		} else if i := strings.IndexFunc(setting, isSpace); i != -1 {
+			tag, value = setting[:i], setting[i:]
+		}
+		if len(tag) != 4 {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+		for i := 0; i < len(tag); i++ {
+			if tag[i] < 0x20 || tag[i] > 0x7e {
+				return fmt.Errorf("invalid tag %q", tag)
+			}
+		}
+		if err := f(loader.NewTag(tag[0], tag[1], tag[2], tag[3]), strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// applyFont sets the features of f on the input, and replaces its face
+// with one with the variations of f.
+func (s *shaperImpl) applyFont(input *shaping.Input, f giofont.Font) {
+	if f.Features != "" {
+		features, ok := s.features[f.Features]
+		if !ok {
+			var err error
+			features, err = parseFeatures(f.Features)
+			if err != nil {
+				s.logger.Printf("Unable to parse features %q: %v", f.Features, err)
+			}
+			s.features[f.Features] = features
+		}
+		input.FontFeatures = features
+	}
+	if f.Variations != "" && input.Face != nil {
+		input.Face = s.varyFace(input.Face, f.Variations)
+	}
+}
+
+// varyFace returns face with variations applied, or face itself if it has
+// no variation axes. Varied faces have fonts of their own, so that they are
+// cached by the shaper and identified by GlyphIDs apart from face.
+func (s *shaperImpl) varyFace(face font.Face, variations giofont.Variations) font.Face {
+	key := faceVariation{font: face.Font, variations: variations}
+	if varied, ok := s.variedFaces[key]; ok {
+		return varied
+	}
+	settings, err := parseVariations(variations)
+	if err != nil {
+		s.logger.Printf("Unable to parse variations %q: %v", variations, err)
+	}
+	varied := face
+	if len(settings) > 0 {
+		ft := *face.Font
+		v := &apifont.Face{Font: &ft, XPpem: face.XPpem, YPpem: face.YPpem}
+		v.SetVariations(settings)
+		if len(v.Coords) > 0 {
+			md := s.faceMeta[s.faceToIndex[face.Font]]
+			md.Variations = variations
+			s.addFace(v, md)
+			varied = v
+		}
+	}
+	s.variedFaces[key] = varied
+	return varied
+}
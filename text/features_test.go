@@ -0,0 +1,62 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/go-text/typesetting/opentype/loader"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/exp/slices"
+
+	giofont "github.com/kanryu/mado/font"
+)
+
+func TestParseFeatures(t *testing.T) {
+	tag := loader.MustNewTag
+	for _, tc := range []struct {
+		input     giofont.Features
+		expected  []shaping.FontFeature
+		shouldErr bool
+	}{
+		{input: ""},
+		{input: "tnum", expected: []shaping.FontFeature{{Tag: tag("tnum"), Value: 1}}},
+		{
+			input: `liga off, "ss01", 'salt' 2, zero on`,
+			expected: []shaping.FontFeature{
+				{Tag: tag("liga"), Value: 0},
+				{Tag: tag("ss01"), Value: 1},
+				{Tag: tag("salt"), Value: 2},
+				{Tag: tag("zero"), Value: 1},
+			},
+		},
+		{input: "kern,, palt", expected: []shaping.FontFeature{{Tag: tag("kern"), Value: 1}, {Tag: tag("palt"), Value: 1}}},
+		{input: "tab", shouldErr: true},
+		{input: "liga maybe", shouldErr: true},
+		{input: `"liga`, shouldErr: true},
+	} {
+		actual, err := parseFeatures(tc.input)
+		if (err != nil) != tc.shouldErr {
+			t.Errorf("%q: expected error %v, got %v", tc.input, tc.shouldErr, err)
+			continue
+		}
+		if !tc.shouldErr && !slices.Equal(actual, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.input, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseVariations(t *testing.T) {
+	actual, err := parseVariations(`wght 650, "wdth" 87.5`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 2 ||
+		actual[0].Tag != loader.MustNewTag("wght") || actual[0].Value != 650 ||
+		actual[1].Tag != loader.MustNewTag("wdth") || actual[1].Value != 87.5 {
+		t.Errorf("unexpected variations %v", actual)
+	}
+	for _, invalid := range []giofont.Variations{"wght", "wght heavy", "weight 700"} {
+		if _, err := parseVariations(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"image"
 	"io"
 	"log"
+	"math"
 	"os"
 
 	"github.com/go-text/typesetting/di"
@@ -36,7 +37,10 @@ type document struct {
 	lines     []line
 	alignment Alignment
 	// alignWidth is the width used when aligning text.
-	alignWidth      int
+	alignWidth int
+	// minWidth is the minimum width of vertical text, whose first column
+	// is at the right edge.
+	minWidth        int
 	unreadRuneCount int
 }
 
@@ -45,14 +49,31 @@ type document struct {
 func (l *document) append(other document) {
 	l.lines = append(l.lines, other.lines...)
 	l.alignWidth = max(l.alignWidth, other.alignWidth)
+	l.minWidth = max(l.minWidth, other.minWidth)
 	calculateYOffsets(l.lines)
 }
 
+// dot returns the document coordinates of the dot at distance along ln.
+// Vertical lines are columns set from right to left, and their dot is on
+// the center line of the column.
+func (l *document) dot(ln line, along fixed.Int26_6) (fixed.Int26_6, int32) {
+	if !ln.vertical() {
+		return along, int32(ln.yOffset)
+	}
+	width := l.minWidth
+	if n := len(l.lines); n > 0 {
+		last := l.lines[n-1]
+		width = max(width, last.yOffset+last.descent.Ceil())
+	}
+	return fixed.I(width - ln.yOffset), int32(along.Round())
+}
+
 // reset empties the document in preparation to reuse its memory.
 func (l *document) reset() {
 	l.lines = l.lines[:0]
 	l.alignment = Start
 	l.alignWidth = 0
+	l.minWidth = 0
 	l.unreadRuneCount = 0
 }
 
@@ -92,7 +113,17 @@ type line struct {
 	// runeCount is the number of text runes represented by this line's runs.
 	runeCount int
 
+	// yOffset is the distance from the top of the document to the baseline,
+	// or for vertical lines from the right edge to the center of the column.
 	yOffset int
+
+	// ruby holds the glyphs of the ruby annotations of the line.
+	ruby []rubyGlyph
+}
+
+// vertical reports whether the line is a column of vertical text.
+func (l *line) vertical() bool {
+	return l.direction.Axis() == system.Vertical
 }
 
 // insertTrailingSyntheticNewline adds a synthetic newline to the final logical run of the line
@@ -204,6 +235,9 @@ type runLayout struct {
 	// truncator indicates that this run is a text truncator standing in for remaining
 	// text.
 	truncator bool
+	// sideways indicates that this run of vertical text was shaped
+	// horizontally, to be drawn rotated.
+	sideways bool
 }
 
 // shaperImpl implements the shaping and line-wrapping of opentype fonts.
@@ -229,6 +263,16 @@ type shaperImpl struct {
 	splitScratch1, splitScratch2 []shaping.Input
 	outScratchBuf                []shaping.Output
 	scratchRunes                 []rune
+	rubyScratch                  []rubyLayout
+	breakScratch                 []rune
+	lineStarts                   []int
+	// tabs are the tabs of the paragraph being laid out.
+	tabs []tab
+
+	// features caches parsed font features, and variedFaces the faces
+	// with variations applied.
+	features    map[giofont.Features][]shaping.FontFeature
+	variedFaces map[faceVariation]font.Face
 
 	// bitmapGlyphCache caches extracted bitmap glyph images.
 	bitmapGlyphCache bitmapCache
@@ -254,6 +298,8 @@ func newShaperImpl(systemFonts bool, collection []FontFace) *shaperImpl {
 	shaper.logger = newDebugLogger()
 	shaper.fontMap = fontscan.NewFontMap(shaper.logger)
 	shaper.faceToIndex = make(map[font.Font]int)
+	shaper.features = make(map[giofont.Features][]shaping.FontFeature)
+	shaper.variedFaces = make(map[faceVariation]font.Face)
 	if systemFonts {
 		str, err := os.UserCacheDir()
 		if err != nil {
@@ -390,23 +436,56 @@ func (s *shaperImpl) ResolveFace(r rune) font.Face {
 }
 
 // splitByFaces divides the inputs by font coverage in the provided faces. It will use the slice provided in buf
-// as the backing storage of the returned slice if buf is non-nil.
-func (s *shaperImpl) splitByFaces(inputs []shaping.Input, buf []shaping.Input) []shaping.Input {
+// as the backing storage of the returned slice if buf is non-nil. Inputs within spans are
+// resolved to faces of the fonts of their span, and the features and variations of their
+// font, or of f outside spans, are applied to them.
+func (s *shaperImpl) splitByFaces(inputs []shaping.Input, f giofont.Font, spans []spanRange, buf []shaping.Input) []shaping.Input {
 	var split []shaping.Input
 	if buf == nil {
 		split = make([]shaping.Input, 0, len(inputs))
 	} else {
 		split = buf
 	}
+	span := -1
 	for _, input := range inputs {
+		font := f
+		if i := spanAt(spans, input.RunStart); i != -1 {
+			if span == -1 || spans[i].font != spans[span].font {
+				s.setFont(spans[i].font)
+				span = i
+			}
+			font = spans[i].font
+		}
+		start := len(split)
 		split = append(split, shaping.SplitByFace(input, s)...)
+		for i := start; i < len(split); i++ {
+			s.applyFont(&split[i], font)
+		}
 	}
 	return split
 }
 
+// setFont directs the resolution of faces to those matching f.
+func (s *shaperImpl) setFont(f giofont.Font) {
+	families := s.defaultFaces
+	if f.Typeface != "" {
+		parsed, err := s.parser.parse(string(f.Typeface))
+		if err != nil {
+			s.logger.Printf("Unable to parse typeface %q: %v", f.Typeface, err)
+		} else {
+			families = parsed
+		}
+	}
+	s.fontMap.SetQuery(fontscan.Query{
+		Families: families,
+		Aspect:   opentype.FontToDescription(f).Aspect,
+	})
+}
+
 // shapeText invokes the text shaper and returns the raw text data in the shaper's native
-// format. It does not wrap lines.
-func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune) []shaping.Output {
+// format. It does not wrap lines. Text within spans is shaped with their font and size, and
+// other text with the features and variations of f.
+func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, f giofont.Font, txt []rune, spans []spanRange) []shaping.Output {
 	lcfg := langConfig{
 		Language:  language.NewLanguage(lc.Language),
 		Direction: mapDirection(lc.Direction),
@@ -421,8 +500,14 @@ func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune)
 	}
 	// Break input on font glyph coverage.
 	inputs := s.splitBidi(input)
-	inputs = s.splitByFaces(inputs, s.splitScratch1[:0])
+	if len(spans) > 0 {
+		inputs = splitBySpans(inputs, spans, s.splitScratch2[:0])
+	}
+	inputs = s.splitByFaces(inputs, f, spans, s.splitScratch1[:0])
 	inputs = splitByScript(inputs, lcfg.Direction, s.splitScratch2[:0])
+	if lcfg.Direction.IsVertical() {
+		inputs = splitByOrientation(inputs, s.splitScratch1[:0])
+	}
 	// Shape all inputs.
 	if needed := len(inputs) - len(s.outScratchBuf); needed > 0 {
 		s.outScratchBuf = slices.Grow(s.outScratchBuf, needed)
@@ -430,7 +515,11 @@ func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune)
 	s.outScratchBuf = s.outScratchBuf[:0]
 	for _, input := range inputs {
 		if input.Face != nil {
-			s.outScratchBuf = append(s.outScratchBuf, s.shaper.Shape(input))
+			out := s.shaper.Shape(input)
+			if out.Direction.IsVertical() {
+				fixVerticalOutput(&out)
+			}
+			s.outScratchBuf = append(s.outScratchBuf, out)
 		} else {
 			s.outScratchBuf = append(s.outScratchBuf, shaping.Output{
 				// Use the text size as the advance of the entire fake run so that
@@ -487,35 +576,54 @@ func wrapPolicyToGoText(p WrapPolicy) shaping.LineBreakPolicy {
 }
 
 // shapeAndWrapText invokes the text shaper and returns wrapped lines in the shaper's native format.
-func (s *shaperImpl) shapeAndWrapText(params Parameters, txt []rune) (_ []shaping.Line, truncated int) {
+// The ruby annotations of m are shaped into s.rubyScratch.
+func (s *shaperImpl) shapeAndWrapText(params Parameters, txt []rune, m markup) (_ []shaping.Line, truncated int) {
 	wc := shaping.WrapConfig{
 		TruncateAfterLines: params.MaxLines,
 		TextContinues:      params.forceTruncate,
 		BreakPolicy:        wrapPolicyToGoText(params.WrapPolicy),
 	}
-	families := s.defaultFaces
-	if params.Font.Typeface != "" {
-		parsed, err := s.parser.parse(string(params.Font.Typeface))
-		if err != nil {
-			s.logger.Printf("Unable to parse typeface %q: %v", params.Font.Typeface, err)
-		} else {
-			families = parsed
-		}
-	}
-	s.fontMap.SetQuery(fontscan.Query{
-		Families: families,
-		Aspect:   opentype.FontToDescription(params.Font).Aspect,
-	})
+	s.setFont(params.Font)
 	if wc.TruncateAfterLines > 0 {
 		if len(params.Truncator) == 0 {
 			params.Truncator = "…"
 		}
 		// We only permit a single run as the truncator, regardless of whether more were generated.
 		// Just use the first one.
-		wc.Truncator = s.shapeText(params.PxPerEm, params.Locale, []rune(params.Truncator))[0]
+		wc.Truncator = s.shapeText(params.PxPerEm, params.Locale, params.Font, []rune(params.Truncator), nil)[0]
+	}
+	maxWidth := params.MaxWidth
+	if params.Locale.Direction.Axis() == system.Vertical {
+		maxWidth = params.MaxHeight
+	}
+	s.rubyScratch = s.rubyScratch[:0]
+	for _, r := range m.ruby {
+		s.rubyScratch = append(s.rubyScratch, rubyLayout{line: s.shapeAnnotation(params, r.annotation)})
+	}
+	outs := s.shapeText(params.PxPerEm, params.Locale, params.Font, txt, m.spans)
+	breaks := txt
+	if len(m.ruby) > 0 {
+		padRuby(outs, m.ruby, s.rubyScratch)
+		s.breakScratch = joinRuby(append(s.breakScratch[:0], txt...), m.ruby)
+		breaks = s.breakScratch
+	}
+	if params.LetterSpacing != 0 || params.WordSpacing != 0 {
+		spaceText(outs, txt, s.tabs, params.LetterSpacing, params.WordSpacing)
+	}
+	if len(s.tabs) > 0 {
+		setTabs(outs, s.tabs, params.TabStops, nil)
 	}
 	// Wrap outputs into lines.
-	return s.wrapper.WrapParagraph(wc, params.MaxWidth, txt, shaping.NewSliceIterator(s.shapeText(params.PxPerEm, params.Locale, txt)))
+	lines, truncated := s.wrapper.WrapParagraph(wc, maxWidth, breaks, shaping.NewSliceIterator(outs))
+	if len(s.tabs) > 0 && len(lines) > 1 {
+		// Tab stops are measured from the start of lines, which are only
+		// known after wrapping. Wrap again with the tabs set from the starts
+		// of the lines.
+		s.lineStarts = lineStarts(s.lineStarts[:0], lines)
+		setTabs(outs, s.tabs, params.TabStops, s.lineStarts)
+		lines, truncated = s.wrapper.WrapParagraph(wc, maxWidth, breaks, shaping.NewSliceIterator(outs))
+	}
+	return lines, truncated
 }
 
 // replaceControlCharacters replaces problematic unicode
@@ -531,6 +639,8 @@ func replaceControlCharacters(in []rune) []rune {
 		case '\u001E':
 		case '\r':
 		case '\n':
+		// Tabs are set to tab stops after shaping.
+		case '\t':
 		// Unicode "next line" character.
 		case '\u0085':
 		// Unicode "paragraph separator".
@@ -574,6 +684,11 @@ func calculateYOffsets(lines []line) {
 
 // LayoutRunes shapes and wraps the text, and returns the result in Gio's shaped text format.
 func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
+	return s.layoutMarkup(params, txt, markup{})
+}
+
+// layoutMarkup is LayoutRunes for text with ruby annotations and spans.
+func (s *shaperImpl) layoutMarkup(params Parameters, txt []rune, m markup) document {
 	hasNewline := len(txt) > 0 && txt[len(txt)-1] == '\n'
 	var ls []shaping.Line
 	var truncated int
@@ -585,7 +700,8 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 		// on the final line (if we hit the limit).
 		params.forceTruncate = true
 	}
-	ls, truncated = s.shapeAndWrapText(params, replaceControlCharacters(txt))
+	s.tabs = findTabs(s.tabs[:0], txt)
+	ls, truncated = s.shapeAndWrapText(params, replaceControlCharacters(txt), m)
 
 	hasTruncator := truncated > 0 || (params.forceTruncate && params.MaxLines == len(ls))
 	if hasTruncator && hasNewline {
@@ -613,6 +729,18 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 		}
 		textLines[i] = otLine
 	}
+	maxWidth := params.MaxWidth
+	if params.Locale.Direction.Axis() == system.Vertical {
+		maxWidth = params.MaxHeight
+	}
+	for i := range textLines {
+		if len(s.tabs) > 0 {
+			textLines[i].setTabs(s.tabs, params.TabStops, fixed.I(maxWidth))
+		}
+		if params.Alignment == Justify && i < len(textLines)-1 {
+			textLines[i].justify(txt, s.tabs, fixed.I(maxWidth))
+		}
+	}
 	if params.LineHeight != 0 {
 		maxHeight = params.LineHeight
 	}
@@ -624,11 +752,19 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 	for i := range textLines {
 		textLines[i].lineHeight = maxHeight
 	}
+	if len(m.ruby) > 0 {
+		placeRuby(textLines, m.ruby, s.rubyScratch)
+	}
 	calculateYOffsets(textLines)
+	minAlign := params.MinWidth
+	if params.Locale.Direction.Axis() == system.Vertical {
+		minAlign = params.MinHeight
+	}
 	return document{
 		lines:      textLines,
 		alignment:  params.Alignment,
-		alignWidth: alignWidth(params.MinWidth, textLines),
+		alignWidth: alignWidth(minAlign, textLines),
+		minWidth:   params.MinWidth,
 	}
 }
 
@@ -644,11 +780,12 @@ func alignWidth(minWidth int, lines []line) int {
 func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 	var lastPos f32.Point
 	var x fixed.Int26_6
+	var y int32
 	var builder clip.Path
 	builder.Begin(pathOps)
 	for i, g := range gs {
 		if i == 0 {
-			x = g.X
+			x, y = g.X, g.Y
 		}
 		ppem, faceIdx, gid := splitGlyphID(g.ID)
 		if faceIdx >= len(s.faces) {
@@ -666,8 +803,9 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 			// Move to glyph position.
 			pos := f32.Point{
 				X: fixedToFloat((g.X - x) - g.Offset.X),
-				Y: -fixedToFloat(g.Offset.Y),
+				Y: float32(g.Y-y) - fixedToFloat(g.Offset.Y),
 			}
+			sideways := g.Flags&FlagSideways != 0
 			builder.Move(pos.Sub(lastPos))
 			lastPos = pos
 			var lastArg f32.Point
@@ -687,6 +825,10 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 						X: fseg.Args[i].X * scaleFactor,
 						Y: -fseg.Args[i].Y * scaleFactor,
 					}
+					if sideways {
+						// Turn the glyph a quarter clockwise.
+						a = f32.Point{X: -a.Y, Y: a.X}
+					}
 					args[i] = a.Sub(lastArg)
 					if i == nargs-1 {
 						lastArg = a
@@ -725,10 +867,11 @@ func floatToFixed(f float32) fixed.Int26_6 {
 // and will align correctly.
 func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 	var x fixed.Int26_6
+	var y int32
 	bitmapMacro := op.Record(ops)
 	for i, g := range gs {
 		if i == 0 {
-			x = g.X
+			x, y = g.X, g.Y
 		}
 		_, faceIdx, gid := splitGlyphID(g.ID)
 		if faceIdx >= len(s.faces) {
@@ -764,12 +907,6 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 				imgOp = bitmapData.img
 				imgSize = bitmapData.size
 			}
-			off := op.Affine(f32.Affine2D{}.Offset(f32.Point{
-				X: fixedToFloat((g.X - x) - g.Offset.X),
-				Y: fixedToFloat(g.Offset.Y + g.Bounds.Min.Y),
-			})).Push(ops)
-			cl := clip.Rect{Max: imgSize}.Push(ops)
-
 			glyphSize := image.Rectangle{
 				Min: image.Point{
 					X: g.Bounds.Min.X.Round(),
@@ -780,6 +917,32 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 					Y: g.Bounds.Max.Y.Round(),
 				},
 			}.Size()
+			dot := f32.Point{X: fixedToFloat(g.X - x), Y: float32(g.Y - y)}
+			var t f32.Affine2D
+			switch {
+			case g.Flags&FlagSideways != 0:
+				// Draw the bitmap upright at its origin, and turn it a
+				// quarter clockwise into place.
+				origin := dot.Sub(f32.Point{X: fixedToFloat(g.Offset.X), Y: fixedToFloat(g.Offset.Y)})
+				t = f32.Affine2D{}.Offset(f32.Point{
+					X: fixedToFloat(g.Bounds.Min.Y + g.Offset.Y),
+					Y: -fixedToFloat(g.Bounds.Max.X + g.Offset.X),
+				}).Rotate(f32.Point{}, math.Pi/2).Offset(origin)
+				glyphSize.X, glyphSize.Y = glyphSize.Y, glyphSize.X
+			case g.Flags&FlagVertical != 0:
+				t = f32.Affine2D{}.Offset(dot.Add(f32.Point{
+					X: fixedToFloat(g.Bounds.Min.X),
+					Y: fixedToFloat(g.Bounds.Min.Y),
+				}))
+			default:
+				t = f32.Affine2D{}.Offset(f32.Point{
+					X: fixedToFloat((g.X - x) - g.Offset.X),
+					Y: fixedToFloat(g.Offset.Y + g.Bounds.Min.Y),
+				})
+			}
+			off := op.Affine(t).Push(ops)
+			cl := clip.Rect{Max: imgSize}.Push(ops)
+
 			aff := op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Point{
 				X: float32(glyphSize.X) / float32(imgSize.X),
 				Y: float32(glyphSize.Y) / float32(imgSize.Y),
@@ -824,6 +987,8 @@ func mapDirection(d system.TextDirection) di.Direction {
 		return di.DirectionLTR
 	case system.RTL:
 		return di.DirectionRTL
+	case system.TTB:
+		return di.DirectionTTB
 	}
 	return di.DirectionLTR
 }
@@ -834,6 +999,8 @@ func unmapDirection(d di.Direction) system.TextDirection {
 		return system.LTR
 	case di.DirectionRTL:
 		return system.RTL
+	case di.DirectionTTB:
+		return system.TTB
 	}
 	return system.LTR
 }
@@ -873,6 +1040,7 @@ func toLine(faceToIndex map[font.Font]int, o shaping.Line, dir system.TextDirect
 		runs:      make([]runLayout, len(o)),
 		direction: dir,
 	}
+	vertical := line.vertical()
 	maxSize := fixed.Int26_6(0)
 	for i := range o {
 		run := o[i]
@@ -883,8 +1051,14 @@ func toLine(faceToIndex map[font.Font]int, o shaping.Line, dir system.TextDirect
 		if run.Face != nil {
 			font = run.Face.Font
 		}
+		var glyphs []glyph
+		if vertical {
+			glyphs = toVerticalGlyphs(run, faceToIndex[font])
+		} else {
+			glyphs = toGioGlyphs(run.Glyphs, run.Size, faceToIndex[font])
+		}
 		line.runs[i] = runLayout{
-			Glyphs: toGioGlyphs(run.Glyphs, run.Size, faceToIndex[font]),
+			Glyphs: glyphs,
 			Runes: Range{
 				Count:  run.Runes.Count,
 				Offset: line.runeCount,
@@ -893,9 +1067,13 @@ func toLine(faceToIndex map[font.Font]int, o shaping.Line, dir system.TextDirect
 			face:      run.Face,
 			Advance:   run.Advance,
 			PPEM:      run.Size,
+			sideways:  vertical && !run.Direction.IsVertical(),
 		}
 		line.runeCount += run.Runes.Count
 		line.width += run.Advance
+		if vertical {
+			continue
+		}
 		if line.ascent < run.LineBounds.Ascent {
 			line.ascent = run.LineBounds.Ascent
 		}
@@ -903,6 +1081,11 @@ func toLine(faceToIndex map[font.Font]int, o shaping.Line, dir system.TextDirect
 			line.descent = -run.LineBounds.Descent + run.LineBounds.Gap
 		}
 	}
+	if vertical {
+		// Columns are as wide as the em box of their text.
+		line.ascent = maxSize / 2
+		line.descent = maxSize - line.ascent
+	}
 	line.lineHeight = maxSize
 	computeVisualOrder(&line)
 	return line
@@ -300,13 +300,13 @@ func makeTestText(shaper *shaperImpl, primaryDir system.TextDirection, fontSize,
 		PxPerEm:  fixed.I(fontSize),
 		MaxWidth: lineWidth,
 		Locale:   locale,
-	}, []rune(simpleSource))
+	}, []rune(simpleSource), markup{})
 	simpleText = copyLines(simpleText)
 	complexText, _ := shaper.shapeAndWrapText(Parameters{
 		PxPerEm:  fixed.I(fontSize),
 		MaxWidth: lineWidth,
 		Locale:   locale,
-	}, []rune(complexSource))
+	}, []rune(complexSource), markup{})
 	complexText = copyLines(complexText)
 	testShaper(rtlFace, ltrFace)
 	return simpleText, complexText
@@ -93,7 +93,7 @@ type glyphLRU[V any] struct {
 
 var seed uint32
 
-// hashGlyphs computes a hash key based on the ID and X offset of
+// hashGlyphs computes a hash key based on the ID and offset of
 // every glyph in the slice.
 func (c *glyphLRU[V]) hashGlyphs(gs []Glyph) uint64 {
 	if c.seed == 0 {
@@ -104,10 +104,12 @@ func (c *glyphLRU[V]) hashGlyphs(gs []Glyph) uint64 {
 	}
 
 	h := c.seed
-	firstX := gs[0].X
+	firstX, firstY := gs[0].X, gs[0].Y
 	for _, g := range gs {
 		h += uint64(g.X - firstX)
 		h *= 6585573582091643
+		h += uint64(g.Y - firstY)
+		h *= 6585573582091643
 		h += uint64(g.ID)
 		h *= 3650802748644053
 	}
@@ -126,12 +128,13 @@ func (c *glyphLRU[V]) Get(key uint64, gs []Glyph) (V, bool) {
 func (c *glyphLRU[V]) Put(key uint64, glyphs []Glyph, v V) {
 	gids := make([]glyphInfo, len(glyphs))
 	firstX := fixed.I(0)
+	firstY := int32(0)
 	for i, glyph := range glyphs {
 		if i == 0 {
-			firstX = glyph.X
+			firstX, firstY = glyph.X, glyph.Y
 		}
-		// Cache glyph X offsets relative to the first glyph.
-		gids[i] = glyphInfo{ID: glyph.ID, X: glyph.X - firstX}
+		// Cache glyph offsets relative to the first glyph.
+		gids[i] = glyphInfo{ID: glyph.ID, X: glyph.X - firstX, Y: glyph.Y - firstY, sideways: glyph.Flags&FlagSideways != 0}
 	}
 	val := glyphValue[V]{
 		glyphs: gids,
@@ -147,20 +150,32 @@ type bitmapShapeCache = glyphLRU[op.CallOp]
 type glyphInfo struct {
 	ID GlyphID
 	X  fixed.Int26_6
+	Y  int32
+	// sideways tracks whether the glyph is drawn rotated.
+	sideways bool
 }
 
 type layoutKey struct {
-	ppem               fixed.Int26_6
-	maxWidth, minWidth int
-	maxLines           int
-	str                string
-	truncator          string
-	locale             system.Locale
-	font               giofont.Font
-	forceTruncate      bool
-	wrapPolicy         WrapPolicy
-	lineHeight         fixed.Int26_6
-	lineHeightScale    float32
+	ppem                 fixed.Int26_6
+	maxWidth, minWidth   int
+	maxHeight, minHeight int
+	maxLines             int
+	str                  string
+	// markup is the encoding of the markup of str by markup.key.
+	markup          string
+	truncator       string
+	locale          system.Locale
+	font            giofont.Font
+	forceTruncate   bool
+	wrapPolicy      WrapPolicy
+	lineHeight      fixed.Int26_6
+	lineHeightScale float32
+	justify         bool
+	letterSpacing   fixed.Int26_6
+	wordSpacing     fixed.Int26_6
+	tabInterval     fixed.Int26_6
+	// tabStops is the encoding of the stops of TabStops by TabStops.key.
+	tabStops string
 }
 
 const maxSize = 1000
@@ -170,12 +185,14 @@ func gidsEqual(a []glyphInfo, glyphs []Glyph) bool {
 		return false
 	}
 	firstX := fixed.Int26_6(0)
+	firstY := int32(0)
 	for i := range a {
+		g := glyphs[i]
 		if i == 0 {
-			firstX = glyphs[i].X
+			firstX, firstY = g.X, g.Y
 		}
-		// Cache glyph X offsets relative to the first glyph.
-		if a[i].ID != glyphs[i].ID || a[i].X != (glyphs[i].X-firstX) {
+		// Cache glyph offsets relative to the first glyph.
+		if a[i].ID != g.ID || a[i].X != (g.X-firstX) || a[i].Y != (g.Y-firstY) || a[i].sideways != (g.Flags&FlagSideways != 0) {
 			return false
 		}
 	}
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// rubyRange is a ruby annotation of a range of runes of base text.
+type rubyRange struct {
+	Range
+	annotation string
+}
+
+// rubyLayout is the shaped annotation of a rubyRange.
+type rubyLayout struct {
+	// line holds the glyphs of the annotation.
+	line line
+	// pad is the space added after each cluster of base text shorter than
+	// its annotation.
+	pad fixed.Int26_6
+}
+
+// rubyGlyph is a glyph of a ruby annotation, positioned relative to the
+// line of its base text.
+type rubyGlyph struct {
+	glyph
+	// along is the distance of the dot from the start of the line.
+	along fixed.Int26_6
+	// rise is the distance of the dot from the dot of the line, towards
+	// the ascent of the line.
+	rise            fixed.Int26_6
+	ascent, descent fixed.Int26_6
+	sideways        bool
+}
+
+// shapeAnnotation shapes the text of a ruby annotation at half the size of
+// its base text.
+func (s *shaperImpl) shapeAnnotation(params Parameters, txt string) line {
+	runes := replaceControlCharacters([]rune(txt))
+	return toLine(s.faceToIndex, s.shapeText(params.PxPerEm/2, params.Locale, params.Font, runes, nil), params.Locale.Direction)
+}
+
+// inRange reports whether the rune at index i is within r.
+func (r Range) inRange(i int) bool {
+	return i >= r.Offset && i < r.Offset+r.Count
+}
+
+// runAdvance returns the advance of g along its run, for updating.
+func runAdvance(out *shaping.Output, g *shaping.Glyph) *fixed.Int26_6 {
+	if out.Direction.IsVertical() {
+		return &g.YAdvance
+	}
+	return &g.XAdvance
+}
+
+// padRuby spaces out the clusters of base text shorter than its
+// annotation, and records the space added after each of them in layouts.
+func padRuby(outs []shaping.Output, ruby []rubyRange, layouts []rubyLayout) {
+	for i, r := range ruby {
+		var width fixed.Int26_6
+		clusters := 0
+		for j := range outs {
+			out := &outs[j]
+			for k := range out.Glyphs {
+				g := &out.Glyphs[k]
+				if !r.inRange(g.ClusterIndex) {
+					continue
+				}
+				width += *runAdvance(out, g)
+				if k == 0 || out.Glyphs[k-1].ClusterIndex != g.ClusterIndex {
+					clusters++
+				}
+			}
+		}
+		extra := layouts[i].line.width - width
+		if extra <= 0 || clusters == 0 {
+			continue
+		}
+		pad := extra / fixed.Int26_6(clusters)
+		layouts[i].pad = pad
+		for j := range outs {
+			out := &outs[j]
+			for k := range out.Glyphs {
+				g := &out.Glyphs[k]
+				if !r.inRange(g.ClusterIndex) {
+					continue
+				}
+				if k == len(out.Glyphs)-1 || out.Glyphs[k+1].ClusterIndex != g.ClusterIndex {
+					*runAdvance(out, g) += pad
+					out.Advance += pad
+				}
+			}
+		}
+	}
+}
+
+// joinRuby prepares the text for the line breaker such that lines are not
+// broken within the base text of ruby. It replaces all but the first rune
+// of each base with combining marks, which neither line breaking nor
+// grapheme clustering separates from the rune before them.
+func joinRuby(txt []rune, ruby []rubyRange) []rune {
+	for _, r := range ruby {
+		for i := r.Offset + 1; i < r.Offset+r.Count && i < len(txt); i++ {
+			txt[i] = '\u0300'
+		}
+	}
+	return txt
+}
+
+// placeRuby centres the shaped annotations of ruby over their base text in
+// lines, and makes room for them in the height of the lines.
+func placeRuby(lines []line, ruby []rubyRange, layouts []rubyLayout) {
+	for i := range lines {
+		ln := &lines[i]
+		var height fixed.Int26_6
+		for j, r := range ruby {
+			start, end, ok := ln.spaceRuby(r.Range, layouts[j].pad/2)
+			if !ok {
+				continue
+			}
+			ann := &layouts[j].line
+			rise := ln.ascent + ann.descent
+			along := start + (end-start-ann.width)/2
+			for _, k := range ann.visualOrder {
+				run := ann.runs[k]
+				x := along + run.X
+				for _, g := range run.Glyphs {
+					ln.ruby = append(ln.ruby, rubyGlyph{
+						glyph:    g,
+						along:    x,
+						rise:     rise,
+						ascent:   ann.ascent,
+						descent:  ann.descent,
+						sideways: run.sideways,
+					})
+					x += g.xAdvance
+				}
+			}
+			if h := ann.ascent + ann.descent; h > height {
+				height = h
+			}
+		}
+		ln.ascent += height
+		ln.lineHeight += height
+	}
+}
+
+// spaceRuby moves the glyphs of the runes in rng by shift along the line,
+// centring them in the space padRuby added after them. It returns the
+// extent of the glyphs along the line, and reports whether the line
+// contains any of them.
+func (l *line) spaceRuby(rng Range, shift fixed.Int26_6) (start, end fixed.Int26_6, ok bool) {
+	vertical := l.vertical()
+	for i := range l.runs {
+		run := &l.runs[i]
+		if run.truncator {
+			continue
+		}
+		x := run.X
+		for j := range run.Glyphs {
+			g := &run.Glyphs[j]
+			if rng.inRange(g.clusterIndex) {
+				if !ok || x < start {
+					start = x
+				}
+				if !ok || x+g.xAdvance > end {
+					end = x + g.xAdvance
+				}
+				ok = true
+				// Glyphs are drawn at their dot less their offset.
+				if vertical {
+					g.yOffset -= shift
+					g.bounds.Min.Y += shift
+					g.bounds.Max.Y += shift
+				} else {
+					g.xOffset -= shift
+					g.bounds.Min.X += shift
+					g.bounds.Max.X += shift
+				}
+			}
+			x += g.xAdvance
+		}
+	}
+	return start, end, ok
+}
@@ -59,6 +59,10 @@ type Parameters struct {
 	// MinWidth and MaxWidth provide the minimum and maximum horizontal space constraints
 	// for the shaped text.
 	MinWidth, MaxWidth int
+	// MinHeight and MaxHeight provide the minimum and maximum vertical space
+	// constraints for text with a vertical Locale direction, whose lines are
+	// columns wrapped to MaxHeight. They are ignored for horizontal text.
+	MinHeight, MaxHeight int
 	// Locale provides primary direction and language information for the shaped text.
 	Locale system.Locale
 
@@ -72,6 +76,13 @@ type Parameters struct {
 	// should set LineHeightScale to 1.
 	LineHeight fixed.Int26_6
 
+	// LetterSpacing is the space added after each grapheme cluster of the text, and
+	// WordSpacing the space added after word separators such as spaces, in addition to
+	// LetterSpacing. Negative values tighten the text.
+	LetterSpacing, WordSpacing fixed.Int26_6
+	// TabStops are the positions that tab characters advance the text to.
+	TabStops TabStops
+
 	// forceTruncate controls whether the truncator string is inserted on the final line of
 	// text with a MaxLines. It is unexported because this behavior only makes sense for the
 	// shaper to control when it iterates paragraphs of text.
@@ -92,6 +103,11 @@ type FontFace = giofont.FontFace
 // "Document coordinates" are pixel values relative to the text's origin at (0,0)
 // in the upper-left corner" Displaying each shaped glyph at the document
 // coordinates of its dot will correctly visualize the text.
+//
+// Glyphs of vertical text have FlagVertical set. Their lines are columns
+// laid out from right to left, and their dot is on the center line of the
+// column: Advance is measured along the Y axis, and Ascent and Descent are
+// the extents of the column to the right and left of the dot.
 type Glyph struct {
 	// ID is a unique, per-shaper identifier for the shape of the glyph.
 	// Glyphs from the same shaper will share an ID when they are from
@@ -155,6 +171,15 @@ const (
 	// FlagTruncator and FlagClusterBreak will have a Runes field accounting for all
 	// runes truncated.
 	FlagTruncator
+	// FlagVertical is set for glyphs in vertical lines of text.
+	FlagVertical
+	// FlagSideways is set for glyphs of vertical text that are shaped
+	// horizontally and rotated a quarter turn clockwise, such as Latin
+	// letters within Japanese.
+	FlagSideways
+	// FlagAnnotation is set for glyphs of ruby annotations. They follow the
+	// glyphs of the line containing their base text and represent no runes.
+	FlagAnnotation
 )
 
 func (f Flags) String() string {
@@ -194,11 +219,49 @@ func (f Flags) String() string {
 	} else {
 		b.WriteString("_")
 	}
+	if f&FlagVertical != 0 {
+		b.WriteString("V")
+	} else {
+		b.WriteString("_")
+	}
+	if f&FlagSideways != 0 {
+		b.WriteString("↻")
+	} else {
+		b.WriteString("_")
+	}
+	if f&FlagAnnotation != 0 {
+		b.WriteString("A")
+	} else {
+		b.WriteString("_")
+	}
 	return b.String()
 }
 
 type GlyphID uint64
 
+// Ruby is a run of base text with an optional annotation, such as the
+// reading of Japanese kanji in small kana (furigana). Annotations are set
+// at half the size of the base text, centred above it, or to its right in
+// vertical text. Base text shorter than its annotation is spaced out to
+// match, and lines are never broken within it. Annotations of base text
+// containing line breaks are ignored.
+type Ruby struct {
+	Base       string
+	Annotation string
+}
+
+// Span is a run of text with its own font and size, such as a bold word or
+// a smaller footnote within a paragraph.
+type Span struct {
+	Text string
+	// Font is the font of the span. Its zero fields take the values of the
+	// Font of the Parameters.
+	Font giofont.Font
+	// PxPerEm is the size of the span, or zero for the PxPerEm of the
+	// Parameters.
+	PxPerEm fixed.Int26_6
+}
+
 // Shaper converts strings of text into glyphs that can be displayed.
 type Shaper struct {
 	config struct {
@@ -213,6 +276,9 @@ type Shaper struct {
 
 	reader    *bufio.Reader
 	paragraph []byte
+	// markup holds the ruby annotations and spans of the text being laid
+	// out, and paragraphMarkup those of its current paragraph.
+	markup, paragraphMarkup markup
 
 	// Iterator state.
 	brokeParagraph   bool
@@ -221,6 +287,9 @@ type Shaper struct {
 	line             int
 	run              int
 	glyph            int
+	// annotation is the index of the next ruby glyph of the current line,
+	// returned after the glyphs of its runs.
+	annotation int
 	// advance is the width of glyphs from the current run that have already been displayed.
 	advance fixed.Int26_6
 	// done tracks whether iteration is over.
@@ -281,8 +350,78 @@ func (l *Shaper) LayoutString(params Parameters, str string) {
 	l.layoutText(params, nil, str)
 }
 
+// LayoutRuby is LayoutString for text with ruby annotations, given as
+// consecutive runs of base text. The glyphs of annotations have
+// FlagAnnotation set.
+func (l *Shaper) LayoutRuby(params Parameters, txt []Ruby) {
+	l.init()
+	var b strings.Builder
+	runes := 0
+	for _, r := range txt {
+		n := utf8.RuneCountInString(r.Base)
+		if r.Annotation != "" && n > 0 && !strings.ContainsRune(r.Base, '\n') {
+			l.markup.ruby = append(l.markup.ruby, rubyRange{
+				Range:      Range{Offset: runes, Count: n},
+				annotation: r.Annotation,
+			})
+		}
+		b.WriteString(r.Base)
+		runes += n
+	}
+	l.layoutText(params, nil, b.String())
+	l.markup.ruby = l.markup.ruby[:0]
+}
+
+// LayoutSpans is LayoutString for text in spans of different fonts and
+// sizes, wrapped as a single paragraph.
+func (l *Shaper) LayoutSpans(params Parameters, txt []Span) {
+	l.init()
+	var b strings.Builder
+	runes := 0
+	for _, s := range txt {
+		n := utf8.RuneCountInString(s.Text)
+		b.WriteString(s.Text)
+		r := spanRange{
+			Range: Range{Offset: runes, Count: n},
+			font:  s.Font,
+			ppem:  s.PxPerEm,
+		}
+		runes += n
+		if r.font.Typeface == "" {
+			r.font.Typeface = params.Font.Typeface
+		}
+		if r.font.Style == giofont.Regular {
+			r.font.Style = params.Font.Style
+		}
+		if r.font.Weight == giofont.Normal {
+			r.font.Weight = params.Font.Weight
+		}
+		if r.font.Features == "" {
+			r.font.Features = params.Font.Features
+		}
+		if r.font.Variations == "" {
+			r.font.Variations = params.Font.Variations
+		}
+		if r.ppem == 0 {
+			r.ppem = params.PxPerEm
+		}
+		if n == 0 {
+			continue
+		}
+		// Shape adjacent spans of the same style together.
+		if k := len(l.markup.spans) - 1; k >= 0 && l.markup.spans[k].font == r.font && l.markup.spans[k].ppem == r.ppem {
+			l.markup.spans[k].Count += n
+			continue
+		}
+		l.markup.spans = append(l.markup.spans, r)
+	}
+	l.layoutText(params, nil, b.String())
+	l.markup.spans = l.markup.spans[:0]
+}
+
 func (l *Shaper) reset(align Alignment) {
 	l.line, l.run, l.glyph, l.advance = 0, 0, 0, 0
+	l.annotation = 0
 	l.done = false
 	l.txt.reset()
 	l.txt.alignment = align
@@ -294,13 +433,16 @@ func (l *Shaper) reset(align Alignment) {
 func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 	l.reset(params.Alignment)
 	if txt == nil && len(str) == 0 {
-		l.txt.append(l.layoutParagraph(params, "", nil))
+		l.txt.append(l.layoutParagraph(params, "", nil, markup{}))
 		return
 	}
 	l.reader.Reset(txt)
 	truncating := params.MaxLines > 0
 	var done bool
 	var endByte int
+	// runeOffset is the position of the paragraph in the text, tracked for
+	// its markup.
+	var runeOffset int
 	for !done {
 		l.paragraph = l.paragraph[:0]
 		if txt != nil {
@@ -335,7 +477,7 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 		}
 		if len(str[:endByte]) > 0 || (len(l.paragraph) > 0 || len(l.txt.lines) == 0) {
 			params.forceTruncate = truncating && !done
-			lines := l.layoutParagraph(params, str[:endByte], l.paragraph)
+			lines := l.layoutParagraph(params, str[:endByte], l.paragraph, l.markupParagraph(runeOffset, str[:endByte]))
 			if truncating {
 				params.MaxLines -= len(lines.lines)
 				if params.MaxLines == 0 {
@@ -362,25 +504,61 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 		if done {
 			return
 		}
+		if !l.markup.empty() {
+			runeOffset += utf8.RuneCountInString(str[:endByte])
+		}
 		str = str[endByte:]
 	}
 }
 
+// markupParagraph returns the markup of the paragraph at rune offset off
+// of the text, relative to the paragraph.
+func (l *Shaper) markupParagraph(off int, paragraph string) markup {
+	if l.markup.empty() {
+		return markup{}
+	}
+	end := off + utf8.RuneCountInString(paragraph)
+	m := &l.paragraphMarkup
+	m.ruby, m.spans = m.ruby[:0], m.spans[:0]
+	for _, r := range l.markup.ruby {
+		if r.Offset >= off && r.Offset+r.Count <= end {
+			r.Offset -= off
+			m.ruby = append(m.ruby, r)
+		}
+	}
+	for _, s := range l.markup.spans {
+		start, stop := s.Offset, s.Offset+s.Count
+		if stop <= off || start >= end {
+			continue
+		}
+		start, stop = max(start, off), min(stop, end)
+		s.Range = Range{Offset: start - off, Count: stop - start}
+		m.spans = append(m.spans, s)
+	}
+	return *m
+}
+
 // layoutParagraph shapes and wraps a paragraph using the provided parameters.
 // It accepts the paragraph data in either string or rune format, preferring the
 // string in order to hit the shaper cache more quickly.
-func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte) document {
+func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte, m markup) document {
 	if l == nil {
 		return document{}
 	}
 	if len(asStr) == 0 && len(asBytes) > 0 {
 		asStr = string(asBytes)
 	}
+	if params.Locale.Direction.Axis() == system.Horizontal {
+		// Don't miss the cache for height changes that don't affect the layout.
+		params.MinHeight, params.MaxHeight = 0, 0
+	}
 	// Alignment is not part of the cache key because changing it does not impact shaping.
 	lk := layoutKey{
 		ppem:            params.PxPerEm,
 		maxWidth:        params.MaxWidth,
 		minWidth:        params.MinWidth,
+		maxHeight:       params.MaxHeight,
+		minHeight:       params.MinHeight,
 		maxLines:        params.MaxLines,
 		truncator:       params.Truncator,
 		locale:          params.Locale,
@@ -388,13 +566,19 @@ func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte
 		forceTruncate:   params.forceTruncate,
 		wrapPolicy:      params.WrapPolicy,
 		str:             asStr,
+		markup:          m.key(),
 		lineHeight:      params.LineHeight,
 		lineHeightScale: params.LineHeightScale,
+		justify:         params.Alignment == Justify,
+		letterSpacing:   params.LetterSpacing,
+		wordSpacing:     params.WordSpacing,
+		tabInterval:     params.TabStops.Interval,
+		tabStops:        params.TabStops.key(),
 	}
 	if l, ok := l.layoutCache.Get(lk); ok {
 		return l
 	}
-	lines := l.shaper.LayoutRunes(params, []rune(asStr))
+	lines := l.shaper.layoutMarkup(params, []rune(asStr), m)
 	l.layoutCache.Put(lk, lines)
 	return lines
 }
@@ -419,8 +603,13 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		}
 		line := l.txt.lines[l.line]
 		if l.run == len(line.runs) {
+			if l.annotation < len(line.ruby) {
+				l.annotation++
+				return l.annotationGlyph(line, line.ruby[l.annotation-1]), true
+			}
 			l.line++
 			l.run = 0
+			l.annotation = 0
 			continue
 		}
 		run := line.runs[l.run]
@@ -430,14 +619,19 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 			// entire text is a shaped empty string. Return a single synthetic
 			// glyph to provide ascent/descent information to the caller.
 			l.done = true
-			return Glyph{
-				X:       align,
-				Y:       int32(line.yOffset),
+			x, y := l.txt.dot(line, align)
+			g := Glyph{
+				X:       x,
+				Y:       y,
 				Runes:   0,
 				Flags:   FlagLineBreak | FlagClusterBreak | FlagRunBreak,
 				Ascent:  line.ascent,
 				Descent: line.descent,
-			}, true
+			}
+			if line.vertical() {
+				g.Flags |= FlagVertical
+			}
+			return g, true
 		}
 		if l.glyph == len(run.Glyphs) {
 			l.run++
@@ -462,10 +656,11 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		if rtl {
 			runOffset = run.Advance - l.advance
 		}
+		x, y := l.txt.dot(line, align+run.X+runOffset)
 		glyph := Glyph{
 			ID:      g.id,
-			X:       align + run.X + runOffset,
-			Y:       int32(line.yOffset),
+			X:       x,
+			Y:       y,
 			Ascent:  line.ascent,
 			Descent: line.descent,
 			Advance: g.xAdvance,
@@ -479,6 +674,12 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		if run.truncator {
 			glyph.Flags |= FlagTruncator
 		}
+		if line.vertical() {
+			glyph.Flags |= FlagVertical
+			if run.sideways {
+				glyph.Flags |= FlagSideways
+			}
+		}
 		l.glyph++
 		if !rtl {
 			l.advance += g.xAdvance
@@ -530,14 +731,52 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 				// at the end of the text. We must inform widgets like the text editor
 				// of a valid cursor position they can use for "after" such a newline,
 				// taking text alignment into account.
-				l.pararagraphStart.X = l.txt.alignment.Align(line.direction, 0, l.txt.alignWidth)
-				l.pararagraphStart.Y = glyph.Y + int32((glyph.Ascent + glyph.Descent).Ceil())
+				start := l.txt.alignment.Align(line.direction, 0, l.txt.alignWidth)
+				lineHeight := (glyph.Ascent + glyph.Descent).Ceil()
+				if line.vertical() {
+					l.pararagraphStart.Flags |= FlagVertical
+					l.pararagraphStart.X = glyph.X - fixed.I(lineHeight)
+					l.pararagraphStart.Y = int32(start.Round())
+				} else {
+					l.pararagraphStart.X = start
+					l.pararagraphStart.Y = glyph.Y + int32(lineHeight)
+				}
 			}
 		}
 		return glyph, true
 	}
 }
 
+// annotationGlyph converts a glyph of the ruby annotations of ln.
+func (l *Shaper) annotationGlyph(ln line, g rubyGlyph) Glyph {
+	align := l.txt.alignment.Align(ln.direction, ln.width, l.txt.alignWidth)
+	x, y := l.txt.dot(ln, align+g.along)
+	glyph := Glyph{
+		ID:      g.id,
+		X:       x,
+		Y:       y,
+		Ascent:  g.ascent,
+		Descent: g.descent,
+		Advance: g.xAdvance,
+		Offset: fixed.Point26_6{
+			X: g.xOffset,
+			Y: g.yOffset,
+		},
+		Bounds: g.bounds,
+		Flags:  FlagAnnotation,
+	}
+	if ln.vertical() {
+		glyph.X += g.rise
+		glyph.Flags |= FlagVertical
+		if g.sideways {
+			glyph.Flags |= FlagSideways
+		}
+	} else {
+		glyph.Y -= int32(g.rise.Round())
+	}
+	return glyph
+}
+
 const (
 	facebits = 16
 	sizebits = 16
@@ -571,7 +810,8 @@ func splitGlyphID(g GlyphID) (fixed.Int26_6, int, font.GID) {
 
 // Shape converts the provided glyphs into a path. The path will enclose the forms
 // of all vector glyphs.
-// All glyphs are expected to be from a single line of text (their Y offsets are ignored).
+// All glyphs are expected to be from a single line of text, and are positioned
+// relative to the dot of the first glyph.
 func (l *Shaper) Shape(gs []Glyph) clip.PathSpec {
 	l.init()
 	key := l.pathCache.hashGlyphs(gs)
@@ -588,7 +828,8 @@ func (l *Shaper) Shape(gs []Glyph) clip.PathSpec {
 // Bitmaps extracts bitmap glyphs from the provided slice and creates an op.CallOp to present
 // them. The returned op.CallOp will align correctly with the return value of Shape() for the
 // same gs slice.
-// All glyphs are expected to be from a single line of text (their Y offsets are ignored).
+// All glyphs are expected to be from a single line of text, and are positioned
+// relative to the dot of the first glyph.
 func (l *Shaper) Bitmaps(gs []Glyph) op.CallOp {
 	l.init()
 	key := l.bitmapShapeCache.hashGlyphs(gs)
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	nsareg "eliasnaur.com/font/noto/sans/arabic/regular"
+	nsjpreg "eliasnaur.com/font/noto/sans/jp/regular"
 	"github.com/kanryu/mado/font"
 	"github.com/kanryu/mado/font/gofont"
 	"github.com/kanryu/mado/font/opentype"
@@ -581,3 +582,335 @@ func TestShapeStringRuneAccounting(t *testing.T) {
 		})
 	}
 }
+
+// TestVerticalLayout checks that vertical text is set in columns from right
+// to left, wrapped by MaxHeight, with Latin letters turned sideways.
+func TestVerticalLayout(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	shaper.LayoutString(Parameters{
+		PxPerEm:   fixed.I(20),
+		MaxWidth:  200,
+		MaxHeight: 50,
+		Locale:    system.Locale{Direction: system.TTB},
+	}, "×ab××\nc")
+	var glyphs []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		glyphs = append(glyphs, g)
+	}
+	var columns []fixed.Int26_6
+	for i, g := range glyphs {
+		checkFlag(t, true, FlagVertical, g, i)
+		if len(columns) == 0 || columns[len(columns)-1] != g.X {
+			columns = append(columns, g.X)
+		}
+		if g.Ascent <= 0 || g.Descent <= 0 {
+			t.Errorf("glyph %d: expected columns to extend on both sides, got ascent %v descent %v", i, g.Ascent, g.Descent)
+		}
+		if g.Flags&FlagParagraphBreak != 0 {
+			continue
+		}
+		if g.Y < 0 || int(g.Y)+g.Advance.Ceil() > 50 {
+			t.Errorf("glyph %d: y %d and advance %v overflow the column height", i, g.Y, g.Advance)
+		}
+		if g.Advance <= 0 {
+			t.Errorf("glyph %d: expected a positive advance down the column, got %v", i, g.Advance)
+		}
+	}
+	if len(columns) < 3 {
+		t.Fatalf("expected at least 3 columns, got %v", columns)
+	}
+	for i := 1; i < len(columns); i++ {
+		if columns[i] >= columns[i-1] {
+			t.Errorf("expected columns from right to left, got %v", columns)
+		}
+	}
+	// The multiplication sign is upright, the Latin letters sideways.
+	checkFlag(t, false, FlagSideways, glyphs[0], 0)
+	checkFlag(t, true, FlagSideways, glyphs[1], 1)
+	checkFlag(t, true, FlagSideways, glyphs[2], 2)
+	if b := glyphs[0].Bounds; b.Min.X >= 0 || b.Max.X <= 0 {
+		t.Errorf("expected upright glyph centered on the column, got bounds %v", b)
+	}
+	if glyphs[1].Y <= glyphs[0].Y {
+		t.Errorf("expected glyphs to advance down the column")
+	}
+}
+
+// TestLayoutRuby checks the placement of ruby annotations over their base
+// text, and that lines are neither broken within the base nor crowded by
+// the annotations.
+func TestLayoutRuby(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	params := Parameters{
+		PxPerEm:  fixed.I(20),
+		MaxWidth: 80,
+		Locale:   english,
+	}
+	collect := func() (base, annotations []Glyph) {
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			if g.Flags&FlagAnnotation != 0 {
+				annotations = append(annotations, g)
+			} else {
+				base = append(base, g)
+			}
+		}
+		return base, annotations
+	}
+
+	shaper.LayoutString(params, "ab hello world")
+	plain, _ := collect()
+	shaper.LayoutRuby(params, []Ruby{
+		{Base: "ab", Annotation: "annotation"},
+		{Base: " "},
+		{Base: "hello world", Annotation: "x"},
+	})
+	base, annotations := collect()
+	if len(annotations) != len("annotation")+len("x") {
+		t.Fatalf("expected %d annotation glyphs, got %d", len("annotation")+len("x"), len(annotations))
+	}
+	for i, g := range annotations {
+		if g.Runes != 0 {
+			t.Errorf("annotation glyph %d represents %d runes", i, g.Runes)
+		}
+	}
+
+	// The annotation is centred over, and as wide as, its spaced out base.
+	ann := annotations[:len("annotation")]
+	annStart, annEnd := ann[0].X, ann[len(ann)-1].X+ann[len(ann)-1].Advance
+	baseStart, baseEnd := base[0].X, base[1].X+base[1].Advance
+	if baseEnd-baseStart < annEnd-annStart {
+		t.Errorf("base [%v, %v] is shorter than its annotation [%v, %v]", baseStart, baseEnd, annStart, annEnd)
+	}
+	if mid, annMid := (baseStart+baseEnd)/2, (annStart+annEnd)/2; fixedAbs(mid-annMid) > 2 {
+		t.Errorf("annotation centred at %v, base at %v", annMid, mid)
+	}
+	if fixed.I(int(ann[0].Y))+ann[0].Descent > fixed.I(int(base[0].Y))-plain[0].Ascent {
+		t.Errorf("annotation baseline %d collides with base text at %d", ann[0].Y, base[0].Y)
+	}
+	if base[0].Y <= plain[0].Y {
+		t.Errorf("expected the first baseline to move down for the annotation, got %d, was %d", base[0].Y, plain[0].Y)
+	}
+
+	// "hello world" is wider than MaxWidth, but must not be broken.
+	hello, world := len("ab "), len("ab hello wor")
+	if plain[hello].Y == plain[world].Y {
+		t.Errorf("expected the plain text to break between hello and world")
+	}
+	if base[hello].Y != base[world].Y {
+		t.Errorf("expected the annotated text not to break between hello and world")
+	}
+	x := annotations[len(annotations)-1]
+	if x.Y >= base[hello].Y || x.Y <= base[0].Y {
+		t.Errorf("expected the annotation of the second line between the lines, got %d", x.Y)
+	}
+
+	// Vertical annotations are set to the right of their column.
+	params.MaxHeight = 200
+	params.Locale = system.Locale{Direction: system.TTB}
+	shaper.LayoutRuby(params, []Ruby{{Base: "×", Annotation: "××"}})
+	base, annotations = collect()
+	if len(annotations) != 2 {
+		t.Fatalf("expected 2 annotation glyphs, got %d", len(annotations))
+	}
+	for i, g := range annotations {
+		checkFlag(t, true, FlagVertical, g, i)
+		// The column of the base is an em wide.
+		if g.X-g.Descent < base[0].X+params.PxPerEm/2 {
+			t.Errorf("annotation glyph %d at %v overlaps its base at %v", i, g.X, base[0].X)
+		}
+	}
+}
+
+// TestLayoutSpans checks that spans are shaped with their own font and
+// size, and wrapped together.
+func TestLayoutSpans(t *testing.T) {
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	params := Parameters{
+		PxPerEm:  fixed.I(10),
+		MaxWidth: 50,
+		Locale:   english,
+	}
+	shaper.LayoutSpans(params, []Span{
+		{Text: "aa "},
+		{Text: "bb bb", PxPerEm: fixed.I(20)},
+		{Text: " cc", Font: font.Font{Weight: font.Bold}},
+	})
+	var glyphs []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		glyphs = append(glyphs, g)
+	}
+	if len(glyphs) != len("aa bb bb cc") {
+		t.Fatalf("expected %d glyphs, got %d", len("aa bb bb cc"), len(glyphs))
+	}
+	ppem := func(g Glyph) fixed.Int26_6 {
+		ppem, _, _ := splitGlyphID(g.ID)
+		return ppem
+	}
+	face := func(g Glyph) int {
+		_, face, _ := splitGlyphID(g.ID)
+		return face
+	}
+	if got := ppem(glyphs[0]); got != fixed.I(10) {
+		t.Errorf("expected the first span at 10px, got %v", got)
+	}
+	if got := ppem(glyphs[3]); got != fixed.I(20) {
+		t.Errorf("expected the second span at 20px, got %v", got)
+	}
+	if face(glyphs[10]) == face(glyphs[0]) {
+		t.Errorf("expected the third span in a bold face")
+	}
+	// The first line holds text of both sizes, on a common baseline tall
+	// enough for the larger.
+	if glyphs[0].Y != glyphs[3].Y {
+		t.Errorf("expected the first two spans to share a line, got baselines %d and %d", glyphs[0].Y, glyphs[3].Y)
+	}
+	if glyphs[0].Ascent < fixed.I(18) {
+		t.Errorf("expected the line to fit the larger span, got ascent %v", glyphs[0].Ascent)
+	}
+	// The second span is broken across lines.
+	if glyphs[6].Y == glyphs[3].Y || glyphs[6].X != 0 {
+		t.Errorf("expected the second span to wrap, got its last word at (%v, %d)", glyphs[6].X, glyphs[6].Y)
+	}
+}
+
+// TestLayoutFeatures checks that the OpenType features and variations of
+// the font are applied and distinguished in the layout cache.
+func TestLayoutFeatures(t *testing.T) {
+	jpFace, err := opentype.Parse(nsjpreg.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: jpFace}}))
+	layout := func(f font.Font) (width fixed.Int26_6, glyphs []Glyph) {
+		shaper.LayoutString(Parameters{
+			PxPerEm:  fixed.I(20),
+			MaxWidth: 1000,
+			Locale:   english,
+			Font:     f,
+		}, "「日本」")
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			width += g.Advance
+			glyphs = append(glyphs, g)
+		}
+		return width, glyphs
+	}
+	plain, plainGlyphs := layout(font.Font{})
+	palt, _ := layout(font.Font{Features: "palt"})
+	if palt >= plain {
+		t.Errorf("expected proportional widths to tighten the brackets, got width %v, %v without", palt, plain)
+	}
+	if off, _ := layout(font.Font{Features: "palt off"}); off != plain {
+		t.Errorf("expected disabled features to leave the width %v, got %v", plain, off)
+	}
+	// The face has no variation axes, and is used unchanged.
+	_, varied := layout(font.Font{Variations: "wght 700"})
+	for i := range varied {
+		if varied[i].ID != plainGlyphs[i].ID {
+			t.Errorf("glyph %d: expected id %v of the plain face, got %v", i, plainGlyphs[i].ID, varied[i].ID)
+		}
+	}
+}
+
+// TestLayoutSpacing checks letter and word spacing, tab stops and
+// justification.
+func TestLayoutSpacing(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	layout := func(params Parameters, txt string) []Glyph {
+		if params.PxPerEm == 0 {
+			params.PxPerEm = fixed.I(10)
+		}
+		if params.MaxWidth == 0 {
+			params.MaxWidth = 1000
+		}
+		params.Locale = english
+		shaper.LayoutString(params, txt)
+		var glyphs []Glyph
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			glyphs = append(glyphs, g)
+		}
+		return glyphs
+	}
+	plain := layout(Parameters{}, "ab c")
+	spaced := layout(Parameters{LetterSpacing: fixed.I(2), WordSpacing: fixed.I(5)}, "ab c")
+	for i, extra := range []fixed.Int26_6{fixed.I(2), fixed.I(2), fixed.I(7), fixed.I(2)} {
+		if got := spaced[i].Advance - plain[i].Advance; got != extra {
+			t.Errorf("glyph %d: expected %v of spacing, got %v", i, extra, got)
+		}
+	}
+
+	space := plain[2].Advance
+	if g := layout(Parameters{}, "a\tb"); g[2].X != 8*space {
+		t.Errorf("expected the default tab stop at 8 spaces (%v), got %v", 8*space, g[2].X)
+	}
+	stops := TabStops{Stops: []fixed.Int26_6{fixed.I(30), fixed.I(45)}, Interval: fixed.I(20)}
+	g := layout(Parameters{TabStops: stops}, "a\tbb\tc\td")
+	for i, x := range map[int]fixed.Int26_6{2: fixed.I(30), 5: fixed.I(45), 7: fixed.I(60)} {
+		if g[i].X != x {
+			t.Errorf("glyph %d: expected a tab stop at %v, got %v", i, x, g[i].X)
+		}
+	}
+	// Tab stops are measured from the start of wrapped lines.
+	g = layout(Parameters{MaxWidth: 50, TabStops: stops}, "aaaaaaaa b\tc")
+	if g[9].Y == g[0].Y || g[11].X != fixed.I(30) {
+		t.Errorf("expected a tab stop at 30 on the second line, got %v", g[11].X)
+	}
+
+	// Wrapped lines of more than one word fill the width; final lines and
+	// trailing spaces do not.
+	g = layout(Parameters{MaxWidth: 50, Alignment: Justify}, "aaa bb c dddd eeeee f")
+	if end := g[7].X + g[7].Advance; end != fixed.I(50) {
+		t.Errorf("expected the first line to end at 50, got %v", end)
+	}
+	if g[8].Advance != 0 {
+		t.Errorf("expected no width for the trailing space, got %v", g[8].Advance)
+	}
+	if g[3].Advance-g[6].Advance > 1 || g[6].Advance-g[3].Advance > 1 {
+		t.Errorf("expected the spaces of the line to widen evenly, got %v and %v", g[3].Advance, g[6].Advance)
+	}
+	if last := g[len(g)-1]; last.X+last.Advance >= fixed.I(50) {
+		t.Errorf("expected the final line to keep its width, got %v", last.X+last.Advance)
+	}
+	// Text before the last tab of a justified line keeps its position.
+	g = layout(Parameters{MaxWidth: 50, Alignment: Justify, TabStops: stops}, "a b\tc d e ffff")
+	if g[1].Advance != space || g[4].X != fixed.I(30) {
+		t.Errorf("expected text before the tab unchanged, got space %v and tab stop %v", g[1].Advance, g[4].X)
+	}
+	if end := g[6].X + g[6].Advance; end != fixed.I(50) || g[7].Y != g[6].Y {
+		t.Errorf("expected the first line to end at 50, got %v", end)
+	}
+
+	// Tabs of wrapped lines do not widen them past the maximum width.
+	g = layout(Parameters{MaxWidth: 50, TabStops: stops}, "aaaaaa bb\tccccc")
+	for i := range g {
+		if end := g[i].X + g[i].Advance; end > fixed.I(50) {
+			t.Errorf("glyph %d: expected to end within 50, got %v", i, end)
+		}
+	}
+	if end := g[9].X + g[9].Advance; end != fixed.I(30) || g[10].Y == g[9].Y {
+		t.Errorf("expected the tab stop at 30 and the text after it on the next line, got %v", end)
+	}
+
+	// CJK text is justified between its characters.
+	jpFace, _ := opentype.Parse(nsjpreg.TTF)
+	shaper = NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: jpFace}}))
+	g = layout(Parameters{MaxWidth: 45, Alignment: Justify}, "日本語の文章を両端揃えにする")
+	lines := 0
+	for i := range g {
+		if i == len(g)-1 || g[i+1].Y != g[i].Y {
+			lines++
+			end := g[i].X + g[i].Advance
+			if i < len(g)-1 && end != fixed.I(45) {
+				t.Errorf("line %d: expected to end at 45, got %v", lines, end)
+			}
+			if i == len(g)-1 && end >= fixed.I(45) {
+				t.Errorf("expected the final line to keep its width, got %v", end)
+			}
+		}
+	}
+	if lines < 2 {
+		t.Errorf("expected the text to wrap, got %d lines", lines)
+	}
+}
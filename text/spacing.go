@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"sort"
+	"strconv"
+	"unicode"
+
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/kanryu/mado/io/system"
+)
+
+// TabStops are the positions, measured from the start of lines, that tab
+// characters advance the text to.
+type TabStops struct {
+	// Stops are explicit positions of tab stops, in increasing order.
+	Stops []fixed.Int26_6
+	// Interval is the distance between the tab stops past the last of
+	// Stops, which are at multiples of Interval. If zero, it is the width
+	// of eight spaces.
+	Interval fixed.Int26_6
+}
+
+// next returns the position of the first tab stop after x. space is the
+// width of a space, for the default interval.
+func (t TabStops) next(x, space fixed.Int26_6) fixed.Int26_6 {
+	for _, s := range t.Stops {
+		if s > x {
+			return s
+		}
+	}
+	interval := t.Interval
+	if interval <= 0 {
+		interval = 8 * space
+	}
+	if interval <= 0 {
+		return x
+	}
+	return (x/interval + 1) * interval
+}
+
+// key encodes the stops for comparison in a layoutKey.
+func (t TabStops) key() string {
+	var b []byte
+	for _, s := range t.Stops {
+		b = strconv.AppendInt(b, int64(s), 10)
+		b = append(b, ',')
+	}
+	return string(b)
+}
+
+// tab is a tab character of a paragraph, shaped as a space.
+type tab struct {
+	// index is the index of the rune of the tab.
+	index int
+	// space is the width of the space the tab was shaped as.
+	space fixed.Int26_6
+}
+
+// findTabs appends the tabs of txt to tabs.
+func findTabs(tabs []tab, txt []rune) []tab {
+	for i, r := range txt {
+		if r == '\t' {
+			tabs = append(tabs, tab{index: i})
+		}
+	}
+	return tabs
+}
+
+// tabAt returns the index of the tab of the rune at index i, or -1 if the
+// rune is not a tab.
+func tabAt(tabs []tab, i int) int {
+	t := sort.Search(len(tabs), func(t int) bool { return tabs[t].index >= i })
+	if t < len(tabs) && tabs[t].index == i {
+		return t
+	}
+	return -1
+}
+
+// isWordSeparator reports whether r separates words, and so receives word
+// spacing and justification.
+func isWordSeparator(r rune) bool {
+	switch r {
+	case ' ', '\u00a0', '\u1361', '\U00010100', '\U00010101', '\U0001039f', '\U0001091f':
+		return true
+	}
+	return false
+}
+
+// isCJK reports whether r is a character of Chinese, Japanese or Korean
+// writing which is justified between characters rather than words.
+func isCJK(r rune) bool {
+	switch {
+	case r >= '\u3000' && r <= '\u30ff':
+		// Punctuation, Hiragana and Katakana.
+	case r >= '\uff01' && r <= '\uff60':
+		// Fullwidth forms.
+	case unicode.In(r, unicode.Han, unicode.Bopomofo):
+	default:
+		return false
+	}
+	return true
+}
+
+// spaceText adds letter and word spacing after the clusters of outs.
+func spaceText(outs []shaping.Output, txt []rune, tabs []tab, letter, word fixed.Int26_6) {
+	for i := range outs {
+		out := &outs[i]
+		for k := range out.Glyphs {
+			g := &out.Glyphs[k]
+			if k < len(out.Glyphs)-1 && out.Glyphs[k+1].ClusterIndex == g.ClusterIndex {
+				continue
+			}
+			space := letter
+			if c := g.ClusterIndex; c < len(txt) && isWordSeparator(txt[c]) && tabAt(tabs, c) == -1 {
+				space += word
+			}
+			*runAdvance(out, g) += space
+			out.Advance += space
+		}
+	}
+}
+
+// setTabs advances the tabs of outs to their tab stops, measured from the
+// start of the text and from starts, the sorted indices of the runes that
+// start lines. The first call, with no starts, records the width of the
+// space each tab was shaped as.
+func setTabs(outs []shaping.Output, tabs []tab, stops TabStops, starts []int) {
+	var x fixed.Int26_6
+	for i := range outs {
+		out := &outs[i]
+		for k := range out.Glyphs {
+			g := &out.Glyphs[k]
+			for len(starts) > 0 && g.ClusterIndex >= starts[0] {
+				x = 0
+				starts = starts[1:]
+			}
+			adv := runAdvance(out, g)
+			if t := tabAt(tabs, g.ClusterIndex); t != -1 {
+				if starts == nil {
+					tabs[t].space = *adv
+				}
+				next := stops.next(x, tabs[t].space) - x
+				out.Advance += next - *adv
+				*adv = next
+			}
+			x += *adv
+		}
+	}
+}
+
+// lineStarts appends the indices of the runes that start the lines after
+// the first to starts.
+func lineStarts(starts []int, lines []shaping.Line) []int {
+	for _, l := range lines[1:] {
+		start := -1
+		for _, run := range l {
+			if start == -1 || run.Runes.Offset < start {
+				start = run.Runes.Offset
+			}
+		}
+		if start != -1 {
+			starts = append(starts, start)
+		}
+	}
+	return starts
+}
+
+// setTabs advances the tabs of the line to their tab stops. Tabs that
+// would widen the line past maxWidth take the remaining width only.
+func (l *line) setTabs(tabs []tab, stops TabStops, maxWidth fixed.Int26_6) {
+	backward := l.direction.Progression() == system.TowardOrigin
+	// avail is the width left for the tabs not yet set.
+	avail := maxWidth - l.width
+	for i := range l.runs {
+		run := &l.runs[i]
+		for _, g := range run.Glyphs {
+			if tabAt(tabs, g.clusterIndex) != -1 && !run.truncator {
+				avail += g.xAdvance
+			}
+		}
+	}
+	var x fixed.Int26_6
+	for i := range l.visualOrder {
+		if backward {
+			i = len(l.visualOrder) - 1 - i
+		}
+		run := &l.runs[l.visualOrder[i]]
+		for j := range run.Glyphs {
+			if backward {
+				j = len(run.Glyphs) - 1 - j
+			}
+			g := &run.Glyphs[j]
+			if t := tabAt(tabs, g.clusterIndex); t != -1 && !run.truncator {
+				next := stops.next(x, tabs[t].space) - x
+				if next > avail {
+					next = avail
+				}
+				if next < 0 {
+					next = 0
+				}
+				avail -= next
+				run.Advance += next - g.xAdvance
+				l.width += next - g.xAdvance
+				g.xAdvance = next
+			}
+			x += g.xAdvance
+		}
+	}
+	l.positionRuns()
+}
+
+// justify widens the spaces between the words of the line, and around its
+// CJK characters, such that the line fills width. Text before the last tab
+// of the line keeps its position, and spaces at the end of the line take no
+// width.
+func (l *line) justify(txt []rune, tabs []tab, width fixed.Int26_6) {
+	type cluster struct {
+		index int
+		g     *glyph
+		run   *runLayout
+	}
+	var clusters []cluster
+	for i := range l.runs {
+		run := &l.runs[i]
+		if run.truncator {
+			continue
+		}
+		for j := range run.Glyphs {
+			g := &run.Glyphs[j]
+			if j < len(run.Glyphs)-1 && run.Glyphs[j+1].clusterIndex == g.clusterIndex {
+				continue
+			}
+			if g.clusterIndex < len(txt) {
+				clusters = append(clusters, cluster{index: g.clusterIndex, g: g, run: run})
+			}
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].index < clusters[j].index })
+	end := len(clusters)
+	for ; end > 0 && isWordSeparator(txt[clusters[end-1].index]); end-- {
+		c := clusters[end-1]
+		c.run.Advance -= c.g.xAdvance
+		l.width -= c.g.xAdvance
+		c.g.xAdvance = 0
+	}
+	start := 0
+	for i := 0; i < end; i++ {
+		if tabAt(tabs, clusters[i].index) != -1 {
+			start = i + 1
+		}
+	}
+	var opportunities []cluster
+	for i := start; i < end-1; i++ {
+		a, b := txt[clusters[i].index], txt[clusters[i+1].index]
+		if isWordSeparator(a) || isCJK(a) || isCJK(b) {
+			opportunities = append(opportunities, clusters[i])
+		}
+	}
+	if extra := width - l.width; extra > 0 && len(opportunities) > 0 {
+		n := fixed.Int26_6(len(opportunities))
+		share, rem := extra/n, extra%n
+		for i, c := range opportunities {
+			space := share
+			if fixed.Int26_6(i) < rem {
+				space++
+			}
+			c.g.xAdvance += space
+			c.run.Advance += space
+		}
+		l.width += extra
+	}
+	l.positionRuns()
+}
+
+// positionRuns updates the offsets of the runs of the line from their
+// advances.
+func (l *line) positionRuns() {
+	var x fixed.Int26_6
+	for _, i := range l.visualOrder {
+		l.runs[i].X = x
+		x += l.runs[i].Advance
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"strconv"
+
+	"github.com/go-text/typesetting/shaping"
+	giofont "github.com/kanryu/mado/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// spanRange is a range of runes shaped with their own font and size.
+type spanRange struct {
+	Range
+	font giofont.Font
+	ppem fixed.Int26_6
+}
+
+// markup is the ruby annotations and spans of a text, in the order of
+// their runes.
+type markup struct {
+	ruby  []rubyRange
+	spans []spanRange
+}
+
+func (m markup) empty() bool {
+	return len(m.ruby) == 0 && len(m.spans) == 0
+}
+
+// key encodes the markup for comparison in a layoutKey.
+func (m markup) key() string {
+	if m.empty() {
+		return ""
+	}
+	var b []byte
+	appendRange := func(tag byte, r Range) {
+		b = append(b, tag)
+		b = strconv.AppendInt(b, int64(r.Offset), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(r.Count), 10)
+		b = append(b, ',')
+	}
+	appendString := func(s string) {
+		b = strconv.AppendInt(b, int64(len(s)), 10)
+		b = append(b, ':')
+		b = append(b, s...)
+	}
+	for _, r := range m.ruby {
+		appendRange('r', r.Range)
+		appendString(r.annotation)
+	}
+	for _, s := range m.spans {
+		appendRange('s', s.Range)
+		b = strconv.AppendInt(b, int64(s.ppem), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(s.font.Style), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(s.font.Weight), 10)
+		b = append(b, ',')
+		appendString(string(s.font.Typeface))
+		appendString(string(s.font.Features))
+		appendString(string(s.font.Variations))
+	}
+	return string(b)
+}
+
+// spanAt returns the index of the span containing the rune at index i, or
+// -1 if there is none.
+func spanAt(spans []spanRange, i int) int {
+	for j, s := range spans {
+		if s.inRange(i) {
+			return j
+		}
+	}
+	return -1
+}
+
+// splitBySpans divides the inputs on the boundaries of spans, and sizes
+// them for their span. It will use buf as the backing memory for the
+// returned slice if buf is non-nil.
+func splitBySpans(inputs []shaping.Input, spans []spanRange, buf []shaping.Input) []shaping.Input {
+	var split []shaping.Input
+	if buf == nil {
+		split = make([]shaping.Input, 0, len(inputs))
+	} else {
+		split = buf
+	}
+	for _, input := range inputs {
+		if input.RunStart == input.RunEnd {
+			split = append(split, input)
+			continue
+		}
+		for _, s := range spans {
+			start, end := max(input.RunStart, s.Offset), min(input.RunEnd, s.Offset+s.Count)
+			if start >= end {
+				continue
+			}
+			in := input
+			in.RunStart, in.RunEnd = start, end
+			in.Size = s.ppem
+			split = append(split, in)
+		}
+	}
+	return split
+}
@@ -15,6 +15,10 @@ const (
 	Start Alignment = iota
 	End
 	Middle
+	// Justify aligns wrapped lines to both their start and end by widening
+	// the spaces between words, and between the characters of CJK text.
+	// The final line of a paragraph is aligned to its start.
+	Justify
 )
 
 func (a Alignment) String() string {
@@ -25,6 +29,8 @@ func (a Alignment) String() string {
 		return "End"
 	case Middle:
 		return "Middle"
+	case Justify:
+		return "Justify"
 	default:
 		panic("invalid Alignment")
 	}
@@ -35,6 +41,10 @@ func (a Alignment) String() string {
 // text direction dir.
 func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth int) fixed.Int26_6 {
 	mw := fixed.I(maxWidth)
+	if a == Justify {
+		// Justified lines fill the width; the others align to the start.
+		a = Start
+	}
 	if dir.Progression() == system.TowardOrigin {
 		switch a {
 		case Start:
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// uprightRunes are the runes set upright in vertical text, after the
+// Vertical_Orientation property of Unicode Standard Annex #50. Runes that
+// the annex transforms for vertical use (Tu and Tr), such as ideographic
+// punctuation and brackets, are included: shaping them vertically
+// substitutes their vertical forms from the font.
+var uprightRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a7, Hi: 0x00a7, Stride: 1},
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x00b1, Hi: 0x00b1, Stride: 1},
+		{Lo: 0x00bc, Hi: 0x00be, Stride: 1},
+		{Lo: 0x00d7, Hi: 0x00d7, Stride: 1},
+		{Lo: 0x00f7, Hi: 0x00f7, Stride: 1},
+		{Lo: 0x02ea, Hi: 0x02eb, Stride: 1},
+		{Lo: 0x1100, Hi: 0x11ff, Stride: 1},
+		{Lo: 0x1401, Hi: 0x167f, Stride: 1},
+		{Lo: 0x18b0, Hi: 0x18ff, Stride: 1},
+		{Lo: 0x2016, Hi: 0x2016, Stride: 1},
+		{Lo: 0x2020, Hi: 0x2021, Stride: 1},
+		{Lo: 0x2025, Hi: 0x2026, Stride: 1},
+		{Lo: 0x2030, Hi: 0x2031, Stride: 1},
+		{Lo: 0x203b, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2042, Hi: 0x2042, Stride: 1},
+		{Lo: 0x2047, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2051, Hi: 0x2051, Stride: 1},
+		{Lo: 0x20dd, Hi: 0x20e0, Stride: 1},
+		{Lo: 0x20e2, Hi: 0x20e4, Stride: 1},
+		{Lo: 0x2100, Hi: 0x2101, Stride: 1},
+		{Lo: 0x2103, Hi: 0x2109, Stride: 1},
+		{Lo: 0x210f, Hi: 0x210f, Stride: 1},
+		{Lo: 0x2113, Hi: 0x2114, Stride: 1},
+		{Lo: 0x2116, Hi: 0x2117, Stride: 1},
+		{Lo: 0x211e, Hi: 0x2123, Stride: 1},
+		{Lo: 0x2125, Hi: 0x2125, Stride: 1},
+		{Lo: 0x2127, Hi: 0x2127, Stride: 1},
+		{Lo: 0x2129, Hi: 0x2129, Stride: 1},
+		{Lo: 0x212e, Hi: 0x212e, Stride: 1},
+		{Lo: 0x2135, Hi: 0x213f, Stride: 1},
+		{Lo: 0x2145, Hi: 0x214a, Stride: 1},
+		{Lo: 0x214c, Hi: 0x214d, Stride: 1},
+		{Lo: 0x214f, Hi: 0x2189, Stride: 1},
+		{Lo: 0x221e, Hi: 0x221e, Stride: 1},
+		{Lo: 0x2234, Hi: 0x2235, Stride: 1},
+		{Lo: 0x2300, Hi: 0x2307, Stride: 1},
+		{Lo: 0x230c, Hi: 0x231f, Stride: 1},
+		{Lo: 0x2324, Hi: 0x2328, Stride: 1},
+		{Lo: 0x232b, Hi: 0x232b, Stride: 1},
+		{Lo: 0x237d, Hi: 0x239a, Stride: 1},
+		{Lo: 0x23be, Hi: 0x23cd, Stride: 1},
+		{Lo: 0x23cf, Hi: 0x23cf, Stride: 1},
+		{Lo: 0x23d1, Hi: 0x23db, Stride: 1},
+		{Lo: 0x23e2, Hi: 0x24ff, Stride: 1},
+		{Lo: 0x25a0, Hi: 0x2619, Stride: 1},
+		{Lo: 0x2620, Hi: 0x2767, Stride: 1},
+		{Lo: 0x2776, Hi: 0x2793, Stride: 1},
+		{Lo: 0x2b12, Hi: 0x2b2f, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b59, Stride: 1},
+		{Lo: 0x2bb8, Hi: 0x2bff, Stride: 1},
+		// CJK radicals and symbols, kana, Bopomofo, ideographs and Yi.
+		{Lo: 0x2e80, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		// Hangul syllables.
+		{Lo: 0xac00, Hi: 0xd7ff, Stride: 1},
+		// Private use and CJK compatibility ideographs.
+		{Lo: 0xe000, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe10, Hi: 0xfe1f, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1},
+		// Fullwidth and halfwidth forms.
+		{Lo: 0xff01, Hi: 0xffef, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x18aff, Stride: 1},
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1},
+		// Game symbols, enclosed alphanumerics and emoji.
+		{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+		// Supplementary ideographs.
+		{Lo: 0x20000, Hi: 0x3fffd, Stride: 1},
+	},
+	LatinOffset: 7,
+}
+
+// inheritsOrientation reports whether r takes the orientation of the
+// rune before it.
+func inheritsOrientation(r rune) bool {
+	return r == '\u200d' || unicode.In(r, unicode.Mn, unicode.Me, unicode.Variation_Selector)
+}
+
+// orientationDirection returns the shaping direction for runs of vertical
+// text.
+func orientationDirection(upright bool) di.Direction {
+	if upright {
+		return di.DirectionTTB
+	}
+	return di.DirectionLTR
+}
+
+// splitByOrientation divides the inputs of vertical text into runs of
+// upright text, shaped vertically, and runs of sideways text, shaped
+// horizontally. It will use buf as the backing memory for the returned
+// slice if buf is non-nil.
+func splitByOrientation(inputs []shaping.Input, buf []shaping.Input) []shaping.Input {
+	var split []shaping.Input
+	if buf == nil {
+		split = make([]shaping.Input, 0, len(inputs))
+	} else {
+		split = buf
+	}
+	for _, input := range inputs {
+		if input.RunStart == input.RunEnd {
+			input.Direction = di.DirectionTTB
+			split = append(split, input)
+			continue
+		}
+		current := input
+		upright := unicode.Is(uprightRunes, input.Text[input.RunStart])
+		for i := input.RunStart + 1; i < input.RunEnd; i++ {
+			r := input.Text[i]
+			if inheritsOrientation(r) {
+				continue
+			}
+			if u := unicode.Is(uprightRunes, r); u != upright {
+				current.RunEnd = i
+				current.Direction = orientationDirection(upright)
+				split = append(split, current)
+				current = input
+				current.RunStart = i
+				upright = u
+			}
+		}
+		current.RunEnd = input.RunEnd
+		current.Direction = orientationDirection(upright)
+		split = append(split, current)
+	}
+	return split
+}
+
+// fixVerticalOutput adjusts vertically shaped text. It negates the
+// advances, which the shaper measures upwards, so that they grow down the
+// column. It also rescales the vertical glyph origins, which the shaper
+// subtracts from the glyph offsets in font units instead of pixels.
+func fixVerticalOutput(out *shaping.Output) {
+	upem := int64(out.Face.Upem())
+	// The shaper scales glyphs to whole pixels per em.
+	ppem := int64(fixed.I(out.Size.Ceil()))
+	for i := range out.Glyphs {
+		g := &out.Glyphs[i]
+		g.YAdvance = -g.YAdvance
+		if x, y, ok := out.Face.GlyphVOrigin(g.GlyphID); ok {
+			g.XOffset += fixed.Int26_6(x) - fixed.Int26_6(int64(x)*ppem/upem)
+			g.YOffset += fixed.Int26_6(y) - fixed.Int26_6(int64(y)*ppem/upem)
+		}
+	}
+	out.Advance = -out.Advance
+}
+
+// toVerticalGlyphs converts the glyphs of a run in a vertical line. Upright
+// glyphs hang from a dot on the center line of the column. Sideways glyphs
+// are turned a quarter clockwise, with their line centered on the column.
+// The advances of both run down the column.
+func toVerticalGlyphs(run shaping.Output, faceIdx int) []glyph {
+	out := make([]glyph, 0, len(run.Glyphs))
+	sideways := !run.Direction.IsVertical()
+	// shift is the distance from the center of the column to the baseline
+	// of sideways glyphs.
+	shift := (-run.LineBounds.Descent - run.LineBounds.Ascent) / 2
+	for _, g := range run.Glyphs {
+		// The bounds relative to the glyph origin, as in toGioGlyphs.
+		var bounds fixed.Rectangle26_6
+		bounds.Min.X = g.XBearing
+		bounds.Min.Y = -g.YBearing
+		bounds.Max = bounds.Min.Add(fixed.Point26_6{X: g.Width, Y: -g.Height})
+		// origin is the position of the glyph origin relative to the dot.
+		origin := fixed.Point26_6{X: g.XOffset, Y: -g.YOffset}
+		advance := g.YAdvance
+		if sideways {
+			origin = fixed.Point26_6{X: shift + g.YOffset, Y: g.XOffset}
+			advance = g.XAdvance
+			bounds = fixed.Rectangle26_6{
+				Min: fixed.Point26_6{X: -bounds.Max.Y, Y: bounds.Min.X},
+				Max: fixed.Point26_6{X: -bounds.Min.Y, Y: bounds.Max.X},
+			}
+		}
+		out = append(out, glyph{
+			id:           newGlyphID(run.Size, faceIdx, g.GlyphID),
+			clusterIndex: g.ClusterIndex,
+			runeCount:    g.RuneCount,
+			glyphCount:   g.GlyphCount,
+			xAdvance:     advance,
+			// Glyphs are drawn at their dot less their offset.
+			xOffset: -origin.X,
+			yOffset: -origin.Y,
+			bounds:  bounds.Add(origin),
+		})
+	}
+	return out
+}
@@ -12,7 +12,7 @@ import (
 	"unsafe"
 
 	"github.com/kanryu/mado"
-	"github.com/kanryu/mado/unix/internal/egl"
+	"github.com/kanryu/mado/internal/egl"
 )
 
 /*
@@ -30,7 +30,7 @@ var _ mado.Context = (*wlContext)(nil)
 
 type wlContext struct {
 	win *window
-	*Context
+	*egl.Context
 	eglWin *C.struct_wl_egl_window
 }
 
@@ -42,7 +42,7 @@ func init() {
 			eglApi = egl.EGL_OPENGL_API
 			mado.GlfwConfig.WindowType = mado.WindowTypeWayland
 		}
-		ctx, err := NewContext(disp, eglApi)
+		ctx, err := egl.NewContext(disp, eglApi)
 		if err != nil {
 			return nil, err
 		}
@@ -11,14 +11,14 @@ import (
 	"unsafe"
 
 	"github.com/kanryu/mado"
-	"github.com/kanryu/mado/unix/internal/egl"
+	"github.com/kanryu/mado/internal/egl"
 )
 
 var _ mado.Context = (*x11Context)(nil)
 
 type x11Context struct {
 	win *x11Window
-	*Context
+	*egl.Context
 }
 
 type PlatformContextState struct{}
@@ -32,7 +32,7 @@ func init() {
 			eglApi = egl.EGL_OPENGL_API
 			mado.GlfwConfig.WindowType = mado.WindowTypeX11
 		}
-		ctx, err := NewContext(disp, eglApi)
+		ctx, err := egl.NewContext(disp, eglApi)
 		if err != nil {
 			return nil, err
 		}
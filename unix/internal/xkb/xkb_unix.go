@@ -37,6 +37,8 @@ type Context struct {
 	compTable *C.struct_xkb_compose_table
 	compState *C.struct_xkb_compose_state
 	utf8Buf   []byte
+	// LockKeyMods enables the caps lock and num lock modifiers.
+	LockKeyMods bool
 }
 
 var (
@@ -44,6 +46,8 @@ var (
 	_XKB_MOD_NAME_SHIFT = []byte("Shift\x00")
 	_XKB_MOD_NAME_ALT   = []byte("Mod1\x00")
 	_XKB_MOD_NAME_LOGO  = []byte("Mod4\x00")
+	_XKB_MOD_NAME_CAPS  = []byte("Lock\x00")
+	_XKB_MOD_NAME_NUM   = []byte("Mod2\x00")
 )
 
 func (x *Context) Destroy() {
@@ -152,6 +156,15 @@ func (x *Context) Modifiers() key.Modifiers {
 	if C.xkb_state_mod_name_is_active(x.state, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_LOGO[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
 		mods |= key.ModSuper
 	}
+	if !x.LockKeyMods {
+		return mods
+	}
+	if C.xkb_state_mod_name_is_active(x.state, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_CAPS[0])), C.XKB_STATE_MODS_LOCKED) == 1 {
+		mods |= key.ModCapsLock
+	}
+	if C.xkb_state_mod_name_is_active(x.state, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_NUM[0])), C.XKB_STATE_MODS_LOCKED) == 1 {
+		mods |= key.ModNumLock
+	}
 	return mods
 }
 
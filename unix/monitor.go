@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package unix
+
+import (
+	"sync"
+
+	"github.com/kanryu/mado"
+)
+
+// monitorDriver starts tracking the monitors of a display server. It
+// calls update with the initial list of monitors before returning, and
+// again from a background goroutine whenever the list changes.
+type monitorDriver func(update func([]mado.Monitor)) error
+
+// Like wlDriver and x11Driver, each driver initializes its own
+// monitorDriver.
+var wlMonitorDriver, x11MonitorDriver monitorDriver
+
+var monitors struct {
+	once     sync.Once
+	mu       sync.Mutex
+	list     []mado.Monitor
+	callback func(mado.MonitorEvent)
+}
+
+func startMonitors() {
+	for _, d := range []monitorDriver{wlMonitorDriver, x11MonitorDriver} {
+		if d != nil && d(updateMonitors) == nil {
+			return
+		}
+	}
+}
+
+func updateMonitors(list []mado.Monitor) {
+	monitors.mu.Lock()
+	old := monitors.list
+	monitors.list = list
+	callback := monitors.callback
+	monitors.mu.Unlock()
+	if callback == nil {
+		return
+	}
+	for _, e := range mado.DiffMonitors(old, list) {
+		callback(e)
+	}
+}
+
+func getMonitors() []mado.Monitor {
+	monitors.once.Do(startMonitors)
+	monitors.mu.Lock()
+	defer monitors.mu.Unlock()
+	return append([]mado.Monitor(nil), monitors.list...)
+}
+
+func setMonitorCallback(f func(mado.MonitorEvent)) {
+	monitors.once.Do(startMonitors)
+	monitors.mu.Lock()
+	defer monitors.mu.Unlock()
+	monitors.callback = f
+}
+
+func containsVideoMode(modes []mado.VideoMode, m mado.VideoMode) bool {
+	for _, vm := range modes {
+		if vm == m {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,8 @@ package unix
 
 import (
 	"errors"
+	"image"
+	"sync"
 	"unsafe"
 
 	"github.com/kanryu/mado"
@@ -46,6 +48,9 @@ func InitUnix() {
 	mado.PollEvents = PollEvents
 	mado.GetTimerValue = GetTimerValue
 	mado.GetTimerFrequency = GetTimerFrequency
+	mado.GetMonitors = getMonitors
+	mado.SetMonitorCallback = setMonitorCallback
+	mado.RawMouseMotionSupported = rawMouseMotionSupported
 }
 
 func osMain() {
@@ -95,6 +100,34 @@ func newWindow(window mado.Callbacks, options []mado.Option) error {
 	return errors.New("app: no window driver available")
 }
 
+// rawMotionDriver reports whether the display server supports raw mouse
+// motion. It returns an error if the display server is not available.
+type rawMotionDriver func() (bool, error)
+
+// Like wlDriver and x11Driver, each driver initializes its own
+// rawMotionDriver.
+var wlRawMotionDriver, x11RawMotionDriver rawMotionDriver
+
+var rawMotion struct {
+	once      sync.Once
+	supported bool
+}
+
+func rawMouseMotionSupported() bool {
+	rawMotion.once.Do(func() {
+		for _, d := range []rawMotionDriver{wlRawMotionDriver, x11RawMotionDriver} {
+			if d == nil {
+				continue
+			}
+			if ok, err := d(); err == nil {
+				rawMotion.supported = ok
+				return
+			}
+		}
+	})
+	return rawMotion.supported
+}
+
 // xCursor contains mapping from pointer.Cursor to XCursor.
 var xCursor = [...]string{
 	pointer.CursorDefault:                  "left_ptr",
@@ -125,6 +158,45 @@ var xCursor = [...]string{
 	pointer.CursorNorthWestSouthEastResize: "bd_double_arrow",
 }
 
+// cursorARGB converts the image of a custom cursor to premultiplied
+// ARGB pixels, the format of both Xcursor images and ARGB8888 wl_shm
+// buffers.
+func cursorARGB(c *pointer.CustomCursor) []uint32 {
+	return nrgbaARGB(c.Image, true)
+}
+
+// nrgbaARGB converts img to packed ARGB pixels, optionally premultiplied
+// by alpha.
+func nrgbaARGB(img *image.NRGBA, premultiply bool) []uint32 {
+	size := img.Rect.Size()
+	pix := make([]uint32, 0, size.X*size.Y)
+	for y := 0; y < size.Y; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+size.X*4]
+		for x := 0; x < len(row); x += 4 {
+			r, g, b, a := uint32(row[x]), uint32(row[x+1]), uint32(row[x+2]), uint32(row[x+3])
+			if premultiply {
+				r, g, b = r*a/0xff, g*a/0xff, b*a/0xff
+			}
+			pix = append(pix, a<<24|r<<16|g<<8|b)
+		}
+	}
+	return pix
+}
+
+// iconChanged reports whether the window icon images differ. Icon images
+// are copied by app.Icon, so comparing their identity suffices.
+func iconChanged(a, b []*image.NRGBA) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return true
+		}
+	}
+	return false
+}
+
 func GetTimerValue() uint64 {
 	return getTime()
 }
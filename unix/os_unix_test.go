@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package unix
+
+import (
+	"io"
+	"testing"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/transfer"
+)
+
+// eventRecorder is window callbacks recording the events of the window.
+type eventRecorder struct {
+	mado.Callbacks
+	events []event.Event
+}
+
+func (r *eventRecorder) Event(e event.Event) bool {
+	r.events = append(r.events, e)
+	return true
+}
+
+// checkEmptyRead checks that the events are a single empty response to
+// a clipboard read of mime.
+func checkEmptyRead(t *testing.T, events []event.Event, mime string, primary bool) {
+	t.Helper()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want a single DataEvent", len(events))
+	}
+	e, ok := events[0].(transfer.DataEvent)
+	if !ok {
+		t.Fatalf("got %T, want a DataEvent", events[0])
+	}
+	if e.Type != mime || e.Primary != primary {
+		t.Errorf("got a DataEvent of %q, primary %v, want %q, primary %v", e.Type, e.Primary, mime, primary)
+	}
+	r := e.Open()
+	defer r.Close()
+	if data, _ := io.ReadAll(r); len(data) != 0 {
+		t.Errorf("got %q, want empty content", data)
+	}
+}
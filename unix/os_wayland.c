@@ -8,6 +8,10 @@
 #include "wayland_xdg_shell.h"
 #include "wayland_xdg_decoration.h"
 #include "wayland_text_input.h"
+#include "wayland_relative_pointer.h"
+#include "wayland_pointer_constraints.h"
+#include "wayland_xdg_output.h"
+#include "wayland_xdg_activation.h"
 #include "_cgo_export.h"
 
 const struct wl_registry_listener gio_registry_listener = {
@@ -30,6 +34,11 @@ const struct xdg_toplevel_listener gio_xdg_toplevel_listener = {
 	.close = gio_onToplevelClose,
 };
 
+const struct xdg_popup_listener gio_xdg_popup_listener = {
+	.configure = gio_onPopupConfigure,
+	.popup_done = gio_onPopupDone,
+};
+
 const struct zxdg_toplevel_decoration_v1_listener gio_zxdg_toplevel_decoration_v1_listener = {
 	.configure = gio_onToplevelDecorationConfigure,
 };
@@ -46,6 +55,10 @@ const struct wl_callback_listener gio_callback_listener = {
 	.done = gio_onFrameDone,
 };
 
+const struct wl_buffer_listener gio_buffer_listener = {
+	.release = gio_onBufferRelease,
+};
+
 const struct wl_output_listener gio_output_listener = {
 	// Cast away const parameter.
 	.geometry = (void (*)(void *, struct wl_output *, int32_t,  int32_t,  int32_t,  int32_t,  int32_t,  const char *, const char *, int32_t))gio_onOutputGeometry,
@@ -122,3 +135,58 @@ const struct wl_data_source_listener gio_data_source_listener = {
 	.dnd_finished = gio_onDataSourceDNDFinished,
 	.action = gio_onDataSourceAction,
 };
+
+const struct wl_registry_listener gio_monitor_registry_listener = {
+	// Cast away const parameter.
+	.global = (void (*)(void *, struct wl_registry *, uint32_t,  const char *, uint32_t))gio_onMonitorRegistryGlobal,
+	.global_remove = gio_onMonitorRegistryGlobalRemove
+};
+
+const struct wl_output_listener gio_monitor_output_listener = {
+	// Cast away const parameter.
+	.geometry = (void (*)(void *, struct wl_output *, int32_t,  int32_t,  int32_t,  int32_t,  int32_t,  const char *, const char *, int32_t))gio_onMonitorOutputGeometry,
+	.mode = gio_onMonitorOutputMode,
+	.done = gio_onMonitorOutputDone,
+	.scale = gio_onMonitorOutputScale,
+};
+
+const struct zxdg_output_v1_listener gio_monitor_xdg_output_listener = {
+	.logical_position = gio_onMonitorXdgOutputLogicalPosition,
+	.logical_size = gio_onMonitorXdgOutputLogicalSize,
+	.done = gio_onMonitorXdgOutputDone,
+	// Cast away const parameter.
+	.name = (void (*)(void *, struct zxdg_output_v1 *, const char *))gio_onMonitorXdgOutputName,
+	.description = (void (*)(void *, struct zxdg_output_v1 *, const char *))gio_onMonitorXdgOutputDescription,
+};
+
+const struct xdg_activation_token_v1_listener gio_xdg_activation_token_v1_listener = {
+	// Cast away const parameter.
+	.done = (void (*)(void *, struct xdg_activation_token_v1 *, const char *))gio_onActivationTokenDone,
+};
+
+static void gio_onProbeRegistryGlobalRemove(void *data, struct wl_registry *reg, uint32_t name) {}
+
+const struct wl_registry_listener gio_probe_registry_listener = {
+	// Cast away const parameter.
+	.global = (void (*)(void *, struct wl_registry *, uint32_t,  const char *, uint32_t))gio_onProbeRegistryGlobal,
+	.global_remove = gio_onProbeRegistryGlobalRemove,
+};
+
+const struct zwp_relative_pointer_v1_listener gio_zwp_relative_pointer_v1_listener = {
+	.relative_motion = gio_onRelativePointerMotion,
+};
+
+// The compositor suppresses wl_pointer motion while the pointer is locked,
+// so the lock and confinement states are not needed.
+static void gio_onLockedPointerEvent(void *data, struct zwp_locked_pointer_v1 *p) {}
+static void gio_onConfinedPointerEvent(void *data, struct zwp_confined_pointer_v1 *p) {}
+
+const struct zwp_locked_pointer_v1_listener gio_zwp_locked_pointer_v1_listener = {
+	.locked = gio_onLockedPointerEvent,
+	.unlocked = gio_onLockedPointerEvent,
+};
+
+const struct zwp_confined_pointer_v1_listener gio_zwp_confined_pointer_v1_listener = {
+	.confined = gio_onConfinedPointerEvent,
+	.unconfined = gio_onConfinedPointerEvent,
+};
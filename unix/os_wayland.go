@@ -15,9 +15,11 @@ import (
 	"math"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	syscall "golang.org/x/sys/unix"
@@ -25,6 +27,7 @@ import (
 	"github.com/kanryu/mado"
 	"github.com/kanryu/mado/f32"
 	"github.com/kanryu/mado/internal/fling"
+	"github.com/kanryu/mado/io/input"
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/io/system"
@@ -43,9 +46,29 @@ import (
 //go:generate wayland-scanner client-header /usr/share/wayland-protocols/unstable/xdg-decoration/xdg-decoration-unstable-v1.xml wayland_xdg_decoration.h
 //go:generate wayland-scanner private-code /usr/share/wayland-protocols/unstable/xdg-decoration/xdg-decoration-unstable-v1.xml wayland_xdg_decoration.c
 
+//go:generate wayland-scanner client-header /usr/share/wayland-protocols/unstable/relative-pointer/relative-pointer-unstable-v1.xml wayland_relative_pointer.h
+//go:generate wayland-scanner private-code /usr/share/wayland-protocols/unstable/relative-pointer/relative-pointer-unstable-v1.xml wayland_relative_pointer.c
+
+//go:generate wayland-scanner client-header /usr/share/wayland-protocols/unstable/pointer-constraints/pointer-constraints-unstable-v1.xml wayland_pointer_constraints.h
+//go:generate wayland-scanner private-code /usr/share/wayland-protocols/unstable/pointer-constraints/pointer-constraints-unstable-v1.xml wayland_pointer_constraints.c
+
+//go:generate wayland-scanner client-header /usr/share/wayland-protocols/unstable/xdg-output/xdg-output-unstable-v1.xml wayland_xdg_output.h
+//go:generate wayland-scanner private-code /usr/share/wayland-protocols/unstable/xdg-output/xdg-output-unstable-v1.xml wayland_xdg_output.c
+
+//go:generate wayland-scanner client-header /usr/share/wayland-protocols/staging/xdg-activation/xdg-activation-v1.xml wayland_xdg_activation.h
+//go:generate wayland-scanner private-code /usr/share/wayland-protocols/staging/xdg-activation/xdg-activation-v1.xml wayland_xdg_activation.c
+
+//go:generate wayland-scanner client-header /usr/share/wayland-protocols/staging/xdg-toplevel-icon/xdg-toplevel-icon-v1.xml wayland_xdg_toplevel_icon.h
+//go:generate wayland-scanner private-code /usr/share/wayland-protocols/staging/xdg-toplevel-icon/xdg-toplevel-icon-v1.xml wayland_xdg_toplevel_icon.c
+
 //go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_shell.c
 //go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_decoration.c
 //go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_text_input.c
+//go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_relative_pointer.c
+//go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_pointer_constraints.c
+//go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_output.c
+//go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_activation.c
+//go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_toplevel_icon.c
 
 /*
 #cgo linux pkg-config: wayland-client wayland-cursor
@@ -59,11 +82,16 @@ import (
 #include "wayland_text_input.h"
 #include "wayland_xdg_shell.h"
 #include "wayland_xdg_decoration.h"
+#include "wayland_relative_pointer.h"
+#include "wayland_pointer_constraints.h"
+#include "wayland_xdg_activation.h"
+#include "wayland_xdg_toplevel_icon.h"
 
 extern const struct wl_registry_listener gio_registry_listener;
 extern const struct wl_surface_listener gio_surface_listener;
 extern const struct xdg_surface_listener gio_xdg_surface_listener;
 extern const struct xdg_toplevel_listener gio_xdg_toplevel_listener;
+extern const struct xdg_popup_listener gio_xdg_popup_listener;
 extern const struct zxdg_toplevel_decoration_v1_listener gio_zxdg_toplevel_decoration_v1_listener;
 extern const struct xdg_wm_base_listener gio_xdg_wm_base_listener;
 extern const struct wl_callback_listener gio_callback_listener;
@@ -76,6 +104,7 @@ extern const struct zwp_text_input_v3_listener gio_zwp_text_input_v3_listener;
 extern const struct wl_data_device_listener gio_data_device_listener;
 extern const struct wl_data_offer_listener gio_data_offer_listener;
 extern const struct wl_data_source_listener gio_data_source_listener;
+extern const struct xdg_activation_token_v1_listener gio_xdg_activation_token_v1_listener;
 */
 import "C"
 
@@ -88,6 +117,10 @@ type wlDisplay struct {
 	shm               *C.struct_wl_shm
 	dataDeviceManager *C.struct_wl_data_device_manager
 	decor             *C.struct_zxdg_decoration_manager_v1
+	relPointer        *C.struct_zwp_relative_pointer_manager_v1
+	constraints       *C.struct_zwp_pointer_constraints_v1
+	activation        *C.struct_xdg_activation_v1
+	iconManager       *C.struct_xdg_toplevel_icon_manager_v1
 	seat              *wlSeat
 	xkb               *xkb.Context
 	outputMap         map[C.uint32_t]*C.struct_wl_output
@@ -99,6 +132,20 @@ type wlDisplay struct {
 	}
 
 	repeat repeatState
+
+	// windows are the open windows of the display, in creation order.
+	windows []*window
+	// funcs are the functions waiting to run on the event loop, guarded
+	// by wlApp.mu.
+	funcs []func()
+}
+
+// wlApp tracks the display shared by the Wayland windows of the
+// program. The display and its event loop live from the creation of the
+// first window until the last window is closed.
+var wlApp struct {
+	mu   sync.Mutex
+	disp *wlDisplay
 }
 
 type wlSeat struct {
@@ -108,10 +155,22 @@ type wlSeat struct {
 	pointer  *C.struct_wl_pointer
 	touch    *C.struct_wl_touch
 	keyboard *C.struct_wl_keyboard
-	im       *C.struct_zwp_text_input_v3
+
+	// Text input support.
+	im *C.struct_zwp_text_input_v3
+	// imFocus is the window that has text input focus.
+	imFocus *window
+	// imEnabled tracks whether im is enabled.
+	imEnabled bool
+	// imCommits counts the commit requests to im.
+	imCommits C.uint32_t
+	// imPending is the text input state waiting for the done event.
+	imPending textInputState
 
 	// The most recent input serial.
 	serial C.uint32_t
+	// pressSerial is the serial of the most recent button press.
+	pressSerial C.uint32_t
 
 	pointerFocus  *window
 	keyboardFocus *window
@@ -131,6 +190,19 @@ type wlSeat struct {
 	source *C.struct_wl_data_source
 	// content is the data belonging to source.
 	content []byte
+
+	// Drag and drop support.
+	drag wlDrag
+}
+
+// textInputState is the double-buffered state sent by the compositor
+// through zwp_text_input_v3 events.
+type textInputState struct {
+	preedit                string
+	cursorBegin, cursorEnd int
+	commit                 string
+	// deleteBefore and deleteAfter are lengths in bytes.
+	deleteBefore, deleteAfter int
 }
 
 type repeatState struct {
@@ -148,13 +220,17 @@ type repeatState struct {
 }
 
 type window struct {
-	w          mado.Callbacks
-	disp       *wlDisplay
-	seat       *wlSeat
-	surf       *C.struct_wl_surface
-	wmSurf     *C.struct_xdg_surface
-	topLvl     *C.struct_xdg_toplevel
-	decor      *C.struct_zxdg_toplevel_decoration_v1
+	w      mado.Callbacks
+	disp   *wlDisplay
+	seat   *wlSeat
+	surf   *C.struct_wl_surface
+	wmSurf *C.struct_xdg_surface
+	topLvl *C.struct_xdg_toplevel
+	// popup is the role of popup windows, which have no topLvl.
+	popup *C.struct_xdg_popup
+	decor *C.struct_zxdg_toplevel_decoration_v1
+	// parent is the window of a popup or transient window.
+	parent     *window
 	ppdp, ppsp float32
 	scroll     struct {
 		time  time.Duration
@@ -172,7 +248,11 @@ type window struct {
 		// such as border resizes and window moves. It
 		// is nil if the pointer is not in a system gesture
 		// area.
-		system  *C.struct_wl_cursor
+		system *C.struct_wl_cursor
+		// custom replaces cursor when set.
+		custom wlCustomCursor
+		// customs caches the cursors created by SetCustomCursor.
+		customs map[*pointer.CustomCursor]wlCustomCursor
 		surf    *C.struct_wl_surface
 		cursors struct {
 			pointer         *C.struct_wl_cursor
@@ -195,9 +275,12 @@ type window struct {
 		dir            f32.Point
 	}
 
-	stage             mado.Stage
-	dead              bool
-	lastFrameCallback *C.struct_wl_callback
+	stage mado.Stage
+	dead  bool
+	// dismissed tracks whether a popup was dismissed before closing,
+	// and grabbing whether it grabbed the input.
+	dismissed, grabbing bool
+	lastFrameCallback   *C.struct_wl_callback
 
 	animating bool
 	redraw    bool
@@ -211,6 +294,23 @@ type window struct {
 	inCompositor bool        // window is moving or being resized
 
 	clipReads chan transfer.DataEvent
+	// drops receives the data of drops from other clients.
+	drops chan wlDrop
+	// dragTypes are the MIME types of the drag source, if any.
+	dragTypes []string
+
+	textInput struct {
+		show bool
+		hint key.InputHint
+	}
+
+	input wlInput
+
+	// activationToken is the pending xdg_activation_v1 token request, if
+	// any.
+	activationToken *C.struct_xdg_activation_token_v1
+	// icon is the toplevel icon, if any.
+	icon wlIcon
 
 	wakeups chan struct{}
 }
@@ -251,20 +351,15 @@ func init() {
 }
 
 func newWLWindow(callbacks mado.Callbacks, options []mado.Option) error {
-	d, err := newWLDisplay()
-	if err != nil {
-		return err
-	}
-	w, err := d.createNativeWindow(options)
-	if err != nil {
-		d.destroy()
-		return err
-	}
-	w.w = callbacks
-	go func() {
-		defer d.destroy()
-		defer w.destroy()
-
+	errs := make(chan error, 1)
+	create := func(d *wlDisplay) {
+		w, err := d.createNativeWindow(options)
+		errs <- err
+		if err != nil {
+			return
+		}
+		w.w = callbacks
+		d.windows = append(d.windows, w)
 		w.w.SetDriver(w)
 
 		// Finish and commit setup from createNativeWindow.
@@ -275,11 +370,31 @@ func newWLWindow(callbacks mado.Callbacks, options []mado.Option) error {
 			Display: unsafe.Pointer(w.display()),
 			Surface: unsafe.Pointer(w.surf),
 		})
+	}
+	if err := wlRun(create); err != nil {
+		return err
+	}
+	return <-errs
+}
 
-		err := w.loop()
-		w.w.Event(WaylandViewEvent{})
-		w.w.Event(mado.DestroyEvent{Err: err})
-	}()
+// wlRun runs f on the event loop of the shared display, connecting to
+// the compositor if no windows are open.
+func wlRun(f func(d *wlDisplay)) error {
+	wlApp.mu.Lock()
+	defer wlApp.mu.Unlock()
+	d := wlApp.disp
+	if d == nil {
+		var err error
+		d, err = newWLDisplay()
+		if err != nil {
+			return err
+		}
+		wlApp.disp = d
+		go d.loop()
+	} else {
+		defer d.wakeup()
+	}
+	d.funcs = append(d.funcs, func() { f(d) })
 	return nil
 }
 
@@ -348,6 +463,15 @@ func (d *wlDisplay) createNativeWindow(options []mado.Option) (*window, error) {
 		}
 	}
 	ppdp := detectUIScale()
+	var cnf mado.Config
+	cnf.Apply(unit.Metric{PxPerDp: ppdp * float32(scale), PxPerSp: ppdp * float32(scale)}, options)
+	var parent *window
+	if cnf.Parent != nil {
+		parent = d.windowOf(cnf.Parent)
+		if parent == nil {
+			return nil, errors.New("wayland: parent window is not open")
+		}
+	}
 
 	w := &window{
 		disp:      d,
@@ -356,6 +480,8 @@ func (d *wlDisplay) createNativeWindow(options []mado.Option) (*window, error) {
 		ppsp:      ppdp,
 		wakeups:   make(chan struct{}, 1),
 		clipReads: make(chan transfer.DataEvent, 1),
+		drops:     make(chan wlDrop, 1),
+		parent:    parent,
 	}
 	w.surf = C.wl_compositor_create_surface(d.compositor)
 	if w.surf == nil {
@@ -369,16 +495,25 @@ func (d *wlDisplay) createNativeWindow(options []mado.Option) (*window, error) {
 		w.destroy()
 		return nil, errors.New("wayland: xdg_wm_base_get_xdg_surface failed")
 	}
-	w.topLvl = C.xdg_surface_get_toplevel(w.wmSurf)
-	if w.topLvl == nil {
-		w.destroy()
-		return nil, errors.New("wayland: xdg_surface_get_toplevel failed")
+	if cnf.Popup != mado.NoPopup && parent != nil {
+		if err := w.createPopup(cnf); err != nil {
+			w.destroy()
+			return nil, err
+		}
+	} else {
+		w.topLvl = C.xdg_surface_get_toplevel(w.wmSurf)
+		if w.topLvl == nil {
+			w.destroy()
+			return nil, errors.New("wayland: xdg_surface_get_toplevel failed")
+		}
+		id := C.CString(mado.ID)
+		defer C.free(unsafe.Pointer(id))
+		C.xdg_toplevel_set_app_id(w.topLvl, id)
+		if parent != nil {
+			C.xdg_toplevel_set_parent(w.topLvl, parent.toplevel().topLvl)
+		}
 	}
 
-	id := C.CString(mado.ID)
-	defer C.free(unsafe.Pointer(id))
-	C.xdg_toplevel_set_app_id(w.topLvl, id)
-
 	cursorTheme := C.CString(os.Getenv("XCURSOR_THEME"))
 	defer C.free(unsafe.Pointer(cursorTheme))
 	cursorSize := 32
@@ -406,12 +541,15 @@ func (d *wlDisplay) createNativeWindow(options []mado.Option) (*window, error) {
 		return nil, errors.New("wayland: wl_compositor_create_surface failed")
 	}
 	C.wl_surface_set_buffer_scale(w.cursor.surf, C.int32_t(w.scale))
-	C.xdg_wm_base_add_listener(d.wm, &C.gio_xdg_wm_base_listener, unsafe.Pointer(w.surf))
 	C.wl_surface_add_listener(w.surf, &C.gio_surface_listener, unsafe.Pointer(w.surf))
 	C.xdg_surface_add_listener(w.wmSurf, &C.gio_xdg_surface_listener, unsafe.Pointer(w.surf))
-	C.xdg_toplevel_add_listener(w.topLvl, &C.gio_xdg_toplevel_listener, unsafe.Pointer(w.surf))
+	if w.popup != nil {
+		C.xdg_popup_add_listener(w.popup, &C.gio_xdg_popup_listener, unsafe.Pointer(w.surf))
+	} else {
+		C.xdg_toplevel_add_listener(w.topLvl, &C.gio_xdg_toplevel_listener, unsafe.Pointer(w.surf))
+	}
 
-	if d.decor != nil {
+	if d.decor != nil && w.topLvl != nil {
 		w.decor = C.zxdg_decoration_manager_v1_get_toplevel_decoration(d.decor, w.topLvl)
 		C.zxdg_toplevel_decoration_v1_add_listener(w.decor, &C.gio_zxdg_toplevel_decoration_v1_listener, unsafe.Pointer(w.surf))
 
@@ -420,9 +558,91 @@ func (d *wlDisplay) createNativeWindow(options []mado.Option) (*window, error) {
 		}
 	}
 	w.updateOpaqueRegion()
+	if cnf.Popup == mado.PopupTooltip {
+		// Let the input through to the windows below.
+		reg := C.wl_compositor_create_region(d.compositor)
+		C.wl_surface_set_input_region(w.surf, reg)
+		C.wl_region_destroy(reg)
+	}
 	return w, nil
 }
 
+// createPopup gives the window the xdg_popup role, placed against the
+// anchor of its parent.
+func (w *window) createPopup(cnf mado.Config) error {
+	d, parent := w.disp, w.parent
+	pos := C.xdg_wm_base_create_positioner(d.wm)
+	if pos == nil {
+		return errors.New("wayland: xdg_wm_base_create_positioner failed")
+	}
+	defer C.xdg_positioner_destroy(pos)
+	size := cnf.Size.Div(w.scale)
+	anchor := image.Rectangle{
+		Min: cnf.Anchor.Min.Div(parent.scale),
+		Max: cnf.Anchor.Max.Div(parent.scale),
+	}
+	// The positioner rejects empty sizes.
+	size.X, size.Y = max(size.X, 1), max(size.Y, 1)
+	anchor.Max.X, anchor.Max.Y = max(anchor.Max.X, anchor.Min.X+1), max(anchor.Max.Y, anchor.Min.Y+1)
+	C.xdg_positioner_set_size(pos, C.int32_t(size.X), C.int32_t(size.Y))
+	C.xdg_positioner_set_anchor_rect(pos, C.int32_t(anchor.Min.X), C.int32_t(anchor.Min.Y), C.int32_t(anchor.Dx()), C.int32_t(anchor.Dy()))
+	C.xdg_positioner_set_anchor(pos, C.XDG_POSITIONER_ANCHOR_BOTTOM_LEFT)
+	C.xdg_positioner_set_gravity(pos, C.XDG_POSITIONER_GRAVITY_BOTTOM_RIGHT)
+	C.xdg_positioner_set_constraint_adjustment(pos, C.XDG_POSITIONER_CONSTRAINT_ADJUSTMENT_FLIP_Y|
+		C.XDG_POSITIONER_CONSTRAINT_ADJUSTMENT_SLIDE_X|C.XDG_POSITIONER_CONSTRAINT_ADJUSTMENT_SLIDE_Y)
+	w.popup = C.xdg_surface_get_popup(w.wmSurf, parent.wmSurf, pos)
+	if w.popup == nil {
+		return errors.New("wayland: xdg_surface_get_popup failed")
+	}
+	w.size = size
+	// The parent of a grabbing popup must be a toplevel or another
+	// grabbing popup.
+	if s := d.seat; s != nil && cnf.Popup == mado.PopupMenu && (parent.popup == nil || parent.grabbing) {
+		C.xdg_popup_grab(w.popup, s.seat, s.serial)
+		w.grabbing = true
+	}
+	return nil
+}
+
+// toplevel returns the toplevel window of a popup, or the window itself.
+func (w *window) toplevel() *window {
+	for w.popup != nil {
+		w = w.parent
+	}
+	return w
+}
+
+// windowOf returns the open window of the display for a Gio window.
+func (d *wlDisplay) windowOf(gw mado.Window) *window {
+	for _, w := range d.windows {
+		if !w.dead && w.w.GetWindow() == gw {
+			return w
+		}
+	}
+	return nil
+}
+
+// dismissPopups dismisses the popups that don't contain w, after a
+// press in w.
+func (d *wlDisplay) dismissPopups(w *window) {
+	for _, p := range d.windows {
+		if p.popup == nil {
+			continue
+		}
+		inside := false
+		for w2 := w; w2 != nil; w2 = w2.parent {
+			if w2 == p {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			p.dismissed = true
+			p.dead = true
+		}
+	}
+}
+
 func (w *window) loadCursors() {
 	w.cursor.cursors.pointer = w.loadCursor(pointer.CursorDefault)
 	w.cursor.cursors.resizeNorth = w.loadCursor(pointer.CursorNorthResize)
@@ -458,6 +678,16 @@ func callbackStore(k unsafe.Pointer, v interface{}) {
 	callbackMap.Store(k, v)
 }
 
+// forEachWindow calls f for every window of d.
+func (d *wlDisplay) forEachWindow(f func(w *window)) {
+	callbackMap.Range(func(k, v any) bool {
+		if w, ok := v.(*window); ok && w.disp == d {
+			f(w)
+		}
+		return true
+	})
+}
+
 func callbackLoad(k unsafe.Pointer) interface{} {
 	v, exists := callbackMap.Load(k)
 	if !exists {
@@ -476,7 +706,7 @@ func gio_onSeatCapabilities(data unsafe.Pointer, seat *C.struct_wl_seat, caps C.
 // content.
 func (s *wlSeat) flushOffers() {
 	for o := range s.offers {
-		if o == s.clipboard {
+		if o == s.clipboard || o == s.drag.offer {
 			continue
 		}
 		// We're only interested in clipboard offers.
@@ -504,7 +734,12 @@ func (s *wlSeat) destroy() {
 	if s.keyboard != nil {
 		C.wl_keyboard_release(s.keyboard)
 	}
+	if s.drag.source != nil {
+		C.wl_data_source_destroy(s.drag.source)
+		s.drag.source = nil
+	}
 	s.clipboard = nil
+	s.drag.offer = nil
 	s.flushOffers()
 	if s.dataDev != nil {
 		C.wl_data_device_release(s.dataDev)
@@ -516,17 +751,17 @@ func (s *wlSeat) destroy() {
 }
 
 func (s *wlSeat) updateCaps(caps C.uint32_t) {
-	if s.im == nil && s.disp.imm != nil {
-		s.im = C.zwp_text_input_manager_v3_get_text_input(s.disp.imm, s.seat)
-		C.zwp_text_input_v3_add_listener(s.im, &C.gio_zwp_text_input_v3_listener, unsafe.Pointer(s.seat))
-	}
+	s.bindTextInput()
 	switch {
 	case s.pointer == nil && caps&C.WL_SEAT_CAPABILITY_POINTER != 0:
 		s.pointer = C.wl_seat_get_pointer(s.seat)
 		C.wl_pointer_add_listener(s.pointer, &C.gio_pointer_listener, unsafe.Pointer(s.seat))
+		// Pointer constraints need a pointer.
+		s.disp.forEachWindow((*window).updateInputMode)
 	case s.pointer != nil && caps&C.WL_SEAT_CAPABILITY_POINTER == 0:
 		C.wl_pointer_release(s.pointer)
 		s.pointer = nil
+		s.disp.forEachWindow((*window).updateInputMode)
 	}
 	switch {
 	case s.touch == nil && caps&C.WL_SEAT_CAPABILITY_TOUCH != 0:
@@ -546,6 +781,13 @@ func (s *wlSeat) updateCaps(caps C.uint32_t) {
 	}
 }
 
+func (s *wlSeat) bindTextInput() {
+	if s.im == nil && s.disp.imm != nil {
+		s.im = C.zwp_text_input_manager_v3_get_text_input(s.disp.imm, s.seat)
+		C.zwp_text_input_v3_add_listener(s.im, &C.gio_zwp_text_input_v3_listener, unsafe.Pointer(s.seat))
+	}
+}
+
 //export gio_onSeatName
 func gio_onSeatName(data unsafe.Pointer, seat *C.struct_wl_seat, name *C.char) {
 }
@@ -567,6 +809,15 @@ func gio_onToplevelClose(data unsafe.Pointer, topLvl *C.struct_xdg_toplevel) {
 
 //export gio_onToplevelConfigure
 func gio_onToplevelConfigure(data unsafe.Pointer, topLvl *C.struct_xdg_toplevel, width, height C.int32_t, states *C.struct_wl_array) {
+	w := callbackLoad(data).(*window)
+	if width != 0 && height != 0 {
+		w.size = w.constrainAspect(image.Pt(int(width), int(height)))
+		w.updateOpaqueRegion()
+	}
+}
+
+//export gio_onPopupConfigure
+func gio_onPopupConfigure(data unsafe.Pointer, popup *C.struct_xdg_popup, x, y, width, height C.int32_t) {
 	w := callbackLoad(data).(*window)
 	if width != 0 && height != 0 {
 		w.size = image.Pt(int(width), int(height))
@@ -574,6 +825,34 @@ func gio_onToplevelConfigure(data unsafe.Pointer, topLvl *C.struct_xdg_toplevel,
 	}
 }
 
+//export gio_onPopupDone
+func gio_onPopupDone(data unsafe.Pointer, popup *C.struct_xdg_popup) {
+	w := callbackLoad(data).(*window)
+	w.dismissed = true
+	w.dead = true
+}
+
+// constrainAspect shrinks the surface size sz until the content area
+// matches the configured aspect ratio. xdg_toplevel has no aspect ratio
+// hint, so the constraint is applied to the sizes the compositor suggests.
+func (w *window) constrainAspect(sz image.Point) image.Point {
+	r := w.config.AspectRatio
+	if r.X <= 0 || r.Y <= 0 || w.config.Mode != mado.Windowed {
+		return sz
+	}
+	deco := w.decoHeight()
+	content := image.Pt(sz.X, sz.Y-deco)
+	if content.X <= 0 || content.Y <= 0 {
+		return sz
+	}
+	if content.X*r.Y < content.Y*r.X {
+		content.Y = content.X * r.Y / r.X
+	} else {
+		content.X = content.Y * r.X / r.Y
+	}
+	return image.Pt(content.X, content.Y+deco)
+}
+
 //export gio_onToplevelDecorationConfigure
 func gio_onToplevelDecorationConfigure(data unsafe.Pointer, deco *C.struct_zxdg_toplevel_decoration_v1, mode C.uint32_t) {
 	w := callbackLoad(data).(*window)
@@ -701,14 +980,25 @@ func gio_onRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C
 		d.shm = (*C.struct_wl_shm)(C.wl_registry_bind(reg, name, &C.wl_shm_interface, 1))
 	case "xdg_wm_base":
 		d.wm = (*C.struct_xdg_wm_base)(C.wl_registry_bind(reg, name, &C.xdg_wm_base_interface, 1))
+		C.xdg_wm_base_add_listener(d.wm, &C.gio_xdg_wm_base_listener, unsafe.Pointer(d.disp))
 	case "zxdg_decoration_manager_v1":
 		d.decor = (*C.struct_zxdg_decoration_manager_v1)(C.wl_registry_bind(reg, name, &C.zxdg_decoration_manager_v1_interface, 1))
-		// TODO: Implement and test text-input support.
-		/*case "zwp_text_input_manager_v3":
-		d.imm = (*C.struct_zwp_text_input_manager_v3)(C.wl_registry_bind(reg, name, &C.zwp_text_input_manager_v3_interface, 1))*/
+	case "zwp_text_input_manager_v3":
+		d.imm = (*C.struct_zwp_text_input_manager_v3)(C.wl_registry_bind(reg, name, &C.zwp_text_input_manager_v3_interface, 1))
+		if d.seat != nil {
+			d.seat.bindTextInput()
+		}
 	case "wl_data_device_manager":
 		d.dataDeviceManager = (*C.struct_wl_data_device_manager)(C.wl_registry_bind(reg, name, &C.wl_data_device_manager_interface, 3))
 		d.bindDataDevice()
+	case "zwp_relative_pointer_manager_v1":
+		d.relPointer = (*C.struct_zwp_relative_pointer_manager_v1)(C.wl_registry_bind(reg, name, &C.zwp_relative_pointer_manager_v1_interface, 1))
+	case "zwp_pointer_constraints_v1":
+		d.constraints = (*C.struct_zwp_pointer_constraints_v1)(C.wl_registry_bind(reg, name, &C.zwp_pointer_constraints_v1_interface, 1))
+	case "xdg_activation_v1":
+		d.activation = (*C.struct_xdg_activation_v1)(C.wl_registry_bind(reg, name, &C.xdg_activation_v1_interface, 1))
+	case "xdg_toplevel_icon_manager_v1":
+		d.iconManager = (*C.struct_xdg_toplevel_icon_manager_v1)(C.wl_registry_bind(reg, name, &C.xdg_toplevel_icon_manager_v1_interface, 1))
 	}
 }
 
@@ -738,19 +1028,25 @@ func gio_onDataDeviceOffer(data unsafe.Pointer, dataDev *C.struct_wl_data_device
 func gio_onDataDeviceEnter(data unsafe.Pointer, dataDev *C.struct_wl_data_device, serial C.uint32_t, surf *C.struct_wl_surface, x, y C.wl_fixed_t, id *C.struct_wl_data_offer) {
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
-	s.flushOffers()
+	s.dragEnter(serial, surf, x, y, id)
 }
 
 //export gio_onDataDeviceLeave
 func gio_onDataDeviceLeave(data unsafe.Pointer, dataDev *C.struct_wl_data_device) {
+	s := callbackLoad(data).(*wlSeat)
+	s.dragLeave()
 }
 
 //export gio_onDataDeviceMotion
 func gio_onDataDeviceMotion(data unsafe.Pointer, dataDev *C.struct_wl_data_device, t C.uint32_t, x, y C.wl_fixed_t) {
+	s := callbackLoad(data).(*wlSeat)
+	s.dragMotion(x, y)
 }
 
 //export gio_onDataDeviceDrop
 func gio_onDataDeviceDrop(data unsafe.Pointer, dataDev *C.struct_wl_data_device) {
+	s := callbackLoad(data).(*wlSeat)
+	s.dragDrop()
 }
 
 //export gio_onDataDeviceSelection
@@ -787,10 +1083,14 @@ func gio_onRegistryGlobalRemove(data unsafe.Pointer, reg *C.struct_wl_registry,
 
 //export gio_onTouchDown
 func gio_onTouchDown(data unsafe.Pointer, touch *C.struct_wl_touch, serial, t C.uint32_t, surf *C.struct_wl_surface, id C.int32_t, x, y C.wl_fixed_t) {
+	if surf == nil {
+		return
+	}
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
 	w := callbackLoad(unsafe.Pointer(surf)).(*window)
 	s.touchFoci[id] = w
+	w.disp.dismissPopups(w)
 	w.lastTouch = f32.Point{
 		X: fromFixed(x) * float32(w.scale),
 		Y: fromFixed(y) * float32(w.scale),
@@ -810,6 +1110,9 @@ func gio_onTouchUp(data unsafe.Pointer, touch *C.struct_wl_touch, serial, t C.ui
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
 	w := s.touchFoci[id]
+	if w == nil {
+		return
+	}
 	delete(s.touchFoci, id)
 	w.w.Event(pointer.Event{
 		Kind:      pointer.Release,
@@ -825,6 +1128,9 @@ func gio_onTouchUp(data unsafe.Pointer, touch *C.struct_wl_touch, serial, t C.ui
 func gio_onTouchMotion(data unsafe.Pointer, touch *C.struct_wl_touch, t C.uint32_t, id C.int32_t, x, y C.wl_fixed_t) {
 	s := callbackLoad(data).(*wlSeat)
 	w := s.touchFoci[id]
+	if w == nil {
+		return
+	}
 	w.lastTouch = f32.Point{
 		X: fromFixed(x) * float32(w.scale),
 		Y: fromFixed(y) * float32(w.scale),
@@ -857,6 +1163,9 @@ func gio_onTouchCancel(data unsafe.Pointer, touch *C.struct_wl_touch) {
 
 //export gio_onPointerEnter
 func gio_onPointerEnter(data unsafe.Pointer, pointer *C.struct_wl_pointer, serial C.uint32_t, surf *C.struct_wl_surface, x, y C.wl_fixed_t) {
+	if surf == nil {
+		return
+	}
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
 	w := callbackLoad(unsafe.Pointer(surf)).(*window)
@@ -868,6 +1177,9 @@ func gio_onPointerEnter(data unsafe.Pointer, pointer *C.struct_wl_pointer, seria
 
 //export gio_onPointerLeave
 func gio_onPointerLeave(data unsafe.Pointer, p *C.struct_wl_pointer, serial C.uint32_t, surf *C.struct_wl_surface) {
+	if surf == nil {
+		return
+	}
 	w := callbackLoad(unsafe.Pointer(surf)).(*window)
 	w.seat = nil
 	s := callbackLoad(data).(*wlSeat)
@@ -882,6 +1194,9 @@ func gio_onPointerLeave(data unsafe.Pointer, p *C.struct_wl_pointer, serial C.ui
 func gio_onPointerMotion(data unsafe.Pointer, p *C.struct_wl_pointer, t C.uint32_t, x, y C.wl_fixed_t) {
 	s := callbackLoad(data).(*wlSeat)
 	w := s.pointerFocus
+	if w == nil {
+		return
+	}
 	w.resetFling()
 	w.onPointerMotion(x, y, t)
 }
@@ -891,6 +1206,9 @@ func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t,
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
 	w := s.pointerFocus
+	if w == nil {
+		return
+	}
 	// From linux-event-codes.h.
 	const (
 		BTN_LEFT   = 0x110
@@ -914,7 +1232,7 @@ func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t,
 			return
 		}
 		act, ok := w.w.ActionAt(w.lastPos)
-		if ok && w.config.Mode == mado.Windowed {
+		if ok && w.config.Mode == mado.Windowed && !w.cursorHidden() {
 			switch act {
 			case system.ActionMove:
 				w.move(serial)
@@ -932,6 +1250,8 @@ func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t,
 	case 1:
 		w.pointerBtns |= btn
 		kind = pointer.Press
+		s.pressSerial = serial
+		w.disp.dismissPopups(w)
 	}
 	w.flushScroll()
 	w.resetFling()
@@ -939,7 +1259,7 @@ func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t,
 		Kind:      kind,
 		Source:    pointer.Mouse,
 		Buttons:   w.pointerBtns,
-		Position:  w.lastPos,
+		Position:  w.pointerPos(),
 		Time:      time.Duration(t) * time.Millisecond,
 		Modifiers: w.disp.xkb.Modifiers(),
 	})
@@ -949,6 +1269,9 @@ func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t,
 func gio_onPointerAxis(data unsafe.Pointer, p *C.struct_wl_pointer, t, axis C.uint32_t, value C.wl_fixed_t) {
 	s := callbackLoad(data).(*wlSeat)
 	w := s.pointerFocus
+	if w == nil {
+		return
+	}
 	v := fromFixed(value)
 	w.resetFling()
 	if w.scroll.dist == (f32.Point{}) {
@@ -959,7 +1282,7 @@ func gio_onPointerAxis(data unsafe.Pointer, p *C.struct_wl_pointer, t, axis C.ui
 		w.scroll.dist.X += v
 	case C.WL_POINTER_AXIS_VERTICAL_SCROLL:
 		// horizontal scroll if shift + mousewheel(up/down) pressed.
-		if w.disp.xkb.Modifiers() == key.ModShift {
+		if w.disp.xkb.Modifiers().Held() == key.ModShift {
 			w.scroll.dist.X += v
 		} else {
 			w.scroll.dist.Y += v
@@ -971,6 +1294,9 @@ func gio_onPointerAxis(data unsafe.Pointer, p *C.struct_wl_pointer, t, axis C.ui
 func gio_onPointerFrame(data unsafe.Pointer, p *C.struct_wl_pointer) {
 	s := callbackLoad(data).(*wlSeat)
 	w := s.pointerFocus
+	if w == nil {
+		return
+	}
 	w.flushScroll()
 	w.flushFling()
 }
@@ -1001,6 +1327,9 @@ func gio_onPointerAxisSource(data unsafe.Pointer, pointer *C.struct_wl_pointer,
 func gio_onPointerAxisStop(data unsafe.Pointer, p *C.struct_wl_pointer, t, axis C.uint32_t) {
 	s := callbackLoad(data).(*wlSeat)
 	w := s.pointerFocus
+	if w == nil {
+		return
+	}
 	w.fling.start = true
 }
 
@@ -1008,13 +1337,16 @@ func gio_onPointerAxisStop(data unsafe.Pointer, p *C.struct_wl_pointer, t, axis
 func gio_onPointerAxisDiscrete(data unsafe.Pointer, p *C.struct_wl_pointer, axis C.uint32_t, discrete C.int32_t) {
 	s := callbackLoad(data).(*wlSeat)
 	w := s.pointerFocus
+	if w == nil {
+		return
+	}
 	w.resetFling()
 	switch axis {
 	case C.WL_POINTER_AXIS_HORIZONTAL_SCROLL:
 		w.scroll.steps.X += int(discrete)
 	case C.WL_POINTER_AXIS_VERTICAL_SCROLL:
 		// horizontal scroll if shift + mousewheel(up/down) pressed.
-		if w.disp.xkb.Modifiers() == key.ModShift {
+		if w.disp.xkb.Modifiers().Held() == key.ModShift {
 			w.scroll.steps.X += int(discrete)
 		} else {
 			w.scroll.steps.Y += int(discrete)
@@ -1022,28 +1354,46 @@ func gio_onPointerAxisDiscrete(data unsafe.Pointer, p *C.struct_wl_pointer, axis
 	}
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(mime string, primary bool) {
+	// Only text is supported, and there is no primary selection. Send
+	// empty responses on unsupported types.
+	if mime != "application/text" || primary {
+		w.w.Event(clipboardEvent(mime, primary, nil))
+		return
+	}
 	r, err := w.disp.readClipboard()
 	// Send empty responses on unavailable clipboards or errors.
 	if r == nil || err != nil {
+		w.w.Event(clipboardEvent(mime, primary, nil))
 		return
 	}
 	// Don't let slow clipboard transfers block event loop.
 	go func() {
 		defer r.Close()
 		data, _ := io.ReadAll(r)
-		w.clipReads <- transfer.DataEvent{
-			Type: "application/text",
-			Open: func() io.ReadCloser {
-				return io.NopCloser(bytes.NewReader(data))
-			},
-		}
+		w.clipReads <- clipboardEvent(mime, primary, data)
 		w.Wakeup()
 	}()
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
-	w.disp.writeClipboard(s)
+// clipboardEvent returns the response to a clipboard read of mime.
+func clipboardEvent(mime string, primary bool, data []byte) transfer.DataEvent {
+	return transfer.DataEvent{
+		Type: mime,
+		Open: func() io.ReadCloser {
+			return io.NopCloser(bytes.NewReader(data))
+		},
+		Primary: primary,
+	}
+}
+
+func (w *window) WriteClipboard(content []input.ClipboardData) {
+	for _, c := range content {
+		if c.Type == "application/text" {
+			w.disp.writeClipboard(c.Data)
+			return
+		}
+	}
 }
 
 func (w *window) Configure(options []mado.Option) {
@@ -1053,6 +1403,17 @@ func (w *window) Configure(options []mado.Option) {
 	cnf.Apply(cfg, options)
 	w.config.DecoHeight = cnf.DecoHeight
 
+	if w.popup != nil {
+		// The compositor places popups, which have no modes.
+		w.config.Mode = mado.Windowed
+		if prev.Size != cnf.Size {
+			w.config.Size = cnf.Size
+			w.size = cnf.Size.Div(w.scale)
+		}
+		w.w.Event(mado.ConfigEvent{Config: w.config})
+		w.redraw = true
+		return
+	}
 	switch cnf.Mode {
 	case mado.Fullscreen:
 		switch prev.Mode {
@@ -1097,6 +1458,15 @@ func (w *window) Configure(options []mado.Option) {
 		w.config.MinSize = cnf.MinSize
 		w.config.MaxSize = cnf.MaxSize
 		w.setWindowConstraints()
+		if prev.AspectRatio != cnf.AspectRatio {
+			w.config.AspectRatio = cnf.AspectRatio
+			w.size = w.constrainAspect(w.size)
+			w.config.Size = w.size.Mul(w.scale)
+		}
+	}
+	if iconChanged(prev.Icon, cnf.Icon) {
+		w.config.Icon = cnf.Icon
+		w.setIcon(cnf.Icon)
 	}
 	w.w.Event(mado.ConfigEvent{Config: w.config})
 	w.redraw = true
@@ -1137,13 +1507,56 @@ func (w *window) Perform(actions system.Action) {
 		switch action {
 		case system.ActionClose:
 			w.dead = true
+		case system.ActionFocus:
+			w.activate(true)
+		case system.ActionRequestAttention:
+			w.activate(false)
 		}
 	})
 }
 
+// activate requests an xdg_activation_v1 token and activates the window
+// with it. Without an input serial compositors treat the activation as a
+// request for attention.
+func (w *window) activate(focus bool) {
+	d := w.disp
+	if d.activation == nil {
+		return
+	}
+	w.cancelActivation()
+	tok := C.xdg_activation_v1_get_activation_token(d.activation)
+	if tok == nil {
+		return
+	}
+	w.activationToken = tok
+	C.xdg_activation_token_v1_add_listener(tok, &C.gio_xdg_activation_token_v1_listener, unsafe.Pointer(w.surf))
+	if s := d.seat; focus && s != nil {
+		C.xdg_activation_token_v1_set_serial(tok, s.serial, s.seat)
+	}
+	C.xdg_activation_token_v1_set_surface(tok, w.surf)
+	C.xdg_activation_token_v1_commit(tok)
+}
+
+func (w *window) cancelActivation() {
+	if w.activationToken != nil {
+		C.xdg_activation_token_v1_destroy(w.activationToken)
+		w.activationToken = nil
+	}
+}
+
+//export gio_onActivationTokenDone
+func gio_onActivationTokenDone(data unsafe.Pointer, tok *C.struct_xdg_activation_token_v1, token *C.char) {
+	w := callbackLoad(data).(*window)
+	if tok != w.activationToken {
+		return
+	}
+	w.cancelActivation()
+	C.xdg_activation_v1_activate(w.disp.activation, token, w.surf)
+}
+
 func (w *window) move(serial C.uint32_t) {
 	s := w.seat
-	if !w.inCompositor && s != nil {
+	if !w.inCompositor && s != nil && w.topLvl != nil {
 		w.inCompositor = true
 		C.xdg_toplevel_move(w.topLvl, s.seat, serial)
 	}
@@ -1151,7 +1564,7 @@ func (w *window) move(serial C.uint32_t) {
 
 func (w *window) resize(serial, edge C.uint32_t) {
 	s := w.seat
-	if w.inCompositor || s == nil {
+	if w.inCompositor || s == nil || w.topLvl == nil {
 		return
 	}
 	w.inCompositor = true
@@ -1172,7 +1585,15 @@ func (w *window) updateCursor() {
 }
 
 func (w *window) setCursor(pointer *C.struct_wl_pointer, serial C.uint32_t) {
+	if w.cursorHidden() {
+		C.wl_pointer_set_cursor(pointer, serial, nil, 0, 0)
+		return
+	}
 	c := w.cursor.system
+	if c == nil && w.cursor.custom.buf != nil {
+		w.setCustomCursor(pointer, serial)
+		return
+	}
 	if c == nil {
 		c = w.cursor.cursor
 	}
@@ -1187,6 +1608,7 @@ func (w *window) setCursor(pointer *C.struct_wl_pointer, serial C.uint32_t) {
 		return
 	}
 	C.wl_pointer_set_cursor(pointer, serial, w.cursor.surf, C.int32_t(img.hotspot_x/C.uint(w.scale)), C.int32_t(img.hotspot_y/C.uint(w.scale)))
+	C.wl_surface_set_buffer_scale(w.cursor.surf, C.int32_t(w.scale))
 	C.wl_surface_attach(w.cursor.surf, buf, 0, 0)
 	C.wl_surface_damage(w.cursor.surf, 0, 0, C.int32_t(img.width), C.int32_t(img.height))
 	C.wl_surface_commit(w.cursor.surf)
@@ -1214,6 +1636,9 @@ func gio_onKeyboardKeymap(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, f
 
 //export gio_onKeyboardEnter
 func gio_onKeyboardEnter(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, serial C.uint32_t, surf *C.struct_wl_surface, keys *C.struct_wl_array) {
+	if surf == nil {
+		return
+	}
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
 	w := callbackLoad(unsafe.Pointer(surf)).(*window)
@@ -1228,6 +1653,9 @@ func gio_onKeyboardLeave(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, se
 	s.serial = serial
 	s.disp.repeat.Stop(0)
 	w := s.keyboardFocus
+	if w == nil {
+		return
+	}
 	w.w.Event(key.FocusEvent{Focus: false})
 }
 
@@ -1236,6 +1664,9 @@ func gio_onKeyboardKey(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, seri
 	s := callbackLoad(data).(*wlSeat)
 	s.serial = serial
 	w := s.keyboardFocus
+	if w == nil {
+		return
+	}
 	t := time.Duration(timestamp) * time.Millisecond
 	s.disp.repeat.Stop(t)
 	w.resetFling()
@@ -1243,7 +1674,7 @@ func gio_onKeyboardKey(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, seri
 	ks := mapXKBKeyState(uint32(state))
 	for _, e := range w.disp.xkb.DispatchKey(kc, ks) {
 		if ee, ok := e.(key.EditEvent); ok {
-			// There's no support for IME yet.
+			// Keys not consumed by the input method, if any.
 			w.w.EditorInsert(ee.Text, false)
 		} else {
 			w.w.Event(e)
@@ -1357,25 +1788,85 @@ func gio_onFrameDone(data unsafe.Pointer, callback *C.struct_wl_callback, t C.ui
 	}
 }
 
-func (w *window) loop() error {
+// loop dispatches the events of the display to its windows, until the
+// last window is closed or the connection fails.
+func (d *wlDisplay) loop() {
+	defer d.destroy()
 	var p poller
 	for {
-		if err := w.disp.dispatch(&p); err != nil {
-			return err
+		wlApp.mu.Lock()
+		funcs := d.funcs
+		d.funcs = nil
+		if len(funcs) == 0 && len(d.windows) == 0 {
+			wlApp.disp = nil
+			wlApp.mu.Unlock()
+			return
 		}
-		select {
-		case e := <-w.clipReads:
-			w.w.Event(e)
-		case <-w.wakeups:
-			w.w.Event(mado.WakeupEvent{})
-		default:
+		wlApp.mu.Unlock()
+		for _, f := range funcs {
+			f()
 		}
-		if w.dead {
-			break
+		if len(d.windows) == 0 {
+			continue
 		}
+		err := d.dispatch(&p)
+		for _, w := range append([]*window(nil), d.windows...) {
+			if !slices.Contains(d.windows, w) {
+				// Closed with its parent.
+				continue
+			}
+			if err == nil {
+				w.process()
+			}
+			if w.dead || err != nil {
+				d.closeWindow(w, err)
+			}
+		}
+	}
+}
+
+// process delivers the pending events of the window and draws it.
+func (w *window) process() {
+	select {
+	case e := <-w.clipReads:
+		w.w.Event(e)
+	case d := <-w.drops:
+		w.finishDrop(d)
+	case <-w.wakeups:
+		w.w.Event(mado.WakeupEvent{})
+	default:
+	}
+	if !w.dead {
 		w.draw()
 	}
-	return nil
+}
+
+// closeWindow destroys a window of the display and removes it.
+// The popups of the window are dismissed first.
+func (d *wlDisplay) closeWindow(w *window, err error) {
+	for _, w2 := range append([]*window(nil), d.windows...) {
+		if w2.parent != w {
+			continue
+		}
+		if w2.popup != nil {
+			w2.dismissed = true
+			d.closeWindow(w2, err)
+		} else {
+			w2.parent = nil
+		}
+	}
+	if w.dismissed {
+		w.w.Event(mado.DismissEvent{})
+	}
+	w.w.Event(WaylandViewEvent{})
+	w.w.Event(mado.DestroyEvent{Err: err})
+	w.destroy()
+	for i, w2 := range d.windows {
+		if w2 == w {
+			d.windows = append(d.windows[:i], d.windows[i+1:]...)
+			break
+		}
+	}
 }
 
 // bindDataDevice initializes the dataDev field if and only if both
@@ -1454,6 +1945,10 @@ func (d *wlDisplay) wakeup() {
 }
 
 func (w *window) destroy() {
+	w.destroyInput()
+	w.destroyCustomCursor()
+	w.cancelActivation()
+	w.destroyIcon()
 	if w.cursor.surf != nil {
 		C.wl_surface_destroy(w.cursor.surf)
 	}
@@ -1463,6 +1958,9 @@ func (w *window) destroy() {
 	if w.topLvl != nil {
 		C.xdg_toplevel_destroy(w.topLvl)
 	}
+	if w.popup != nil {
+		C.xdg_popup_destroy(w.popup)
+	}
 	if w.surf != nil {
 		C.wl_surface_destroy(w.surf)
 	}
@@ -1472,6 +1970,31 @@ func (w *window) destroy() {
 	if w.decor != nil {
 		C.zxdg_toplevel_decoration_v1_destroy(w.decor)
 	}
+	// Input events for the destroyed surface refer to a nil surface or
+	// a nil focus and are ignored.
+	if s := w.disp.seat; s != nil {
+		if s.imFocus == w {
+			s.imFocus = nil
+		}
+		if s.pointerFocus == w {
+			s.pointerFocus = nil
+		}
+		if s.keyboardFocus == w {
+			s.keyboardFocus = nil
+			w.disp.repeat.Stop(0)
+		}
+		for id, w2 := range s.touchFoci {
+			if w2 == w {
+				delete(s.touchFoci, id)
+			}
+		}
+		if s.drag.target == w {
+			s.dragLeave()
+		}
+		if s.drag.origin == w {
+			s.abandonDrag()
+		}
+	}
 	callbackDelete(unsafe.Pointer(w.surf))
 }
 
@@ -1498,28 +2021,232 @@ func gio_onKeyboardRepeatInfo(data unsafe.Pointer, keyboard *C.struct_wl_keyboar
 
 //export gio_onTextInputEnter
 func gio_onTextInputEnter(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, surf *C.struct_wl_surface) {
+	s := callbackLoad(data).(*wlSeat)
+	w, ok := callbackMap.Load(unsafe.Pointer(surf))
+	if !ok {
+		return
+	}
+	s.imFocus = w.(*window)
+	s.imEnabled = false
+	s.imFocus.updateTextInput()
 }
 
 //export gio_onTextInputLeave
 func gio_onTextInputLeave(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, surf *C.struct_wl_surface) {
+	s := callbackLoad(data).(*wlSeat)
+	if s.imEnabled {
+		C.zwp_text_input_v3_disable(s.im)
+		s.commitTextInput()
+		s.imEnabled = false
+	}
+	if w := s.imFocus; w != nil {
+		// Remove any composition left behind.
+		if comp := w.w.EditorState().Compose; comp.Start != -1 {
+			w.w.EditorReplace(comp, "", true)
+			w.w.SetComposingRegion(key.Range{Start: -1, End: -1})
+		}
+	}
+	s.imFocus = nil
+	s.imPending = textInputState{}
 }
 
 //export gio_onTextInputPreeditString
 func gio_onTextInputPreeditString(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, ctxt *C.char, begin, end C.int32_t) {
+	s := callbackLoad(data).(*wlSeat)
+	s.imPending.preedit = C.GoString(ctxt)
+	s.imPending.cursorBegin = int(begin)
+	s.imPending.cursorEnd = int(end)
 }
 
 //export gio_onTextInputCommitString
 func gio_onTextInputCommitString(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, ctxt *C.char) {
+	s := callbackLoad(data).(*wlSeat)
+	s.imPending.commit = C.GoString(ctxt)
 }
 
 //export gio_onTextInputDeleteSurroundingText
 func gio_onTextInputDeleteSurroundingText(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, before, after C.uint32_t) {
+	s := callbackLoad(data).(*wlSeat)
+	s.imPending.deleteBefore = int(before)
+	s.imPending.deleteAfter = int(after)
 }
 
 //export gio_onTextInputDone
 func gio_onTextInputDone(data unsafe.Pointer, im *C.struct_zwp_text_input_v3, serial C.uint32_t) {
 	s := callbackLoad(data).(*wlSeat)
-	s.serial = serial
+	st := s.imPending
+	s.imPending = textInputState{}
+	w := s.imFocus
+	if w == nil {
+		return
+	}
+	// The state is applied even if serial doesn't match the number of
+	// commits, as required by the protocol.
+	w.applyTextInput(st)
+	// A mismatched serial means the compositor hasn't seen all our
+	// requests yet, and the current state must be left alone.
+	if serial == s.imCommits {
+		// Report the resulting surrounding text and cursor.
+		w.updateTextInput()
+	}
+}
+
+// applyTextInput applies a text input state in the order specified by
+// zwp_text_input_v3.done: replace the preedit, delete surrounding text,
+// insert the commit string and finally insert the new preedit.
+func (w *window) applyTextInput(st textInputState) {
+	state := w.w.EditorState()
+	rng := state.Compose
+	hasCompose := rng.Start != -1
+	if !hasCompose {
+		rng = state.Selection.Range
+	}
+	if rng.Start > rng.End {
+		rng.Start, rng.End = rng.End, rng.Start
+	}
+	if st.commit != "" || st.deleteBefore > 0 || st.deleteAfter > 0 || (hasCompose && st.preedit == "") {
+		rng.Start -= runesBefore(state, rng.Start, st.deleteBefore)
+		rng.End += runesAfter(state, rng.End, st.deleteAfter)
+		w.w.EditorReplace(rng, st.commit, false)
+		pos := rng.Start + utf8.RuneCountInString(st.commit)
+		rng = key.Range{Start: pos, End: pos}
+		w.w.SetEditorSelection(rng)
+	}
+	comp := key.Range{Start: -1, End: -1}
+	if st.preedit != "" {
+		w.w.EditorReplace(rng, st.preedit, true)
+		comp = key.Range{Start: rng.Start, End: rng.Start + utf8.RuneCountInString(st.preedit)}
+		pos := comp.End
+		if st.cursorBegin >= 0 && st.cursorBegin <= len(st.preedit) {
+			pos = comp.Start + utf8.RuneCountInString(st.preedit[:st.cursorBegin])
+		}
+		w.w.SetEditorSelection(key.Range{Start: pos, End: pos})
+	}
+	w.w.SetComposingRegion(comp)
+}
+
+// runesBefore converts a length in bytes before the rune offset pos
+// to a length in runes, using the editor snippet. The length is clamped
+// to the text of the snippet.
+func runesBefore(state mado.EditorState, pos, n int) int {
+	snip := state.Snippet
+	if pos < snip.Start || pos > snip.End {
+		return 0
+	}
+	text := []rune(snip.Text)
+	runes := 0
+	for i := min(pos-snip.Start, len(text)); n > 0 && i > 0; i-- {
+		n -= utf8.RuneLen(text[i-1])
+		runes++
+	}
+	return runes
+}
+
+// runesAfter is like runesBefore for text after pos.
+func runesAfter(state mado.EditorState, pos, n int) int {
+	snip := state.Snippet
+	if pos < snip.Start || pos > snip.End {
+		return 0
+	}
+	text := []rune(snip.Text)
+	runes := 0
+	for i := pos - snip.Start; n > 0 && i < len(text); i++ {
+		n -= utf8.RuneLen(text[i])
+		runes++
+	}
+	return runes
+}
+
+// commitTextInput commits the pending text input requests.
+func (s *wlSeat) commitTextInput() {
+	C.zwp_text_input_v3_commit(s.im)
+	s.imCommits++
+}
+
+// updateTextInput sends the text input state of w to the compositor, if
+// w has text input focus.
+func (w *window) updateTextInput() {
+	s := w.disp.seat
+	if s == nil || s.im == nil || s.imFocus != w {
+		return
+	}
+	if !w.textInput.show {
+		if s.imEnabled {
+			s.imEnabled = false
+			C.zwp_text_input_v3_disable(s.im)
+			s.commitTextInput()
+		}
+		return
+	}
+	if !s.imEnabled {
+		s.imEnabled = true
+		C.zwp_text_input_v3_enable(s.im)
+	}
+	state := w.w.EditorState()
+	if text, cursor, anchor, ok := surroundingText(state); ok {
+		ctext := C.CString(text)
+		C.zwp_text_input_v3_set_surrounding_text(s.im, ctext, C.int32_t(cursor), C.int32_t(anchor))
+		C.free(unsafe.Pointer(ctext))
+	}
+	hint, purpose := textInputContentType(w.textInput.hint)
+	C.zwp_text_input_v3_set_content_type(s.im, hint, purpose)
+	sel := state.Selection
+	caret := sel.Transform.Transform(sel.Caret.Pos.Sub(f32.Pt(0, sel.Caret.Ascent)))
+	bottom := sel.Transform.Transform(sel.Caret.Pos.Add(f32.Pt(0, sel.Caret.Descent)))
+	scale := float32(w.scale)
+	x, y := int(caret.X/scale+.5), int(caret.Y/scale+.5)
+	h := int((bottom.Y-caret.Y)/scale + .5)
+	C.zwp_text_input_v3_set_cursor_rectangle(s.im, C.int32_t(x), C.int32_t(y), 1, C.int32_t(max(h, 1)))
+	s.commitTextInput()
+}
+
+// surroundingText returns the editor snippet along with the byte
+// offsets of the cursor and selection anchor inside it. It returns
+// false if the selection is not covered by the snippet.
+func surroundingText(state mado.EditorState) (text string, cursor, anchor int, ok bool) {
+	// The protocol limits the surrounding text to 4000 bytes.
+	const maxLen = 4000
+	snip := state.Snippet
+	sel := state.Selection.Range
+	inSnippet := func(pos int) bool {
+		return pos >= snip.Start && pos <= snip.End
+	}
+	if !inSnippet(sel.Start) || !inSnippet(sel.End) || len(snip.Text) > maxLen {
+		return "", 0, 0, false
+	}
+	runes := []rune(snip.Text)
+	byteOffset := func(pos int) int {
+		pos -= snip.Start
+		if pos > len(runes) {
+			pos = len(runes)
+		}
+		return len(string(runes[:pos]))
+	}
+	// The editor caret is at the start of the selection.
+	return snip.Text, byteOffset(sel.Start), byteOffset(sel.End), true
+}
+
+// textInputContentType maps an input hint to a zwp_text_input_v3
+// content hint and purpose.
+func textInputContentType(hint key.InputHint) (C.uint32_t, C.uint32_t) {
+	switch hint {
+	case key.HintText:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_COMPLETION | C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_SPELLCHECK | C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_AUTO_CAPITALIZATION,
+			C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_NORMAL
+	case key.HintNumeric:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_NONE, C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_NUMBER
+	case key.HintEmail:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_COMPLETION, C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_EMAIL
+	case key.HintURL:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_COMPLETION, C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_URL
+	case key.HintTelephone:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_NONE, C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_PHONE
+	case key.HintPassword:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_HIDDEN_TEXT | C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_SENSITIVE_DATA,
+			C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_PASSWORD
+	default:
+		return C.ZWP_TEXT_INPUT_V3_CONTENT_HINT_NONE, C.ZWP_TEXT_INPUT_V3_CONTENT_PURPOSE_NORMAL
+	}
 }
 
 //export gio_onDataSourceTarget
@@ -1529,6 +2256,10 @@ func gio_onDataSourceTarget(data unsafe.Pointer, source *C.struct_wl_data_source
 //export gio_onDataSourceSend
 func gio_onDataSourceSend(data unsafe.Pointer, source *C.struct_wl_data_source, mime *C.char, fd C.int32_t) {
 	s := callbackLoad(data).(*wlSeat)
+	if source == s.drag.source {
+		s.dragSend(C.GoString(mime), int(fd))
+		return
+	}
 	content := s.content
 	go func() {
 		defer syscall.Close(int(fd))
@@ -1543,15 +2274,27 @@ func gio_onDataSourceCancelled(data unsafe.Pointer, source *C.struct_wl_data_sou
 		s.content = nil
 		s.source = nil
 	}
+	if s.drag.source == source {
+		s.endDrag()
+	}
 	C.wl_data_source_destroy(source)
 }
 
 //export gio_onDataSourceDNDDropPerformed
 func gio_onDataSourceDNDDropPerformed(data unsafe.Pointer, source *C.struct_wl_data_source) {
+	s := callbackLoad(data).(*wlSeat)
+	if s.drag.source == source {
+		s.dragDropPerformed()
+	}
 }
 
 //export gio_onDataSourceDNDFinished
 func gio_onDataSourceDNDFinished(data unsafe.Pointer, source *C.struct_wl_data_source) {
+	s := callbackLoad(data).(*wlSeat)
+	if s.drag.source == source {
+		s.endDrag()
+	}
+	C.wl_data_source_destroy(source)
 }
 
 //export gio_onDataSourceAction
@@ -1582,7 +2325,7 @@ func (w *window) flushScroll() {
 		Kind:      pointer.Scroll,
 		Source:    pointer.Mouse,
 		Buttons:   w.pointerBtns,
-		Position:  w.lastPos,
+		Position:  w.pointerPos(),
 		Scroll:    total,
 		Time:      w.scroll.time,
 		Modifiers: w.disp.xkb.Modifiers(),
@@ -1597,10 +2340,35 @@ func (w *window) flushScroll() {
 
 func (w *window) onPointerMotion(x, y C.wl_fixed_t, t C.uint32_t) {
 	w.flushScroll()
-	w.lastPos = f32.Point{
+	pos := f32.Point{
 		X: fromFixed(x) * float32(w.scale),
 		Y: fromFixed(y) * float32(w.scale),
 	}
+	if w.input.mode.Cursor == mado.CursorModeDisabled {
+		if w.input.relative != nil {
+			// Motion is reported by the relative pointer.
+			w.lastPos = pos
+			return
+		}
+		// Without relative pointer support, follow the absolute
+		// motion.
+		w.input.virtualPos = w.input.virtualPos.Add(pos.Sub(w.lastPos))
+		w.lastPos = pos
+		w.w.Event(pointer.Event{
+			Kind:      pointer.Move,
+			Position:  w.input.virtualPos,
+			Buttons:   w.pointerBtns,
+			Source:    pointer.Mouse,
+			Time:      time.Duration(t) * time.Millisecond,
+			Modifiers: w.disp.xkb.Modifiers(),
+		})
+		return
+	}
+	w.lastPos = pos
+	if w.dragTypes != nil && w.pointerBtns != 0 && !image.Pt(int(pos.X), int(pos.Y)).In(image.Rectangle{Max: w.config.Size}) {
+		// The drag left the window.
+		w.startDrag()
+	}
 	w.w.Event(pointer.Event{
 		Kind:      pointer.Move,
 		Position:  w.lastPos,
@@ -1619,7 +2387,7 @@ func (w *window) onPointerMotion(x, y C.wl_fixed_t, t C.uint32_t) {
 // updateCursor updates the system gesture cursor according to the pointer
 // position.
 func (w *window) systemGesture() (*C.struct_wl_cursor, C.uint32_t) {
-	if w.config.Mode != mado.Windowed || w.config.Decorated {
+	if w.config.Mode != mado.Windowed || w.config.Decorated || w.popup != nil {
 		return nil, 0
 	}
 	//border := w.w.GetWindow().(*app.Window).Metric.Dp(3)
@@ -1743,11 +2511,27 @@ func (w *window) surface() (*C.struct_wl_surface, int, int) {
 	return w.surf, sz.X, sz.Y
 }
 
-func (w *window) ShowTextInput(show bool) {}
+func (w *window) ShowTextInput(show bool) {
+	if w.textInput.show == show {
+		return
+	}
+	w.textInput.show = show
+	w.updateTextInput()
+}
 
-func (w *window) SetInputHint(_ key.InputHint) {}
+func (w *window) SetInputHint(hint key.InputHint) {
+	if w.textInput.hint == hint {
+		return
+	}
+	w.textInput.hint = hint
+	w.updateTextInput()
+}
 
-func (w *window) EditorStateChanged(old, new mado.EditorState) {}
+func (w *window) EditorStateChanged(old, new mado.EditorState) {
+	if old.Selection != new.Selection || old.Snippet != new.Snippet {
+		w.updateTextInput()
+	}
+}
 
 func (w *window) NewContext() (mado.Context, error) {
 	var firstErr error
@@ -1765,10 +2549,23 @@ func (w *window) NewContext() (mado.Context, error) {
 		}
 		firstErr = err
 	}
+	// Without a GPU, present frames drawn by the CPU.
+	c, err := newWaylandSoftwareContext(w)
+	if err == nil {
+		return c, nil
+	}
 	if firstErr != nil {
 		return nil, firstErr
 	}
-	return nil, errors.New("wayland: no available GPU backends")
+	return nil, err
+}
+
+func (w *window) NewFramebuffer() (mado.Framebuffer, error) {
+	p, err := newWaylandPresenter(w)
+	if err != nil {
+		return mado.NewContextFramebuffer(w)
+	}
+	return &swFramebuffer{p: p}, nil
 }
 
 // detectUIScale reports the system UI scale, or 1.0 if it fails.
@@ -1848,6 +2645,18 @@ func (d *wlDisplay) destroy() {
 	if d.decor != nil {
 		C.zxdg_decoration_manager_v1_destroy(d.decor)
 	}
+	if d.relPointer != nil {
+		C.zwp_relative_pointer_manager_v1_destroy(d.relPointer)
+	}
+	if d.constraints != nil {
+		C.zwp_pointer_constraints_v1_destroy(d.constraints)
+	}
+	if d.activation != nil {
+		C.xdg_activation_v1_destroy(d.activation)
+	}
+	if d.iconManager != nil {
+		C.xdg_toplevel_icon_manager_v1_destroy(d.iconManager)
+	}
 	if d.shm != nil {
 		C.wl_shm_destroy(d.shm)
 	}
@@ -1878,7 +2687,13 @@ func fromFixed(v C.wl_fixed_t) float32 {
 	return float32(f)
 }
 
+// GetFrameExtents reports the fallback decoration as the top edge. The
+// compositor doesn't report the size of server-side decorations.
+func (w *window) GetFrameExtents() (left, top, right, bottom int) {
+	return 0, w.decoHeight() * w.scale, 0, 0
+}
+
 func (w *window) GetFrameBufferSize() image.Point {
-	// TODO
-	return image.Pt(int(640), int(480))
+	size, _ := w.getConfig()
+	return size
 }
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+/*
+#include <wayland-client.h>
+*/
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"os"
+
+	"github.com/kanryu/mado/io/pointer"
+)
+
+// wlCustomCursor is a cursor created by SetCustomCursor.
+type wlCustomCursor struct {
+	buf     *C.struct_wl_buffer
+	size    image.Point
+	hotspot image.Point
+}
+
+func (w *window) SetCustomCursor(c *pointer.CustomCursor) {
+	w.cursor.custom = wlCustomCursor{}
+	if c != nil {
+		w.cursor.custom = w.loadCustomCursor(c)
+	}
+	w.updateCursor()
+}
+
+func (w *window) ReleaseCustomCursor(c *pointer.CustomCursor) {
+	cc, ok := w.cursor.customs[c]
+	if !ok {
+		return
+	}
+	delete(w.cursor.customs, c)
+	if cc.buf == nil {
+		return
+	}
+	if cc.buf == w.cursor.custom.buf {
+		w.SetCustomCursor(nil)
+	}
+	C.wl_buffer_destroy(cc.buf)
+}
+
+// loadCustomCursor returns the cursor for the image of c, creating its
+// buffer on first use.
+func (w *window) loadCustomCursor(c *pointer.CustomCursor) wlCustomCursor {
+	if cc, ok := w.cursor.customs[c]; ok {
+		return cc
+	}
+	var cc wlCustomCursor
+	if buf, err := w.disp.newShmBuffer(c.Size(), cursorARGB(c)); err == nil {
+		cc = wlCustomCursor{
+			buf:     buf,
+			size:    c.Size(),
+			hotspot: c.Hotspot,
+		}
+	}
+	if w.cursor.customs == nil {
+		w.cursor.customs = make(map[*pointer.CustomCursor]wlCustomCursor)
+	}
+	w.cursor.customs[c] = cc
+	return cc
+}
+
+// setCustomCursor attaches the custom cursor image to the cursor
+// surface. The image is in pixels, so the surface is not scaled.
+func (w *window) setCustomCursor(pointer *C.struct_wl_pointer, serial C.uint32_t) {
+	c := w.cursor.custom
+	C.wl_pointer_set_cursor(pointer, serial, w.cursor.surf, C.int32_t(c.hotspot.X), C.int32_t(c.hotspot.Y))
+	C.wl_surface_set_buffer_scale(w.cursor.surf, 1)
+	C.wl_surface_attach(w.cursor.surf, c.buf, 0, 0)
+	C.wl_surface_damage(w.cursor.surf, 0, 0, C.int32_t(c.size.X), C.int32_t(c.size.Y))
+	C.wl_surface_commit(w.cursor.surf)
+}
+
+func (w *window) destroyCustomCursor() {
+	for _, cc := range w.cursor.customs {
+		if cc.buf != nil {
+			C.wl_buffer_destroy(cc.buf)
+		}
+	}
+	w.cursor.customs = nil
+	w.cursor.custom = wlCustomCursor{}
+}
+
+// newShmBuffer creates an ARGB8888 buffer of the given size from
+// premultiplied pixels.
+func (d *wlDisplay) newShmBuffer(size image.Point, pix []uint32) (*C.struct_wl_buffer, error) {
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, errors.New("wayland: empty shm buffer")
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "mado-shm-")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// The compositor gets the file descriptor; the name is not needed.
+	os.Remove(f.Name())
+	stride := size.X * 4
+	data := make([]byte, stride*size.Y)
+	for i, p := range pix {
+		// ARGB8888 is little endian.
+		binary.LittleEndian.PutUint32(data[i*4:], p)
+	}
+	if _, err := f.Write(data); err != nil {
+		return nil, err
+	}
+	pool := C.wl_shm_create_pool(d.shm, C.int32_t(f.Fd()), C.int32_t(len(data)))
+	if pool == nil {
+		return nil, errors.New("wayland: wl_shm_create_pool failed")
+	}
+	defer C.wl_shm_pool_destroy(pool)
+	buf := C.wl_shm_pool_create_buffer(pool, 0, C.int32_t(size.X), C.int32_t(size.Y), C.int32_t(stride), C.WL_SHM_FORMAT_ARGB8888)
+	if buf == nil {
+		return nil, errors.New("wayland: wl_shm_pool_create_buffer failed")
+	}
+	return buf, nil
+}
@@ -0,0 +1,379 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+/*
+#include <stdlib.h>
+#include <wayland-client.h>
+
+extern const struct wl_data_source_listener gio_data_source_listener;
+*/
+import "C"
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/input"
+	"github.com/kanryu/mado/io/pointer"
+)
+
+// wlDrag is the drag and drop state of a seat.
+type wlDrag struct {
+	// offer is the drag over target, from another client or from
+	// a window, and serial the serial of its enter event.
+	offer  *C.struct_wl_data_offer
+	target *window
+	serial C.uint32_t
+	// pos is the drag position over target.
+	pos f32.Point
+	// mime is the MIME type accepted at pos, and wlMime the offered
+	// type it was converted from.
+	mime, wlMime string
+
+	// source is the drag started from origin.
+	source *C.struct_wl_data_source
+	origin *window
+	// dropped tracks whether the source was dropped on another client,
+	// and internal whether it was dropped back on origin.
+	dropped, internal bool
+	// requests are the transfers waiting for OfferDragData.
+	requests []wlDragRequest
+	// offers are the data offered by OfferDragData.
+	offers map[string][]byte
+}
+
+type wlDragRequest struct {
+	mime string
+	fd   int
+}
+
+// wlDrop is the data of a drop, read from its offer.
+type wlDrop struct {
+	offer *C.struct_wl_data_offer
+	event input.DropEvent
+	ok    bool
+}
+
+// wlTextTypes are the MIME types of text, in order of preference.
+var wlTextTypes = append([]string{"text/plain;charset=utf-8"}, clipboardMimeTypes...)
+
+// dropTypes returns the MIME types of an offer the window may accept,
+// with the offered types they convert from.
+func (s *wlSeat) dropTypes(offer *C.struct_wl_data_offer) (types, wlTypes []string) {
+	offered := s.offers[offer]
+loop:
+	for _, t := range wlTextTypes {
+		for _, t2 := range offered {
+			if t == t2 {
+				types = append(types, "application/text")
+				wlTypes = append(wlTypes, t)
+				break loop
+			}
+		}
+	}
+	for _, t := range offered {
+		if strings.Contains(t, "/") && !strings.HasPrefix(t, "text/plain") {
+			types = append(types, t)
+			wlTypes = append(wlTypes, t)
+		}
+	}
+	return types, wlTypes
+}
+
+// dragTypes returns the offered types of a drag source with MIME types.
+func dragTypes(types []string) []string {
+	var offered []string
+	for _, t := range types {
+		if t == "application/text" {
+			offered = append(offered, wlTextTypes...)
+			continue
+		}
+		offered = append(offered, t)
+	}
+	return offered
+}
+
+// dragMIME converts an offered type to its MIME type.
+func dragMIME(wlType string) string {
+	for _, t := range wlTextTypes {
+		if t == wlType {
+			return "application/text"
+		}
+	}
+	return wlType
+}
+
+func (s *wlSeat) dragEnter(serial C.uint32_t, surf *C.struct_wl_surface, x, y C.wl_fixed_t, offer *C.struct_wl_data_offer) {
+	d := &s.drag
+	d.offer = offer
+	d.target = callbackLoad(unsafe.Pointer(surf)).(*window)
+	d.serial = serial
+	d.mime, d.wlMime = "", ""
+	s.flushOffers()
+	s.dragMotion(x, y)
+}
+
+func (s *wlSeat) dragMotion(x, y C.wl_fixed_t) {
+	d := &s.drag
+	w := d.target
+	if w == nil || d.offer == nil {
+		return
+	}
+	d.pos = f32.Point{
+		X: fromFixed(x) * float32(w.scale),
+		Y: fromFixed(y) * float32(w.scale),
+	}
+	if d.source != nil && w == d.origin {
+		// The drag is back over its window, which routes it like
+		// any other pointer motion.
+		w.lastPos = d.pos
+		w.w.Event(pointer.Event{
+			Kind:      pointer.Move,
+			Source:    pointer.Mouse,
+			Buttons:   w.pointerBtns,
+			Position:  d.pos,
+			Modifiers: w.disp.xkb.Modifiers(),
+		})
+	}
+	types, wlTypes := s.dropTypes(d.offer)
+	mime, wlMime := "", ""
+	if t, ok := w.w.DropTarget(d.pos, types); ok {
+		for i, t2 := range types {
+			if t == t2 {
+				mime, wlMime = t, wlTypes[i]
+				break
+			}
+		}
+	}
+	if wlMime == d.wlMime {
+		return
+	}
+	d.mime, d.wlMime = mime, wlMime
+	if wlMime == "" {
+		C.wl_data_offer_accept(d.offer, d.serial, nil)
+		C.wl_data_offer_set_actions(d.offer, C.WL_DATA_DEVICE_MANAGER_DND_ACTION_NONE, C.WL_DATA_DEVICE_MANAGER_DND_ACTION_NONE)
+		return
+	}
+	cmime := C.CString(wlMime)
+	defer C.free(unsafe.Pointer(cmime))
+	C.wl_data_offer_accept(d.offer, d.serial, cmime)
+	C.wl_data_offer_set_actions(d.offer, C.WL_DATA_DEVICE_MANAGER_DND_ACTION_COPY, C.WL_DATA_DEVICE_MANAGER_DND_ACTION_COPY)
+}
+
+func (s *wlSeat) dragLeave() {
+	d := &s.drag
+	d.offer = nil
+	d.target = nil
+	d.mime, d.wlMime = "", ""
+	s.flushOffers()
+}
+
+// dragDrop receives the accepted type of the dropped offer.
+func (s *wlSeat) dragDrop() {
+	d := &s.drag
+	w, offer, mime, wlMime, pos := d.target, d.offer, d.mime, d.wlMime, d.pos
+	if w == nil || offer == nil {
+		return
+	}
+	// Keep the offer from flushOffers until the transfer completes.
+	delete(s.offers, offer)
+	d.offer = nil
+	d.target = nil
+	d.mime, d.wlMime = "", ""
+	if d.source != nil && w == d.origin {
+		// The window routes the drop like any other pointer
+		// release.
+		d.internal = true
+		w.releaseDrag()
+		if wlMime != "" {
+			C.wl_data_offer_finish(offer)
+		}
+		w.finishDrop(wlDrop{offer: offer})
+		return
+	}
+	if wlMime == "" {
+		w.finishDrop(wlDrop{offer: offer})
+		return
+	}
+	r, wp, err := os.Pipe()
+	if err != nil {
+		w.finishDrop(wlDrop{offer: offer})
+		return
+	}
+	cmime := C.CString(wlMime)
+	defer C.free(unsafe.Pointer(cmime))
+	C.wl_data_offer_receive(offer, cmime, C.int(wp.Fd()))
+	// wl_data_offer_receive duplicates the write end.
+	wp.Close()
+	// Don't let slow transfers block the event loop.
+	go func() {
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		w.drops <- wlDrop{
+			offer: offer,
+			event: input.DropEvent{
+				Position: pos,
+				Type:     mime,
+				Open: func() io.ReadCloser {
+					return io.NopCloser(bytes.NewReader(data))
+				},
+			},
+			ok: err == nil,
+		}
+		w.Wakeup()
+	}()
+}
+
+// finishDrop delivers the data of a drop and completes it.
+func (w *window) finishDrop(d wlDrop) {
+	if d.ok {
+		w.w.Event(d.event)
+		C.wl_data_offer_finish(d.offer)
+	}
+	callbackDelete(unsafe.Pointer(d.offer))
+	C.wl_data_offer_destroy(d.offer)
+}
+
+func (w *window) SetDragSource(types []string) {
+	w.dragTypes = types
+}
+
+// startDrag hands the drag of the window over to the compositor when
+// the pointer leaves the window, to let other clients accept it.
+func (w *window) startDrag() {
+	s := w.disp.seat
+	if s == nil || s.dataDev == nil || w.disp.dataDeviceManager == nil || s.drag.source != nil {
+		return
+	}
+	src := C.wl_data_device_manager_create_data_source(w.disp.dataDeviceManager)
+	C.wl_data_source_add_listener(src, &C.gio_data_source_listener, unsafe.Pointer(s.seat))
+	for _, t := range dragTypes(w.dragTypes) {
+		ct := C.CString(t)
+		C.wl_data_source_offer(src, ct)
+		C.free(unsafe.Pointer(ct))
+	}
+	C.wl_data_source_set_actions(src, C.WL_DATA_DEVICE_MANAGER_DND_ACTION_COPY)
+	C.wl_data_device_start_drag(s.dataDev, src, w.surf, nil, s.pressSerial)
+	s.drag.source = src
+	s.drag.origin = w
+	s.drag.dropped = false
+	s.drag.internal = false
+	s.drag.requests = nil
+	s.drag.offers = make(map[string][]byte)
+}
+
+// releaseDrag releases the pointer button held by a drag taken over by
+// the compositor.
+func (w *window) releaseDrag() {
+	w.pointerBtns = 0
+	w.w.Event(pointer.Event{
+		Kind:      pointer.Release,
+		Source:    pointer.Mouse,
+		Position:  w.lastPos,
+		Time:      time.Duration(time.Now().UnixMilli()) * time.Millisecond,
+		Modifiers: w.disp.xkb.Modifiers(),
+	})
+}
+
+// dragDropPerformed handles the drop of the drag source.
+func (s *wlSeat) dragDropPerformed() {
+	d := &s.drag
+	if d.internal || d.target == d.origin && d.offer != nil {
+		// Dropped on the window; dragDrop delivers it.
+		return
+	}
+	d.dropped = true
+	w := d.origin
+	w.w.Event(input.ExternalDropEvent{})
+	w.releaseDrag()
+}
+
+// dragSend requests the data of the dropped drag source for a client.
+func (s *wlSeat) dragSend(wlType string, fd int) {
+	d := &s.drag
+	mime := dragMIME(wlType)
+	if data, ok := d.offers[mime]; ok {
+		go writeDragData(fd, data)
+		return
+	}
+	if !d.dropped {
+		syscall.Close(fd)
+		return
+	}
+	d.requests = append(d.requests, wlDragRequest{mime: mime, fd: fd})
+	d.origin.w.Event(input.ExternalRequestEvent{Type: mime})
+}
+
+func (w *window) OfferDragData(mime string, data []byte) {
+	s := w.disp.seat
+	if s == nil || s.drag.origin != w || !s.drag.dropped {
+		return
+	}
+	d := &s.drag
+	d.offers[mime] = data
+	requests := d.requests
+	d.requests = nil
+	for _, r := range requests {
+		if r.mime != mime {
+			d.requests = append(d.requests, r)
+			continue
+		}
+		go writeDragData(r.fd, data)
+	}
+}
+
+func writeDragData(fd int, data []byte) {
+	defer syscall.Close(fd)
+	for len(data) > 0 {
+		n, err := syscall.Write(fd, data)
+		if err != nil {
+			return
+		}
+		data = data[n:]
+	}
+}
+
+// endDrag ends the drag source, after it was cancelled or finished.
+func (s *wlSeat) endDrag() {
+	d := &s.drag
+	w := d.origin
+	switch {
+	case d.internal:
+		// The window delivered the drop.
+	case d.dropped:
+		w.w.Event(input.ExternalDoneEvent{})
+	default:
+		w.releaseDrag()
+	}
+	for _, r := range d.requests {
+		syscall.Close(r.fd)
+	}
+	d.source = nil
+	d.origin = nil
+	d.requests = nil
+	d.offers = nil
+}
+
+// abandonDrag destroys the drag source of a closed window.
+func (s *wlSeat) abandonDrag() {
+	d := &s.drag
+	for _, r := range d.requests {
+		syscall.Close(r.fd)
+	}
+	C.wl_data_source_destroy(d.source)
+	d.source = nil
+	d.origin = nil
+	d.requests = nil
+	d.offers = nil
+}
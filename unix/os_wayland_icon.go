@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+/*
+#include <wayland-client.h>
+#include "wayland_xdg_shell.h"
+#include "wayland_xdg_toplevel_icon.h"
+*/
+import "C"
+import (
+	"image"
+	"image/draw"
+)
+
+// wlIcon is the window icon set through xdg-toplevel-icon-v1. The
+// buffers must outlive the icon object.
+type wlIcon struct {
+	icon *C.struct_xdg_toplevel_icon_v1
+	bufs []*C.struct_wl_buffer
+}
+
+// setIcon replaces the toplevel icon with the images of icon. Without
+// compositor support the icon is left to the compositor, which usually
+// derives it from the desktop entry matching the application id.
+func (w *window) setIcon(icon []*image.NRGBA) {
+	mgr := w.disp.iconManager
+	if mgr == nil || w.topLvl == nil {
+		return
+	}
+	w.destroyIcon()
+	if len(icon) == 0 {
+		C.xdg_toplevel_icon_manager_v1_set_icon(mgr, w.topLvl, nil)
+		return
+	}
+	w.icon.icon = C.xdg_toplevel_icon_manager_v1_create_icon(mgr)
+	for _, img := range icon {
+		img = squareIcon(img)
+		buf, err := w.disp.newShmBuffer(img.Rect.Size(), nrgbaARGB(img, true))
+		if err != nil {
+			continue
+		}
+		w.icon.bufs = append(w.icon.bufs, buf)
+		C.xdg_toplevel_icon_v1_add_buffer(w.icon.icon, buf, 1)
+	}
+	C.xdg_toplevel_icon_manager_v1_set_icon(mgr, w.topLvl, w.icon.icon)
+}
+
+func (w *window) destroyIcon() {
+	if w.icon.icon != nil {
+		C.xdg_toplevel_icon_v1_destroy(w.icon.icon)
+	}
+	for _, buf := range w.icon.bufs {
+		C.wl_buffer_destroy(buf)
+	}
+	w.icon = wlIcon{}
+}
+
+// squareIcon returns img centered in a transparent square image, because
+// xdg-toplevel-icon-v1 only accepts square buffers.
+func squareIcon(img *image.NRGBA) *image.NRGBA {
+	sz := img.Rect.Size()
+	if sz.X == sz.Y {
+		return img
+	}
+	side := max(sz.X, sz.Y)
+	sq := image.NewNRGBA(image.Rect(0, 0, side, side))
+	off := image.Pt(side-sz.X, side-sz.Y).Div(2)
+	draw.Draw(sq, image.Rectangle{Min: off, Max: off.Add(sz)}, img, img.Rect.Min, draw.Src)
+	return sq
+}
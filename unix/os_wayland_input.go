@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+/*
+#include <wayland-client.h>
+#include "wayland_relative_pointer.h"
+#include "wayland_pointer_constraints.h"
+
+extern const struct zwp_relative_pointer_v1_listener gio_zwp_relative_pointer_v1_listener;
+extern const struct zwp_locked_pointer_v1_listener gio_zwp_locked_pointer_v1_listener;
+extern const struct zwp_confined_pointer_v1_listener gio_zwp_confined_pointer_v1_listener;
+extern const struct wl_registry_listener gio_probe_registry_listener;
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/pointer"
+)
+
+// wlInput is the cursor mode state of a window.
+type wlInput struct {
+	mode mado.InputMode
+	// virtualPos is the cursor position reported while the cursor is
+	// disabled.
+	virtualPos f32.Point
+	// locked is the pointer lock of the disabled mode.
+	locked *C.struct_zwp_locked_pointer_v1
+	// relative reports the pointer motion while it is locked.
+	relative *C.struct_zwp_relative_pointer_v1
+	// confined is the pointer confinement of the captured mode.
+	confined *C.struct_zwp_confined_pointer_v1
+}
+
+func init() {
+	wlRawMotionDriver = wlRawMouseMotionSupported
+}
+
+// wlRawMouseMotionSupported reports whether the compositor supports
+// unaccelerated relative pointer motion.
+func wlRawMouseMotionSupported() (bool, error) {
+	globals, err := wlProbeGlobals()
+	if err != nil {
+		return false, err
+	}
+	return globals["zwp_relative_pointer_manager_v1"] && globals["zwp_pointer_constraints_v1"], nil
+}
+
+var wlProbeMap sync.Map // map[unsafe.Pointer]map[string]bool
+
+// wlProbeGlobals lists the global interfaces of the compositor, from a
+// short-lived connection of its own.
+func wlProbeGlobals() (map[string]bool, error) {
+	disp, err := C.wl_display_connect(nil)
+	if disp == nil {
+		return nil, fmt.Errorf("wayland: wl_display_connect failed: %v", err)
+	}
+	defer C.wl_display_disconnect(disp)
+	reg := C.wl_display_get_registry(disp)
+	if reg == nil {
+		return nil, errors.New("wayland: wl_display_get_registry failed")
+	}
+	defer C.wl_registry_destroy(reg)
+	globals := make(map[string]bool)
+	wlProbeMap.Store(unsafe.Pointer(disp), globals)
+	defer wlProbeMap.Delete(unsafe.Pointer(disp))
+	C.wl_registry_add_listener(reg, &C.gio_probe_registry_listener, unsafe.Pointer(disp))
+	C.wl_display_roundtrip(disp)
+	return globals, nil
+}
+
+//export gio_onProbeRegistryGlobal
+func gio_onProbeRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C.uint32_t, cintf *C.char, version C.uint32_t) {
+	if globals, ok := wlProbeMap.Load(data); ok {
+		globals.(map[string]bool)[C.GoString(cintf)] = true
+	}
+}
+
+func (w *window) SetInputMode(mode mado.InputMode) {
+	if mode.Cursor == mado.CursorModeDisabled && w.input.mode.Cursor != mado.CursorModeDisabled {
+		// Start the virtual cursor where the real cursor is.
+		w.input.virtualPos = w.lastPos
+	}
+	w.input.mode = mode
+	w.disp.xkb.LockKeyMods = mode.LockKeyMods
+	w.updateInputMode()
+	w.updateCursor()
+}
+
+// SetCursorPos moves the virtual cursor of the disabled mode. Wayland
+// clients can't move the cursor otherwise.
+func (w *window) SetCursorPos(pos f32.Point) {
+	if w.input.mode.Cursor != mado.CursorModeDisabled {
+		return
+	}
+	w.input.virtualPos = pos
+	if w.input.locked != nil {
+		// Leave the cursor at pos when the lock is released.
+		scale := float32(w.scale)
+		C.zwp_locked_pointer_v1_set_cursor_position_hint(w.input.locked, toFixed(pos.X/scale), toFixed(pos.Y/scale))
+		C.wl_surface_commit(w.surf)
+	}
+}
+
+// updateInputMode locks or confines the pointer according to the input
+// mode. The constraints are persistent and re-activated by the
+// compositor whenever the window regains focus.
+func (w *window) updateInputMode() {
+	var ptr *C.struct_wl_pointer
+	if s := w.disp.seat; s != nil {
+		ptr = s.pointer
+	}
+	constraints := w.disp.constraints
+	// A locked pointer only reports relative motion. Without it, the
+	// disabled mode falls back to the hidden absolute pointer.
+	relPointer := w.disp.relPointer
+	lock := ptr != nil && constraints != nil && relPointer != nil && w.input.mode.Cursor == mado.CursorModeDisabled
+	confine := ptr != nil && constraints != nil && w.input.mode.Cursor == mado.CursorModeCaptured
+	if !lock {
+		w.unlockPointer()
+	}
+	if !confine && w.input.confined != nil {
+		C.zwp_confined_pointer_v1_destroy(w.input.confined)
+		w.input.confined = nil
+	}
+	switch {
+	case lock && w.input.locked == nil:
+		w.input.locked = C.zwp_pointer_constraints_v1_lock_pointer(constraints, w.surf, ptr, nil, C.ZWP_POINTER_CONSTRAINTS_V1_LIFETIME_PERSISTENT)
+		C.zwp_locked_pointer_v1_add_listener(w.input.locked, &C.gio_zwp_locked_pointer_v1_listener, unsafe.Pointer(w.surf))
+		w.input.relative = C.zwp_relative_pointer_manager_v1_get_relative_pointer(relPointer, ptr)
+		C.zwp_relative_pointer_v1_add_listener(w.input.relative, &C.gio_zwp_relative_pointer_v1_listener, unsafe.Pointer(w.surf))
+	case confine && w.input.confined == nil:
+		w.input.confined = C.zwp_pointer_constraints_v1_confine_pointer(constraints, w.surf, ptr, nil, C.ZWP_POINTER_CONSTRAINTS_V1_LIFETIME_PERSISTENT)
+		C.zwp_confined_pointer_v1_add_listener(w.input.confined, &C.gio_zwp_confined_pointer_v1_listener, unsafe.Pointer(w.surf))
+	}
+}
+
+func (w *window) unlockPointer() {
+	if w.input.relative != nil {
+		C.zwp_relative_pointer_v1_destroy(w.input.relative)
+		w.input.relative = nil
+	}
+	if w.input.locked != nil {
+		C.zwp_locked_pointer_v1_destroy(w.input.locked)
+		w.input.locked = nil
+	}
+}
+
+func (w *window) destroyInput() {
+	w.unlockPointer()
+	if w.input.confined != nil {
+		C.zwp_confined_pointer_v1_destroy(w.input.confined)
+		w.input.confined = nil
+	}
+}
+
+// cursorHidden reports whether the input mode hides the cursor.
+func (w *window) cursorHidden() bool {
+	switch w.input.mode.Cursor {
+	case mado.CursorModeHidden, mado.CursorModeDisabled:
+		return true
+	}
+	return false
+}
+
+// pointerPos returns the position to report for pointer events.
+func (w *window) pointerPos() f32.Point {
+	if w.input.mode.Cursor == mado.CursorModeDisabled {
+		return w.input.virtualPos
+	}
+	return w.lastPos
+}
+
+//export gio_onRelativePointerMotion
+func gio_onRelativePointerMotion(data unsafe.Pointer, p *C.struct_zwp_relative_pointer_v1, utimeHi, utimeLo C.uint32_t, dx, dy, dxUnaccel, dyUnaccel C.wl_fixed_t) {
+	w := callbackLoad(data).(*window)
+	if w.input.mode.Cursor != mado.CursorModeDisabled {
+		return
+	}
+	d := f32.Point{X: fromFixed(dx), Y: fromFixed(dy)}
+	if w.input.mode.RawMouseMotion {
+		d = f32.Point{X: fromFixed(dxUnaccel), Y: fromFixed(dyUnaccel)}
+	}
+	if d == (f32.Point{}) {
+		return
+	}
+	w.flushScroll()
+	w.input.virtualPos = w.input.virtualPos.Add(d.Mul(float32(w.scale)))
+	utime := uint64(utimeHi)<<32 | uint64(utimeLo)
+	w.w.Event(pointer.Event{
+		Kind:      pointer.Move,
+		Position:  w.input.virtualPos,
+		Buttons:   w.pointerBtns,
+		Source:    pointer.Mouse,
+		Time:      time.Duration(utime) * time.Microsecond,
+		Modifiers: w.disp.xkb.Modifiers(),
+	})
+}
+
+// toFixed converts a float32 to a Wayland wl_fixed_t 23.8 number.
+func toFixed(v float32) C.wl_fixed_t {
+	return C.wl_fixed_t(v * 256)
+}
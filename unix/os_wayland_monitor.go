@@ -0,0 +1,380 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+/*
+#include <stdlib.h>
+#include <wayland-client.h>
+#include "wayland_xdg_output.h"
+
+extern const struct wl_registry_listener gio_monitor_registry_listener;
+extern const struct wl_output_listener gio_monitor_output_listener;
+extern const struct zxdg_output_v1_listener gio_monitor_xdg_output_listener;
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+)
+
+// wlMonitors tracks the wl_outputs of a Wayland display connection of
+// its own, because monitors are needed before and independently of any
+// window.
+type wlMonitors struct {
+	disp    *C.struct_wl_display
+	reg     *C.struct_wl_registry
+	update  func([]mado.Monitor)
+	outputs map[C.uint32_t]*wlMonitor
+	// xdgOutputs provides the logical output geometry, if the
+	// compositor supports it.
+	xdgOutputs *C.struct_zxdg_output_manager_v1
+	// ready is set when the initial output configurations are known.
+	ready bool
+	// serial orders the outputs by their announcement.
+	serial int
+}
+
+// wlMonitor is the state of a single wl_output.
+type wlMonitor struct {
+	output     *C.struct_wl_output
+	name       C.uint32_t
+	serial     int
+	x, y       int
+	physWidth  int
+	physHeight int
+	transform  C.int32_t
+	scale      int
+	desc       string
+	mode       mado.VideoMode
+	modes      []mado.VideoMode
+	// done is set once the output sent its first done event.
+	done bool
+
+	// xdg is the xdg_output of the output. It describes the area of
+	// the output in the compositor space, which accounts for
+	// fractional scales and transforms.
+	xdg *C.struct_zxdg_output_v1
+	// logical is the area reported by xdg, if any.
+	logical image.Rectangle
+	// xdgDesc is the description reported by xdg, if any.
+	xdgDesc string
+}
+
+var wlMonitorMap sync.Map // map[unsafe.Pointer]*wlMonitors
+
+func init() {
+	wlMonitorDriver = newWLMonitors
+}
+
+func newWLMonitors(update func([]mado.Monitor)) error {
+	disp, err := C.wl_display_connect(nil)
+	if disp == nil {
+		return fmt.Errorf("wayland: wl_display_connect failed: %v", err)
+	}
+	m := &wlMonitors{
+		disp:    disp,
+		update:  update,
+		outputs: make(map[C.uint32_t]*wlMonitor),
+	}
+	wlMonitorMap.Store(unsafe.Pointer(disp), m)
+	m.reg = C.wl_display_get_registry(disp)
+	if m.reg == nil {
+		m.destroy()
+		return errors.New("wayland: wl_display_get_registry failed")
+	}
+	C.wl_registry_add_listener(m.reg, &C.gio_monitor_registry_listener, unsafe.Pointer(disp))
+	// The first roundtrip announces the outputs, the second their
+	// configurations.
+	C.wl_display_roundtrip(disp)
+	C.wl_display_roundtrip(disp)
+	m.ready = true
+	update(m.monitors())
+	go m.loop()
+	return nil
+}
+
+func wlMonitorsLoad(data unsafe.Pointer) *wlMonitors {
+	m, _ := wlMonitorMap.Load(data)
+	return m.(*wlMonitors)
+}
+
+func (m *wlMonitors) loop() {
+	for C.wl_display_dispatch(m.disp) != -1 {
+	}
+	// The connection is gone; so are the monitors.
+	m.update(nil)
+	m.destroy()
+}
+
+func (m *wlMonitors) destroy() {
+	for _, o := range m.outputs {
+		o.destroy()
+	}
+	m.outputs = nil
+	if m.xdgOutputs != nil {
+		C.zxdg_output_manager_v1_destroy(m.xdgOutputs)
+		m.xdgOutputs = nil
+	}
+	if m.reg != nil {
+		C.wl_registry_destroy(m.reg)
+		m.reg = nil
+	}
+	C.wl_display_disconnect(m.disp)
+	wlMonitorMap.Delete(unsafe.Pointer(m.disp))
+}
+
+// changed reports the new monitor list, once the initial configuration
+// is known.
+func (m *wlMonitors) changed() {
+	if m.ready {
+		m.update(m.monitors())
+	}
+}
+
+// monitors returns the configured outputs in the order the compositor
+// announced them. Wayland has no notion of a primary output; the first
+// one is reported as primary.
+func (m *wlMonitors) monitors() []mado.Monitor {
+	var outputs []*wlMonitor
+	for _, o := range m.outputs {
+		if o.done {
+			outputs = append(outputs, o)
+		}
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].serial < outputs[j].serial
+	})
+	list := make([]mado.Monitor, 0, len(outputs))
+	for i, o := range outputs {
+		list = append(list, o.monitor(i == 0))
+	}
+	return list
+}
+
+// monitor describes the output. Sizes are rotated to match the output
+// transform.
+func (o *wlMonitor) monitor(primary bool) mado.Monitor {
+	scale := o.scale
+	if scale < 1 {
+		scale = 1
+	}
+	// Odd transforms rotate the output by 90 or 270 degrees.
+	rotated := o.transform&1 != 0
+	rotate := func(p image.Point) image.Point {
+		if rotated {
+			p.X, p.Y = p.Y, p.X
+		}
+		return p
+	}
+	mode := o.mode
+	mode.Size = rotate(mode.Size)
+	modes := make([]mado.VideoMode, len(o.modes))
+	for i, vm := range o.modes {
+		vm.Size = rotate(vm.Size)
+		modes[i] = vm
+	}
+	mado.SortVideoModes(modes)
+	// Without xdg_output, assume the compositor scales the output by
+	// its integer scale.
+	bounds := o.logical
+	if bounds.Empty() {
+		bounds = image.Rectangle{
+			Min: image.Pt(o.x, o.y),
+			Max: image.Pt(o.x, o.y).Add(mode.Size.Div(scale)),
+		}
+	}
+	name := o.xdgDesc
+	if name == "" {
+		name = o.desc
+	}
+	return mado.Monitor{
+		ID:           uint64(o.name),
+		Name:         name,
+		Primary:      primary,
+		Bounds:       bounds,
+		WorkArea:     bounds,
+		PhysicalSize: rotate(image.Pt(o.physWidth, o.physHeight)),
+		Scale:        float32(scale),
+		Mode:         mode,
+		Modes:        modes,
+	}
+}
+
+func (o *wlMonitor) destroy() {
+	if o.xdg != nil {
+		C.zxdg_output_v1_destroy(o.xdg)
+		o.xdg = nil
+	}
+	C.wl_output_destroy(o.output)
+}
+
+// bindXdgOutput creates the xdg_output of o, if the compositor
+// supports it.
+func (m *wlMonitors) bindXdgOutput(o *wlMonitor) {
+	if m.xdgOutputs == nil || o.xdg != nil {
+		return
+	}
+	o.xdg = C.zxdg_output_manager_v1_get_xdg_output(m.xdgOutputs, o.output)
+	C.zxdg_output_v1_add_listener(o.xdg, &C.gio_monitor_xdg_output_listener, unsafe.Pointer(m.disp))
+}
+
+//export gio_onMonitorRegistryGlobal
+func gio_onMonitorRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C.uint32_t, cintf *C.char, version C.uint32_t) {
+	m := wlMonitorsLoad(data)
+	switch C.GoString(cintf) {
+	case "wl_output":
+		output := (*C.struct_wl_output)(C.wl_registry_bind(reg, name, &C.wl_output_interface, 2))
+		m.serial++
+		o := &wlMonitor{
+			output: output,
+			name:   name,
+			serial: m.serial,
+			scale:  1,
+		}
+		m.outputs[name] = o
+		C.wl_output_add_listener(output, &C.gio_monitor_output_listener, data)
+		m.bindXdgOutput(o)
+	case "zxdg_output_manager_v1":
+		// Version 2 adds the output description.
+		m.xdgOutputs = (*C.struct_zxdg_output_manager_v1)(C.wl_registry_bind(reg, name, &C.zxdg_output_manager_v1_interface, min(version, 3)))
+		for _, o := range m.outputs {
+			m.bindXdgOutput(o)
+		}
+	}
+}
+
+//export gio_onMonitorRegistryGlobalRemove
+func gio_onMonitorRegistryGlobalRemove(data unsafe.Pointer, reg *C.struct_wl_registry, name C.uint32_t) {
+	m := wlMonitorsLoad(data)
+	o, exists := m.outputs[name]
+	if !exists {
+		return
+	}
+	o.destroy()
+	delete(m.outputs, name)
+	m.changed()
+}
+
+func (m *wlMonitors) lookup(output *C.struct_wl_output) *wlMonitor {
+	for _, o := range m.outputs {
+		if o.output == output {
+			return o
+		}
+	}
+	return nil
+}
+
+//export gio_onMonitorOutputGeometry
+func gio_onMonitorOutputGeometry(data unsafe.Pointer, output *C.struct_wl_output, x, y, physWidth, physHeight, subpixel C.int32_t, make, model *C.char, transform C.int32_t) {
+	o := wlMonitorsLoad(data).lookup(output)
+	if o == nil {
+		return
+	}
+	o.x, o.y = int(x), int(y)
+	o.physWidth, o.physHeight = int(physWidth), int(physHeight)
+	o.transform = transform
+	o.desc = strings.TrimSpace(C.GoString(make) + " " + C.GoString(model))
+}
+
+//export gio_onMonitorOutputMode
+func gio_onMonitorOutputMode(data unsafe.Pointer, output *C.struct_wl_output, flags C.uint32_t, width, height, refresh C.int32_t) {
+	o := wlMonitorsLoad(data).lookup(output)
+	if o == nil {
+		return
+	}
+	red, green, blue := mado.SplitBPP(24)
+	vm := mado.VideoMode{
+		Size:      image.Pt(int(width), int(height)),
+		RedBits:   red,
+		GreenBits: green,
+		BlueBits:  blue,
+		// The refresh rate is in mHz.
+		RefreshRate: int((refresh + 500) / 1000),
+	}
+	if !containsVideoMode(o.modes, vm) {
+		o.modes = append(o.modes, vm)
+	}
+	if flags&C.WL_OUTPUT_MODE_CURRENT != 0 {
+		o.mode = vm
+	}
+}
+
+//export gio_onMonitorOutputScale
+func gio_onMonitorOutputScale(data unsafe.Pointer, output *C.struct_wl_output, scale C.int32_t) {
+	o := wlMonitorsLoad(data).lookup(output)
+	if o == nil {
+		return
+	}
+	o.scale = int(scale)
+}
+
+//export gio_onMonitorOutputDone
+func gio_onMonitorOutputDone(data unsafe.Pointer, output *C.struct_wl_output) {
+	m := wlMonitorsLoad(data)
+	o := m.lookup(output)
+	if o == nil {
+		return
+	}
+	o.done = true
+	m.changed()
+}
+
+func (m *wlMonitors) lookupXdg(xdg *C.struct_zxdg_output_v1) *wlMonitor {
+	for _, o := range m.outputs {
+		if o.xdg == xdg {
+			return o
+		}
+	}
+	return nil
+}
+
+//export gio_onMonitorXdgOutputLogicalPosition
+func gio_onMonitorXdgOutputLogicalPosition(data unsafe.Pointer, xdg *C.struct_zxdg_output_v1, x, y C.int32_t) {
+	o := wlMonitorsLoad(data).lookupXdg(xdg)
+	if o == nil {
+		return
+	}
+	o.logical = o.logical.Sub(o.logical.Min).Add(image.Pt(int(x), int(y)))
+}
+
+//export gio_onMonitorXdgOutputLogicalSize
+func gio_onMonitorXdgOutputLogicalSize(data unsafe.Pointer, xdg *C.struct_zxdg_output_v1, width, height C.int32_t) {
+	o := wlMonitorsLoad(data).lookupXdg(xdg)
+	if o == nil {
+		return
+	}
+	o.logical.Max = o.logical.Min.Add(image.Pt(int(width), int(height)))
+}
+
+//export gio_onMonitorXdgOutputDone
+func gio_onMonitorXdgOutputDone(data unsafe.Pointer, xdg *C.struct_zxdg_output_v1) {
+	m := wlMonitorsLoad(data)
+	// From version 3, wl_output.done ends the xdg_output updates too.
+	if o := m.lookupXdg(xdg); o != nil && o.done && C.zxdg_output_v1_get_version(xdg) < 3 {
+		m.changed()
+	}
+}
+
+//export gio_onMonitorXdgOutputName
+func gio_onMonitorXdgOutputName(data unsafe.Pointer, xdg *C.struct_zxdg_output_v1, name *C.char) {
+}
+
+//export gio_onMonitorXdgOutputDescription
+func gio_onMonitorXdgOutputDescription(data unsafe.Pointer, xdg *C.struct_zxdg_output_v1, desc *C.char) {
+	o := wlMonitorsLoad(data).lookupXdg(xdg)
+	if o == nil {
+		return
+	}
+	o.xdgDesc = C.GoString(desc)
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+import (
+	"image"
+	"testing"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/io/key"
+)
+
+func editorState(snippet string, start, selStart, selEnd int) mado.EditorState {
+	var st mado.EditorState
+	st.Snippet = key.Snippet{
+		Range: key.Range{Start: start, End: start + len([]rune(snippet))},
+		Text:  snippet,
+	}
+	st.Selection.Range = key.Range{Start: selStart, End: selEnd}
+	st.Compose = key.Range{Start: -1, End: -1}
+	return st
+}
+
+func TestRunesAround(t *testing.T) {
+	// The snippet covers runes 10 to 15 of the editor.
+	st := editorState("aかbんc", 10, 0, 0)
+	tests := []struct {
+		pos, n        int
+		before, after int
+	}{
+		{12, 0, 0, 0},
+		{12, 4, 2, 2},
+		{12, 3, 1, 2},
+		{13, 4, 2, 2},
+		// Deletions are clamped to the snippet.
+		{12, 100, 2, 3},
+		{10, 5, 0, 3},
+		{15, 5, 3, 0},
+		// Positions outside the snippet delete nothing.
+		{5, 3, 0, 0},
+		{20, 3, 0, 0},
+	}
+	for _, test := range tests {
+		if got := runesBefore(st, test.pos, test.n); got != test.before {
+			t.Errorf("runesBefore(%d, %d) = %d, want %d", test.pos, test.n, got, test.before)
+		}
+		if got := runesAfter(st, test.pos, test.n); got != test.after {
+			t.Errorf("runesAfter(%d, %d) = %d, want %d", test.pos, test.n, got, test.after)
+		}
+	}
+}
+
+func TestSurroundingText(t *testing.T) {
+	st := editorState("aかbんc", 10, 13, 11)
+	text, cursor, anchor, ok := surroundingText(st)
+	if !ok {
+		t.Fatal("selection not in snippet")
+	}
+	if text != "aかbんc" || cursor != 5 || anchor != 1 {
+		t.Errorf("got %q, cursor %d, anchor %d; want %q, cursor 5, anchor 1", text, cursor, anchor, "aかbんc")
+	}
+	st = editorState("abc", 10, 2, 11)
+	if _, _, _, ok := surroundingText(st); ok {
+		t.Error("selection outside snippet reported")
+	}
+}
+
+func TestTextInputContentType(t *testing.T) {
+	const (
+		hintNone          = 0x0
+		hintCompletion    = 0x1
+		hintHiddenText    = 0x40
+		hintSensitiveData = 0x80
+
+		purposeNormal   = 0
+		purposeNumber   = 3
+		purposeEmail    = 6
+		purposePassword = 8
+	)
+	tests := []struct {
+		hint            key.InputHint
+		cHint, cPurpose uint32
+	}{
+		{key.HintAny, hintNone, purposeNormal},
+		{key.HintNumeric, hintNone, purposeNumber},
+		{key.HintEmail, hintCompletion, purposeEmail},
+		{key.HintPassword, hintHiddenText | hintSensitiveData, purposePassword},
+	}
+	for _, test := range tests {
+		h, p := textInputContentType(test.hint)
+		if uint32(h) != test.cHint || uint32(p) != test.cPurpose {
+			t.Errorf("textInputContentType(%v) = %#x, %d; want %#x, %d", test.hint, h, p, test.cHint, test.cPurpose)
+		}
+	}
+}
+
+func TestConstrainAspect(t *testing.T) {
+	w := &window{config: mado.Config{Mode: mado.Windowed, Decorated: true}}
+	tests := []struct {
+		ratio, size, want image.Point
+	}{
+		{image.Pt(0, 0), image.Pt(800, 600), image.Pt(800, 600)},
+		{image.Pt(16, 9), image.Pt(800, 600), image.Pt(800, 450)},
+		{image.Pt(16, 9), image.Pt(1600, 600), image.Pt(1066, 600)},
+		{image.Pt(1, 1), image.Pt(300, 300), image.Pt(300, 300)},
+	}
+	for _, test := range tests {
+		w.config.AspectRatio = test.ratio
+		if got := w.constrainAspect(test.size); got != test.want {
+			t.Errorf("constrainAspect(%v) with ratio %v = %v, want %v", test.size, test.ratio, got, test.want)
+		}
+	}
+	// The fallback decoration is not part of the content area.
+	w.config.Decorated = false
+	w.config.DecoHeight = 50
+	w.config.AspectRatio = image.Pt(1, 1)
+	if got, want := w.constrainAspect(image.Pt(400, 350)), image.Pt(300, 350); got != want {
+		t.Errorf("constrainAspect with decorations = %v, want %v", got, want)
+	}
+}
+
+func TestSquareIcon(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	sq := squareIcon(img)
+	if got, want := sq.Rect.Size(), image.Pt(4, 4); got != want {
+		t.Fatalf("squareIcon size = %v, want %v", got, want)
+	}
+	// The image is centered vertically between transparent rows.
+	for y, want := range []uint32{0, 0xffffffff, 0xffffffff, 0} {
+		if got := nrgbaARGB(sq, true)[y*4]; got != want {
+			t.Errorf("row %d = %#x, want %#x", y, got, want)
+		}
+	}
+	if squareIcon(sq) != sq {
+		t.Error("squareIcon copied a square image")
+	}
+}
+
+func TestClipboardReadUnsupported(t *testing.T) {
+	rec := new(eventRecorder)
+	w := &window{w: rec}
+	w.ReadClipboard("application/text", true)
+	checkEmptyRead(t, rec.events, "application/text", true)
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+#include <stdint.h>
+#include <stdlib.h>
+#include <wchar.h>
+#include <X11/Xlib.h>
+#include "_cgo_export.h"
+
+// The XIM callbacks below forward to Go. The client data of every
+// callback is the X window the input context belongs to.
+
+static int gio_x11PreeditStart(XIC xic, XPointer client_data, XPointer call_data) {
+	gio_onX11PreeditStart((uintptr_t)client_data);
+	// No limit on the length of the preedit string.
+	return -1;
+}
+
+static void gio_x11PreeditDone(XIC xic, XPointer client_data, XPointer call_data) {
+	gio_onX11PreeditDone((uintptr_t)client_data);
+}
+
+static void gio_x11PreeditDraw(XIC xic, XPointer client_data, XPointer call_data) {
+	XIMPreeditDrawCallbackStruct *d = (XIMPreeditDrawCallbackStruct *)call_data;
+	wchar_t *text = NULL, *decoded = NULL;
+	int length = 0, feedbackOnly = 0;
+	if (d->text != NULL) {
+		if (d->text->encoding_is_wchar) {
+			text = d->text->string.wide_char;
+			length = d->text->length;
+		} else if (d->text->string.multi_byte != NULL) {
+			// Multibyte text is in the encoding of the locale.
+			const char *mb = d->text->string.multi_byte;
+			size_t n = mbstowcs(NULL, mb, 0);
+			if (n != (size_t)-1) {
+				decoded = malloc((n + 1) * sizeof(wchar_t));
+				if (decoded != NULL) {
+					length = mbstowcs(decoded, mb, n + 1);
+					text = decoded;
+				}
+			}
+		}
+		// A NULL string with a non-NULL text means that only the
+		// feedback (highlighting) changed.
+		feedbackOnly = d->text->string.multi_byte == NULL;
+	}
+	gio_onX11PreeditDraw((uintptr_t)client_data, d->caret, d->chg_first, d->chg_length, text, length, feedbackOnly);
+	free(decoded);
+}
+
+static void gio_x11PreeditCaret(XIC xic, XPointer client_data, XPointer call_data) {
+	XIMPreeditCaretCallbackStruct *c = (XIMPreeditCaretCallbackStruct *)call_data;
+	c->position = gio_onX11PreeditCaret((uintptr_t)client_data, c->direction, c->position);
+}
+
+static void gio_x11IMInstantiate(Display *dpy, XPointer client_data, XPointer call_data) {
+	gio_onX11IMInstantiate((uintptr_t)client_data);
+}
+
+static void gio_x11IMDestroy(XIM xim, XPointer client_data, XPointer call_data) {
+	gio_onX11IMDestroy((uintptr_t)client_data);
+}
+
+void gio_x11RegisterIMInstantiate(Display *dpy, uintptr_t handle) {
+	XRegisterIMInstantiateCallback(dpy, NULL, NULL, NULL, gio_x11IMInstantiate, (XPointer)handle);
+}
+
+void gio_x11UnregisterIMInstantiate(Display *dpy, uintptr_t handle) {
+	XUnregisterIMInstantiateCallback(dpy, NULL, NULL, NULL, gio_x11IMInstantiate, (XPointer)handle);
+}
+
+XIM gio_x11OpenIM(Display *dpy, uintptr_t handle) {
+	XIM xim = XOpenIM(dpy, NULL, NULL, NULL);
+	if (xim == NULL) {
+		return NULL;
+	}
+	XIMCallback destroy = {(XPointer)handle, (XIMProc)gio_x11IMDestroy};
+	XSetIMValues(xim, XNDestroyCallback, &destroy, NULL);
+	return xim;
+}
+
+// gio_x11CreateIC creates an input context for win. It prefers on-the-spot
+// preedit through callbacks and falls back to root window preedit, where
+// the input method draws the preedit itself. The chosen style is stored in
+// style.
+XIC gio_x11CreateIC(Display *dpy, XIM xim, Window win, uintptr_t handle, XIMStyle *style) {
+	XIMStyles *styles = NULL;
+	if (XGetIMValues(xim, XNQueryInputStyle, &styles, NULL) != NULL || styles == NULL) {
+		return NULL;
+	}
+	const XIMStyle onTheSpot = XIMPreeditCallbacks | XIMStatusNothing;
+	const XIMStyle root = XIMPreeditNothing | XIMStatusNothing;
+	*style = 0;
+	for (int i = 0; i < styles->count_styles; i++) {
+		XIMStyle s = styles->supported_styles[i];
+		if (s == onTheSpot) {
+			*style = s;
+			break;
+		}
+		if (s == root) {
+			*style = s;
+		}
+	}
+	XFree(styles);
+
+	XIC xic = NULL;
+	if (*style == onTheSpot) {
+		XIMCallback start = {(XPointer)handle, (XIMProc)gio_x11PreeditStart};
+		XIMCallback done = {(XPointer)handle, (XIMProc)gio_x11PreeditDone};
+		XIMCallback draw = {(XPointer)handle, (XIMProc)gio_x11PreeditDraw};
+		XIMCallback caret = {(XPointer)handle, (XIMProc)gio_x11PreeditCaret};
+		XVaNestedList preedit = XVaCreateNestedList(0,
+			XNPreeditStartCallback, &start,
+			XNPreeditDoneCallback, &done,
+			XNPreeditDrawCallback, &draw,
+			XNPreeditCaretCallback, &caret,
+			NULL);
+		xic = XCreateIC(xim,
+			XNInputStyle, *style,
+			XNClientWindow, win,
+			XNFocusWindow, win,
+			XNPreeditAttributes, preedit,
+			NULL);
+		XFree(preedit);
+	} else if (*style == root) {
+		xic = XCreateIC(xim,
+			XNInputStyle, *style,
+			XNClientWindow, win,
+			XNFocusWindow, win,
+			NULL);
+	}
+	if (xic == NULL) {
+		return NULL;
+	}
+	// Make sure the window receives the events the input method needs.
+	unsigned long filter = 0;
+	if (XGetICValues(xic, XNFilterEvents, &filter, NULL) == NULL && filter != 0) {
+		XWindowAttributes attrs;
+		XGetWindowAttributes(dpy, win, &attrs);
+		XSelectInput(dpy, win, attrs.your_event_mask | filter);
+	}
+	return xic;
+}
+
+// gio_x11SetICSpot moves the input method candidate window to (x, y)
+// in window coordinates.
+void gio_x11SetICSpot(XIC xic, short x, short y) {
+	XPoint spot = {x, y};
+	XVaNestedList attrs = XVaCreateNestedList(0, XNSpotLocation, &spot, NULL);
+	XSetICValues(xic, XNPreeditAttributes, attrs, NULL);
+	XFree(attrs);
+}
+
+// gio_x11ResetIC discards any composition in progress.
+void gio_x11ResetIC(XIC xic) {
+	char *s = Xutf8ResetIC(xic);
+	if (s != NULL) {
+		XFree(s);
+	}
+}
@@ -21,6 +21,7 @@ package unix
 #include <X11/XKBlib.h>
 #include <X11/Xlib-xcb.h>
 #include <X11/extensions/Xfixes.h>
+#include <X11/extensions/shapeconst.h>
 #include <X11/Xcursor/Xcursor.h>
 #include <xkbcommon/xkbcommon-x11.h>
 
@@ -30,10 +31,10 @@ import (
 	"errors"
 	"fmt"
 	"image"
-	"io"
+	"slices"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -42,7 +43,6 @@ import (
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/io/system"
-	"github.com/kanryu/mado/io/transfer"
 	iowindow "github.com/kanryu/mado/io/window"
 	"github.com/kanryu/mado/unit"
 
@@ -56,18 +56,56 @@ const (
 	_NET_WM_STATE_ADD    = 1
 )
 
-type x11Window struct {
-	w            mado.Callbacks
+// x11Display is the X server connection shared by the X11 windows of
+// the program. The display and its event loop live from the creation of
+// the first window until the last window is closed.
+type x11Display struct {
 	x            *C.Display
 	xkb          *xkb.Context
 	xkbEventBase C.int
-	xw           C.Window
+	// Notification pipe fds.
+	notify struct {
+		read, write int
+	}
+
+	// windows are the open windows of the display, in creation order.
+	windows []*x11Window
+	// funcs are the functions waiting to run on the event loop, guarded
+	// by x11App.mu.
+	funcs []func()
+}
+
+var x11App struct {
+	mu   sync.Mutex
+	disp *x11Display
+}
+
+type x11Window struct {
+	w    mado.Callbacks
+	disp *x11Display
+	// x and xkb are the connection and keyboard state of disp.
+	x   *C.Display
+	xkb *xkb.Context
+	xw  C.Window
+	// parent is the window of a popup or transient window, and popup
+	// the kind of popup.
+	parent *x11Window
+	popup  mado.PopupKind
+	// dismissed tracks whether a popup was dismissed before closing,
+	// and grabbed whether it grabbed the pointer.
+	dismissed, grabbed bool
 
 	atoms struct {
 		// "UTF8_STRING".
 		utf8string C.Atom
 		// "text/plain;charset=utf-8".
 		plaintext C.Atom
+		// "text/plain".
+		textPlain C.Atom
+		// "TEXT".
+		text C.Atom
+		// "INCR", the type of incremental selection transfers.
+		incr C.Atom
 		// "TARGETS"
 		targets C.Atom
 		// "CLIPBOARD".
@@ -94,25 +132,53 @@ type x11Window struct {
 		wmStateMaximizedHorz C.Atom
 		// _NET_WM_STATE_MAXIMIZED_VERT
 		wmStateMaximizedVert C.Atom
+		// "_NET_WM_WINDOW_OPACITY"
+		wmWindowOpacity C.Atom
+		// "_NET_FRAME_EXTENTS"
+		frameExtents C.Atom
+		// "_NET_WM_ICON"
+		wmIcon C.Atom
+		// The XDND atoms: "XdndAware", "XdndSelection", "XdndTypeList",
+		// "XdndActionCopy" and the client messages.
+		xdndAware      C.Atom
+		xdndSelection  C.Atom
+		xdndTypeList   C.Atom
+		xdndActionCopy C.Atom
+		xdndEnter      C.Atom
+		xdndPosition   C.Atom
+		xdndStatus     C.Atom
+		xdndLeave      C.Atom
+		xdndDrop       C.Atom
+		xdndFinished   C.Atom
 	}
 	stage  mado.Stage
 	metric unit.Metric
-	notify struct {
-		read, write int
-	}
-	dead bool
+	dead   bool
+	// redraw tracks whether the window is exposed.
+	redraw bool
+	// exposed tracks whether the window lost the content presented by
+	// a framebuffer, which may present from another goroutine.
+	exposed atomic.Bool
 
 	animating bool
 
 	pointerBtns pointer.Buttons
 
-	clipboard struct {
-		content []byte
-	}
+	clipboard x11Clipboard
+	dnd       x11DnD
+
 	cursor pointer.Cursor
 	config mado.Config
 
-	prevWindowPos image.Point
+	// customCursor is the cursor set by SetCustomCursor, or 0.
+	customCursor C.Cursor
+	// customCursors caches the cursors created by SetCustomCursor.
+	customCursors map[*pointer.CustomCursor]C.Cursor
+	// fixesHidden tracks whether the cursor is hidden by XFixes.
+	fixesHidden bool
+
+	ime   x11IME
+	input x11Input
 
 	wakeups chan struct{}
 }
@@ -142,29 +208,30 @@ func (w *x11Window) NewContext() (mado.Context, error) {
 		}
 		firstErr = err
 	}
+	// Without a GPU, present frames drawn by the CPU.
+	c, err := newX11SoftwareContext(w)
+	if err == nil {
+		return c, nil
+	}
 	if firstErr != nil {
 		return nil, firstErr
 	}
-	return nil, errors.New("x11: no available GPU backends")
+	return nil, err
 }
 
-func (w *x11Window) SetAnimating(anim bool) {
-	w.animating = anim
-}
-
-func (w *x11Window) ReadClipboard() {
-	C.XDeleteProperty(w.x, w.xw, w.atoms.clipboardContent)
-	C.XConvertSelection(w.x, w.atoms.clipboard, w.atoms.utf8string, w.atoms.clipboardContent, w.xw, C.CurrentTime)
+func (w *x11Window) NewFramebuffer() (mado.Framebuffer, error) {
+	p, err := newX11Presenter(w)
+	if err != nil {
+		return mado.NewContextFramebuffer(w)
+	}
+	return &swFramebuffer{p: p}, nil
 }
 
-func (w *x11Window) WriteClipboard(mime string, s []byte) {
-	w.clipboard.content = s
-	C.XSetSelectionOwner(w.x, w.atoms.clipboard, w.xw, C.CurrentTime)
-	C.XSetSelectionOwner(w.x, w.atoms.primary, w.xw, C.CurrentTime)
+func (w *x11Window) SetAnimating(anim bool) {
+	w.animating = anim
 }
 
 func (w *x11Window) Configure(options []mado.Option) {
-	var shints C.XSizeHints
 	prev := w.config
 	cnf := w.config
 	cnf.Apply(w.metric, options)
@@ -219,28 +286,87 @@ func (w *x11Window) Configure(options []mado.Option) {
 			w.config.Size = cnf.Size
 			C.XResizeWindow(w.x, w.xw, C.uint(cnf.Size.X), C.uint(cnf.Size.Y))
 		}
-		if prev.MinSize != cnf.MinSize {
-			w.config.MinSize = cnf.MinSize
-			shints.min_width = C.int(cnf.MinSize.X)
-			shints.min_height = C.int(cnf.MinSize.Y)
-			shints.flags = C.PMinSize
+		if prev.Pos != cnf.Pos {
+			w.config.Pos = cnf.Pos
+			C.XMoveWindow(w.x, w.xw, C.int(cnf.Pos.X), C.int(cnf.Pos.Y))
 		}
-		if prev.MaxSize != cnf.MaxSize {
+		if prev.MinSize != cnf.MinSize || prev.MaxSize != cnf.MaxSize || prev.AspectRatio != cnf.AspectRatio {
+			w.config.MinSize = cnf.MinSize
 			w.config.MaxSize = cnf.MaxSize
-			shints.max_width = C.int(cnf.MaxSize.X)
-			shints.max_height = C.int(cnf.MaxSize.Y)
-			shints.flags = shints.flags | C.PMaxSize
-		}
-		if shints.flags != 0 {
-			C.XSetWMNormalHints(w.x, w.xw, &shints)
+			w.config.AspectRatio = cnf.AspectRatio
+			w.setSizeHints()
 		}
 	}
 	if cnf.Decorated != prev.Decorated {
 		w.config.Decorated = cnf.Decorated
 	}
+	if cnf.Opacity != prev.Opacity {
+		w.config.Opacity = cnf.Opacity
+		w.setOpacity(cnf.Opacity)
+	}
+	if iconChanged(prev.Icon, cnf.Icon) {
+		w.config.Icon = cnf.Icon
+		w.setIcon(cnf.Icon)
+	}
 	w.w.Event(mado.ConfigEvent{Config: w.config})
 }
 
+// setSizeHints replaces the WM_NORMAL_HINTS size constraints with those of
+// the window configuration.
+func (w *x11Window) setSizeHints() {
+	var shints C.XSizeHints
+	if sz := w.config.MinSize; sz != (image.Point{}) {
+		shints.min_width = C.int(sz.X)
+		shints.min_height = C.int(sz.Y)
+		shints.flags |= C.PMinSize
+	}
+	if sz := w.config.MaxSize; sz != (image.Point{}) {
+		shints.max_width = C.int(sz.X)
+		shints.max_height = C.int(sz.Y)
+		shints.flags |= C.PMaxSize
+	}
+	if r := w.config.AspectRatio; r != (image.Point{}) {
+		shints.min_aspect.x = C.int(r.X)
+		shints.min_aspect.y = C.int(r.Y)
+		shints.max_aspect = shints.min_aspect
+		shints.flags |= C.PAspect
+	}
+	C.XSetWMNormalHints(w.x, w.xw, &shints)
+}
+
+// setOpacity sets _NET_WM_WINDOW_OPACITY, or removes it for opaque
+// windows.
+func (w *x11Window) setOpacity(opacity float32) {
+	if opacity <= 0 || opacity >= 1 {
+		C.XDeleteProperty(w.x, w.xw, w.atoms.wmWindowOpacity)
+		return
+	}
+	// Format 32 properties are passed as longs.
+	v := C.ulong(float64(opacity) * 0xffffffff)
+	C.XChangeProperty(w.x, w.xw, w.atoms.wmWindowOpacity, C.XA_CARDINAL,
+		32, C.PropModeReplace, (*C.uchar)(unsafe.Pointer(&v)), 1)
+}
+
+// setIcon replaces the _NET_WM_ICON property with every image of icon.
+func (w *x11Window) setIcon(icon []*image.NRGBA) {
+	if len(icon) == 0 {
+		C.XDeleteProperty(w.x, w.xw, w.atoms.wmIcon)
+		return
+	}
+	// The property is a list of width, height and non-premultiplied ARGB
+	// pixels, one entry per image. Format 32 properties are passed as longs.
+	var data []C.ulong
+	for _, img := range icon {
+		sz := img.Rect.Size()
+		data = append(data, C.ulong(sz.X), C.ulong(sz.Y))
+		for _, p := range nrgbaARGB(img, false) {
+			data = append(data, C.ulong(p))
+		}
+	}
+	C.XChangeProperty(w.x, w.xw, w.atoms.wmIcon, C.XA_CARDINAL,
+		32, C.PropModeReplace, (*C.uchar)(unsafe.Pointer(&data[0])), C.int(len(data)))
+}
+
 func (w *x11Window) setTitle(prev, cnf mado.Config) {
 	if prev.Title != cnf.Title {
 		title := cnf.Title
@@ -264,8 +390,10 @@ func (w *x11Window) Perform(acts system.Action) {
 		switch a {
 		case system.ActionCenter:
 			w.center()
-		case system.ActionRaise:
+		case system.ActionRaise, system.ActionFocus:
 			w.raise()
+		case system.ActionRequestAttention:
+			w.setUrgency(true)
 		}
 	})
 	if acts&system.ActionClose != 0 {
@@ -273,6 +401,58 @@ func (w *x11Window) Perform(acts system.Action) {
 	}
 }
 
+// setUrgency sets or clears the XUrgencyHint of the WM_HINTS property.
+func (w *x11Window) setUrgency(urgent bool) {
+	hints := C.XGetWMHints(w.x, w.xw)
+	if hints == nil {
+		hints = C.XAllocWMHints()
+		if hints == nil {
+			return
+		}
+	}
+	defer C.XFree(unsafe.Pointer(hints))
+	if urgent == (hints.flags&C.XUrgencyHint != 0) {
+		return
+	}
+	if urgent {
+		hints.flags |= C.XUrgencyHint
+	} else {
+		hints.flags &^= C.XUrgencyHint
+	}
+	C.XSetWMHints(w.x, w.xw, hints)
+}
+
+// position returns the screen position of the window.
+func (w *x11Window) position() image.Point {
+	var x, y C.int
+	var child C.Window
+	C.XTranslateCoordinates(w.x, w.xw, C.XDefaultRootWindow(w.x), 0, 0, &x, &y, &child)
+	return image.Pt(int(x), int(y))
+}
+
+// GetFrameExtents reads the frame size the window manager publishes in
+// _NET_FRAME_EXTENTS.
+func (w *x11Window) GetFrameExtents() (left, top, right, bottom int) {
+	var (
+		actualType   C.Atom
+		actualFormat C.int
+		nitems       C.ulong
+		bytesAfter   C.ulong
+		data         *C.uchar
+	)
+	if C.XGetWindowProperty(w.x, w.xw, w.atoms.frameExtents, 0, 4, C.False, C.XA_CARDINAL,
+		&actualType, &actualFormat, &nitems, &bytesAfter, &data) != C.Success || data == nil {
+		return 0, 0, 0, 0
+	}
+	defer C.XFree(unsafe.Pointer(data))
+	if actualType != C.XA_CARDINAL || actualFormat != 32 || nitems < 4 {
+		return 0, 0, 0, 0
+	}
+	// Format 32 properties are returned as longs.
+	e := unsafe.Slice((*C.long)(unsafe.Pointer(data)), 4)
+	return int(e[0]), int(e[2]), int(e[1]), int(e[3])
+}
+
 func (w *x11Window) center() {
 	screen := C.XDefaultScreen(w.x)
 	width := C.XDisplayWidth(w.x, screen)
@@ -311,9 +491,15 @@ func (w *x11Window) raise() {
 }
 
 func (w *x11Window) SetCursor(cursor pointer.Cursor) {
+	if w.cursorHidden() || w.customCursor != 0 {
+		// Applied when the input mode shows the cursor again, or the
+		// custom cursor is removed.
+		w.cursor = cursor
+		return
+	}
+	w.hideCursor(cursor == pointer.CursorNone)
 	if cursor == pointer.CursorNone {
 		w.cursor = cursor
-		C.XFixesHideCursor(w.x, w.xw)
 		return
 	}
 
@@ -330,11 +516,105 @@ func (w *x11Window) SetCursor(cursor pointer.Cursor) {
 	C.XDefineCursor(w.x, w.xw, c)
 }
 
-func (w *x11Window) ShowTextInput(show bool) {}
+// hideCursor hides or shows the cursor while it is over the window.
+func (w *x11Window) hideCursor(hide bool) {
+	if hide == w.fixesHidden {
+		return
+	}
+	w.fixesHidden = hide
+	if hide {
+		C.XFixesHideCursor(w.x, w.xw)
+	} else {
+		C.XFixesShowCursor(w.x, w.xw)
+	}
+}
+
+func (w *x11Window) SetCustomCursor(c *pointer.CustomCursor) {
+	w.customCursor = 0
+	if c != nil {
+		w.customCursor = w.loadCustomCursor(c)
+	}
+	switch {
+	case w.cursorHidden():
+		// Applied when the input mode shows the cursor again.
+	case w.customCursor != 0:
+		w.hideCursor(false)
+		C.XDefineCursor(w.x, w.xw, w.customCursor)
+	default:
+		w.SetCursor(w.cursor)
+	}
+	C.XFlush(w.x)
+}
 
-func (w *x11Window) SetInputHint(_ key.InputHint) {}
+func (w *x11Window) ReleaseCustomCursor(c *pointer.CustomCursor) {
+	xc, ok := w.customCursors[c]
+	if !ok {
+		return
+	}
+	delete(w.customCursors, c)
+	if xc == 0 {
+		return
+	}
+	if xc == w.customCursor {
+		w.SetCustomCursor(nil)
+	}
+	C.XFreeCursor(w.x, xc)
+}
 
-func (w *x11Window) EditorStateChanged(old, new mado.EditorState) {}
+// loadCustomCursor returns the ARGB cursor for the image of c, creating
+// it on first use. It returns 0 if the server doesn't support ARGB
+// cursors.
+func (w *x11Window) loadCustomCursor(c *pointer.CustomCursor) C.Cursor {
+	if xc, ok := w.customCursors[c]; ok {
+		return xc
+	}
+	var xc C.Cursor
+	size := c.Size()
+	if img := C.XcursorImageCreate(C.int(size.X), C.int(size.Y)); img != nil {
+		img.xhot = C.XcursorDim(c.Hotspot.X)
+		img.yhot = C.XcursorDim(c.Hotspot.Y)
+		pix := unsafe.Slice(img.pixels, size.X*size.Y)
+		for i, p := range cursorARGB(c) {
+			pix[i] = C.XcursorPixel(p)
+		}
+		xc = C.XcursorImageLoadCursor(w.x, img)
+		C.XcursorImageDestroy(img)
+	}
+	if w.customCursors == nil {
+		w.customCursors = make(map[*pointer.CustomCursor]C.Cursor)
+	}
+	w.customCursors[c] = xc
+	return xc
+}
+
+func (w *x11Window) ShowTextInput(show bool) {
+	if w.ime.show == show {
+		return
+	}
+	w.ime.show = show
+	w.updateICFocus()
+}
+
+func (w *x11Window) SetInputHint(hint key.InputHint) {
+	if w.ime.hint == hint {
+		return
+	}
+	w.ime.hint = hint
+	w.updateICFocus()
+}
+
+func (w *x11Window) EditorStateChanged(old, new mado.EditorState) {
+	if w.ime.xic == nil {
+		return
+	}
+	if old.Selection.Range != new.Selection.Range || old.Snippet != new.Snippet {
+		// The editor moved away from the composition.
+		w.cancelComposition()
+	}
+	if old.Selection.Caret != new.Selection.Caret || old.Selection.Transform != new.Selection.Transform {
+		w.updateICSpot()
+	}
+}
 
 // close the window.
 func (w *x11Window) close() {
@@ -386,7 +666,12 @@ func (w *x11Window) Wakeup() {
 	case w.wakeups <- struct{}{}:
 	default:
 	}
-	if _, err := syscall.Write(w.notify.write, x11OneByte); err != nil && err != syscall.EAGAIN {
+	w.disp.wakeup()
+}
+
+// wakeup wakes up the event loop through the notification pipe.
+func (d *x11Display) wakeup() {
+	if _, err := syscall.Write(d.notify.write, x11OneByte); err != nil && err != syscall.EAGAIN {
 		panic(fmt.Errorf("failed to write to pipe: %v", err))
 	}
 }
@@ -407,48 +692,59 @@ func (w *x11Window) setStage(s mado.Stage) {
 	w.w.Event(mado.StageEvent{Stage: s})
 }
 
-func (w *x11Window) loop() {
-	h := x11EventHandler{w: w, xev: new(C.XEvent), text: make([]byte, 4)}
-	xfd := C.XConnectionNumber(w.x)
+// loop dispatches the events of the display to its windows, until the
+// last window is closed or the connection is lost.
+func (d *x11Display) loop() {
+	defer d.destroy()
+	h := x11EventHandler{d: d, xev: new(C.XEvent), text: make([]byte, 4)}
+	xfd := C.XConnectionNumber(d.x)
 
 	// Poll for events and notifications.
 	pollfds := []syscall.PollFd{
 		{Fd: int32(xfd), Events: syscall.POLLIN | syscall.POLLERR},
-		{Fd: int32(w.notify.read), Events: syscall.POLLIN | syscall.POLLERR},
+		{Fd: int32(d.notify.read), Events: syscall.POLLIN | syscall.POLLERR},
 	}
 	xEvents := &pollfds[0].Revents
 	// Plenty of room for a backlog of notifications.
 	buf := make([]byte, 100)
 
-loop:
-	for !w.dead {
-		var syn, anim bool
+	for {
+		x11App.mu.Lock()
+		funcs := d.funcs
+		d.funcs = nil
+		if len(funcs) == 0 && len(d.windows) == 0 {
+			x11App.disp = nil
+			x11App.mu.Unlock()
+			return
+		}
+		x11App.mu.Unlock()
+		for _, f := range funcs {
+			f()
+		}
+		if len(d.windows) == 0 {
+			continue
+		}
+		lost := false
 		// Check for pending draw events before checking animation or blocking.
 		// This fixes an issue on Xephyr where on startup XPending() > 0 but
 		// poll will still block. This also prevents no-op calls to poll.
-		if syn = h.handleEvents(); !syn {
-			anim = w.animating
-			if !anim {
-				// Clear poll events.
-				*xEvents = 0
-				// Wait for X event or gio notification.
-				if _, err := syscall.Poll(pollfds, -1); err != nil && err != syscall.EINTR {
-					panic(fmt.Errorf("x11 loop: poll failed: %w", err))
-				}
-				switch {
-				case *xEvents&syscall.POLLIN != 0:
-					syn = h.handleEvents()
-					if w.dead {
-						break loop
-					}
-				case *xEvents&(syscall.POLLERR|syscall.POLLHUP) != 0:
-					break loop
-				}
+		if !h.handleEvents() && !d.animating() {
+			// Clear poll events.
+			*xEvents = 0
+			// Wait for X event or gio notification.
+			if _, err := syscall.Poll(pollfds, -1); err != nil && err != syscall.EINTR {
+				panic(fmt.Errorf("x11 loop: poll failed: %w", err))
+			}
+			switch {
+			case *xEvents&syscall.POLLIN != 0:
+				h.handleEvents()
+			case *xEvents&(syscall.POLLERR|syscall.POLLHUP) != 0:
+				lost = true
 			}
 		}
 		// Clear notifications.
 		for {
-			_, err := syscall.Read(w.notify.read, buf)
+			_, err := syscall.Read(d.notify.read, buf)
 			if err == syscall.EAGAIN {
 				break
 			}
@@ -456,38 +752,139 @@ loop:
 				panic(fmt.Errorf("x11 loop: read from notify pipe failed: %w", err))
 			}
 		}
-		select {
-		case <-w.wakeups:
-			w.w.Event(mado.WakeupEvent{})
-		default:
+		for _, w := range append([]*x11Window(nil), d.windows...) {
+			if !slices.Contains(d.windows, w) {
+				// Closed with its parent.
+				continue
+			}
+			if !lost && !w.dead {
+				w.process()
+			}
+			if lost || w.dead {
+				d.closeWindow(w)
+			}
 		}
+	}
+}
 
-		if (anim || syn) && w.config.Size.X != 0 && w.config.Size.Y != 0 {
-			w.w.Event(mado.FrameEvent{
-				Now:    time.Now(),
-				Size:   w.config.Size,
-				Metric: w.metric,
-				Sync:   syn,
-			})
+// animating reports whether a window of the display is animating.
+func (d *x11Display) animating() bool {
+	for _, w := range d.windows {
+		if w.animating {
+			return true
 		}
 	}
+	return false
 }
 
-func (w *x11Window) destroy() {
-	if w.notify.write != 0 {
-		syscall.Close(w.notify.write)
-		w.notify.write = 0
+// process delivers the pending events of the window and draws it.
+func (w *x11Window) process() {
+	select {
+	case <-w.wakeups:
+		w.w.Event(mado.WakeupEvent{})
+	default:
+	}
+	sync := w.redraw
+	w.redraw = false
+	if (w.animating || sync) && w.config.Size.X != 0 && w.config.Size.Y != 0 {
+		w.w.Event(mado.FrameEvent{
+			Now:    time.Now(),
+			Size:   w.config.Size,
+			Metric: w.metric,
+			Sync:   sync,
+		})
+	}
+}
+
+// closeWindow destroys a window of the display and removes it.
+// The popups of the window are dismissed first.
+func (d *x11Display) closeWindow(w *x11Window) {
+	for _, w2 := range append([]*x11Window(nil), d.windows...) {
+		if w2.parent != w {
+			continue
+		}
+		if w2.popup != mado.NoPopup {
+			w2.dismissed = true
+			d.closeWindow(w2)
+		} else {
+			w2.parent = nil
+		}
+	}
+	if w.dismissed {
+		w.w.Event(mado.DismissEvent{})
+	}
+	w.w.Event(X11ViewEvent{})
+	w.w.Event(mado.DestroyEvent{Err: nil})
+	w.destroy()
+	for i, w2 := range d.windows {
+		if w2 == w {
+			d.windows = append(d.windows[:i], d.windows[i+1:]...)
+			break
+		}
+	}
+	// Return the input to the menu the closed menu was opened from.
+	if p := w.parent; w.grabbed && p != nil && p.grabbed && !p.dead {
+		p.grab()
+	}
+}
+
+// lookup returns the window of the display with the X window ID xw.
+func (d *x11Display) lookup(xw C.Window) *x11Window {
+	for _, w := range d.windows {
+		if w.xw == xw {
+			return w
+		}
+	}
+	return nil
+}
+
+// lookupRequestor returns the window sending an INCR transfer through
+// the property of the requestor window, or nil.
+func (d *x11Display) lookupRequestor(requestor C.Window, property C.Atom) *x11Window {
+	for _, w := range d.windows {
+		for _, s := range w.clipboard.sends {
+			if s.requestor == requestor && s.property == property {
+				return w
+			}
+		}
 	}
-	if w.notify.read != 0 {
-		syscall.Close(w.notify.read)
-		w.notify.read = 0
+	return nil
+}
+
+func (w *x11Window) destroy() {
+	w.destroyIME()
+	w.destroyInput()
+	for _, xc := range w.customCursors {
+		if xc != 0 {
+			C.XFreeCursor(w.x, xc)
+		}
 	}
-	if w.xkb != nil {
-		w.xkb.Destroy()
-		w.xkb = nil
+	w.customCursors = nil
+	w.customCursor = 0
+	if w.grabbed {
+		C.XUngrabPointer(w.x, C.CurrentTime)
+		C.XUngrabKeyboard(w.x, C.CurrentTime)
 	}
 	C.XDestroyWindow(w.x, w.xw)
-	C.XCloseDisplay(w.x)
+}
+
+func (d *x11Display) destroy() {
+	if d.notify.write != 0 {
+		syscall.Close(d.notify.write)
+		d.notify.write = 0
+	}
+	if d.notify.read != 0 {
+		syscall.Close(d.notify.read)
+		d.notify.read = 0
+	}
+	if d.xkb != nil {
+		d.xkb.Destroy()
+		d.xkb = nil
+	}
+	if d.x != nil {
+		C.XCloseDisplay(d.x)
+		d.x = nil
+	}
 }
 
 // atom is a wrapper around XInternAtom. Callers should cache the result
@@ -506,306 +903,412 @@ func (w *x11Window) atom(name string, onlyIfExists bool) C.Atom {
 // Its sole purpose is to prevent heap allocation and reduce clutter
 // in x11window.loop.
 type x11EventHandler struct {
-	w    *x11Window
+	d    *x11Display
 	text []byte
 	xev  *C.XEvent
 }
 
-// handleEvents returns true if the window needs to be redrawn.
+// handleEvents dispatches the pending events to the windows of the
+// display. It returns true if a window needs to be redrawn.
 func (h *x11EventHandler) handleEvents() bool {
-	w := h.w
+	d := h.d
 	xev := h.xev
 	redraw := false
-	for C.XPending(w.x) != 0 {
-		C.XNextEvent(w.x, xev)
+	for C.XPending(d.x) != 0 {
+		C.XNextEvent(d.x, xev)
 		if C.XFilterEvent(xev, C.None) == C.True {
 			continue
 		}
 		switch _type := (*C.XAnyEvent)(unsafe.Pointer(xev))._type; _type {
-		case h.w.xkbEventBase:
+		case d.xkbEventBase:
 			xkbEvent := (*C.XkbAnyEvent)(unsafe.Pointer(xev))
 			switch xkbEvent.xkb_type {
 			case C.XkbNewKeyboardNotify, C.XkbMapNotify:
-				if err := h.w.updateXkbKeymap(); err != nil {
+				if err := d.updateXkbKeymap(); err != nil {
 					panic(err)
 				}
 			case C.XkbStateNotify:
 				state := (*C.XkbStateNotifyEvent)(unsafe.Pointer(xev))
-				h.w.xkb.UpdateMask(uint32(state.base_mods), uint32(state.latched_mods), uint32(state.locked_mods),
+				d.xkb.UpdateMask(uint32(state.base_mods), uint32(state.latched_mods), uint32(state.locked_mods),
 					uint32(state.base_group), uint32(state.latched_group), uint32(state.locked_group))
 			}
-		case C.KeyPress, C.KeyRelease:
-			ks := key.Press
-			if _type == C.KeyRelease {
-				ks = key.Release
-			}
-			kevt := (*C.XKeyPressedEvent)(unsafe.Pointer(xev))
-			for _, e := range h.w.xkb.DispatchKey(uint32(kevt.keycode), ks) {
-				if ee, ok := e.(key.EditEvent); ok {
-					// There's no support for IME yet.
-					w.w.EditorInsert(ee.Text, false)
-				} else {
-					w.w.Event(e)
+			continue
+		case C.GenericEvent:
+			// Raw input events are selected on the root window, on
+			// behalf of the focused window.
+			for _, w := range d.windows {
+				if w.input.focus && !w.dead {
+					w.handleGenericEvent(xev)
+					break
 				}
 			}
-		case C.ButtonPress, C.ButtonRelease:
-			bevt := (*C.XButtonEvent)(unsafe.Pointer(xev))
-			ev := pointer.Event{
-				Kind:   pointer.Press,
-				Source: pointer.Mouse,
-				Position: f32.Point{
-					X: float32(bevt.x),
-					Y: float32(bevt.y),
-				},
-				Time:      time.Duration(bevt.time) * time.Millisecond,
-				Modifiers: w.xkb.Modifiers(),
-			}
-			if bevt._type == C.ButtonRelease {
-				ev.Kind = pointer.Release
-			}
-			var btn pointer.Buttons
-			const scrollScale = 10
-			switch bevt.button {
-			case C.Button1:
-				btn = pointer.ButtonPrimary
-			case C.Button2:
-				btn = pointer.ButtonTertiary
-			case C.Button3:
-				btn = pointer.ButtonSecondary
-			case C.Button4:
-				ev.Kind = pointer.Scroll
-				// scroll up or left (if shift is pressed).
-				if ev.Modifiers == key.ModShift {
-					ev.Scroll.X = -scrollScale
-				} else {
-					ev.Scroll.Y = -scrollScale
-				}
-			case C.Button5:
-				// scroll down or right (if shift is pressed).
-				ev.Kind = pointer.Scroll
-				if ev.Modifiers == key.ModShift {
-					ev.Scroll.X = +scrollScale
-				} else {
-					ev.Scroll.Y = +scrollScale
+			continue
+		}
+		xany := (*C.XAnyEvent)(unsafe.Pointer(xev))
+		var w *x11Window
+		if xany._type == C.PropertyNotify {
+			// The requestor of an INCR transfer may be a window of
+			// another client, or another window of the display.
+			w = d.lookupRequestor(xany.window, (*C.XPropertyEvent)(unsafe.Pointer(xev)).atom)
+		}
+		if w == nil {
+			w = d.lookup(xany.window)
+		}
+		if w == nil || w.dead {
+			continue
+		}
+		if h.handleEvent(w) {
+			w.redraw = true
+			redraw = true
+		}
+	}
+	return redraw
+}
+
+// handleEvent handles the event for w. It returns true if the window
+// needs to be redrawn.
+func (h *x11EventHandler) handleEvent(w *x11Window) bool {
+	xev := h.xev
+	redraw := false
+	switch _type := (*C.XAnyEvent)(unsafe.Pointer(xev))._type; _type {
+	case C.KeyPress, C.KeyRelease:
+		ks := key.Press
+		if _type == C.KeyRelease {
+			ks = key.Release
+		}
+		kevt := (*C.XKeyPressedEvent)(unsafe.Pointer(xev))
+		if w.ime.xic != nil {
+			// Text comes from the input method, either
+			// committed compositions or plain key presses.
+			if _type == C.KeyPress {
+				if text := w.lookupString(kevt); text != "" {
+					w.commitText(text)
 				}
-			case 6:
-				// http://xahlee.info/linux/linux_x11_mouse_button_number.html
-				// scroll left.
-				ev.Kind = pointer.Scroll
-				ev.Scroll.X = -scrollScale * 2
-			case 7:
-				// scroll right
-				ev.Kind = pointer.Scroll
-				ev.Scroll.X = +scrollScale * 2
-			default:
-				continue
 			}
-			switch _type {
-			case C.ButtonPress:
-				w.pointerBtns |= btn
-			case C.ButtonRelease:
-				w.pointerBtns &^= btn
-			}
-			ev.Buttons = w.pointerBtns
-			w.w.Event(ev)
-		case C.MotionNotify:
-			mevt := (*C.XMotionEvent)(unsafe.Pointer(xev))
-			w.w.Event(pointer.Event{
-				Kind:    pointer.Move,
-				Source:  pointer.Mouse,
-				Buttons: w.pointerBtns,
-				Position: f32.Point{
-					X: float32(mevt.x),
-					Y: float32(mevt.y),
-				},
-				Time:      time.Duration(mevt.time) * time.Millisecond,
-				Modifiers: w.xkb.Modifiers(),
-			})
-		case C.Expose: // update
-			// redraw only on the last expose event
-			redraw = (*C.XExposeEvent)(unsafe.Pointer(xev)).count == 0
-		case C.FocusIn:
-			w.w.Event(key.FocusEvent{Focus: true})
-		case C.FocusOut:
-			w.w.Event(key.FocusEvent{Focus: false})
-		case C.ConfigureNotify: // window configuration change
-			cevt := (*C.XConfigureEvent)(unsafe.Pointer(xev))
-			if sz := image.Pt(int(cevt.width), int(cevt.height)); sz != w.config.Size {
-				w.w.Event(iowindow.SizeEvent{Size: sz})
-				w.config.Size = sz
-				w.w.Event(mado.ConfigEvent{Config: w.config})
-			}
-			if pos := image.Pt(int(cevt.x), int(cevt.y)); pos != w.prevWindowPos {
-				w.prevWindowPos = pos
-				w.w.Event(iowindow.MoveEvent{Pos: pos})
-			}
-			// redraw will be done by a later expose event
-		case C.SelectionNotify:
-			cevt := (*C.XSelectionEvent)(unsafe.Pointer(xev))
-			prop := w.atoms.clipboardContent
-			if cevt.property != prop {
-				break
-			}
-			if cevt.selection != w.atoms.clipboard {
-				break
-			}
-			var text C.XTextProperty
-			if st := C.XGetTextProperty(w.x, w.xw, &text, prop); st == 0 {
-				// Failed; ignore.
-				break
-			}
-			if text.format != 8 || text.encoding != w.atoms.utf8string {
-				// Ignore non-utf-8 encoded strings.
+			if kevt.keycode == 0 {
+				// Synthetic key press carrying committed text.
 				break
 			}
-			str := C.GoStringN((*C.char)(unsafe.Pointer(text.value)), C.int(text.nitems))
-			w.w.Event(transfer.DataEvent{
-				Type: "application/text",
-				Open: func() io.ReadCloser {
-					return io.NopCloser(strings.NewReader(str))
-				},
-			})
-		case C.SelectionRequest:
-			cevt := (*C.XSelectionRequestEvent)(unsafe.Pointer(xev))
-			if (cevt.selection != w.atoms.clipboard && cevt.selection != w.atoms.primary) || cevt.property == C.None {
-				// Unsupported clipboard or obsolete requestor.
-				break
-			}
-			notify := func() {
-				var xev C.XEvent
-				ev := (*C.XSelectionEvent)(unsafe.Pointer(&xev))
-				*ev = C.XSelectionEvent{
-					_type:     C.SelectionNotify,
-					display:   cevt.display,
-					requestor: cevt.requestor,
-					selection: cevt.selection,
-					target:    cevt.target,
-					property:  cevt.property,
-					time:      cevt.time,
-				}
-				C.XSendEvent(w.x, cevt.requestor, 0, 0, &xev)
-			}
-			switch cevt.target {
-			case w.atoms.targets:
-				// The requestor wants the supported clipboard
-				// formats. First write the targets...
-				formats := [...]C.long{
-					C.long(w.atoms.targets),
-					C.long(w.atoms.utf8string),
-					C.long(w.atoms.plaintext),
-					// GTK clients need this.
-					C.long(w.atoms.gtk_text_buffer_contents),
-				}
-				C.XChangeProperty(w.x, cevt.requestor, cevt.property, w.atoms.atom,
-					32 /* bitwidth of formats */, C.PropModeReplace,
-					(*C.uchar)(unsafe.Pointer(&formats)), C.int(len(formats)),
-				)
-				// ...then notify the requestor.
-				notify()
-			case w.atoms.plaintext, w.atoms.utf8string, w.atoms.gtk_text_buffer_contents:
-				content := w.clipboard.content
-				var ptr *C.uchar
-				if len(content) > 0 {
-					ptr = (*C.uchar)(unsafe.Pointer(&content[0]))
+		}
+		for _, e := range w.xkb.DispatchKey(uint32(kevt.keycode), ks) {
+			if ee, ok := e.(key.EditEvent); ok {
+				if w.ime.xic == nil {
+					w.w.EditorInsert(ee.Text, false)
 				}
-				C.XChangeProperty(w.x, cevt.requestor, cevt.property, cevt.target,
-					8 /* bitwidth */, C.PropModeReplace,
-					ptr, C.int(len(content)),
-				)
-				notify()
+			} else {
+				w.w.Event(e)
 			}
-		case C.ClientMessage: // extensions
-			cevt := (*C.XClientMessageEvent)(unsafe.Pointer(xev))
-			switch *(*C.long)(unsafe.Pointer(&cevt.data)) {
-			case C.long(w.atoms.evDelWindow):
+		}
+	case C.ButtonPress, C.ButtonRelease:
+		bevt := (*C.XButtonEvent)(unsafe.Pointer(xev))
+		if _type == C.ButtonPress {
+			// The pointer grab of a popup reports the presses
+			// outside the windows of the program to the popup.
+			if w.grabbed && !image.Pt(int(bevt.x), int(bevt.y)).In(image.Rectangle{Max: w.config.Size}) {
+				w.dismissed = true
 				w.dead = true
 				return false
 			}
+			w.disp.dismissPopups(w)
+		}
+		ev := pointer.Event{
+			Kind:   pointer.Press,
+			Source: pointer.Mouse,
+			Position: w.cursorPos(f32.Point{
+				X: float32(bevt.x),
+				Y: float32(bevt.y),
+			}),
+			Time:      time.Duration(bevt.time) * time.Millisecond,
+			Modifiers: w.xkb.Modifiers(),
+		}
+		if bevt._type == C.ButtonRelease {
+			ev.Kind = pointer.Release
+		}
+		var btn pointer.Buttons
+		const scrollScale = 10
+		switch bevt.button {
+		case C.Button1:
+			btn = pointer.ButtonPrimary
+		case C.Button2:
+			btn = pointer.ButtonTertiary
+		case C.Button3:
+			btn = pointer.ButtonSecondary
+		case C.Button4:
+			ev.Kind = pointer.Scroll
+			// scroll up or left (if shift is pressed).
+			if ev.Modifiers.Held() == key.ModShift {
+				ev.Scroll.X = -scrollScale
+			} else {
+				ev.Scroll.Y = -scrollScale
+			}
+		case C.Button5:
+			// scroll down or right (if shift is pressed).
+			ev.Kind = pointer.Scroll
+			if ev.Modifiers.Held() == key.ModShift {
+				ev.Scroll.X = +scrollScale
+			} else {
+				ev.Scroll.Y = +scrollScale
+			}
+		case 6:
+			// http://xahlee.info/linux/linux_x11_mouse_button_number.html
+			// scroll left.
+			ev.Kind = pointer.Scroll
+			ev.Scroll.X = -scrollScale * 2
+		case 7:
+			// scroll right
+			ev.Kind = pointer.Scroll
+			ev.Scroll.X = +scrollScale * 2
+		default:
+			return false
+		}
+		switch _type {
+		case C.ButtonPress:
+			w.pointerBtns |= btn
+		case C.ButtonRelease:
+			w.pointerBtns &^= btn
+		}
+		ev.Buttons = w.pointerBtns
+		if ev.Kind == pointer.Release && w.pointerBtns == 0 {
+			w.dragRelease(bevt.time)
+		}
+		w.w.Event(ev)
+	case C.MotionNotify:
+		mevt := (*C.XMotionEvent)(unsafe.Pointer(xev))
+		w.dragMotion(mevt)
+		pos := f32.Point{
+			X: float32(mevt.x),
+			Y: float32(mevt.y),
+		}
+		if w.input.mode.Cursor == mado.CursorModeDisabled && !w.disabledMotion(pos) {
+			break
+		}
+		w.w.Event(pointer.Event{
+			Kind:      pointer.Move,
+			Source:    pointer.Mouse,
+			Buttons:   w.pointerBtns,
+			Position:  w.cursorPos(pos),
+			Time:      time.Duration(mevt.time) * time.Millisecond,
+			Modifiers: w.xkb.Modifiers(),
+		})
+	case C.MapNotify:
+		if w.popup == mado.PopupMenu && !w.grabbed {
+			w.grab()
+		}
+	case C.Expose: // update
+		// redraw only on the last expose event
+		redraw = (*C.XExposeEvent)(unsafe.Pointer(xev)).count == 0
+		w.exposed.Store(true)
+	case C.FocusIn:
+		w.setUrgency(false)
+		w.ime.focus = true
+		w.updateICFocus()
+		w.input.focus = true
+		w.updateInputMode()
+		w.w.Event(key.FocusEvent{Focus: true})
+	case C.FocusOut:
+		w.ime.focus = false
+		w.updateICFocus()
+		w.input.focus = false
+		w.updateInputMode()
+		w.w.Event(key.FocusEvent{Focus: false})
+	case C.ConfigureNotify: // window configuration change
+		cevt := (*C.XConfigureEvent)(unsafe.Pointer(xev))
+		if sz := image.Pt(int(cevt.width), int(cevt.height)); sz != w.config.Size {
+			w.w.Event(iowindow.SizeEvent{Size: sz})
+			w.config.Size = sz
+			w.w.Event(mado.ConfigEvent{Config: w.config})
+		}
+		// The event coordinates are relative to the window manager
+		// frame unless the event is synthetic.
+		pos := image.Pt(int(cevt.x), int(cevt.y))
+		if cevt.send_event == 0 {
+			pos = w.position()
+		}
+		if pos != w.config.Pos {
+			w.config.Pos = pos
+			w.w.Event(iowindow.MoveEvent{Pos: pos})
+			w.w.Event(mado.ConfigEvent{Config: w.config})
+		}
+		// redraw will be done by a later expose event
+	case C.SelectionNotify:
+		w.handleSelectionNotify((*C.XSelectionEvent)(unsafe.Pointer(xev)))
+	case C.SelectionRequest:
+		w.handleSelectionRequest((*C.XSelectionRequestEvent)(unsafe.Pointer(xev)))
+	case C.PropertyNotify:
+		w.handlePropertyNotify((*C.XPropertyEvent)(unsafe.Pointer(xev)))
+	case C.ClientMessage: // extensions
+		cevt := (*C.XClientMessageEvent)(unsafe.Pointer(xev))
+		if w.handleDnDMessage(cevt) {
+			break
+		}
+		switch *(*C.long)(unsafe.Pointer(&cevt.data)) {
+		case C.long(w.atoms.evDelWindow):
+			w.dead = true
+			return false
 		}
 	}
 	return redraw
 }
 
 var (
-	x11Threads sync.Once
+	x11Threads    sync.Once
+	x11ThreadsErr error
 )
 
+// initX11 initializes Xlib for use from multiple goroutines.
+func initX11() error {
+	x11Threads.Do(func() {
+		if C.XInitThreads() == 0 {
+			x11ThreadsErr = errors.New("x11: threads init failed")
+		}
+		C.XrmInitialize()
+		// Input methods and Xutf8LookupString don't work in the "C"
+		// locale, which is the default for Go programs.
+		if loc := C.GoString(C.setlocale(C.LC_CTYPE, nil)); loc == "C" || loc == "POSIX" {
+			C.setlocale(C.LC_CTYPE, (*C.char)(unsafe.Pointer(&[]byte("\x00")[0])))
+		}
+	})
+	return x11ThreadsErr
+}
+
 func init() {
 	x11Driver = newX11Window
 }
 
 func newX11Window(gioWin mado.Callbacks, options []mado.Option) error {
-	var err error
+	errs := make(chan error, 1)
+	create := func(d *x11Display) {
+		w, err := d.createWindow(gioWin, options)
+		errs <- err
+		if err != nil {
+			return
+		}
+		d.windows = append(d.windows, w)
+		w.w.SetDriver(w)
 
+		// make the window visible on the screen
+		C.XMapWindow(w.x, w.xw)
+		w.Configure(options)
+		w.w.Event(X11ViewEvent{Display: unsafe.Pointer(w.x), Window: uintptr(w.xw)})
+		w.setStage(mado.StageRunning)
+	}
+	if err := x11Run(create); err != nil {
+		return err
+	}
+	return <-errs
+}
+
+// x11Run runs f on the event loop of the shared display, connecting to
+// the X server if no windows are open.
+func x11Run(f func(d *x11Display)) error {
+	x11App.mu.Lock()
+	defer x11App.mu.Unlock()
+	d := x11App.disp
+	if d == nil {
+		var err error
+		d, err = newX11Display()
+		if err != nil {
+			return err
+		}
+		x11App.disp = d
+		go d.loop()
+	} else {
+		defer d.wakeup()
+	}
+	d.funcs = append(d.funcs, func() { f(d) })
+	return nil
+}
+
+func newX11Display() (*x11Display, error) {
 	pipe := make([]int, 2)
 	if err := syscall.Pipe2(pipe, syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
-		return fmt.Errorf("NewX11Window: failed to create pipe: %w", err)
+		return nil, fmt.Errorf("NewX11Window: failed to create pipe: %w", err)
 	}
+	d := new(x11Display)
+	d.notify.read = pipe[0]
+	d.notify.write = pipe[1]
 
-	x11Threads.Do(func() {
-		if C.XInitThreads() == 0 {
-			err = errors.New("x11: threads init failed")
-		}
-		C.XrmInitialize()
-	})
-	if err != nil {
-		return err
+	if err := initX11(); err != nil {
+		d.destroy()
+		return nil, err
 	}
-	dpy := C.XOpenDisplay(nil)
-	if dpy == nil {
-		return errors.New("x11: cannot connect to the X server")
+	d.x = C.XOpenDisplay(nil)
+	if d.x == nil {
+		d.destroy()
+		return nil, errors.New("x11: cannot connect to the X server")
 	}
 	var major, minor C.int = C.XkbMajorVersion, C.XkbMinorVersion
-	var xkbEventBase C.int
-	if C.XkbQueryExtension(dpy, nil, &xkbEventBase, nil, &major, &minor) != C.True {
-		C.XCloseDisplay(dpy)
-		return errors.New("x11: XkbQueryExtension failed")
+	if C.XkbQueryExtension(d.x, nil, &d.xkbEventBase, nil, &major, &minor) != C.True {
+		d.destroy()
+		return nil, errors.New("x11: XkbQueryExtension failed")
 	}
 	const bits = C.uint(C.XkbNewKeyboardNotifyMask | C.XkbMapNotifyMask | C.XkbStateNotifyMask)
-	if C.XkbSelectEvents(dpy, C.XkbUseCoreKbd, bits, bits) != C.True {
-		C.XCloseDisplay(dpy)
-		return errors.New("x11: XkbSelectEvents failed")
+	if C.XkbSelectEvents(d.x, C.XkbUseCoreKbd, bits, bits) != C.True {
+		d.destroy()
+		return nil, errors.New("x11: XkbSelectEvents failed")
 	}
 	xkb, err := xkb.New()
 	if err != nil {
-		C.XCloseDisplay(dpy)
-		return fmt.Errorf("x11: %v", err)
+		d.destroy()
+		return nil, fmt.Errorf("x11: %v", err)
 	}
+	d.xkb = xkb
+	if err := d.updateXkbKeymap(); err != nil {
+		d.destroy()
+		return nil, err
+	}
+	return d, nil
+}
 
+func (d *x11Display) createWindow(gioWin mado.Callbacks, options []mado.Option) (*x11Window, error) {
+	dpy := d.x
 	ppsp := x11DetectUIScale(dpy)
 	cfg := unit.Metric{PxPerDp: ppsp, PxPerSp: ppsp}
-	// Only use cnf for getting the window size.
+	// Only use cnf for getting the window size and parent.
 	var cnf mado.Config
 	cnf.Apply(cfg, options)
+	var parent *x11Window
+	if cnf.Parent != nil {
+		parent = d.windowOf(cnf.Parent)
+		if parent == nil {
+			return nil, errors.New("x11: parent window is not open")
+		}
+	}
+	popup := parent != nil && cnf.Popup != mado.NoPopup
+	var pos image.Point
+	if popup {
+		pos = parent.popupPosition(cnf.Anchor, cnf.Size)
+	}
 
 	swa := C.XSetWindowAttributes{
 		event_mask: C.ExposureMask | C.FocusChangeMask | // update
 			C.KeyPressMask | C.KeyReleaseMask | // keyboard
 			C.ButtonPressMask | C.ButtonReleaseMask | // mouse clicks
 			C.PointerMotionMask | // mouse movement
-			C.StructureNotifyMask, // resize
+			C.StructureNotifyMask | // resize
+			C.PropertyChangeMask, // clipboard transfers
 		background_pixmap: C.None,
 		override_redirect: C.False,
 	}
+	if popup {
+		// Popups are placed by the program, not the window manager.
+		swa.override_redirect = C.True
+	}
 	win := C.XCreateWindow(dpy, C.XDefaultRootWindow(dpy),
-		0, 0, C.uint(cnf.Size.X), C.uint(cnf.Size.Y),
+		C.int(pos.X), C.int(pos.Y), C.uint(cnf.Size.X), C.uint(cnf.Size.Y),
 		0, C.CopyFromParent, C.InputOutput, nil,
 		C.CWEventMask|C.CWBackPixmap|C.CWOverrideRedirect, &swa)
 
 	w := &x11Window{
-		w: gioWin, x: dpy, xw: win,
-		metric:       cfg,
-		xkb:          xkb,
-		xkbEventBase: xkbEventBase,
-		wakeups:      make(chan struct{}, 1),
-		config:       mado.Config{Size: cnf.Size},
-	}
-	w.notify.read = pipe[0]
-	w.notify.write = pipe[1]
-
-	if err := w.updateXkbKeymap(); err != nil {
-		w.destroy()
-		return err
+		w: gioWin, disp: d, x: dpy, xkb: d.xkb, xw: win,
+		metric:  cfg,
+		wakeups: make(chan struct{}, 1),
+		config:  mado.Config{Size: cnf.Size},
+		parent:  parent,
+	}
+	if popup {
+		w.popup = cnf.Popup
+		w.config.Pos = pos
 	}
 
 	var hints C.XWMHints
@@ -820,6 +1323,9 @@ func newX11Window(gioWin mado.Callbacks, options []mado.Option) error {
 
 	w.atoms.utf8string = w.atom("UTF8_STRING", false)
 	w.atoms.plaintext = w.atom("text/plain;charset=utf-8", false)
+	w.atoms.textPlain = w.atom("text/plain", false)
+	w.atoms.text = w.atom("TEXT", false)
+	w.atoms.incr = w.atom("INCR", false)
 	w.atoms.gtk_text_buffer_contents = w.atom("GTK_TEXT_BUFFER_CONTENTS", false)
 	w.atoms.evDelWindow = w.atom("WM_DELETE_WINDOW", false)
 	w.atoms.clipboard = w.atom("CLIPBOARD", false)
@@ -833,26 +1339,122 @@ func newX11Window(gioWin mado.Callbacks, options []mado.Option) error {
 	w.atoms.wmActiveWindow = w.atom("_NET_ACTIVE_WINDOW", false)
 	w.atoms.wmStateMaximizedHorz = w.atom("_NET_WM_STATE_MAXIMIZED_HORZ", false)
 	w.atoms.wmStateMaximizedVert = w.atom("_NET_WM_STATE_MAXIMIZED_VERT", false)
+	w.atoms.wmWindowOpacity = w.atom("_NET_WM_WINDOW_OPACITY", false)
+	w.atoms.frameExtents = w.atom("_NET_FRAME_EXTENTS", false)
+	w.atoms.wmIcon = w.atom("_NET_WM_ICON", false)
+	w.atoms.xdndAware = w.atom("XdndAware", false)
+	w.atoms.xdndSelection = w.atom("XdndSelection", false)
+	w.atoms.xdndTypeList = w.atom("XdndTypeList", false)
+	w.atoms.xdndActionCopy = w.atom("XdndActionCopy", false)
+	w.atoms.xdndEnter = w.atom("XdndEnter", false)
+	w.atoms.xdndPosition = w.atom("XdndPosition", false)
+	w.atoms.xdndStatus = w.atom("XdndStatus", false)
+	w.atoms.xdndLeave = w.atom("XdndLeave", false)
+	w.atoms.xdndDrop = w.atom("XdndDrop", false)
+	w.atoms.xdndFinished = w.atom("XdndFinished", false)
 
 	// extensions
 	C.XSetWMProtocols(dpy, win, &w.atoms.evDelWindow, 1)
 
-	go func() {
-		w.w.SetDriver(w)
+	if parent != nil {
+		w.setWindowType(cnf.Popup)
+	}
 
-		// make the window visible on the screen
-		C.XMapWindow(dpy, win)
-		w.Configure(options)
-		w.w.Event(X11ViewEvent{Display: unsafe.Pointer(dpy), Window: uintptr(win)})
-		w.setStage(mado.StageRunning)
-		w.loop()
-		w.w.Event(X11ViewEvent{})
-		w.w.Event(mado.DestroyEvent{Err: nil})
-		w.destroy()
-	}()
+	w.initIME()
+	w.initInput()
+	w.initDnD()
+
+	return w, nil
+}
+
+// setWindowType marks the window as a popup of its parent, or as a
+// dialog transient for it.
+func (w *x11Window) setWindowType(kind mado.PopupKind) {
+	typ := "_NET_WM_WINDOW_TYPE_DIALOG"
+	switch kind {
+	case mado.PopupMenu:
+		typ = "_NET_WM_WINDOW_TYPE_POPUP_MENU"
+	case mado.PopupTooltip:
+		typ = "_NET_WM_WINDOW_TYPE_TOOLTIP"
+		// Let the input through to the windows below.
+		reg := C.XFixesCreateRegion(w.x, nil, 0)
+		C.XFixesSetWindowShapeRegion(w.x, w.xw, C.ShapeInput, 0, 0, reg)
+		C.XFixesDestroyRegion(w.x, reg)
+	default:
+		C.XSetTransientForHint(w.x, w.xw, w.parent.xw)
+	}
+	v := C.ulong(w.atom(typ, false))
+	C.XChangeProperty(w.x, w.xw, w.atom("_NET_WM_WINDOW_TYPE", false), C.XA_ATOM,
+		32, C.PropModeReplace, (*C.uchar)(unsafe.Pointer(&v)), 1)
+}
+
+// popupPosition returns the screen position of a popup of the given
+// size, placed against anchor in the window.
+func (w *x11Window) popupPosition(anchor image.Rectangle, size image.Point) image.Point {
+	var x, y C.int
+	var child C.Window
+	root := C.XDefaultRootWindow(w.x)
+	C.XTranslateCoordinates(w.x, w.xw, root, C.int(anchor.Min.X), C.int(anchor.Min.Y), &x, &y, &child)
+	anchor = anchor.Add(image.Pt(int(x), int(y)).Sub(anchor.Min))
+	screen := C.XDefaultScreen(w.x)
+	bounds := image.Rect(0, 0, int(C.XDisplayWidth(w.x, screen)), int(C.XDisplayHeight(w.x, screen)))
+	return placePopup(anchor, size, bounds)
+}
+
+// placePopup places a popup of the given size below anchor, or above it
+// if there is no room below, and slides it to stay within bounds.
+func placePopup(anchor image.Rectangle, size image.Point, bounds image.Rectangle) image.Point {
+	pos := image.Pt(anchor.Min.X, anchor.Max.Y)
+	if pos.Y+size.Y > bounds.Max.Y && anchor.Min.Y-size.Y >= bounds.Min.Y {
+		pos.Y = anchor.Min.Y - size.Y
+	}
+	pos.X = max(min(pos.X, bounds.Max.X-size.X), bounds.Min.X)
+	pos.Y = max(min(pos.Y, bounds.Max.Y-size.Y), bounds.Min.Y)
+	return pos
+}
+
+// grab directs the pointer and keyboard input to the popup, to detect
+// clicks outside it.
+func (w *x11Window) grab() {
+	const mask = C.ButtonPressMask | C.ButtonReleaseMask | C.PointerMotionMask
+	// Events in other windows of the program are reported to them.
+	if C.XGrabPointer(w.x, w.xw, C.True, mask, C.GrabModeAsync, C.GrabModeAsync, C.None, C.None, C.CurrentTime) == C.GrabSuccess {
+		w.grabbed = true
+	}
+	C.XGrabKeyboard(w.x, w.xw, C.True, C.GrabModeAsync, C.GrabModeAsync, C.CurrentTime)
+}
+
+// windowOf returns the open window of the display for a Gio window.
+func (d *x11Display) windowOf(gw mado.Window) *x11Window {
+	for _, w := range d.windows {
+		if !w.dead && w.w.GetWindow() == gw {
+			return w
+		}
+	}
 	return nil
 }
 
+// dismissPopups dismisses the popups that don't contain w, after a
+// press in w.
+func (d *x11Display) dismissPopups(w *x11Window) {
+	for _, p := range d.windows {
+		if p.popup == mado.NoPopup {
+			continue
+		}
+		inside := false
+		for w2 := w; w2 != nil; w2 = w2.parent {
+			if w2 == p {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			p.dismissed = true
+			p.dead = true
+		}
+	}
+}
+
 // detectUIScale reports the system UI scale, or 1.0 if it fails.
 func x11DetectUIScale(dpy *C.Display) float32 {
 	// default fixed DPI value used in most desktop UI toolkits
@@ -886,10 +1488,10 @@ func x11DetectUIScale(dpy *C.Display) float32 {
 	return scale
 }
 
-func (w *x11Window) updateXkbKeymap() error {
-	w.xkb.DestroyKeymapState()
-	ctx := (*C.struct_xkb_context)(unsafe.Pointer(w.xkb.Ctx))
-	xcb := C.XGetXCBConnection(w.x)
+func (d *x11Display) updateXkbKeymap() error {
+	d.xkb.DestroyKeymapState()
+	ctx := (*C.struct_xkb_context)(unsafe.Pointer(d.xkb.Ctx))
+	xcb := C.XGetXCBConnection(d.x)
 	if xcb == nil {
 		return errors.New("x11: XGetXCBConnection failed")
 	}
@@ -906,13 +1508,11 @@ func (w *x11Window) updateXkbKeymap() error {
 		C.xkb_keymap_unref(keymap)
 		return errors.New("x11: xkb_x11_keymap_new_from_device failed")
 	}
-	w.xkb.SetKeymap(unsafe.Pointer(keymap), unsafe.Pointer(state))
+	d.xkb.SetKeymap(unsafe.Pointer(keymap), unsafe.Pointer(state))
 	return nil
 }
 
 func (w *x11Window) GetFrameBufferSize() image.Point {
-	var size C.XSizeHints
-	var sr C.long
-	C.XGetWMNormalHints(w.x, w.xw, &size, &sr)
-	return image.Pt(int(size.width), int(size.height))
+	_, width, height := w.window()
+	return image.Pt(width, height)
 }
@@ -0,0 +1,426 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+/*
+#include <stdlib.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+*/
+import "C"
+import (
+	"bytes"
+	"io"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/kanryu/mado/io/input"
+	"github.com/kanryu/mado/io/transfer"
+)
+
+// x11ClipboardTimeout is how long a selection owner may take to answer
+// a read, or to send the next chunk of an INCR transfer, before the
+// read is abandoned.
+const x11ClipboardTimeout = 5 * time.Second
+
+// x11Clipboard is the state of the CLIPBOARD and PRIMARY selections
+// of a window.
+type x11Clipboard struct {
+	// offers are the targets of the content written by WriteClipboard,
+	// in order of preference.
+	offers []x11Offer
+	// reads are the pending reads. They are converted one at a time,
+	// because they share the destination property.
+	reads []x11ClipboardRead
+	// sends are the INCR transfers to requestors.
+	sends []*x11IncrSend
+}
+
+type x11Offer struct {
+	target C.Atom
+	data   []byte
+}
+
+// x11ClipboardRead is a selection read. It converts the TARGETS of the
+// selection first, unless the target is known, then the target matching
+// mime, and finally receives the chunks of an INCR transfer, if the owner
+// starts one.
+type x11ClipboardRead struct {
+	mime      string
+	primary   bool
+	selection C.Atom
+	// done, if set, receives the data instead of a DataEvent.
+	done func(data []byte)
+	// started is the time of the most recent step.
+	started time.Time
+	// target is the converted target, or 0 while converting TARGETS.
+	target C.Atom
+	// incr is set while receiving an INCR transfer into data.
+	incr bool
+	data []byte
+}
+
+// x11IncrSend is an INCR transfer of data to a requestor, in chunks.
+// Each chunk is sent when the requestor deletes the previous one, and
+// an empty chunk ends the transfer.
+type x11IncrSend struct {
+	requestor C.Window
+	property  C.Atom
+	target    C.Atom
+	data      []byte
+}
+
+func (w *x11Window) ReadClipboard(mime string, primary bool) {
+	c := &w.clipboard
+	// Abandon a read stuck on an unresponsive owner.
+	if len(c.reads) > 0 && time.Since(c.reads[0].started) > x11ClipboardTimeout {
+		w.finishRead(nil)
+	}
+	w.queueRead(x11ClipboardRead{mime: mime, primary: primary, selection: w.selection(primary)})
+}
+
+// queueRead adds a read, and starts it if no other read is pending.
+func (w *x11Window) queueRead(r x11ClipboardRead) {
+	c := &w.clipboard
+	c.reads = append(c.reads, r)
+	if len(c.reads) == 1 {
+		w.startRead()
+	}
+}
+
+func (w *x11Window) WriteClipboard(content []input.ClipboardData) {
+	c := &w.clipboard
+	c.offers = nil
+	for _, d := range content {
+		if d.Type != "application/text" {
+			c.offers = append(c.offers, x11Offer{target: w.atom(d.Type, false), data: d.Data})
+			continue
+		}
+		for _, t := range w.textTargets() {
+			c.offers = append(c.offers, x11Offer{target: t, data: d.Data})
+		}
+		// GTK clients need this.
+		c.offers = append(c.offers, x11Offer{target: w.atoms.gtk_text_buffer_contents, data: d.Data})
+	}
+	C.XSetSelectionOwner(w.x, w.atoms.clipboard, w.xw, C.CurrentTime)
+	C.XSetSelectionOwner(w.x, w.atoms.primary, w.xw, C.CurrentTime)
+}
+
+// textTargets returns the text targets, in order of preference.
+func (w *x11Window) textTargets() []C.Atom {
+	return []C.Atom{w.atoms.utf8string, w.atoms.plaintext, w.atoms.textPlain, C.XA_STRING, w.atoms.text}
+}
+
+func (w *x11Window) selection(primary bool) C.Atom {
+	if primary {
+		return w.atoms.primary
+	}
+	return w.atoms.clipboard
+}
+
+// startRead converts the target, or the TARGETS, of the selection of
+// the first read.
+func (w *x11Window) startRead() {
+	r := &w.clipboard.reads[0]
+	r.started = time.Now()
+	if r.target != 0 {
+		w.convertSelection(r.selection, r.target)
+		return
+	}
+	w.convertSelection(r.selection, w.atoms.targets)
+}
+
+func (w *x11Window) convertSelection(selection, target C.Atom) {
+	C.XDeleteProperty(w.x, w.xw, w.atoms.clipboardContent)
+	C.XConvertSelection(w.x, selection, target, w.atoms.clipboardContent, w.xw, C.CurrentTime)
+}
+
+// finishRead completes the first read, and starts the next one. A nil
+// data means that the selection has no content of the requested type,
+// which is delivered as empty content.
+func (w *x11Window) finishRead(data []byte) {
+	c := &w.clipboard
+	r := c.reads[0]
+	c.reads = c.reads[1:]
+	if len(c.reads) > 0 {
+		w.startRead()
+	}
+	if r.done != nil {
+		r.done(data)
+		return
+	}
+	w.w.Event(transfer.DataEvent{
+		Type: r.mime,
+		Open: func() io.ReadCloser {
+			return io.NopCloser(bytes.NewReader(data))
+		},
+		Primary: r.primary,
+	})
+}
+
+// chooseTarget returns the target of targets for mime, or 0.
+func (w *x11Window) chooseTarget(mime string, targets []C.Atom) C.Atom {
+	want := []C.Atom{w.atom(mime, false)}
+	if mime == "application/text" {
+		want = w.textTargets()
+	}
+	for _, t := range want {
+		for _, t2 := range targets {
+			if t == t2 {
+				return t
+			}
+		}
+	}
+	return 0
+}
+
+func (w *x11Window) handleSelectionNotify(ev *C.XSelectionEvent) {
+	c := &w.clipboard
+	if len(c.reads) == 0 || ev.requestor != w.xw {
+		return
+	}
+	r := &c.reads[0]
+	if ev.selection != r.selection {
+		return
+	}
+	if ev.property == C.None {
+		if ev.target != w.atoms.targets {
+			w.finishRead(nil)
+			return
+		}
+		// The owner doesn't support TARGETS; try the preferred target.
+		r.target = w.atom(r.mime, false)
+		if r.mime == "application/text" {
+			r.target = w.atoms.utf8string
+		}
+		w.convertSelection(ev.selection, r.target)
+		return
+	}
+	typ, data, ok := w.getProperty(w.xw, ev.property, true)
+	switch {
+	case !ok:
+		w.finishRead(nil)
+	case ev.target == w.atoms.targets:
+		r.target = w.chooseTarget(r.mime, atomList(data))
+		if r.target == 0 {
+			w.finishRead(nil)
+			return
+		}
+		r.started = time.Now()
+		w.convertSelection(ev.selection, r.target)
+	case typ == w.atoms.incr:
+		// Deleting the property, as getProperty did, asks the owner
+		// for the first chunk.
+		r.incr = true
+		r.started = time.Now()
+	default:
+		w.finishRead(w.decodeTarget(r.target, data))
+	}
+}
+
+func (w *x11Window) handleSelectionRequest(ev *C.XSelectionRequestEvent) {
+	if ev.selection == w.atoms.xdndSelection {
+		w.handleDragRequest(ev)
+		return
+	}
+	if (ev.selection != w.atoms.clipboard && ev.selection != w.atoms.primary) || ev.property == C.None {
+		// Unsupported clipboard or obsolete requestor.
+		return
+	}
+	w.answerSelection(ev, w.clipboard.offers)
+}
+
+// answerSelection converts a requested target of offers, or refuses
+// the request if there is no such target.
+func (w *x11Window) answerSelection(ev *C.XSelectionRequestEvent, offers []x11Offer) {
+	c := &w.clipboard
+	property := ev.property
+	switch ev.target {
+	case w.atoms.targets:
+		// The requestor wants the supported formats.
+		formats := []C.long{C.long(w.atoms.targets)}
+		for _, o := range offers {
+			formats = append(formats, C.long(o.target))
+		}
+		C.XChangeProperty(w.x, ev.requestor, property, w.atoms.atom,
+			32 /* bitwidth of formats */, C.PropModeReplace,
+			(*C.uchar)(unsafe.Pointer(&formats[0])), C.int(len(formats)),
+		)
+	default:
+		var data []byte
+		found := false
+		for _, o := range offers {
+			if o.target == ev.target {
+				data, found = o.data, true
+				break
+			}
+		}
+		switch {
+		case !found:
+			property = C.None
+		case len(data) > w.maxPropertySize():
+			// Announce an INCR transfer with a lower bound of
+			// its size, and send the chunks when the requestor
+			// deletes the property.
+			if ev.requestor != w.xw {
+				C.XSelectInput(w.x, ev.requestor, C.PropertyChangeMask)
+			}
+			size := C.long(len(data))
+			C.XChangeProperty(w.x, ev.requestor, property, w.atoms.incr,
+				32, C.PropModeReplace,
+				(*C.uchar)(unsafe.Pointer(&size)), 1,
+			)
+			c.sends = append(c.sends, &x11IncrSend{
+				requestor: ev.requestor,
+				property:  property,
+				target:    ev.target,
+				data:      data,
+			})
+		default:
+			w.changeProperty(ev.requestor, property, ev.target, data)
+		}
+	}
+	var xev C.XEvent
+	sev := (*C.XSelectionEvent)(unsafe.Pointer(&xev))
+	*sev = C.XSelectionEvent{
+		_type:     C.SelectionNotify,
+		display:   ev.display,
+		requestor: ev.requestor,
+		selection: ev.selection,
+		target:    ev.target,
+		property:  property,
+		time:      ev.time,
+	}
+	C.XSendEvent(w.x, ev.requestor, 0, 0, &xev)
+}
+
+// next removes and returns the next chunk of at most max bytes. The
+// empty chunk is the last.
+func (s *x11IncrSend) next(max int) []byte {
+	n := min(len(s.data), max)
+	chunk := s.data[:n]
+	s.data = s.data[n:]
+	return chunk
+}
+
+// handlePropertyNotify continues the INCR transfers to and from the
+// window.
+func (w *x11Window) handlePropertyNotify(ev *C.XPropertyEvent) {
+	c := &w.clipboard
+	if ev.window == w.xw && ev.atom == w.atoms.clipboardContent && ev.state == C.PropertyNewValue &&
+		len(c.reads) > 0 && c.reads[0].incr {
+		r := &c.reads[0]
+		_, data, ok := w.getProperty(w.xw, ev.atom, true)
+		switch {
+		case !ok:
+			w.finishRead(nil)
+		case len(data) == 0:
+			w.finishRead(w.decodeTarget(r.target, r.data))
+		default:
+			r.data = append(r.data, data...)
+			r.started = time.Now()
+		}
+		return
+	}
+	if ev.state != C.PropertyDelete {
+		return
+	}
+	for i, s := range c.sends {
+		if s.requestor != ev.window || s.property != ev.atom {
+			continue
+		}
+		chunk := s.next(w.maxPropertySize())
+		w.changeProperty(s.requestor, s.property, s.target, chunk)
+		if len(chunk) == 0 {
+			// The empty chunk ends the transfer.
+			c.sends = append(c.sends[:i], c.sends[i+1:]...)
+			if s.requestor != w.xw {
+				C.XSelectInput(w.x, s.requestor, C.NoEventMask)
+			}
+		}
+		return
+	}
+}
+
+// changeProperty replaces the property of a window with 8-bit data.
+func (w *x11Window) changeProperty(win C.Window, property, typ C.Atom, data []byte) {
+	var ptr *C.uchar
+	if len(data) > 0 {
+		ptr = (*C.uchar)(unsafe.Pointer(&data[0]))
+	}
+	C.XChangeProperty(w.x, win, property, typ,
+		8 /* bitwidth */, C.PropModeReplace,
+		ptr, C.int(len(data)),
+	)
+}
+
+// getProperty reads, and optionally deletes, a property of a window.
+// The items of 32-bit data are C.long sized, as returned by Xlib.
+func (w *x11Window) getProperty(win C.Window, property C.Atom, delete bool) (typ C.Atom, data []byte, ok bool) {
+	var (
+		format        C.int
+		nitems, after C.ulong
+		ptr           *C.uchar
+	)
+	// The length is in 32-bit units.
+	const maxLength = 1<<31/4 - 1
+	del := C.Bool(C.False)
+	if delete {
+		del = C.True
+	}
+	if C.XGetWindowProperty(w.x, win, property, 0, maxLength, del, C.AnyPropertyType,
+		&typ, &format, &nitems, &after, &ptr) != C.Success || typ == C.None {
+		return 0, nil, false
+	}
+	if ptr == nil {
+		return typ, []byte{}, true
+	}
+	defer C.XFree(unsafe.Pointer(ptr))
+	size := int(nitems)
+	switch format {
+	case 16:
+		size *= int(unsafe.Sizeof(C.short(0)))
+	case 32:
+		size *= int(unsafe.Sizeof(C.long(0)))
+	}
+	return typ, C.GoBytes(unsafe.Pointer(ptr), C.int(size)), true
+}
+
+// atomList returns the atoms of 32-bit property data.
+func atomList(data []byte) []C.Atom {
+	n := len(data) / int(unsafe.Sizeof(C.Atom(0)))
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*C.Atom)(unsafe.Pointer(&data[0])), n)
+}
+
+// maxPropertySize returns the size of the largest property that fits
+// in a request. Larger content is sent with INCR transfers.
+func (w *x11Window) maxPropertySize() int {
+	n := C.XExtendedMaxRequestSize(w.x)
+	if n == 0 {
+		n = C.XMaxRequestSize(w.x)
+	}
+	// The size is in 4-byte units, and includes the request header.
+	return int(n)*4 - 64
+}
+
+// decodeTarget converts selection data to the representation of its
+// MIME type.
+func (w *x11Window) decodeTarget(target C.Atom, data []byte) []byte {
+	if target != C.XA_STRING {
+		return data
+	}
+	// STRING is Latin-1.
+	buf := make([]byte, 0, len(data))
+	for _, b := range data {
+		buf = utf8.AppendRune(buf, rune(b))
+	}
+	return buf
+}
@@ -0,0 +1,435 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+/*
+#include <stdlib.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+*/
+import "C"
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unsafe"
+
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/input"
+)
+
+// x11DnDVersion is the supported version of the XDND protocol.
+const x11DnDVersion = 5
+
+// x11DnD is the XDND drag and drop state of a window.
+type x11DnD struct {
+	in  x11DragIn
+	out x11DragOut
+}
+
+// x11DragIn is a drag from another application over the window.
+type x11DragIn struct {
+	source C.Window
+	// targets are the offered targets the window may accept, and
+	// mimes their MIME types.
+	targets []C.Atom
+	mimes   []string
+	pos     f32.Point
+	// target is the accepted target at pos, and mime its MIME type.
+	target C.Atom
+	mime   string
+}
+
+// x11DragOut is a drag from the window over other applications.
+type x11DragOut struct {
+	// types are the MIME types of the drag source, if any.
+	types []string
+	// target is the XDND aware window under the pointer, and version
+	// its protocol version.
+	target  C.Window
+	version int
+	// accepted tracks whether the target accepts a drop.
+	accepted bool
+	// dropTarget is the target of the drop waiting for XdndFinished,
+	// and dropTypes the MIME types offered to it.
+	dropTarget C.Window
+	dropTypes  []string
+	// requests are the target conversions waiting for OfferDragData.
+	requests []x11DragRequest
+	// offers are the data offered by OfferDragData.
+	offers map[string][]byte
+}
+
+type x11DragRequest struct {
+	ev   C.XSelectionRequestEvent
+	mime string
+}
+
+func (w *x11Window) initDnD() {
+	version := C.long(x11DnDVersion)
+	C.XChangeProperty(w.x, w.xw, w.atoms.xdndAware, C.XA_ATOM,
+		32, C.PropModeReplace,
+		(*C.uchar)(unsafe.Pointer(&version)), 1,
+	)
+}
+
+// handleDnDMessage handles the XDND client messages, and reports whether
+// ev is one.
+func (w *x11Window) handleDnDMessage(ev *C.XClientMessageEvent) bool {
+	l := (*[5]C.long)(unsafe.Pointer(&ev.data))
+	switch ev.message_type {
+	case w.atoms.xdndEnter:
+		w.dragEnter(l)
+	case w.atoms.xdndPosition:
+		w.dragPosition(l)
+	case w.atoms.xdndLeave:
+		if C.Window(l[0]) == w.dnd.in.source {
+			w.dnd.in = x11DragIn{}
+		}
+	case w.atoms.xdndDrop:
+		w.dragDrop(l)
+	case w.atoms.xdndStatus:
+		if out := &w.dnd.out; C.Window(l[0]) == out.target {
+			out.accepted = l[1]&1 != 0
+		}
+	case w.atoms.xdndFinished:
+		w.dragFinished(l)
+	default:
+		return false
+	}
+	return true
+}
+
+func (w *x11Window) sendDnDMessage(win C.Window, typ C.Atom, l [5]C.long) {
+	var xev C.XEvent
+	ev := (*C.XClientMessageEvent)(unsafe.Pointer(&xev))
+	*ev = C.XClientMessageEvent{
+		_type:        C.ClientMessage,
+		display:      w.x,
+		window:       win,
+		message_type: typ,
+		format:       32,
+	}
+	*(*[5]C.long)(unsafe.Pointer(&ev.data)) = l
+	C.XSendEvent(w.x, win, C.False, C.NoEventMask, &xev)
+}
+
+func (w *x11Window) dragEnter(l *[5]C.long) {
+	in := &w.dnd.in
+	*in = x11DragIn{source: C.Window(l[0])}
+	var types []C.Atom
+	if l[1]&1 != 0 {
+		// The source lists more than three types in a property.
+		if _, data, ok := w.getProperty(in.source, w.atoms.xdndTypeList, false); ok {
+			types = atomList(data)
+		}
+	} else {
+		for _, t := range l[2:] {
+			if t != C.None {
+				types = append(types, C.Atom(t))
+			}
+		}
+	}
+	in.targets, in.mimes = w.dropTargets(types)
+}
+
+// dropTargets returns the targets of types the window may accept, and
+// their MIME types: the preferred text target as "application/text",
+// and the targets named by MIME types, such as text/uri-list for
+// files.
+func (w *x11Window) dropTargets(types []C.Atom) (targets []C.Atom, mimes []string) {
+	if text := w.chooseTarget("application/text", types); text != 0 {
+		targets = append(targets, text)
+		mimes = append(mimes, "application/text")
+	}
+	for _, t := range types {
+		if t == w.atoms.textPlain || t == w.atoms.plaintext {
+			continue
+		}
+		cname := C.XGetAtomName(w.x, t)
+		if cname == nil {
+			continue
+		}
+		name := C.GoString(cname)
+		C.XFree(unsafe.Pointer(cname))
+		if strings.Contains(name, "/") {
+			targets = append(targets, t)
+			mimes = append(mimes, name)
+		}
+	}
+	return targets, mimes
+}
+
+func (w *x11Window) dragPosition(l *[5]C.long) {
+	in := &w.dnd.in
+	if C.Window(l[0]) != in.source {
+		return
+	}
+	var (
+		x, y  C.int
+		child C.Window
+	)
+	C.XTranslateCoordinates(w.x, C.XDefaultRootWindow(w.x), w.xw, C.int(l[2]>>16), C.int(l[2]&0xffff), &x, &y, &child)
+	in.pos = f32.Point{X: float32(x), Y: float32(y)}
+	in.target, in.mime = 0, ""
+	if mime, ok := w.w.DropTarget(in.pos, in.mimes); ok {
+		for i, m := range in.mimes {
+			if m == mime {
+				in.target, in.mime = in.targets[i], m
+				break
+			}
+		}
+	}
+	// Ask for positions in the whole window, with an empty rectangle.
+	status := [5]C.long{C.long(w.xw), 1 << 1}
+	if in.target != 0 {
+		status[1] |= 1
+		status[4] = C.long(w.atoms.xdndActionCopy)
+	}
+	w.sendDnDMessage(in.source, w.atoms.xdndStatus, status)
+}
+
+// dragDrop converts the accepted target, delivers it as a DropEvent
+// and finishes the drop.
+func (w *x11Window) dragDrop(l *[5]C.long) {
+	in := w.dnd.in
+	if C.Window(l[0]) != in.source {
+		return
+	}
+	w.dnd.in = x11DragIn{}
+	finish := func(accepted bool) {
+		finished := [5]C.long{C.long(w.xw)}
+		if accepted {
+			finished[1] = 1
+			finished[2] = C.long(w.atoms.xdndActionCopy)
+		}
+		w.sendDnDMessage(in.source, w.atoms.xdndFinished, finished)
+	}
+	if in.target == 0 {
+		finish(false)
+		return
+	}
+	w.queueRead(x11ClipboardRead{
+		mime:      in.mime,
+		selection: w.atoms.xdndSelection,
+		target:    in.target,
+		done: func(data []byte) {
+			if data != nil {
+				w.w.Event(input.DropEvent{
+					Position: in.pos,
+					Type:     in.mime,
+					Open: func() io.ReadCloser {
+						return io.NopCloser(bytes.NewReader(data))
+					},
+				})
+			}
+			finish(data != nil)
+		},
+	})
+}
+
+func (w *x11Window) SetDragSource(types []string) {
+	out := &w.dnd.out
+	if types == nil && out.target != 0 {
+		// The drag ended without a drop on the target.
+		w.sendDnDMessage(out.target, w.atoms.xdndLeave, [5]C.long{C.long(w.xw)})
+		out.target = 0
+	}
+	out.types = types
+}
+
+// dragMotion follows a drag from the window over other applications.
+func (w *x11Window) dragMotion(ev *C.XMotionEvent) {
+	out := &w.dnd.out
+	if out.types == nil || w.pointerBtns == 0 {
+		return
+	}
+	target, version := w.dragTargetAt(ev.x_root, ev.y_root)
+	if target != out.target {
+		if out.target != 0 {
+			w.sendDnDMessage(out.target, w.atoms.xdndLeave, [5]C.long{C.long(w.xw)})
+		}
+		out.target = target
+		out.version = min(version, x11DnDVersion)
+		out.accepted = false
+		if target != 0 {
+			w.dragSendEnter()
+		}
+	}
+	if out.target != 0 {
+		w.sendDnDMessage(out.target, w.atoms.xdndPosition, [5]C.long{
+			C.long(w.xw),
+			0,
+			C.long(ev.x_root)<<16 | C.long(ev.y_root)&0xffff,
+			C.long(ev.time),
+			C.long(w.atoms.xdndActionCopy),
+		})
+	}
+}
+
+// dragTargetAt returns the XDND aware window of another application at
+// a root window position, and its protocol version.
+func (w *x11Window) dragTargetAt(x, y C.int) (C.Window, int) {
+	root := C.XDefaultRootWindow(w.x)
+	win := root
+	for {
+		var (
+			cx, cy C.int
+			child  C.Window
+		)
+		if C.XTranslateCoordinates(w.x, root, win, x, y, &cx, &cy, &child) == 0 || child == C.None {
+			return 0, 0
+		}
+		win = child
+		if win == w.xw {
+			// Drags within the window are routed by the window.
+			return 0, 0
+		}
+		typ, data, ok := w.getProperty(win, w.atoms.xdndAware, false)
+		if ok && typ == C.XA_ATOM {
+			if v := atomList(data); len(v) > 0 && v[0] >= 3 {
+				return win, int(v[0])
+			}
+		}
+	}
+}
+
+// dragSendEnter offers the drag source types to the target.
+func (w *x11Window) dragSendEnter() {
+	out := &w.dnd.out
+	C.XSetSelectionOwner(w.x, w.atoms.xdndSelection, w.xw, C.CurrentTime)
+	targets := w.dragTargets(out.types)
+	enter := [5]C.long{C.long(w.xw), C.long(out.version) << 24}
+	if len(targets) > 3 {
+		enter[1] |= 1
+		list := make([]C.long, len(targets))
+		for i, t := range targets {
+			list[i] = C.long(t)
+		}
+		C.XChangeProperty(w.x, w.xw, w.atoms.xdndTypeList, C.XA_ATOM,
+			32, C.PropModeReplace,
+			(*C.uchar)(unsafe.Pointer(&list[0])), C.int(len(list)),
+		)
+	}
+	for i, t := range targets[:min(len(targets), 3)] {
+		enter[2+i] = C.long(t)
+	}
+	w.sendDnDMessage(out.target, w.atoms.xdndEnter, enter)
+}
+
+// dragTargets returns the targets of a drag source with MIME types.
+func (w *x11Window) dragTargets(types []string) []C.Atom {
+	var targets []C.Atom
+	for _, t := range types {
+		if t == "application/text" {
+			targets = append(targets, w.textTargets()...)
+			continue
+		}
+		targets = append(targets, w.atom(t, false))
+	}
+	return targets
+}
+
+// dragMIME returns the MIME type of types for target, or the empty
+// string.
+func (w *x11Window) dragMIME(target C.Atom, types []string) string {
+	for _, t := range types {
+		for _, t2 := range w.dragTargets([]string{t}) {
+			if t2 == target {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// dragRelease drops the drag on the target under the pointer, if it
+// accepts it. It is called before the pointer release is delivered.
+func (w *x11Window) dragRelease(time C.Time) {
+	out := &w.dnd.out
+	if out.target == 0 {
+		return
+	}
+	if !out.accepted {
+		w.sendDnDMessage(out.target, w.atoms.xdndLeave, [5]C.long{C.long(w.xw)})
+		out.target = 0
+		return
+	}
+	w.sendDnDMessage(out.target, w.atoms.xdndDrop, [5]C.long{C.long(w.xw), 0, C.long(time)})
+	out.dropTarget = out.target
+	out.dropTypes = out.types
+	out.requests = nil
+	out.offers = make(map[string][]byte)
+	out.target = 0
+	w.w.Event(input.ExternalDropEvent{})
+}
+
+// handleDragRequest answers a conversion of the XdndSelection, or
+// requests the data from the drag source.
+func (w *x11Window) handleDragRequest(ev *C.XSelectionRequestEvent) {
+	out := &w.dnd.out
+	if ev.property == C.None {
+		return
+	}
+	types := out.dropTypes
+	if out.dropTarget == 0 {
+		types = out.types
+	}
+	var offers []x11Offer
+	if ev.target == w.atoms.targets {
+		for _, t := range w.dragTargets(types) {
+			offers = append(offers, x11Offer{target: t})
+		}
+		w.answerSelection(ev, offers)
+		return
+	}
+	mime := w.dragMIME(ev.target, types)
+	if mime == "" || out.dropTarget == 0 {
+		// The data is only available after the drop.
+		w.answerSelection(ev, nil)
+		return
+	}
+	if data, ok := out.offers[mime]; ok {
+		w.answerSelection(ev, []x11Offer{{target: ev.target, data: data}})
+		return
+	}
+	out.requests = append(out.requests, x11DragRequest{ev: *ev, mime: mime})
+	w.w.Event(input.ExternalRequestEvent{Type: mime})
+}
+
+func (w *x11Window) OfferDragData(mime string, data []byte) {
+	out := &w.dnd.out
+	if out.dropTarget == 0 {
+		return
+	}
+	out.offers[mime] = data
+	requests := out.requests
+	out.requests = nil
+	for _, r := range requests {
+		if r.mime != mime {
+			out.requests = append(out.requests, r)
+			continue
+		}
+		w.answerSelection(&r.ev, []x11Offer{{target: r.ev.target, data: data}})
+	}
+}
+
+func (w *x11Window) dragFinished(l *[5]C.long) {
+	out := &w.dnd.out
+	if C.Window(l[0]) != out.dropTarget {
+		return
+	}
+	// Refuse the requests the source didn't answer.
+	for _, r := range out.requests {
+		w.answerSelection(&r.ev, nil)
+	}
+	out.dropTarget = 0
+	out.dropTypes = nil
+	out.requests = nil
+	out.offers = nil
+	w.w.Event(input.ExternalDoneEvent{})
+}
@@ -0,0 +1,357 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include <wchar.h>
+#include <X11/Xlib.h>
+
+void gio_x11RegisterIMInstantiate(Display *dpy, uintptr_t handle);
+void gio_x11UnregisterIMInstantiate(Display *dpy, uintptr_t handle);
+XIM gio_x11OpenIM(Display *dpy, uintptr_t handle);
+XIC gio_x11CreateIC(Display *dpy, XIM xim, Window win, uintptr_t handle, XIMStyle *style);
+void gio_x11SetICSpot(XIC xic, short x, short y);
+void gio_x11ResetIC(XIC xic);
+*/
+import "C"
+import (
+	"image"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/key"
+)
+
+// x11IME is the state of the X input method (XIM) client of a window.
+type x11IME struct {
+	xim   C.XIM
+	xic   C.XIC
+	style C.XIMStyle
+	// show tracks ShowTextInput.
+	show bool
+	// focus tracks the window keyboard focus.
+	focus bool
+	hint  key.InputHint
+	// preedit is the composition drawn by the input method.
+	preedit []rune
+	// caret is the rune offset of the caret inside preedit.
+	caret int
+	// spot is the last candidate window position.
+	spot image.Point
+	buf  []byte
+}
+
+// x11IMEWindows maps X windows to their x11Window for the XIM callbacks.
+var x11IMEWindows sync.Map // map[C.Window]*x11Window
+
+func x11IMEWindow(handle C.uintptr_t) *x11Window {
+	w, ok := x11IMEWindows.Load(C.Window(handle))
+	if !ok {
+		return nil
+	}
+	return w.(*x11Window)
+}
+
+// initIME connects to the input method named by XMODIFIERS, or waits
+// for it to appear.
+func (w *x11Window) initIME() {
+	x11IMEWindows.Store(w.xw, w)
+	C.XSetLocaleModifiers((*C.char)(unsafe.Pointer(&[]byte("\x00")[0])))
+	if !w.openIME() {
+		C.gio_x11RegisterIMInstantiate(w.x, C.uintptr_t(w.xw))
+	}
+}
+
+// openIME opens the input method and creates the input context. It
+// reports whether it succeeded.
+func (w *x11Window) openIME() bool {
+	xim := C.gio_x11OpenIM(w.x, C.uintptr_t(w.xw))
+	if xim == nil {
+		return false
+	}
+	var style C.XIMStyle
+	xic := C.gio_x11CreateIC(w.x, xim, w.xw, C.uintptr_t(w.xw), &style)
+	if xic == nil {
+		C.XCloseIM(xim)
+		return false
+	}
+	w.ime.xim = xim
+	w.ime.xic = xic
+	w.ime.style = style
+	w.ime.preedit = nil
+	w.ime.caret = 0
+	w.ime.spot = image.Point{}
+	w.updateICFocus()
+	return true
+}
+
+func (w *x11Window) destroyIME() {
+	x11IMEWindows.Delete(w.xw)
+	C.gio_x11UnregisterIMInstantiate(w.x, C.uintptr_t(w.xw))
+	if w.ime.xic != nil {
+		C.XDestroyIC(w.ime.xic)
+		w.ime.xic = nil
+	}
+	if w.ime.xim != nil {
+		C.XCloseIM(w.ime.xim)
+		w.ime.xim = nil
+	}
+}
+
+// updateICFocus gives the input context focus when the window has
+// keyboard focus and text input is requested. Password fields never
+// get an input method.
+func (w *x11Window) updateICFocus() {
+	if w.ime.xic == nil {
+		return
+	}
+	if w.ime.show && w.ime.focus && w.ime.hint != key.HintPassword {
+		w.updateICSpot()
+		C.XSetICFocus(w.ime.xic)
+	} else {
+		w.cancelComposition()
+		C.XUnsetICFocus(w.ime.xic)
+	}
+}
+
+// updateICSpot moves the candidate window below the editor caret.
+func (w *x11Window) updateICSpot() {
+	if w.ime.xic == nil {
+		return
+	}
+	sel := w.w.EditorState().Selection
+	caret := sel.Transform.Transform(sel.Caret.Pos.Add(f32.Pt(0, sel.Caret.Descent)))
+	spot := image.Pt(int(caret.X+.5), int(caret.Y+.5))
+	if spot == w.ime.spot {
+		return
+	}
+	w.ime.spot = spot
+	C.gio_x11SetICSpot(w.ime.xic, C.short(spot.X), C.short(spot.Y))
+}
+
+// cancelComposition discards the composition in progress, if any.
+func (w *x11Window) cancelComposition() {
+	if w.ime.xic == nil || len(w.ime.preedit) == 0 {
+		return
+	}
+	C.gio_x11ResetIC(w.ime.xic)
+	w.clearPreedit()
+}
+
+// clearPreedit removes the preedit text from the editor.
+func (w *x11Window) clearPreedit() {
+	if len(w.ime.preedit) == 0 {
+		return
+	}
+	w.ime.preedit = nil
+	w.ime.caret = 0
+	if w.w.EditorState().Compose.Start != -1 {
+		w.updatePreedit()
+	}
+}
+
+// lookupString returns the text committed by the key press, if any.
+func (w *x11Window) lookupString(kevt *C.XKeyPressedEvent) string {
+	if len(w.ime.buf) == 0 {
+		w.ime.buf = make([]byte, 64)
+	}
+	var status C.Status
+	n := C.Xutf8LookupString(w.ime.xic, kevt, (*C.char)(unsafe.Pointer(&w.ime.buf[0])), C.int(len(w.ime.buf)), nil, &status)
+	if status == C.XBufferOverflow {
+		w.ime.buf = make([]byte, n)
+		n = C.Xutf8LookupString(w.ime.xic, kevt, (*C.char)(unsafe.Pointer(&w.ime.buf[0])), C.int(len(w.ime.buf)), nil, &status)
+	}
+	if status != C.XLookupChars && status != C.XLookupBoth {
+		return ""
+	}
+	// Text from regular key presses follows the xkb rules: shortcuts
+	// don't produce text. Synthetic key presses (keycode 0) carry text
+	// committed by the input method.
+	if kevt.keycode != 0 && w.xkb.Modifiers()&(key.ModCtrl|key.ModAlt|key.ModSuper) != 0 {
+		return ""
+	}
+	// Report only printable runes.
+	text := make([]rune, 0, n)
+	for _, r := range string(w.ime.buf[:n]) {
+		if unicode.IsPrint(r) {
+			text = append(text, r)
+		}
+	}
+	return string(text)
+}
+
+// commitText replaces the composition, or the selection if there is
+// none, with text.
+func (w *x11Window) commitText(text string) {
+	w.ime.preedit = nil
+	w.ime.caret = 0
+	state := w.w.EditorState()
+	if state.Compose.Start == -1 {
+		w.w.EditorInsert(text, false)
+		return
+	}
+	rng := state.Compose
+	if rng.Start > rng.End {
+		rng.Start, rng.End = rng.End, rng.Start
+	}
+	w.w.EditorReplace(rng, text, false)
+	w.w.SetComposingRegion(key.Range{Start: -1, End: -1})
+	pos := rng.Start + utf8.RuneCountInString(text)
+	w.w.SetEditorSelection(key.Range{Start: pos, End: pos})
+}
+
+// updatePreedit replaces the composition, or the selection if there is
+// none, with the preedit text.
+func (w *x11Window) updatePreedit() {
+	state := w.w.EditorState()
+	rng := state.Compose
+	if rng.Start == -1 {
+		rng = state.Selection.Range
+	}
+	if rng.Start > rng.End {
+		rng.Start, rng.End = rng.End, rng.Start
+	}
+	w.w.EditorReplace(rng, string(w.ime.preedit), true)
+	comp := key.Range{Start: -1, End: -1}
+	if len(w.ime.preedit) > 0 {
+		comp = key.Range{Start: rng.Start, End: rng.Start + len(w.ime.preedit)}
+	}
+	w.w.SetComposingRegion(comp)
+	pos := rng.Start + w.ime.caret
+	w.w.SetEditorSelection(key.Range{Start: pos, End: pos})
+}
+
+//export gio_onX11PreeditStart
+func gio_onX11PreeditStart(handle C.uintptr_t) {
+	w := x11IMEWindow(handle)
+	if w == nil {
+		return
+	}
+	w.ime.preedit = nil
+	w.ime.caret = 0
+	w.updateICSpot()
+}
+
+//export gio_onX11PreeditDone
+func gio_onX11PreeditDone(handle C.uintptr_t) {
+	w := x11IMEWindow(handle)
+	if w == nil {
+		return
+	}
+	// The composition may be abandoned without being cleared.
+	w.clearPreedit()
+	w.w.SetComposingRegion(key.Range{Start: -1, End: -1})
+}
+
+//export gio_onX11PreeditDraw
+func gio_onX11PreeditDraw(handle C.uintptr_t, caret, first, length C.int, text *C.wchar_t, textLen, feedbackOnly C.int) {
+	w := x11IMEWindow(handle)
+	if w == nil {
+		return
+	}
+	var repl []rune
+	if text != nil {
+		// wchar_t is UTF-32 on the supported platforms.
+		for _, r := range unsafe.Slice((*int32)(unsafe.Pointer(text)), int(textLen)) {
+			repl = append(repl, rune(r))
+		}
+	}
+	old := w.ime.preedit
+	preedit := drawPreedit(old, int(first), int(length), repl, feedbackOnly != 0)
+	w.ime.caret = clampInt(int(caret), 0, len(preedit))
+	if len(old) == 0 && len(preedit) == 0 {
+		// Nothing to replace; don't clobber the selection.
+		return
+	}
+	w.ime.preedit = preedit
+	w.updatePreedit()
+}
+
+// drawPreedit applies a XIM preedit draw to preedit: the length runes
+// at first are replaced with repl, clamped to preedit. A draw that only
+// changes the feedback leaves the text alone.
+func drawPreedit(preedit []rune, first, length int, repl []rune, feedbackOnly bool) []rune {
+	if feedbackOnly {
+		return preedit
+	}
+	start := clampInt(first, 0, len(preedit))
+	end := clampInt(start+length, start, len(preedit))
+	res := make([]rune, 0, len(preedit)-(end-start)+len(repl))
+	res = append(res, preedit[:start]...)
+	res = append(res, repl...)
+	return append(res, preedit[end:]...)
+}
+
+//export gio_onX11PreeditCaret
+func gio_onX11PreeditCaret(handle C.uintptr_t, direction C.XIMCaretDirection, position C.int) C.int {
+	w := x11IMEWindow(handle)
+	if w == nil {
+		return position
+	}
+	caret := w.ime.caret
+	switch direction {
+	case C.XIMAbsolutePosition:
+		caret = int(position)
+	case C.XIMForwardChar:
+		caret++
+	case C.XIMBackwardChar:
+		caret--
+	case C.XIMLineStart:
+		caret = 0
+	case C.XIMLineEnd:
+		caret = len(w.ime.preedit)
+	}
+	caret = clampInt(caret, 0, len(w.ime.preedit))
+	if caret != w.ime.caret && len(w.ime.preedit) > 0 {
+		w.ime.caret = caret
+		if comp := w.w.EditorState().Compose; comp.Start != -1 {
+			pos := min(comp.Start, comp.End) + caret
+			w.w.SetEditorSelection(key.Range{Start: pos, End: pos})
+		}
+	}
+	return C.int(caret)
+}
+
+//export gio_onX11IMInstantiate
+func gio_onX11IMInstantiate(handle C.uintptr_t) {
+	w := x11IMEWindow(handle)
+	if w == nil || w.ime.xim != nil {
+		return
+	}
+	if w.openIME() {
+		C.gio_x11UnregisterIMInstantiate(w.x, C.uintptr_t(w.xw))
+	}
+}
+
+//export gio_onX11IMDestroy
+func gio_onX11IMDestroy(handle C.uintptr_t) {
+	w := x11IMEWindow(handle)
+	if w == nil {
+		return
+	}
+	// The input method server went away and took the input context with
+	// it. Wait for it, or another input method, to come back.
+	w.ime.xim = nil
+	w.ime.xic = nil
+	w.clearPreedit()
+	C.gio_x11RegisterIMInstantiate(w.x, C.uintptr_t(w.xw))
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+import "testing"
+
+func TestDrawPreedit(t *testing.T) {
+	tests := []struct {
+		old           string
+		first, length int
+		repl          string
+		feedbackOnly  bool
+		want          string
+	}{
+		{"", 0, 0, "か", false, "か"},
+		{"か", 0, 1, "かん", false, "かん"},
+		{"かんじ", 1, 1, "ン", false, "かンじ"},
+		{"かんじ", 3, 0, "を", false, "かんじを"},
+		{"かんじ", 0, 3, "", false, ""},
+		// Out of range changes are clamped to the preedit.
+		{"かんじ", 2, 10, "", false, "かん"},
+		{"かんじ", 5, 1, "x", false, "かんじx"},
+		{"かんじ", -1, 1, "", false, "んじ"},
+		// Feedback changes leave the text alone.
+		{"かんじ", 0, 3, "", true, "かんじ"},
+	}
+	for _, test := range tests {
+		got := string(drawPreedit([]rune(test.old), test.first, test.length, []rune(test.repl), test.feedbackOnly))
+		if got != test.want {
+			t.Errorf("drawPreedit(%q, %d, %d, %q, %v) = %q, want %q", test.old, test.first, test.length, test.repl, test.feedbackOnly, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+/*
+#cgo freebsd openbsd LDFLAGS: -lXi
+#cgo linux pkg-config: xi
+
+#include <stdlib.h>
+#include <X11/Xlib.h>
+#include <X11/extensions/XInput2.h>
+
+static int gio_x11SelectRawMotion(Display *dpy, int enable) {
+	unsigned char mask[XIMaskLen(XI_RawMotion)] = { 0 };
+	if (enable) {
+		XISetMask(mask, XI_RawMotion);
+	}
+	XIEventMask em = {
+		.deviceid = XIAllMasterDevices,
+		.mask_len = sizeof(mask),
+		.mask = mask,
+	};
+	return XISelectEvents(dpy, DefaultRootWindow(dpy), &em, 1);
+}
+
+static void gio_x11RawMotion(XIRawEvent *re, double *dx, double *dy) {
+	const double *v = re->raw_values;
+	*dx = 0;
+	*dy = 0;
+	if (re->valuators.mask_len == 0) {
+		return;
+	}
+	if (XIMaskIsSet(re->valuators.mask, 0)) {
+		*dx = *v++;
+	}
+	if (XIMaskIsSet(re->valuators.mask, 1)) {
+		*dy = *v;
+	}
+}
+*/
+import "C"
+import (
+	"errors"
+	"time"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/pointer"
+)
+
+// x11Input is the cursor mode state of a window.
+type x11Input struct {
+	mode mado.InputMode
+	// focus tracks the window keyboard focus.
+	focus bool
+	// lastPos is the most recent cursor position.
+	lastPos f32.Point
+	// virtualPos is the cursor position reported while the cursor is
+	// disabled.
+	virtualPos f32.Point
+	// grabbed tracks whether the pointer is grabbed.
+	grabbed bool
+	// rawMotion tracks whether XI_RawMotion events are selected.
+	rawMotion bool
+	// hiddenCursor is an invisible cursor, created on demand.
+	hiddenCursor C.Cursor
+	// xiOpcode is the major opcode of the XInput extension, or 0 if
+	// XInput 2.0 is not available.
+	xiOpcode C.int
+}
+
+func init() {
+	x11RawMotionDriver = x11RawMouseMotionSupported
+}
+
+// x11RawMouseMotionSupported reports whether the X server supports
+// XInput 2.0 raw motion events.
+func x11RawMouseMotionSupported() (bool, error) {
+	if err := initX11(); err != nil {
+		return false, err
+	}
+	dpy := C.XOpenDisplay(nil)
+	if dpy == nil {
+		return false, errors.New("x11: cannot connect to the X server")
+	}
+	defer C.XCloseDisplay(dpy)
+	return x11XIOpcode(dpy) != 0, nil
+}
+
+// x11XIOpcode returns the major opcode of the XInput extension, or 0 if
+// the server doesn't support XInput 2.0.
+func x11XIOpcode(dpy *C.Display) C.int {
+	name := C.CString("XInputExtension")
+	defer C.free(unsafe.Pointer(name))
+	var opcode, eventBase, errorBase C.int
+	if C.XQueryExtension(dpy, name, &opcode, &eventBase, &errorBase) == 0 {
+		return 0
+	}
+	major, minor := C.int(2), C.int(0)
+	if C.XIQueryVersion(dpy, &major, &minor) != C.Success {
+		return 0
+	}
+	return opcode
+}
+
+func (w *x11Window) initInput() {
+	w.input.xiOpcode = x11XIOpcode(w.x)
+}
+
+func (w *x11Window) destroyInput() {
+	if w.input.rawMotion {
+		// The display outlives the window.
+		C.gio_x11SelectRawMotion(w.x, 0)
+		w.input.rawMotion = false
+	}
+	if w.input.hiddenCursor != 0 {
+		C.XFreeCursor(w.x, w.input.hiddenCursor)
+		w.input.hiddenCursor = 0
+	}
+}
+
+func (w *x11Window) SetInputMode(mode mado.InputMode) {
+	old := w.input.mode
+	if mode.Cursor == mado.CursorModeDisabled && old.Cursor != mado.CursorModeDisabled {
+		// Start the virtual cursor where the real cursor is.
+		w.input.virtualPos = w.input.lastPos
+	}
+	w.input.mode = mode
+	w.xkb.LockKeyMods = mode.LockKeyMods
+	if old.Cursor == mado.CursorModeDisabled && mode.Cursor != mado.CursorModeDisabled && w.input.focus {
+		// Leave the real cursor where the virtual cursor was.
+		w.warpCursor(w.input.virtualPos)
+	}
+	w.updateInputMode()
+}
+
+func (w *x11Window) SetCursorPos(pos f32.Point) {
+	if w.input.mode.Cursor == mado.CursorModeDisabled {
+		w.input.virtualPos = pos
+		return
+	}
+	if !w.input.focus {
+		// Don't steal the cursor from other windows.
+		return
+	}
+	w.warpCursor(pos)
+}
+
+func (w *x11Window) warpCursor(pos f32.Point) {
+	C.XWarpPointer(w.x, C.None, w.xw, 0, 0, 0, 0, C.int(pos.X), C.int(pos.Y))
+	C.XFlush(w.x)
+}
+
+// cursorHidden reports whether the input mode hides the cursor.
+func (w *x11Window) cursorHidden() bool {
+	switch w.input.mode.Cursor {
+	case mado.CursorModeHidden, mado.CursorModeDisabled:
+		return true
+	}
+	return false
+}
+
+// updateInputMode applies the cursor image, pointer grab and raw motion
+// selection of the input mode. Grabs are released while the window
+// doesn't have focus.
+func (w *x11Window) updateInputMode() {
+	switch {
+	case w.cursorHidden():
+		C.XDefineCursor(w.x, w.xw, w.invisibleCursor())
+	case w.customCursor != 0:
+		w.hideCursor(false)
+		C.XDefineCursor(w.x, w.xw, w.customCursor)
+	default:
+		w.SetCursor(w.cursor)
+	}
+	mode := w.input.mode.Cursor
+	grab := w.input.focus && (mode == mado.CursorModeCaptured || mode == mado.CursorModeDisabled)
+	if grab {
+		// Grab again to update the grab cursor.
+		w.grabPointer()
+	} else if w.input.grabbed {
+		C.XUngrabPointer(w.x, C.CurrentTime)
+		w.input.grabbed = false
+	}
+	disabled := w.input.focus && mode == mado.CursorModeDisabled
+	if disabled {
+		w.warpCursor(w.cursorCenter())
+	}
+	raw := disabled && w.input.mode.RawMouseMotion && w.input.xiOpcode != 0
+	if raw != w.input.rawMotion {
+		C.gio_x11SelectRawMotion(w.x, boolToCInt(raw))
+		w.input.rawMotion = raw
+	}
+	C.XFlush(w.x)
+}
+
+func (w *x11Window) grabPointer() {
+	cursor := C.Cursor(C.None)
+	if w.cursorHidden() {
+		cursor = w.invisibleCursor()
+	}
+	const mask = C.ButtonPressMask | C.ButtonReleaseMask | C.PointerMotionMask
+	st := C.XGrabPointer(w.x, w.xw, C.True, mask, C.GrabModeAsync, C.GrabModeAsync, w.xw, cursor, C.CurrentTime)
+	w.input.grabbed = st == C.GrabSuccess
+}
+
+// invisibleCursor returns a blank cursor for hiding the cursor while it
+// is over the window.
+func (w *x11Window) invisibleCursor() C.Cursor {
+	if w.input.hiddenCursor == 0 {
+		var data C.char
+		pix := C.XCreateBitmapFromData(w.x, w.xw, &data, 1, 1)
+		var col C.XColor
+		w.input.hiddenCursor = C.XCreatePixmapCursor(w.x, pix, pix, &col, &col, 0, 0)
+		C.XFreePixmap(w.x, pix)
+	}
+	return w.input.hiddenCursor
+}
+
+func (w *x11Window) cursorCenter() f32.Point {
+	return f32.Pt(float32(w.config.Size.X/2), float32(w.config.Size.Y/2))
+}
+
+// cursorPos returns the position to report for an event at pos.
+func (w *x11Window) cursorPos(pos f32.Point) f32.Point {
+	if w.input.mode.Cursor == mado.CursorModeDisabled {
+		return w.input.virtualPos
+	}
+	w.input.lastPos = pos
+	return pos
+}
+
+// disabledMotion handles a motion event at pos while the cursor is
+// disabled, and reports whether the virtual cursor moved.
+func (w *x11Window) disabledMotion(pos f32.Point) bool {
+	if !w.input.focus {
+		return false
+	}
+	c := w.cursorCenter()
+	if pos == c {
+		// The motion caused by re-centering.
+		return false
+	}
+	w.warpCursor(c)
+	if w.input.rawMotion {
+		// Motion is reported by the raw events.
+		return false
+	}
+	w.input.virtualPos = w.input.virtualPos.Add(pos.Sub(c))
+	return true
+}
+
+// handleGenericEvent handles XInput 2 raw motion events.
+func (w *x11Window) handleGenericEvent(xev *C.XEvent) {
+	cookie := (*C.XGenericEventCookie)(unsafe.Pointer(xev))
+	if w.input.xiOpcode == 0 || cookie.extension != w.input.xiOpcode {
+		return
+	}
+	if C.XGetEventData(w.x, cookie) == 0 {
+		return
+	}
+	defer C.XFreeEventData(w.x, cookie)
+	if cookie.evtype != C.XI_RawMotion || !w.input.rawMotion {
+		return
+	}
+	re := (*C.XIRawEvent)(cookie.data)
+	var dx, dy C.double
+	C.gio_x11RawMotion(re, &dx, &dy)
+	if dx == 0 && dy == 0 {
+		return
+	}
+	w.input.virtualPos = w.input.virtualPos.Add(f32.Pt(float32(dx), float32(dy)))
+	w.w.Event(pointer.Event{
+		Kind:      pointer.Move,
+		Source:    pointer.Mouse,
+		Buttons:   w.pointerBtns,
+		Position:  w.input.virtualPos,
+		Time:      time.Duration(re.time) * time.Millisecond,
+		Modifiers: w.xkb.Modifiers(),
+	})
+}
+
+func boolToCInt(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,274 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+/*
+#cgo freebsd openbsd LDFLAGS: -lXrandr
+#cgo linux pkg-config: xrandr
+
+#include <stdlib.h>
+#include <limits.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+#include <X11/extensions/Xrandr.h>
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// x11Monitors tracks monitors through the XRandR extension. It uses a
+// display connection of its own, because monitors are needed before
+// and independently of any window.
+type x11Monitors struct {
+	x         *C.Display
+	root      C.Window
+	eventBase C.int
+	update    func([]mado.Monitor)
+
+	atoms struct {
+		// "_NET_WORKAREA"
+		workArea C.Atom
+		// "_NET_CURRENT_DESKTOP"
+		currentDesktop C.Atom
+	}
+}
+
+func init() {
+	x11MonitorDriver = newX11Monitors
+}
+
+func newX11Monitors(update func([]mado.Monitor)) error {
+	if err := initX11(); err != nil {
+		return err
+	}
+	dpy := C.XOpenDisplay(nil)
+	if dpy == nil {
+		return errors.New("x11: cannot connect to the X server")
+	}
+	var eventBase, errorBase C.int
+	if C.XRRQueryExtension(dpy, &eventBase, &errorBase) == 0 {
+		C.XCloseDisplay(dpy)
+		return errors.New("x11: XRandR extension not available")
+	}
+	var major, minor C.int
+	if C.XRRQueryVersion(dpy, &major, &minor) == 0 || major < 1 || (major == 1 && minor < 3) {
+		C.XCloseDisplay(dpy)
+		return fmt.Errorf("x11: XRandR %d.%d is too old, need 1.3", major, minor)
+	}
+	m := &x11Monitors{
+		x:         dpy,
+		root:      C.XDefaultRootWindow(dpy),
+		eventBase: eventBase,
+		update:    update,
+	}
+	m.atoms.workArea = m.atom("_NET_WORKAREA")
+	m.atoms.currentDesktop = m.atom("_NET_CURRENT_DESKTOP")
+	C.XRRSelectInput(dpy, m.root, C.RRScreenChangeNotifyMask|C.RRCrtcChangeNotifyMask|C.RROutputChangeNotifyMask)
+	// Listen for work area changes.
+	C.XSelectInput(dpy, m.root, C.PropertyChangeMask)
+	update(m.monitors())
+	go m.loop()
+	return nil
+}
+
+func (m *x11Monitors) atom(name string) C.Atom {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.XInternAtom(m.x, cname, C.False)
+}
+
+func (m *x11Monitors) loop() {
+	pollfds := []syscall.PollFd{
+		{Fd: int32(C.XConnectionNumber(m.x)), Events: syscall.POLLIN | syscall.POLLERR},
+	}
+	var xev C.XEvent
+	for {
+		changed := false
+		for C.XPending(m.x) != 0 {
+			C.XNextEvent(m.x, &xev)
+			switch _type := (*C.XAnyEvent)(unsafe.Pointer(&xev))._type; _type {
+			case m.eventBase + C.RRScreenChangeNotify:
+				C.XRRUpdateConfiguration(&xev)
+				changed = true
+			case m.eventBase + C.RRNotify:
+				changed = true
+			case C.PropertyNotify:
+				pevt := (*C.XPropertyEvent)(unsafe.Pointer(&xev))
+				if pevt.atom == m.atoms.workArea || pevt.atom == m.atoms.currentDesktop {
+					changed = true
+				}
+			}
+		}
+		if changed {
+			m.update(m.monitors())
+		}
+		pollfds[0].Revents = 0
+		if _, err := syscall.Poll(pollfds, -1); err != nil && err != syscall.EINTR {
+			panic(fmt.Errorf("x11 monitors: poll failed: %w", err))
+		}
+		if pollfds[0].Revents&(syscall.POLLERR|syscall.POLLHUP) != 0 {
+			return
+		}
+	}
+}
+
+// monitors returns the connected and active outputs, primary first.
+func (m *x11Monitors) monitors() []mado.Monitor {
+	sr := C.XRRGetScreenResourcesCurrent(m.x, m.root)
+	if sr == nil {
+		return nil
+	}
+	defer C.XRRFreeScreenResources(sr)
+	primary := C.XRRGetOutputPrimary(m.x, m.root)
+	scale := x11DetectUIScale(m.x)
+	workArea, hasWorkArea := m.workArea()
+	red, green, blue := mado.SplitBPP(int(C.XDefaultDepth(m.x, C.XDefaultScreen(m.x))))
+
+	var modeInfos []C.XRRModeInfo
+	if sr.nmode > 0 {
+		modeInfos = unsafe.Slice(sr.modes, sr.nmode)
+	}
+	var list []mado.Monitor
+	if sr.noutput == 0 {
+		return nil
+	}
+	for _, output := range unsafe.Slice(sr.outputs, sr.noutput) {
+		oi := C.XRRGetOutputInfo(m.x, sr, output)
+		if oi == nil {
+			continue
+		}
+		if oi.connection != C.RR_Connected || oi.crtc == C.None {
+			C.XRRFreeOutputInfo(oi)
+			continue
+		}
+		ci := C.XRRGetCrtcInfo(m.x, sr, oi.crtc)
+		if ci == nil {
+			C.XRRFreeOutputInfo(oi)
+			continue
+		}
+		rotated := ci.rotation == C.RR_Rotate_90 || ci.rotation == C.RR_Rotate_270
+		mon := mado.Monitor{
+			ID:           uint64(output),
+			Name:         C.GoStringN(oi.name, oi.nameLen),
+			Primary:      output == primary,
+			Bounds:       image.Rect(int(ci.x), int(ci.y), int(ci.x)+int(ci.width), int(ci.y)+int(ci.height)),
+			PhysicalSize: image.Pt(int(oi.mm_width), int(oi.mm_height)),
+			Scale:        scale,
+		}
+		if rotated {
+			mon.PhysicalSize.X, mon.PhysicalSize.Y = mon.PhysicalSize.Y, mon.PhysicalSize.X
+		}
+		mon.WorkArea = mon.Bounds
+		if hasWorkArea {
+			if wa := workArea.Intersect(mon.Bounds); !wa.Empty() {
+				mon.WorkArea = wa
+			}
+		}
+		var modes []C.RRMode
+		if oi.nmode > 0 {
+			modes = unsafe.Slice(oi.modes, oi.nmode)
+		}
+		for _, id := range modes {
+			for i := range modeInfos {
+				mi := &modeInfos[i]
+				if mi.id != id {
+					continue
+				}
+				if mi.modeFlags&C.RR_Interlace != 0 {
+					break
+				}
+				vm := mado.VideoMode{
+					Size:        image.Pt(int(mi.width), int(mi.height)),
+					RedBits:     red,
+					GreenBits:   green,
+					BlueBits:    blue,
+					RefreshRate: x11RefreshRate(mi),
+				}
+				if rotated {
+					vm.Size.X, vm.Size.Y = vm.Size.Y, vm.Size.X
+				}
+				if !containsVideoMode(mon.Modes, vm) {
+					mon.Modes = append(mon.Modes, vm)
+				}
+				if id == ci.mode {
+					mon.Mode = vm
+				}
+				break
+			}
+		}
+		mado.SortVideoModes(mon.Modes)
+		C.XRRFreeCrtcInfo(ci)
+		C.XRRFreeOutputInfo(oi)
+		if mon.Primary {
+			list = append([]mado.Monitor{mon}, list...)
+		} else {
+			list = append(list, mon)
+		}
+	}
+	if len(list) > 0 && primary == C.None {
+		list[0].Primary = true
+	}
+	return list
+}
+
+// workArea returns the _NET_WORKAREA of the current desktop, if the
+// window manager supports it.
+func (m *x11Monitors) workArea() (image.Rectangle, bool) {
+	desktop := 0
+	if v := m.cardinals(m.atoms.currentDesktop); len(v) > 0 {
+		desktop = int(v[0])
+	}
+	areas := m.cardinals(m.atoms.workArea)
+	if len(areas) < (desktop+1)*4 {
+		return image.Rectangle{}, false
+	}
+	a := areas[desktop*4:]
+	x, y, w, h := int(a[0]), int(a[1]), int(a[2]), int(a[3])
+	return image.Rect(x, y, x+w, y+h), true
+}
+
+// cardinals reads a CARDINAL property of the root window.
+func (m *x11Monitors) cardinals(prop C.Atom) []C.long {
+	var (
+		actualType   C.Atom
+		actualFormat C.int
+		nitems       C.ulong
+		bytesAfter   C.ulong
+		data         *C.uchar
+	)
+	if C.XGetWindowProperty(m.x, m.root, prop, 0, C.LONG_MAX, C.False, C.XA_CARDINAL,
+		&actualType, &actualFormat, &nitems, &bytesAfter, &data) != C.Success || data == nil {
+		return nil
+	}
+	defer C.XFree(unsafe.Pointer(data))
+	if actualType != C.XA_CARDINAL || actualFormat != 32 || nitems == 0 {
+		return nil
+	}
+	// Format 32 properties are returned as longs.
+	return append([]C.long(nil), unsafe.Slice((*C.long)(unsafe.Pointer(data)), nitems)...)
+}
+
+// x11RefreshRate computes the refresh rate of a mode in Hz.
+func x11RefreshRate(mi *C.XRRModeInfo) int {
+	if mi.hTotal == 0 || mi.vTotal == 0 {
+		return 0
+	}
+	vTotal := float64(mi.vTotal)
+	if mi.modeFlags&C.RR_DoubleScan != 0 {
+		vTotal *= 2
+	}
+	return int(math.Round(float64(mi.dotClock) / (float64(mi.hTotal) * vTotal)))
+}
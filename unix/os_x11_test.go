@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestPlacePopup(t *testing.T) {
+	screen := image.Rect(0, 0, 1000, 800)
+	size := image.Pt(200, 300)
+	tests := []struct {
+		anchor image.Rectangle
+		want   image.Point
+	}{
+		// Below the anchor.
+		{image.Rect(100, 100, 180, 120), image.Pt(100, 120)},
+		// Flipped above the anchor.
+		{image.Rect(100, 600, 180, 620), image.Pt(100, 300)},
+		// Slid left to stay on screen.
+		{image.Rect(900, 100, 980, 120), image.Pt(800, 120)},
+		// No room above or below.
+		{image.Rect(100, 200, 180, 700), image.Pt(100, 500)},
+		// Slid right of an anchor partly off screen.
+		{image.Rect(-50, 100, 30, 120), image.Pt(0, 120)},
+	}
+	for _, test := range tests {
+		if got := placePopup(test.anchor, size, screen); got != test.want {
+			t.Errorf("placePopup(%v) = %v, want %v", test.anchor, got, test.want)
+		}
+	}
+}
+
+func TestIncrSendRouting(t *testing.T) {
+	// A large selection sent to the window of another client, which
+	// is not a window of the display.
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	owner := &x11Window{xw: 1}
+	owner.clipboard.sends = []*x11IncrSend{{requestor: 100, property: 7, data: data}}
+	d := &x11Display{windows: []*x11Window{{xw: 2}, owner}}
+	if w := d.lookupRequestor(100, 7); w != owner {
+		t.Fatalf("PropertyNotify of the requestor routed to %v, want the owner", w)
+	}
+	if w := d.lookupRequestor(100, 8); w != nil {
+		t.Errorf("PropertyNotify of another property routed to %v", w)
+	}
+	if w := d.lookupRequestor(1, 7); w != nil {
+		t.Errorf("PropertyNotify of the owner routed as a requestor's")
+	}
+	var got []byte
+	chunks := 0
+	for {
+		chunk := owner.clipboard.sends[0].next(300)
+		if len(chunk) == 0 {
+			break
+		}
+		if len(chunk) > 300 {
+			t.Fatalf("chunk of %d bytes exceeds the maximum", len(chunk))
+		}
+		got = append(got, chunk...)
+		chunks++
+	}
+	if chunks != 4 || !bytes.Equal(got, data) {
+		t.Errorf("received %d bytes in %d chunks, want %d bytes in 4", len(got), chunks, len(data))
+	}
+}
+
+func TestClipboardReadFailure(t *testing.T) {
+	rec := new(eventRecorder)
+	w := &x11Window{w: rec}
+	w.clipboard.reads = []x11ClipboardRead{{mime: "image/png", primary: true}}
+	// The owner has no content of the type.
+	w.finishRead(nil)
+	checkEmptyRead(t, rec.events, "image/png", true)
+}
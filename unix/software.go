@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package unix
+
+import (
+	"bytes"
+	"image"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/gpu"
+)
+
+// swContext is a mado.Context that renders frames with the CPU renderer
+// and presents them without a GPU API, for systems where neither EGL nor
+// Vulkan work, such as plain Xvfb, containers or remote X11 connections.
+type swContext struct {
+	p      swPresenter
+	img    *image.RGBA
+	damage swDamage
+}
+
+// swFramebuffer is a mado.Framebuffer presented without a GPU.
+type swFramebuffer struct {
+	p   swPresenter
+	img *image.RGBA
+	// resized tracks whether img changed size since the last present.
+	resized bool
+}
+
+// swPresenter uploads frames to a window.
+type swPresenter interface {
+	// size returns the size of the window framebuffer.
+	size() image.Point
+	// present uploads the rectangles rects of img to the window.
+	present(img *image.RGBA, rects []image.Rectangle) error
+	release()
+}
+
+// swDamage tracks the changes of a framebuffer between presents, in
+// tiles of swTileSize pixels.
+type swDamage struct {
+	// prev is a copy of the pixels of the last frame.
+	prev []byte
+	size image.Point
+}
+
+const swTileSize = 64
+
+var (
+	_ mado.Context     = (*swContext)(nil)
+	_ mado.Framebuffer = (*swFramebuffer)(nil)
+)
+
+func (c *swContext) API() gpu.API {
+	return gpu.CPU{}
+}
+
+func (c *swContext) RenderTarget() (gpu.RenderTarget, error) {
+	return gpu.CPURenderTarget{Image: c.img}, nil
+}
+
+func (c *swContext) Refresh() error {
+	sz := c.p.size()
+	if c.img == nil || c.img.Rect.Size() != sz {
+		c.img = image.NewRGBA(image.Rectangle{Max: sz})
+	}
+	// The window content may be lost.
+	c.damage.reset()
+	return nil
+}
+
+func (c *swContext) Present() error {
+	rects := c.damage.update(c.img)
+	if len(rects) == 0 {
+		return nil
+	}
+	return c.p.present(c.img, rects)
+}
+
+func (c *swContext) Release() {
+	if c.p != nil {
+		c.p.release()
+		c.p = nil
+	}
+	c.img = nil
+}
+
+func (c *swContext) Lock() error {
+	return nil
+}
+
+func (c *swContext) Unlock() {}
+
+func (c *swContext) SwapBuffers() error {
+	return c.Present()
+}
+
+func (c *swContext) SwapInterval(interval int) {}
+
+func (c *swContext) GetProcAddress(procname string) unsafe.Pointer {
+	return nil
+}
+
+func (c *swContext) ExtensionSupported(extension string) bool {
+	return false
+}
+
+func (f *swFramebuffer) Image() *image.RGBA {
+	sz := f.p.size()
+	if f.img == nil || f.img.Rect.Size() != sz {
+		f.img = image.NewRGBA(image.Rectangle{Max: sz})
+		f.resized = true
+	}
+	return f.img
+}
+
+func (f *swFramebuffer) Present(dirty ...image.Rectangle) error {
+	img := f.Image()
+	rects := []image.Rectangle{img.Rect}
+	if !f.resized && len(dirty) > 0 {
+		rects = rects[:0]
+		for _, r := range dirty {
+			if r = r.Intersect(img.Rect); !r.Empty() {
+				rects = append(rects, r)
+			}
+		}
+	}
+	f.resized = false
+	if len(rects) == 0 {
+		return nil
+	}
+	return f.p.present(img, rects)
+}
+
+func (f *swFramebuffer) Release() {
+	if f.p != nil {
+		f.p.release()
+		f.p = nil
+	}
+	f.img = nil
+}
+
+func (d *swDamage) reset() {
+	d.prev = nil
+}
+
+// update returns the rectangles of img that changed since the last
+// update, and records img. Changed tiles are merged into horizontal
+// runs, and runs of the same width into columns.
+func (d *swDamage) update(img *image.RGBA) []image.Rectangle {
+	size := img.Rect.Size()
+	if d.prev == nil || size != d.size {
+		d.size = size
+		d.prev = make([]byte, size.X*size.Y*4)
+		d.store(img, image.Rectangle{Max: size})
+		return []image.Rectangle{{Max: size}}
+	}
+	var rects []image.Rectangle
+	// add adds a run of changed tiles, extending the run of the same
+	// width in the row above if there is one.
+	add := func(r image.Rectangle) {
+		for i := len(rects) - 1; i >= 0 && rects[i].Max.Y >= r.Min.Y; i-- {
+			if rects[i].Max.Y == r.Min.Y && rects[i].Min.X == r.Min.X && rects[i].Max.X == r.Max.X {
+				rects[i].Max.Y = r.Max.Y
+				return
+			}
+		}
+		rects = append(rects, r)
+	}
+	for y := 0; y < size.Y; y += swTileSize {
+		var run image.Rectangle
+		for x := 0; x < size.X; x += swTileSize {
+			t := image.Rect(x, y, min(x+swTileSize, size.X), min(y+swTileSize, size.Y))
+			if d.changed(img, t) {
+				d.store(img, t)
+				run = run.Union(t)
+				continue
+			}
+			if !run.Empty() {
+				add(run)
+				run = image.Rectangle{}
+			}
+		}
+		if !run.Empty() {
+			add(run)
+		}
+	}
+	return rects
+}
+
+// changed reports whether the pixels of img in r differ from the last
+// frame.
+func (d *swDamage) changed(img *image.RGBA, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		off := (y*d.size.X + r.Min.X) * 4
+		n := r.Dx() * 4
+		if !bytes.Equal(img.Pix[img.PixOffset(img.Rect.Min.X+r.Min.X, img.Rect.Min.Y+y):][:n], d.prev[off:][:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// store records the pixels of img in r.
+func (d *swDamage) store(img *image.RGBA, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		off := (y*d.size.X + r.Min.X) * 4
+		n := r.Dx() * 4
+		copy(d.prev[off:][:n], img.Pix[img.PixOffset(img.Rect.Min.X+r.Min.X, img.Rect.Min.Y+y):][:n])
+	}
+}
+
+// copyBGRA converts the rectangle r of src to the BGRA or BGRX pixels
+// of dst, an image with the given stride.
+func copyBGRA(dst []byte, stride int, src *image.RGBA, r image.Rectangle) {
+	n := r.Dx() * 4
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		s := src.Pix[src.PixOffset(src.Rect.Min.X+r.Min.X, src.Rect.Min.Y+y):][:n]
+		d := dst[y*stride+r.Min.X*4:][:n]
+		for i := 0; i < n; i += 4 {
+			d[i], d[i+1], d[i+2], d[i+3] = s[i+2], s[i+1], s[i], s[i+3]
+		}
+	}
+}
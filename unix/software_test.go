@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package unix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+func TestSoftwareDamage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	var d swDamage
+	if got, want := d.update(img), []image.Rectangle{img.Rect}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first update: got %v, want %v", got, want)
+	}
+	if got := d.update(img); len(got) != 0 {
+		t.Errorf("unchanged update: got %v, want none", got)
+	}
+	fill := func(r image.Rectangle) {
+		draw.Draw(img, r, image.NewUniform(color.RGBA{R: 0xff, A: 0xff}), image.Point{}, draw.Src)
+	}
+	// A change within a tile damages the whole tile.
+	fill(image.Rect(70, 10, 80, 20))
+	if got, want := d.update(img), []image.Rectangle{image.Rect(64, 0, 128, 64)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tile update: got %v, want %v", got, want)
+	}
+	// Adjacent tiles merge into rows, and rows of equal width into
+	// columns; the edge tiles are clipped to the image.
+	fill(image.Rect(200, 70, 299, 199))
+	fill(image.Rect(0, 150, 10, 160))
+	want := []image.Rectangle{image.Rect(192, 64, 300, 200), image.Rect(0, 128, 64, 192)}
+	if got := d.update(img); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged update: got %v, want %v", got, want)
+	}
+	d.reset()
+	if got, want := d.update(img), []image.Rectangle{img.Rect}; !reflect.DeepEqual(got, want) {
+		t.Errorf("update after reset: got %v, want %v", got, want)
+	}
+	img = image.NewRGBA(image.Rect(0, 0, 100, 100))
+	if got, want := d.update(img), []image.Rectangle{img.Rect}; !reflect.DeepEqual(got, want) {
+		t.Errorf("update after resize: got %v, want %v", got, want)
+	}
+}
+
+func TestCopyBGRA(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	src.SetRGBA(1, 1, color.RGBA{R: 1, G: 2, B: 3, A: 4})
+	src.SetRGBA(3, 2, color.RGBA{R: 5, G: 6, B: 7, A: 8})
+	const stride = 20
+	dst := make([]byte, stride*3)
+	copyBGRA(dst, stride, src, image.Rect(1, 1, 2, 2))
+	if got, want := dst[stride+4:stride+8], []byte{3, 2, 1, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("copied pixel: got %v, want %v", got, want)
+	}
+	if got := dst[2*stride+12 : 2*stride+16]; !reflect.DeepEqual(got, make([]byte, 4)) {
+		t.Errorf("pixel outside the rectangle: got %v, want zeroes", got)
+	}
+}
+
+// testPresenter records the presented rectangles.
+type testPresenter struct {
+	sz    image.Point
+	rects [][]image.Rectangle
+}
+
+func (p *testPresenter) size() image.Point {
+	return p.sz
+}
+
+func (p *testPresenter) present(img *image.RGBA, rects []image.Rectangle) error {
+	p.rects = append(p.rects, append([]image.Rectangle(nil), rects...))
+	return nil
+}
+
+func (p *testPresenter) release() {}
+
+func TestSoftwareFramebuffer(t *testing.T) {
+	p := &testPresenter{sz: image.Pt(100, 80)}
+	fb := &swFramebuffer{p: p}
+	full := image.Rect(0, 0, 100, 80)
+	fb.Present(image.Rect(10, 10, 20, 20))
+	fb.Present(image.Rect(10, 10, 20, 20), image.Rect(90, 70, 120, 120), image.Rect(200, 0, 210, 10))
+	fb.Present()
+	fb.Present(image.Rect(200, 0, 210, 10))
+	p.sz = image.Pt(50, 50)
+	fb.Present(image.Rect(0, 0, 1, 1))
+	want := [][]image.Rectangle{
+		// The first present uploads all of the image.
+		{full},
+		// Dirty rectangles are clipped to the image.
+		{image.Rect(10, 10, 20, 20), image.Rect(90, 70, 100, 80)},
+		{full},
+		// The resize uploads all of the image.
+		{image.Rect(0, 0, 50, 50)},
+	}
+	if !reflect.DeepEqual(p.rects, want) {
+		t.Errorf("presented %v, want %v", p.rects, want)
+	}
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !nowayland
+// +build linux,!android freebsd
+// +build !nowayland
+
+package unix
+
+/*
+#include <wayland-client.h>
+
+extern const struct wl_buffer_listener gio_buffer_listener;
+*/
+import "C"
+import (
+	"errors"
+	"image"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+
+	"github.com/kanryu/mado"
+)
+
+// wlPresenter presents software frames in wl_shm buffers.
+type wlPresenter struct {
+	win *window
+	// buffers are the buffers of the current size, reused when the
+	// compositor releases them.
+	buffers []*wlSoftwareBuffer
+}
+
+type wlSoftwareBuffer struct {
+	buf  *C.struct_wl_buffer
+	pix  []byte
+	size image.Point
+	// busy tracks whether the compositor may read the buffer. It is
+	// cleared by the event loop, which may run concurrently with the
+	// presents of a framebuffer.
+	busy atomic.Bool
+	// damage are the regions of later frames missing from the buffer.
+	damage []image.Rectangle
+}
+
+// maxBufferDamage is the number of damaged regions of a buffer before
+// they are merged into their union.
+const maxBufferDamage = 16
+
+var _ swPresenter = (*wlPresenter)(nil)
+
+func newWaylandSoftwareContext(w *window) (mado.Context, error) {
+	p, err := newWaylandPresenter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &swContext{p: p}, nil
+}
+
+func newWaylandPresenter(w *window) (*wlPresenter, error) {
+	if w.disp.shm == nil {
+		return nil, errors.New("wayland: no wl_shm available")
+	}
+	return &wlPresenter{win: w}, nil
+}
+
+func (p *wlPresenter) size() image.Point {
+	_, width, height := p.win.surface()
+	return image.Pt(width, height)
+}
+
+func (p *wlPresenter) present(img *image.RGBA, rects []image.Rectangle) error {
+	sz := img.Rect.Size()
+	if sz.X == 0 || sz.Y == 0 {
+		return nil
+	}
+	var b *wlSoftwareBuffer
+	for _, b2 := range p.buffers {
+		if b2.size != sz {
+			p.release()
+			break
+		}
+		if !b2.busy.Load() {
+			b = b2
+			break
+		}
+	}
+	if b == nil {
+		var err error
+		b, err = p.newBuffer(sz)
+		if err != nil {
+			return err
+		}
+		p.buffers = append(p.buffers, b)
+	}
+	for _, b2 := range p.buffers {
+		if b2 == b {
+			continue
+		}
+		b2.damage = append(b2.damage, rects...)
+		if len(b2.damage) > maxBufferDamage {
+			u := b2.damage[0]
+			for _, r := range b2.damage[1:] {
+				u = u.Union(r)
+			}
+			b2.damage = append(b2.damage[:0], u)
+		}
+	}
+	stride := sz.X * 4
+	for _, r := range b.damage {
+		copyBGRA(b.pix, stride, img, r)
+	}
+	b.damage = b.damage[:0]
+	w := p.win
+	scale := w.scale
+	C.wl_surface_attach(w.surf, b.buf, 0, 0)
+	for _, r := range rects {
+		copyBGRA(b.pix, stride, img, r)
+		// Damage is in surface coordinates; round outwards.
+		x0, y0 := r.Min.X/scale, r.Min.Y/scale
+		x1, y1 := (r.Max.X+scale-1)/scale, (r.Max.Y+scale-1)/scale
+		C.wl_surface_damage(w.surf, C.int32_t(x0), C.int32_t(y0), C.int32_t(x1-x0), C.int32_t(y1-y0))
+	}
+	C.wl_surface_commit(w.surf)
+	b.busy.Store(true)
+	return nil
+}
+
+// newBuffer creates a buffer of size sz, backed by shared memory.
+func (p *wlPresenter) newBuffer(sz image.Point) (*wlSoftwareBuffer, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "mado-shm-")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// The compositor gets the file descriptor; the name is not needed.
+	os.Remove(f.Name())
+	stride := sz.X * 4
+	n := stride * sz.Y
+	if err := f.Truncate(int64(n)); err != nil {
+		return nil, err
+	}
+	pix, err := syscall.Mmap(int(f.Fd()), 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	pool := C.wl_shm_create_pool(p.win.disp.shm, C.int32_t(f.Fd()), C.int32_t(n))
+	if pool == nil {
+		syscall.Munmap(pix)
+		return nil, errors.New("wayland: wl_shm_create_pool failed")
+	}
+	defer C.wl_shm_pool_destroy(pool)
+	buf := C.wl_shm_pool_create_buffer(pool, 0, C.int32_t(sz.X), C.int32_t(sz.Y), C.int32_t(stride), C.WL_SHM_FORMAT_XRGB8888)
+	if buf == nil {
+		syscall.Munmap(pix)
+		return nil, errors.New("wayland: wl_shm_pool_create_buffer failed")
+	}
+	b := &wlSoftwareBuffer{
+		buf:  buf,
+		pix:  pix,
+		size: sz,
+		// A new buffer lacks every earlier frame.
+		damage: []image.Rectangle{{Max: sz}},
+	}
+	callbackStore(unsafe.Pointer(buf), b)
+	C.wl_buffer_add_listener(buf, &C.gio_buffer_listener, unsafe.Pointer(buf))
+	return b, nil
+}
+
+func (p *wlPresenter) release() {
+	for _, b := range p.buffers {
+		callbackDelete(unsafe.Pointer(b.buf))
+		// The compositor keeps its own mapping of a buffer in use.
+		C.wl_buffer_destroy(b.buf)
+		syscall.Munmap(b.pix)
+	}
+	p.buffers = nil
+}
+
+//export gio_onBufferRelease
+func gio_onBufferRelease(data unsafe.Pointer, buf *C.struct_wl_buffer) {
+	b := callbackLoad(data).(*wlSoftwareBuffer)
+	b.busy.Store(false)
+}
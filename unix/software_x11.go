@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd || openbsd) && !nox11
+// +build linux,!android freebsd openbsd
+// +build !nox11
+
+package unix
+
+/*
+#cgo freebsd openbsd LDFLAGS: -lXext
+#cgo linux pkg-config: x11 xext
+
+#include <stdlib.h>
+#include <sys/ipc.h>
+#include <sys/shm.h>
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+#include <X11/extensions/XShm.h>
+
+static int gio_x11ShmFailed;
+static unsigned long gio_x11ShmSerial;
+static int (*gio_x11PrevErrorHandler)(Display *, XErrorEvent *);
+
+static int gio_x11ShmErrorHandler(Display *dpy, XErrorEvent *ev) {
+	if (ev->serial == gio_x11ShmSerial) {
+		gio_x11ShmFailed = 1;
+		return 0;
+	}
+	// The error of another request is not ours to handle.
+	if (gio_x11PrevErrorHandler != NULL) {
+		return gio_x11PrevErrorHandler(dpy, ev);
+	}
+	return 0;
+}
+
+// gio_x11ShmAttach attaches a shared memory segment to the server, and
+// reports whether it succeeded. The attachment fails when the server
+// doesn't share the memory of the program, for example over a network.
+//
+// The display is shared by the windows, so it is locked while the
+// process wide error handler is replaced, and only the error of the
+// XShmAttach request is trapped.
+static Bool gio_x11ShmAttach(Display *dpy, XShmSegmentInfo *info) {
+	XLockDisplay(dpy);
+	XSync(dpy, False);
+	gio_x11ShmFailed = 0;
+	gio_x11ShmSerial = NextRequest(dpy);
+	gio_x11PrevErrorHandler = XSetErrorHandler(gio_x11ShmErrorHandler);
+	Status ok = XShmAttach(dpy, info);
+	XSync(dpy, False);
+	XSetErrorHandler(gio_x11PrevErrorHandler);
+	gio_x11PrevErrorHandler = NULL;
+	XUnlockDisplay(dpy);
+	return ok && !gio_x11ShmFailed;
+}
+
+static void gio_x11DestroyImage(XImage *img) {
+	XDestroyImage(img);
+}
+*/
+import "C"
+import (
+	"errors"
+	"image"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+)
+
+// x11Presenter presents software frames with the MIT-SHM extension, or
+// with XPutImage where shared memory isn't available.
+type x11Presenter struct {
+	win    *x11Window
+	gc     C.GC
+	visual *C.Visual
+	depth  C.int
+	// noShm is set when the server doesn't support shared memory images.
+	noShm bool
+
+	img *C.XImage
+	shm C.XShmSegmentInfo
+	// shared tracks whether img is in shared memory.
+	shared bool
+	pix    []byte
+}
+
+var _ swPresenter = (*x11Presenter)(nil)
+
+func newX11SoftwareContext(w *x11Window) (mado.Context, error) {
+	p, err := newX11Presenter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &swContext{p: p}, nil
+}
+
+func newX11Presenter(w *x11Window) (*x11Presenter, error) {
+	var attrs C.XWindowAttributes
+	if C.XGetWindowAttributes(w.x, w.xw, &attrs) == 0 {
+		return nil, errors.New("x11: XGetWindowAttributes failed")
+	}
+	v := attrs.visual
+	if attrs.depth != 24 && attrs.depth != 32 || v.red_mask != 0xff0000 || v.green_mask != 0xff00 || v.blue_mask != 0xff {
+		return nil, errors.New("x11: unsupported visual for software rendering")
+	}
+	p := &x11Presenter{
+		win:    w,
+		gc:     C.XCreateGC(w.x, C.Drawable(w.xw), 0, nil),
+		visual: v,
+		depth:  attrs.depth,
+		noShm:  C.XShmQueryExtension(w.x) == 0,
+	}
+	return p, nil
+}
+
+func (p *x11Presenter) size() image.Point {
+	_, width, height := p.win.window()
+	return image.Pt(width, height)
+}
+
+// alloc creates an image of size sz, in shared memory if possible.
+func (p *x11Presenter) alloc(sz image.Point) error {
+	p.free()
+	dpy := p.win.x
+	w, h := C.uint(sz.X), C.uint(sz.Y)
+	if !p.noShm {
+		if p.allocShm(w, h) {
+			return nil
+		}
+		p.noShm = true
+	}
+	img := C.XCreateImage(dpy, p.visual, C.uint(p.depth), C.ZPixmap, 0, nil, w, h, 32, 0)
+	if img == nil {
+		return errors.New("x11: XCreateImage failed")
+	}
+	n := C.size_t(img.bytes_per_line) * C.size_t(h)
+	img.data = (*C.char)(C.malloc(n))
+	p.img = img
+	p.pix = unsafe.Slice((*byte)(unsafe.Pointer(img.data)), n)
+	return p.checkFormat()
+}
+
+func (p *x11Presenter) allocShm(w, h C.uint) bool {
+	dpy := p.win.x
+	img := C.XShmCreateImage(dpy, p.visual, C.uint(p.depth), C.ZPixmap, nil, &p.shm, w, h)
+	if img == nil {
+		return false
+	}
+	n := C.size_t(img.bytes_per_line) * C.size_t(h)
+	id := C.shmget(C.IPC_PRIVATE, n, C.IPC_CREAT|0600)
+	if id < 0 {
+		C.gio_x11DestroyImage(img)
+		return false
+	}
+	addr := C.shmat(id, nil, 0)
+	if uintptr(addr) == ^uintptr(0) {
+		C.shmctl(id, C.IPC_RMID, nil)
+		C.gio_x11DestroyImage(img)
+		return false
+	}
+	p.shm.shmid = id
+	p.shm.shmaddr = (*C.char)(addr)
+	p.shm.readOnly = C.False
+	img.data = p.shm.shmaddr
+	ok := C.gio_x11ShmAttach(dpy, &p.shm) != 0
+	// The segment is destroyed when both the program and the server
+	// detach.
+	C.shmctl(id, C.IPC_RMID, nil)
+	if !ok {
+		img.data = nil
+		C.gio_x11DestroyImage(img)
+		C.shmdt(addr)
+		return false
+	}
+	p.img = img
+	p.shared = true
+	p.pix = unsafe.Slice((*byte)(addr), n)
+	return p.checkFormat() == nil
+}
+
+// checkFormat reports an error if the image pixels aren't in the BGRX
+// layout of copyBGRA.
+func (p *x11Presenter) checkFormat() error {
+	if p.img.byte_order != C.LSBFirst || p.img.bits_per_pixel != 32 {
+		p.free()
+		return errors.New("x11: unsupported image format for software rendering")
+	}
+	return nil
+}
+
+func (p *x11Presenter) present(img *image.RGBA, rects []image.Rectangle) error {
+	sz := img.Rect.Size()
+	if sz.X == 0 || sz.Y == 0 {
+		return nil
+	}
+	if p.img == nil || int(p.img.width) != sz.X || int(p.img.height) != sz.Y {
+		if err := p.alloc(sz); err != nil {
+			return err
+		}
+		rects = []image.Rectangle{{Max: sz}}
+	}
+	if p.win.exposed.Swap(false) {
+		// The server lost the window content.
+		rects = []image.Rectangle{{Max: sz}}
+	}
+	dpy, d := p.win.x, C.Drawable(p.win.xw)
+	stride := int(p.img.bytes_per_line)
+	for _, r := range rects {
+		copyBGRA(p.pix, stride, img, r)
+		x, y, w, h := C.int(r.Min.X), C.int(r.Min.Y), C.uint(r.Dx()), C.uint(r.Dy())
+		if p.shared {
+			C.XShmPutImage(dpy, d, p.gc, p.img, x, y, x, y, w, h, C.False)
+		} else {
+			C.XPutImage(dpy, d, p.gc, p.img, x, y, x, y, w, h)
+		}
+	}
+	if p.shared {
+		// Don't touch the shared pixels until the server has read them.
+		C.XSync(dpy, C.False)
+	} else {
+		C.XFlush(dpy)
+	}
+	return nil
+}
+
+// free destroys the image.
+func (p *x11Presenter) free() {
+	if p.img == nil {
+		return
+	}
+	if p.shared {
+		C.XShmDetach(p.win.x, &p.shm)
+		// The data is freed by shmdt, not XDestroyImage.
+		p.img.data = nil
+		C.gio_x11DestroyImage(p.img)
+		C.shmdt(unsafe.Pointer(p.shm.shmaddr))
+		p.shared = false
+	} else {
+		C.gio_x11DestroyImage(p.img)
+	}
+	p.img = nil
+	p.pix = nil
+}
+
+func (p *x11Presenter) release() {
+	p.free()
+	C.XFreeGC(p.win.x, p.gc)
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build ((linux && !android) || freebsd) && !novulkan
+// +build linux,!android freebsd
+// +build !novulkan
+
+package unix
+
+import (
+	"errors"
+	"slices"
+	"unsafe"
+
+	"github.com/kanryu/mado"
+	"github.com/kanryu/mado/internal/vk"
+)
+
+// newXlibVkSurface and newWaylandVkSurface create Vulkan surfaces from the
+// window handles of a ViewEvent, if the windowing system is built in.
+var (
+	newXlibVkSurface    func(inst vk.Instance, v X11ViewEvent, alloc unsafe.Pointer) (vk.Surface, error)
+	newWaylandVkSurface func(inst vk.Instance, v WaylandViewEvent, alloc unsafe.Pointer) (vk.Surface, error)
+)
+
+// VulkanGetInstanceProcAddr returns the vkGetInstanceProcAddr function of
+// the Vulkan loader, or nil if the loader is not available.
+func VulkanGetInstanceProcAddr() unsafe.Pointer {
+	f, err := vk.GetInstanceProcAddr()
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// VulkanInstanceExtensions returns the Vulkan instance extensions required
+// to create surfaces for the window of view, or nil if the Vulkan loader
+// doesn't support them.
+func VulkanInstanceExtensions(view mado.ViewEvent) []string {
+	var ext string
+	switch view.(type) {
+	case X11ViewEvent:
+		if newXlibVkSurface == nil {
+			return nil
+		}
+		ext = "VK_KHR_xlib_surface"
+	case WaylandViewEvent:
+		if newWaylandVkSurface == nil {
+			return nil
+		}
+		ext = "VK_KHR_wayland_surface"
+	default:
+		return nil
+	}
+	avail, err := vk.EnumerateInstanceExtensions()
+	if err != nil {
+		return nil
+	}
+	required := []string{"VK_KHR_surface", ext}
+	for _, r := range required {
+		if !slices.Contains(avail, r) {
+			return nil
+		}
+	}
+	return required
+}
+
+// CreateVulkanSurface creates a VkSurfaceKHR for the window of view in the
+// VkInstance inst, which must have the extensions returned by
+// VulkanInstanceExtensions enabled. The optional alloc points to the
+// VkAllocationCallbacks for the surface.
+func CreateVulkanSurface(view mado.ViewEvent, inst, alloc unsafe.Pointer) (uint64, error) {
+	var (
+		surf vk.Surface
+		err  = errors.New("vulkan: surface creation is not supported for the window")
+	)
+	switch v := view.(type) {
+	case X11ViewEvent:
+		if newXlibVkSurface != nil && v.Window != 0 {
+			surf, err = newXlibVkSurface(vk.Instance(inst), v, alloc)
+		}
+	case WaylandViewEvent:
+		if newWaylandVkSurface != nil && v.Surface != nil {
+			surf, err = newWaylandVkSurface(vk.Instance(inst), v, alloc)
+		}
+	}
+	return uint64(surf), err
+}
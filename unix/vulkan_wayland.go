@@ -23,6 +23,9 @@ type wlVkContext struct {
 }
 
 func init() {
+	newWaylandVkSurface = func(inst vk.Instance, v WaylandViewEvent, alloc unsafe.Pointer) (vk.Surface, error) {
+		return vk.CreateWaylandSurface(inst, v.Display, v.Surface, alloc)
+	}
 	newWaylandVulkanContext = func(w *window) (mado.Context, error) {
 		inst, err := vk.CreateInstance("VK_KHR_surface", "VK_KHR_wayland_surface")
 		if err != nil {
@@ -30,7 +33,7 @@ func init() {
 		}
 		disp := w.display()
 		wlSurf, _, _ := w.surface()
-		surf, err := vk.CreateWaylandSurface(inst, unsafe.Pointer(disp), unsafe.Pointer(wlSurf))
+		surf, err := vk.CreateWaylandSurface(inst, unsafe.Pointer(disp), unsafe.Pointer(wlSurf), nil)
 		if err != nil {
 			vk.DestroyInstance(inst)
 			return nil, err
@@ -59,6 +62,14 @@ func (c *wlVkContext) SwapInterval(interval int) {
 	// It seems swapInterval is not supported.
 }
 
+func (c *wlVkContext) GetProcAddress(procname string) unsafe.Pointer {
+	return nil
+}
+
+func (c *wlVkContext) ExtensionSupported(extension string) bool {
+	return false
+}
+
 func (c *wlVkContext) RenderTarget() (gpu.RenderTarget, error) {
 	return c.ctx.RenderTarget()
 }
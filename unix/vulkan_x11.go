@@ -23,6 +23,9 @@ type x11VkContext struct {
 }
 
 func init() {
+	newXlibVkSurface = func(inst vk.Instance, v X11ViewEvent, alloc unsafe.Pointer) (vk.Surface, error) {
+		return vk.CreateXlibSurface(inst, v.Display, v.Window, alloc)
+	}
 	newX11VulkanContext = func(w *x11Window) (mado.Context, error) {
 		inst, err := vk.CreateInstance("VK_KHR_surface", "VK_KHR_xlib_surface")
 		if err != nil {
@@ -30,7 +33,7 @@ func init() {
 		}
 		disp := w.display()
 		window, _, _ := w.window()
-		surf, err := vk.CreateXlibSurface(inst, unsafe.Pointer(disp), uintptr(window))
+		surf, err := vk.CreateXlibSurface(inst, unsafe.Pointer(disp), uintptr(window), nil)
 		if err != nil {
 			vk.DestroyInstance(inst)
 			return nil, err
@@ -75,6 +78,14 @@ func (c *x11VkContext) SwapInterval(interval int) {
 	// TODO
 }
 
+func (c *x11VkContext) GetProcAddress(procname string) unsafe.Pointer {
+	return nil
+}
+
+func (c *x11VkContext) ExtensionSupported(extension string) bool {
+	return false
+}
+
 func (c *x11VkContext) Present() error {
 	return c.ctx.present()
 }
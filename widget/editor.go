@@ -42,6 +42,11 @@ type Editor struct {
 	// LineHeightScale is multiplied by LineHeight to determine the final gap
 	// between baselines. If zero, a sensible default will be used.
 	LineHeightScale float32
+	// LetterSpacing is added after each character of the text, and
+	// WordSpacing after each word separator such as a space.
+	LetterSpacing, WordSpacing unit.Sp
+	// TabStops are the positions that tab characters advance the text to.
+	TabStops TabStops
 	// SingleLine force the text to stay on a single line.
 	// SingleLine also sets the scrolling direction to
 	// horizontal.
@@ -221,7 +226,8 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 	sbounds := e.text.ScrollBounds()
 	var smin, smax int
 	var axis gesture.Axis
-	if e.SingleLine {
+	horizontal := e.text.scrollsHorizontally()
+	if horizontal {
 		axis = gesture.Horizontal
 		smin, smax = sbounds.Min.X, sbounds.Max.X
 	} else {
@@ -231,7 +237,7 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 	var scrollRange image.Rectangle
 	textDims := e.text.FullDimensions()
 	visibleDims := e.text.Dimensions()
-	if e.SingleLine {
+	if horizontal {
 		scrollOffX := e.text.ScrollOff().X
 		scrollRange.Min.X = min(-scrollOffX, 0)
 		scrollRange.Max.X = max(0, textDims.Size.X-(scrollOffX+visibleDims.Size.X))
@@ -242,7 +248,7 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 	}
 	sdist := e.scroller.Update(gtx.Metric, gtx.Source, gtx.Now, axis, scrollRange)
 	var soff int
-	if e.SingleLine {
+	if horizontal {
 		e.text.ScrollRel(sdist, 0)
 		soff = e.text.ScrollOff().X
 	} else {
@@ -293,7 +299,7 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 				e.scrollCaret = true
 			}
 
-			if evt.Modifiers == key.ModShift {
+			if evt.Modifiers.Held() == key.ModShift {
 				start, end := e.text.Selection()
 				// If they clicked closer to the end, then change the end to
 				// where the caret used to be (effectively swapping start & end).
@@ -359,6 +365,7 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 	if gtx.Locale.Direction.Progression() != system.FromOrigin {
 		atEnd, atBeginning = atBeginning, atEnd
 	}
+	prevLine, nextLine, prevChar, nextChar := arrowKeys(gtx.Locale)
 	filters := []event.Filter{
 		key.FocusFilter{Target: e},
 		transfer.TargetFilter{Target: e, Type: "application/text"},
@@ -378,10 +385,10 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		key.Filter{Focus: e, Name: key.NameEnd, Optional: key.ModShift},
 		key.Filter{Focus: e, Name: key.NamePageDown, Optional: key.ModShift},
 		key.Filter{Focus: e, Name: key.NamePageUp, Optional: key.ModShift},
-		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameLeftArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameUpArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameRightArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameDownArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atBeginning, key.Filter{Focus: e, Name: prevChar, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atBeginning, key.Filter{Focus: e, Name: prevLine, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atEnd, key.Filter{Focus: e, Name: nextChar, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atEnd, key.Filter{Focus: e, Name: nextLine, Optional: key.ModShortcutAlt | key.ModShift}),
 	}
 	// adjust keeps track of runes dropped because of MaxLen.
 	var adjust int
@@ -477,11 +484,23 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 	return nil, false
 }
 
+// arrowKeys returns the keys that move the caret to the previous and next
+// line, and the keys that move it left and right within a line, or up and
+// down within the columns of vertical text.
+func arrowKeys(lc system.Locale) (prevLine, nextLine, prevChar, nextChar key.Name) {
+	if lc.Direction.Axis() == system.Vertical {
+		// Columns are read from right to left.
+		return key.NameRightArrow, key.NameLeftArrow, key.NameUpArrow, key.NameDownArrow
+	}
+	return key.NameUpArrow, key.NameDownArrow, key.NameLeftArrow, key.NameRightArrow
+}
+
 func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 	direction := 1
 	if gtx.Locale.Direction.Progression() == system.TowardOrigin {
 		direction = -1
 	}
+	prevLine, nextLine, prevChar, nextChar := arrowKeys(gtx.Locale)
 	moveByWord := k.Modifiers.Contain(key.ModShortcutAlt)
 	selAct := selectionClear
 	if k.Modifiers.Contain(key.ModShift) {
@@ -555,11 +574,11 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 				}
 			}
 		}
-	case key.NameUpArrow:
+	case prevLine:
 		e.text.MoveLines(-1, selAct)
-	case key.NameDownArrow:
+	case nextLine:
 		e.text.MoveLines(+1, selAct)
-	case key.NameLeftArrow:
+	case prevChar:
 		if moveByWord {
 			e.text.MoveWord(-1*direction, selAct)
 		} else {
@@ -568,7 +587,7 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 			}
 			e.text.MoveCaret(-1*direction, -1*direction*int(selAct))
 		}
-	case key.NameRightArrow:
+	case nextChar:
 		if moveByWord {
 			e.text.MoveWord(1*direction, selAct)
 		} else {
@@ -600,6 +619,9 @@ func (e *Editor) initBuffer() {
 	e.text.Alignment = e.Alignment
 	e.text.LineHeight = e.LineHeight
 	e.text.LineHeightScale = e.LineHeightScale
+	e.text.LetterSpacing = e.LetterSpacing
+	e.text.WordSpacing = e.WordSpacing
+	e.text.TabStops = e.TabStops
 	e.text.SingleLine = e.SingleLine
 	e.text.Mask = e.Mask
 	e.text.WrapPolicy = e.WrapPolicy
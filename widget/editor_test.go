@@ -1223,3 +1223,89 @@ func textBaseline(e *Editor, lineNum int) float32 {
 	start := e.text.closestToLineCol(lineNum, 0)
 	return float32(start.y)
 }
+
+// TestEditorVertical checks caret placement and arrow key movement in
+// vertical text, whose columns run from right to left.
+func TestEditorVertical(t *testing.T) {
+	e := new(Editor)
+	e.SetText("abc\ndef")
+
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+		Locale:      system.Locale{Language: "ja", Direction: system.TTB},
+		Source:      r.Source(),
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	font := font.Font{}
+	fontSize := unit.Sp(10)
+
+	gtx.Execute(key.FocusCmd{Tag: e})
+	e.Layout(gtx, cache, font, fontSize, op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+
+	start := e.CaretCoords()
+	if start.X < 100 {
+		t.Errorf("expected the first column at the right edge, got caret %v", start)
+	}
+	e.SetCaret(1, 1)
+	if next := e.CaretCoords(); next.Y <= start.Y || next.X != start.X {
+		t.Errorf("expected the caret to move down the column, from %v to %v", start, next)
+	}
+
+	// The left arrow moves to the next column.
+	r.Queue(key.Event{State: key.Press, Name: key.NameLeftArrow})
+	gtx.Ops.Reset()
+	e.Layout(gtx, cache, font, fontSize, op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	if caret, _ := e.Selection(); caret != 5 {
+		t.Errorf("expected the left arrow to move the caret to 5, got %d", caret)
+	}
+	if coords := e.CaretCoords(); coords.X >= start.X {
+		t.Errorf("expected the second column left of the first, got caret %v", coords)
+	}
+
+	// The down arrow moves along the column.
+	r.Queue(key.Event{State: key.Press, Name: key.NameDownArrow})
+	gtx.Ops.Reset()
+	e.Layout(gtx, cache, font, fontSize, op.CallOp{}, op.CallOp{})
+	if caret, _ := e.Selection(); caret != 6 {
+		t.Errorf("expected the down arrow to move the caret to 6, got %d", caret)
+	}
+}
+
+func TestEditorTabStops(t *testing.T) {
+	e := new(Editor)
+	e.SetText("a\tb\tc")
+	e.TabStops.Interval = 40
+
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	font := font.Font{}
+	fontSize := unit.Sp(10)
+
+	caretX := func(col int) float32 {
+		gtx.Ops.Reset()
+		e.Layout(gtx, cache, font, fontSize, op.CallOp{}, op.CallOp{})
+		e.SetCaret(col, col)
+		return e.CaretCoords().X
+	}
+	if x := caretX(2); x != 40 {
+		t.Errorf("expected the caret after the first tab at 40, got %v", x)
+	}
+	if x := caretX(4); x != 80 {
+		t.Errorf("expected the caret after the second tab at 80, got %v", x)
+	}
+	e.TabStops.Stops = []unit.Sp{25}
+	if x := caretX(2); x != 25 {
+		t.Errorf("expected the caret after the first tab at 25, got %v", x)
+	}
+	if x := caretX(4); x != 40 {
+		t.Errorf("expected the caret after the second tab at 40, got %v", x)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"sort"
 
 	"github.com/go-text/typesetting/segmenter"
+	"github.com/kanryu/mado/f32"
 	"github.com/kanryu/mado/text"
 	"golang.org/x/image/math/fixed"
 )
@@ -49,6 +50,11 @@ type glyphIndex struct {
 	// midCluster tracks whether the next glyph processed is not the first glyph in a
 	// cluster.
 	midCluster bool
+	// vertical tracks whether the text is vertical. The index turns the
+	// columns of vertical text a quarter counter-clockwise, so that they
+	// read like lines from top to bottom; positions and lines are in that
+	// frame. See toFrame.
+	vertical bool
 }
 
 // reset prepares the index for reuse.
@@ -64,6 +70,34 @@ func (g *glyphIndex) reset() {
 	g.clusterAdvance = 0
 	g.truncated = false
 	g.midCluster = false
+	g.vertical = false
+}
+
+// toFrame converts a rectangle in document coordinates to the frame of
+// the index.
+func (g *glyphIndex) toFrame(r image.Rectangle) image.Rectangle {
+	if !g.vertical {
+		return r
+	}
+	return image.Rect(r.Min.Y, -r.Max.X, r.Max.Y, -r.Min.X)
+}
+
+// fromFrame converts a rectangle in the frame of the index to document
+// coordinates.
+func (g *glyphIndex) fromFrame(r image.Rectangle) image.Rectangle {
+	if !g.vertical {
+		return r
+	}
+	return image.Rect(-r.Max.Y, r.Min.X, -r.Min.Y, r.Max.X)
+}
+
+// pointFromFrame converts a position in the frame of the index to document
+// coordinates.
+func (g *glyphIndex) pointFromFrame(x fixed.Int26_6, y int) f32.Point {
+	if !g.vertical {
+		return f32.Pt(float32(x)/64, float32(y))
+	}
+	return f32.Pt(float32(-y), float32(x)/64)
 }
 
 // screenPos represents a character position in text line and column numbers,
@@ -129,6 +163,10 @@ func (g *glyphIndex) insertPosition(pos combinedPos) {
 // Glyph indexes the provided glyph, generating text cursor positions for it.
 func (g *glyphIndex) Glyph(gl text.Glyph) {
 	g.glyphs = append(g.glyphs, gl)
+	if gl.Flags&text.FlagVertical != 0 {
+		g.vertical = true
+		gl.X, gl.Y = fixed.I(int(gl.Y)), int32(-gl.X.Round())
+	}
 	g.currentLineGlyphs++
 	if len(g.positions) == 0 {
 		// First-iteration setup.
@@ -352,7 +390,8 @@ type Region struct {
 // return results instead of allocating, provided that there is enough capacity.
 // The returned regions have their Bounds specified relative to the provided
 // viewport.
-func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, rects []Region) []Region {
+func (g *glyphIndex) locate(docViewport image.Rectangle, startRune, endRune int, rects []Region) []Region {
+	viewport := g.toFrame(docViewport)
 	if startRune > endRune {
 		startRune, endRune = endRune, startRune
 	}
@@ -433,7 +472,7 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 		}
 	}
 	for i := range rects {
-		rects[i].Bounds = rects[i].Bounds.Sub(viewport.Min)
+		rects[i].Bounds = g.fromFrame(rects[i].Bounds).Sub(docViewport.Min)
 	}
 	return rects
 }
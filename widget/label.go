@@ -4,10 +4,12 @@ package widget
 
 import (
 	"image"
+	"strings"
 
 	"github.com/kanryu/mado/f32"
 	"github.com/kanryu/mado/font"
 	"github.com/kanryu/mado/io/semantic"
+	"github.com/kanryu/mado/io/system"
 	"github.com/kanryu/mado/layout"
 	"github.com/kanryu/mado/op"
 	"github.com/kanryu/mado/op/clip"
@@ -36,6 +38,33 @@ type Label struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
+	// LetterSpacing is added after each character of the text, and
+	// WordSpacing after each word separator such as a space.
+	LetterSpacing, WordSpacing unit.Sp
+	// TabStops are the positions that tab characters advance the text to.
+	TabStops TabStops
+}
+
+// TabStops are the positions, measured from the start of lines, that tab
+// characters advance text to.
+type TabStops struct {
+	// Stops are explicit positions of tab stops, in increasing order.
+	Stops []unit.Sp
+	// Interval is the distance between the tab stops past the last of
+	// Stops. If zero, it is the width of eight spaces.
+	Interval unit.Sp
+}
+
+// params returns the tab stops in pixels, reusing the memory of stops.
+func (t TabStops) params(gtx layout.Context, stops []fixed.Int26_6) text.TabStops {
+	stops = stops[:0]
+	for _, s := range t.Stops {
+		stops = append(stops, fixed.I(gtx.Sp(s)))
+	}
+	if len(stops) == 0 {
+		stops = nil
+	}
+	return text.TabStops{Stops: stops, Interval: fixed.I(gtx.Sp(t.Interval))}
 }
 
 // Layout the label with the given shaper, font, size, text, and material.
@@ -53,10 +82,32 @@ type TextInfo struct {
 
 // Layout the label with the given shaper, font, size, text, and material, returning metadata about the shaped text.
 func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt string, textMaterial op.CallOp) (layout.Dimensions, TextInfo) {
+	lt.LayoutString(l.params(gtx, font, size), txt)
+	return l.paint(gtx, lt, txt, textMaterial, nil)
+}
+
+// LayoutRuby is like Layout, for text with ruby annotations such as
+// furigana.
+func (l Label) LayoutRuby(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt []text.Ruby, textMaterial op.CallOp) layout.Dimensions {
+	lt.LayoutRuby(l.params(gtx, font, size), txt)
+	var base strings.Builder
+	for _, r := range txt {
+		base.WriteString(r.Base)
+	}
+	dims, _ := l.paint(gtx, lt, base.String(), textMaterial, nil)
+	return dims
+}
+
+// params returns the shaping parameters of the label.
+func (l Label) params(gtx layout.Context, font font.Font, size unit.Sp) text.Parameters {
 	cs := gtx.Constraints
 	textSize := fixed.I(gtx.Sp(size))
 	lineHeight := fixed.I(gtx.Sp(l.LineHeight))
-	lt.LayoutString(text.Parameters{
+	var minHeight, maxHeight int
+	if gtx.Locale.Direction.Axis() == system.Vertical {
+		minHeight, maxHeight = cs.Min.Y, cs.Max.Y
+	}
+	return text.Parameters{
 		Font:            font,
 		PxPerEm:         textSize,
 		MaxLines:        l.MaxLines,
@@ -65,10 +116,21 @@ func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Fon
 		WrapPolicy:      l.WrapPolicy,
 		MaxWidth:        cs.Max.X,
 		MinWidth:        cs.Min.X,
+		MaxHeight:       maxHeight,
+		MinHeight:       minHeight,
 		Locale:          gtx.Locale,
 		LineHeight:      lineHeight,
 		LineHeightScale: l.LineHeightScale,
-	}, txt)
+		LetterSpacing:   fixed.I(gtx.Sp(l.LetterSpacing)),
+		WordSpacing:     fixed.I(gtx.Sp(l.WordSpacing)),
+		TabStops:        l.TabStops.params(gtx, nil),
+	}
+}
+
+// paint draws the glyphs laid out by lt, labelled with txt. If styler is
+// non-nil, glyphs are painted in the style of their span.
+func (l Label) paint(gtx layout.Context, lt *text.Shaper, txt string, textMaterial op.CallOp, styler *spanStyler) (layout.Dimensions, TextInfo) {
+	cs := gtx.Constraints
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{Max: cs.Max}
 	it := textIterator{
@@ -77,19 +139,39 @@ func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Fon
 		material: textMaterial,
 	}
 	semantic.LabelOp(txt).Add(gtx.Ops)
+	if styler != nil {
+		it.material = styler.material(textMaterial)
+	}
 	var glyphs [32]text.Glyph
 	line := glyphs[:0]
 	for g, ok := lt.NextGlyph(); ok; g, ok = lt.NextGlyph() {
+		if styler != nil && styler.next(g) {
+			// Paint the glyphs of the span before in its material.
+			it.paintLine(gtx, lt, line)
+			line = line[:0]
+			it.material = styler.material(textMaterial)
+		}
 		var ok bool
 		if line, ok = it.paintGlyph(gtx, lt, g, line); !ok {
 			break
 		}
+		if styler != nil && it.visible {
+			styler.add(g)
+		}
 	}
+	// Ruby annotations follow the final line break.
+	it.paintLine(gtx, lt, line)
 	call := m.Stop()
 	viewport.Min = viewport.Min.Add(it.padding.Min)
 	viewport.Max = viewport.Max.Add(it.padding.Max)
 	clipStack := clip.Rect(viewport).Push(gtx.Ops)
+	if styler != nil {
+		styler.paintBackgrounds(gtx.Ops)
+	}
 	call.Add(gtx.Ops)
+	if styler != nil {
+		styler.paintDecorations(gtx.Ops, textMaterial)
+	}
 	dims := layout.Dimensions{Size: it.bounds.Size()}
 	dims.Size = cs.Constrain(dims.Size)
 	dims.Baseline = dims.Size.Y - it.baseline
@@ -143,6 +225,9 @@ func (it *textIterator) processGlyph(g text.Glyph, ok bool) (visibleOrBefore boo
 			return false
 		}
 	}
+	if g.Flags&text.FlagVertical != 0 {
+		return it.processVerticalGlyph(g, ok)
+	}
 	// Compute the maximum extent to which glyphs overhang on the horizontal
 	// axis.
 	if d := g.Bounds.Min.X.Floor(); d < it.padding.Min.X {
@@ -170,6 +255,40 @@ func (it *textIterator) processGlyph(g text.Glyph, ok bool) (visibleOrBefore boo
 		Min: image.Pt(g.X.Floor(), int(g.Y)-g.Ascent.Ceil()),
 		Max: image.Pt((g.X + g.Advance).Ceil(), int(g.Y)+g.Descent.Ceil()),
 	}
+	below, _ := it.include(g, logicalBounds)
+	return ok && !below
+}
+
+// processVerticalGlyph is processGlyph for the glyphs of vertical text,
+// whose columns extend the ascent of glyphs to the right of their dot,
+// and their descent to the left.
+func (it *textIterator) processVerticalGlyph(g text.Glyph, ok bool) (visibleOrBefore bool) {
+	if d := (g.Bounds.Min.X + g.Descent).Floor(); d < it.padding.Min.X {
+		it.padding.Min.X = d
+	}
+	if d := (g.Bounds.Max.X - g.Ascent).Ceil(); d > it.padding.Max.X {
+		it.padding.Max.X = d
+	}
+	if d := g.Bounds.Min.Y.Floor(); d < it.padding.Min.Y {
+		it.padding.Min.Y = d
+	}
+	if d := (g.Bounds.Max.Y - g.Advance).Ceil(); d > it.padding.Max.Y {
+		it.padding.Max.Y = d
+	}
+	logicalBounds := image.Rectangle{
+		Min: image.Pt((g.X - g.Descent).Floor(), int(g.Y)),
+		Max: image.Pt((g.X + g.Ascent).Ceil(), int(g.Y)+g.Advance.Ceil()),
+	}
+	// Columns run from right to left, so glyphs past the bottom of the
+	// viewport may be followed by visible glyphs in the next column.
+	_, left := it.include(g, logicalBounds)
+	return ok && !left
+}
+
+// include updates the visibility of the glyph with the given logical
+// bounds, and the text dimensions to include it if visible. It reports
+// whether the glyph is below or left of the viewport.
+func (it *textIterator) include(g text.Glyph, logicalBounds image.Rectangle) (below, left bool) {
 	if !it.first {
 		it.first = true
 		it.baseline = int(g.Y)
@@ -177,8 +296,8 @@ func (it *textIterator) processGlyph(g text.Glyph, ok bool) (visibleOrBefore boo
 	}
 
 	above := logicalBounds.Max.Y < it.viewport.Min.Y
-	below := logicalBounds.Min.Y > it.viewport.Max.Y
-	left := logicalBounds.Max.X < it.viewport.Min.X
+	below = logicalBounds.Min.Y > it.viewport.Max.Y
+	left = logicalBounds.Max.X < it.viewport.Min.X
 	right := logicalBounds.Min.X > it.viewport.Max.X
 	it.visible = !above && !below && !left && !right
 	if it.visible {
@@ -187,7 +306,7 @@ func (it *textIterator) processGlyph(g text.Glyph, ok bool) (visibleOrBefore boo
 		it.bounds.Max.X = max(it.bounds.Max.X, logicalBounds.Max.X)
 		it.bounds.Max.Y = max(it.bounds.Max.Y, logicalBounds.Max.Y)
 	}
-	return ok && !below
+	return below, left
 }
 
 func fixedToFloat(i fixed.Int26_6) float32 {
@@ -210,17 +329,25 @@ func (it *textIterator) paintGlyph(gtx layout.Context, shaper *text.Shaper, glyp
 		line = append(line, glyph)
 	}
 	if glyph.Flags&text.FlagLineBreak != 0 || cap(line)-len(line) == 0 || !visibleOrBefore {
-		t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
-		path := shaper.Shape(line)
-		outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-		it.material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		outline.Pop()
-		if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
-			call.Add(gtx.Ops)
-		}
-		t.Pop()
+		it.paintLine(gtx, shaper, line)
 		line = line[:0]
 	}
 	return line, visibleOrBefore
 }
+
+// paintLine paints the glyphs buffered by paintGlyph.
+func (it *textIterator) paintLine(gtx layout.Context, shaper *text.Shaper, line []text.Glyph) {
+	if len(line) == 0 {
+		return
+	}
+	t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
+	path := shaper.Shape(line)
+	outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
+	it.material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
+		call.Add(gtx.Ops)
+	}
+	t.Pop()
+}
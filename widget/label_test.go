@@ -5,6 +5,13 @@ import (
 	"math"
 	"testing"
 
+	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/font"
+	"github.com/kanryu/mado/font/gofont"
+	"github.com/kanryu/mado/io/input"
+	"github.com/kanryu/mado/io/pointer"
+	"github.com/kanryu/mado/layout"
+	"github.com/kanryu/mado/op"
 	"github.com/kanryu/mado/text"
 	"golang.org/x/image/math/fixed"
 )
@@ -227,3 +234,59 @@ func TestGlyphIteratorPadding(t *testing.T) {
 		})
 	}
 }
+
+// TestLabelRuby checks that ruby annotations make room for themselves above
+// the text of a label.
+func TestLabelRuby(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+	}
+	gtx.Constraints.Min = image.Point{}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	var l Label
+	plain := l.Layout(gtx, cache, font.Font{}, 20, "ab", op.CallOp{})
+	ruby := l.LayoutRuby(gtx, cache, font.Font{}, 20, []text.Ruby{{Base: "ab", Annotation: "annotation"}}, op.CallOp{})
+	if ruby.Size.Y <= plain.Size.Y {
+		t.Errorf("expected the annotation to add height, got %v, plain %v", ruby.Size, plain.Size)
+	}
+	if ruby.Size.X <= plain.Size.X {
+		t.Errorf("expected the base to be spaced out to the width of the annotation, got %v, plain %v", ruby.Size, plain.Size)
+	}
+	if top, plainTop := ruby.Size.Y-ruby.Baseline, plain.Size.Y-plain.Baseline; top <= plainTop {
+		t.Errorf("expected the baseline below the annotation, got %d from the top, plain %d", top, plainTop)
+	}
+}
+
+// TestLabelSpanLink checks that pointer events over a link span are
+// delivered to its tag.
+func TestLabelSpanLink(t *testing.T) {
+	var r input.Router
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(200, 200)},
+		Source:      r.Source(),
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	link := new(int)
+	filter := pointer.Filter{Target: link, Kinds: pointer.Press}
+	r.Event(filter)
+	var l Label
+	dims := l.LayoutSpans(gtx, cache, font.Font{}, 20, []Span{
+		{Text: "see "},
+		{Text: "here", Link: link, Underline: true},
+	}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	y := float32(dims.Size.Y) / 2
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Position: f32.Pt(10, y)},
+		pointer.Event{Kind: pointer.Release, Position: f32.Pt(10, y)},
+	)
+	if _, ok := r.Event(filter); ok {
+		t.Errorf("unexpected event outside of the link")
+	}
+	r.Queue(pointer.Event{Kind: pointer.Press, Position: f32.Pt(float32(dims.Size.X)-5, y)})
+	if _, ok := r.Event(filter); !ok {
+		t.Errorf("expected a press on the link")
+	}
+}
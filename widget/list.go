@@ -67,7 +67,7 @@ func (s *Scrollbar) Update(gtx layout.Context, axis layout.Axis, viewportStart,
 			break
 		}
 		if event.Kind != gesture.KindClick ||
-			event.Modifiers != key.Modifiers(0) ||
+			event.Modifiers.Held() != key.Modifiers(0) ||
 			event.NumClicks > 1 {
 			continue
 		}
@@ -44,6 +44,11 @@ type LabelStyle struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
+	// LetterSpacing is added after each character of the text, and
+	// WordSpacing after each word separator such as a space.
+	LetterSpacing, WordSpacing unit.Sp
+	// TabStops are the positions that tab characters advance the text to.
+	TabStops widget.TabStops
 
 	// Shaper is the text shaper used to display this labe. This field is automatically
 	// set using by all constructor functions. If constructing a LabelStyle literal, you
@@ -140,6 +145,9 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		l.State.WrapPolicy = l.WrapPolicy
 		l.State.LineHeight = l.LineHeight
 		l.State.LineHeightScale = l.LineHeightScale
+		l.State.LetterSpacing = l.LetterSpacing
+		l.State.WordSpacing = l.WordSpacing
+		l.State.TabStops = l.TabStops
 		return l.State.Layout(gtx, l.Shaper, l.Font, l.TextSize, textColor, selectColor)
 	}
 	tl := widget.Label{
@@ -149,6 +157,9 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		WrapPolicy:      l.WrapPolicy,
 		LineHeight:      l.LineHeight,
 		LineHeightScale: l.LineHeightScale,
+		LetterSpacing:   l.LetterSpacing,
+		WordSpacing:     l.WordSpacing,
+		TabStops:        l.TabStops,
 	}
 	return tl.Layout(gtx, l.Shaper, l.Font, l.TextSize, l.Text, textColor)
 }
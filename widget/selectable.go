@@ -67,8 +67,13 @@ type Selectable struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
-	initialized     bool
-	source          stringSource
+	// LetterSpacing is added after each character of the text, and
+	// WordSpacing after each word separator such as a space.
+	LetterSpacing, WordSpacing unit.Sp
+	// TabStops are the positions that tab characters advance the text to.
+	TabStops    TabStops
+	initialized bool
+	source      stringSource
 	// scratch is a buffer reused to efficiently read text out of the
 	// textView.
 	scratch   []byte
@@ -189,6 +194,9 @@ func (l *Selectable) Layout(gtx layout.Context, lt *text.Shaper, font font.Font,
 	l.text.MaxLines = l.MaxLines
 	l.text.Truncator = l.Truncator
 	l.text.WrapPolicy = l.WrapPolicy
+	l.text.LetterSpacing = l.LetterSpacing
+	l.text.WordSpacing = l.WordSpacing
+	l.text.TabStops = l.TabStops
 	l.text.Layout(gtx, lt, font, size)
 	dims := l.text.Dimensions()
 	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
@@ -228,7 +236,7 @@ func (e *Selectable) processPointer(gtx layout.Context) {
 					Y: int(math.Round(float64(evt.Position.Y))),
 				})
 				gtx.Execute(key.FocusCmd{Tag: e})
-				if evt.Modifiers == key.ModShift {
+				if evt.Modifiers.Held() == key.ModShift {
 					start, end := e.text.Selection()
 					// If they clicked closer to the end, then change the end to
 					// where the caret used to be (effectively swapping start & end).
@@ -336,7 +344,7 @@ func (e *Selectable) command(gtx layout.Context, k key.Event) {
 	if k.Modifiers.Contain(key.ModShift) {
 		selAct = selectionExtend
 	}
-	if k.Modifiers == key.ModShortcut {
+	if k.Modifiers.Held() == key.ModShortcut {
 		switch k.Name {
 		// Copy or Cut selection -- ignored if nothing selected.
 		case "C", "X":
@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kanryu/mado/font"
+	"github.com/kanryu/mado/io/event"
+	"github.com/kanryu/mado/io/pointer"
+	"github.com/kanryu/mado/layout"
+	"github.com/kanryu/mado/op"
+	"github.com/kanryu/mado/op/clip"
+	"github.com/kanryu/mado/op/paint"
+	"github.com/kanryu/mado/text"
+	"github.com/kanryu/mado/unit"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Span is a run of styled text in a paragraph laid out by
+// Label.LayoutSpans.
+type Span struct {
+	Text string
+	// Font is the font of the span. Its zero fields take the values of the
+	// font of the label.
+	Font font.Font
+	// Size is the size of the span, or zero for the size of the label.
+	Size unit.Sp
+	// Material paints the text and lines of the span. If zero, the text
+	// material of the label is used.
+	Material op.CallOp
+	// Background, if non-zero, paints the area behind the span.
+	Background op.CallOp
+	// Underline and Strikethrough draw lines under and through the span,
+	// or to its right and through it in vertical text.
+	Underline, Strikethrough bool
+	// Link, if non-nil, is the target of pointer events over the span,
+	// such as a *gesture.Click.
+	Link event.Tag
+}
+
+// LayoutSpans is like Layout, for a paragraph of styled spans. Lines are
+// wrapped across spans as for a single text.
+func (l Label) LayoutSpans(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt []Span, textMaterial op.CallOp) layout.Dimensions {
+	spans := make([]text.Span, len(txt))
+	styler := &spanStyler{
+		spans: txt,
+		sizes: make([]int, len(txt)),
+		ends:  make([]int, len(txt)),
+	}
+	var str strings.Builder
+	runes := 0
+	for i, s := range txt {
+		spans[i] = text.Span{Text: s.Text, Font: s.Font}
+		styler.sizes[i] = gtx.Sp(size)
+		if s.Size != 0 {
+			styler.sizes[i] = gtx.Sp(s.Size)
+			spans[i].PxPerEm = fixed.I(styler.sizes[i])
+		}
+		runes += utf8.RuneCountInString(s.Text)
+		styler.ends[i] = runes
+		str.WriteString(s.Text)
+	}
+	lt.LayoutSpans(l.params(gtx, font, size), spans)
+	if len(txt) == 0 {
+		styler = nil
+	}
+	dims, _ := l.paint(gtx, lt, str.String(), textMaterial, styler)
+	return dims
+}
+
+// spanStyler tracks the spans of the glyphs of a paragraph of spans, and
+// their decorations.
+type spanStyler struct {
+	spans []Span
+	// sizes are the sizes in pixels of the spans.
+	sizes []int
+	// ends are the offsets of the runes after each span.
+	ends []int
+	// span is the index of the span of the current glyph, runes the offset
+	// of its runes and line that of its line.
+	span, runes, line int
+	// broke tracks whether the glyph before ended a line.
+	broke bool
+	// decorations are the areas of the visible glyphs of the spans.
+	decorations []spanDecoration
+}
+
+// spanDecoration is the area of glyphs of a span in a line.
+type spanDecoration struct {
+	span, line int
+	// box is the logical bounds of the glyphs.
+	box image.Rectangle
+	// base is the baseline of horizontal glyphs, or the center line of
+	// vertical glyphs.
+	base     int
+	vertical bool
+}
+
+// next advances to the span of g. It reports whether the span differs
+// from that of the glyph before.
+func (s *spanStyler) next(g text.Glyph) (changed bool) {
+	span := s.span
+	for s.span < len(s.ends)-1 && s.runes >= s.ends[s.span] {
+		s.span++
+	}
+	s.runes += int(g.Runes)
+	if s.broke {
+		s.line++
+	}
+	s.broke = g.Flags&text.FlagLineBreak != 0
+	return s.span != span
+}
+
+// material returns the text material of the current span.
+func (s *spanStyler) material(textMaterial op.CallOp) op.CallOp {
+	if m := s.spans[s.span].Material; m != (op.CallOp{}) {
+		return m
+	}
+	return textMaterial
+}
+
+// add includes the visible glyph g in the decorations of its span.
+func (s *spanStyler) add(g text.Glyph) {
+	sp := s.spans[s.span]
+	if g.Flags&(text.FlagParagraphBreak|text.FlagAnnotation) != 0 {
+		return
+	}
+	if sp.Background == (op.CallOp{}) && !sp.Underline && !sp.Strikethrough && sp.Link == nil {
+		return
+	}
+	d := spanDecoration{
+		span: s.span,
+		line: s.line,
+		box: image.Rectangle{
+			Min: image.Pt(g.X.Floor(), int(g.Y)-g.Ascent.Ceil()),
+			Max: image.Pt((g.X + g.Advance).Ceil(), int(g.Y)+g.Descent.Ceil()),
+		},
+		base: int(g.Y),
+	}
+	if g.Flags&text.FlagVertical != 0 {
+		d.vertical = true
+		d.box = image.Rectangle{
+			Min: image.Pt((g.X - g.Descent).Floor(), int(g.Y)),
+			Max: image.Pt((g.X + g.Ascent).Ceil(), int(g.Y)+g.Advance.Ceil()),
+		}
+		d.base = g.X.Round()
+	}
+	if n := len(s.decorations); n > 0 {
+		if last := &s.decorations[n-1]; last.span == d.span && last.line == d.line {
+			last.box = last.box.Union(d.box)
+			return
+		}
+	}
+	s.decorations = append(s.decorations, d)
+}
+
+// paintBackgrounds paints the backgrounds of the spans.
+func (s *spanStyler) paintBackgrounds(ops *op.Ops) {
+	for _, d := range s.decorations {
+		if bg := s.spans[d.span].Background; bg != (op.CallOp{}) {
+			paintRect(ops, d.box, bg)
+		}
+	}
+}
+
+// paintDecorations paints the lines of the spans over their text, and
+// adds the areas of their links.
+func (s *spanStyler) paintDecorations(ops *op.Ops, textMaterial op.CallOp) {
+	for _, d := range s.decorations {
+		sp := s.spans[d.span]
+		material := sp.Material
+		if material == (op.CallOp{}) {
+			material = textMaterial
+		}
+		em := s.sizes[d.span]
+		thickness := max(1, em/16)
+		if sp.Underline {
+			r := image.Rect(d.box.Min.X, d.base+max(1, em/10), d.box.Max.X, d.base+max(1, em/10)+thickness)
+			if d.vertical {
+				r = image.Rect(d.base+em/2, d.box.Min.Y, d.base+em/2+thickness, d.box.Max.Y)
+			}
+			paintRect(ops, r, material)
+		}
+		if sp.Strikethrough {
+			mid := d.base - em*3/10 - thickness/2
+			r := image.Rect(d.box.Min.X, mid, d.box.Max.X, mid+thickness)
+			if d.vertical {
+				mid = d.base - thickness/2
+				r = image.Rect(mid, d.box.Min.Y, mid+thickness, d.box.Max.Y)
+			}
+			paintRect(ops, r, material)
+		}
+		if sp.Link != nil {
+			area := clip.Rect(d.box).Push(ops)
+			event.Op(ops, sp.Link)
+			pointer.CursorPointer.Add(ops)
+			area.Pop()
+		}
+	}
+}
+
+// paintRect fills r with material.
+func paintRect(ops *op.Ops, r image.Rectangle, material op.CallOp) {
+	defer clip.Rect(r).Push(ops).Pop()
+	material.Add(ops)
+	paint.PaintOp{}.Add(ops)
+}
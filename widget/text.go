@@ -11,6 +11,7 @@ import (
 
 	"github.com/kanryu/mado/f32"
 	"github.com/kanryu/mado/font"
+	"github.com/kanryu/mado/io/system"
 	"github.com/kanryu/mado/layout"
 	"github.com/kanryu/mado/op"
 	"github.com/kanryu/mado/op/clip"
@@ -61,6 +62,11 @@ type textView struct {
 	Truncator string
 	// WrapPolicy configures how displayed text will be broken into lines.
 	WrapPolicy text.WrapPolicy
+	// LetterSpacing is added after each character of the text, and
+	// WordSpacing after each word separator such as a space.
+	LetterSpacing, WordSpacing unit.Sp
+	// TabStops are the positions that tab characters advance the text to.
+	TabStops TabStops
 	// Mask replaces the visual display of each rune in the contents with the given rune.
 	// Newline characters are not masked. When non-zero, the unmasked contents
 	// are accessed by Len, Text, and SetText.
@@ -81,6 +87,9 @@ type textView struct {
 	regions         []Region
 	dims            layout.Dimensions
 
+	// tabScratch is reused to compare the TabStops with those of params.
+	tabScratch []fixed.Int26_6
+
 	// offIndex is an index of rune index to byte offsets.
 	offIndex []offEntry
 
@@ -105,6 +114,17 @@ func (e *textView) Changed() bool {
 	return e.rr.Changed()
 }
 
+// vertical reports whether the text is laid out in vertical columns.
+func (e *textView) vertical() bool {
+	return e.params.Locale.Direction.Axis() == system.Vertical
+}
+
+// scrollsHorizontally reports whether the text scrolls along the X axis:
+// a single line of horizontal text, or the columns of vertical text.
+func (e *textView) scrollsHorizontally() bool {
+	return e.SingleLine != e.vertical()
+}
+
 // Dimensions returns the dimensions of the visible text.
 func (e *textView) Dimensions() layout.Dimensions {
 	basePos := e.dims.Size.Y - e.dims.Baseline
@@ -245,6 +265,18 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 		maxWidth = math.MaxInt
 	}
 	minWidth := gtx.Constraints.Min.X
+	var minHeight, maxHeight int
+	if e.vertical() {
+		minHeight, maxHeight = gtx.Constraints.Min.Y, gtx.Constraints.Max.Y
+		if e.SingleLine {
+			maxWidth = gtx.Constraints.Max.X
+			maxHeight = math.MaxInt
+		}
+	}
+	if maxHeight != e.params.MaxHeight || minHeight != e.params.MinHeight {
+		e.params.MinHeight, e.params.MaxHeight = minHeight, maxHeight
+		e.invalidate()
+	}
 	if maxWidth != e.params.MaxWidth {
 		e.params.MaxWidth = maxWidth
 		e.invalidate()
@@ -285,6 +317,21 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 		e.params.LineHeightScale = e.LineHeightScale
 		e.invalidate()
 	}
+	if ls := fixed.I(gtx.Sp(e.LetterSpacing)); ls != e.params.LetterSpacing {
+		e.params.LetterSpacing = ls
+		e.invalidate()
+	}
+	if ws := fixed.I(gtx.Sp(e.WordSpacing)); ws != e.params.WordSpacing {
+		e.params.WordSpacing = ws
+		e.invalidate()
+	}
+	if tabs := e.TabStops.params(gtx, e.tabScratch); !slices.Equal(tabs.Stops, e.params.TabStops.Stops) || tabs.Interval != e.params.TabStops.Interval {
+		e.tabScratch = e.params.TabStops.Stops
+		e.params.TabStops = tabs
+		e.invalidate()
+	} else {
+		e.tabScratch = tabs.Stops
+	}
 
 	e.makeValid()
 
@@ -324,8 +371,9 @@ func (e *textView) PaintText(gtx layout.Context, material op.CallOp) {
 	}
 
 	startGlyph := 0
+	frame := e.index.toFrame(viewport)
 	for _, line := range e.index.lines {
-		if line.descent.Ceil()+line.yOff >= viewport.Min.Y {
+		if line.descent.Ceil()+line.yOff >= frame.Min.Y {
 			break
 		}
 		startGlyph += line.glyphs
@@ -366,6 +414,13 @@ func (e *textView) PaintCaret(gtx layout.Context, material op.CallOp) {
 		Min: caretPos.Sub(image.Pt(carWidth2, carAsc)),
 		Max: caretPos.Add(image.Pt(carWidth2, carDesc)),
 	}
+	if e.vertical() {
+		// The caret lies across the column.
+		carRect = image.Rectangle{
+			Min: caretPos.Sub(image.Pt(carDesc, carWidth2)),
+			Max: caretPos.Add(image.Pt(carAsc, carWidth2)),
+		}
+	}
 	cl := image.Rectangle{Max: e.viewSize}
 	carRect = cl.Intersect(carRect)
 	if !carRect.Empty() {
@@ -385,6 +440,9 @@ func (e *textView) CaretInfo() (pos image.Point, ascent, descent int) {
 		X: caretStart.x.Round(),
 		Y: caretStart.y,
 	}
+	if e.vertical() {
+		pos = image.Point{X: -caretStart.y, Y: caretStart.x.Round()}
+	}
 	pos = pos.Sub(e.scrollOff)
 	return
 }
@@ -418,6 +476,14 @@ func (e *textView) Text(buf []byte) []byte {
 
 func (e *textView) ScrollBounds() image.Rectangle {
 	var b image.Rectangle
+	if e.vertical() {
+		if e.SingleLine {
+			b.Max.Y = e.dims.Size.Y - e.viewSize.Y
+		} else {
+			b.Max.X = e.dims.Size.X - e.viewSize.X
+		}
+		return b
+	}
 	if e.SingleLine {
 		if len(e.index.lines) > 0 {
 			line := e.index.lines[0]
@@ -465,6 +531,10 @@ func (e *textView) scrollAbs(x, y int) {
 func (e *textView) MoveCoord(pos image.Point) {
 	x := fixed.I(pos.X + e.scrollOff.X)
 	y := pos.Y + e.scrollOff.Y
+	if e.vertical() {
+		// Find the position in the frame of the index.
+		x, y = fixed.I(y), -x.Round()
+	}
 	e.caret.start = e.closestToXYGraphemes(x, y).runes
 	e.caret.xoff = 0
 }
@@ -525,7 +595,7 @@ func (e *textView) CaretPos() (line, col int) {
 // editor itself.
 func (e *textView) CaretCoords() f32.Point {
 	pos := e.closestToRune(e.caret.start)
-	return f32.Pt(float32(pos.x)/64-float32(e.scrollOff.X), float32(pos.y-e.scrollOff.Y))
+	return e.index.pointFromFrame(pos.x, pos.y).Sub(layout.FPt(e.scrollOff))
 }
 
 // indexRune returns the latest rune index and byte offset no later than r.
@@ -602,7 +672,11 @@ func (e *textView) Replace(start, end int, s string) int {
 func (e *textView) MovePages(pages int, selAct selectionAction) {
 	caret := e.closestToRune(e.caret.start)
 	x := caret.x + e.caret.xoff
-	y := caret.y + pages*e.viewSize.Y
+	page := e.viewSize.Y
+	if e.vertical() {
+		page = e.viewSize.X
+	}
+	y := caret.y + pages*page
 	pos := e.closestToXYGraphemes(x, y)
 	e.caret.start = pos.runes
 	e.caret.xoff = x - pos.x
@@ -656,7 +730,11 @@ func (e *textView) MoveEnd(selAct selectionAction) {
 	caret := e.closestToRune(e.caret.start)
 	caret = e.closestToLineCol(caret.lineCol.line, math.MaxInt)
 	e.caret.start = caret.runes
-	e.caret.xoff = fixed.I(e.params.MaxWidth) - caret.x
+	lineLen := e.params.MaxWidth
+	if e.vertical() {
+		lineLen = e.params.MaxHeight
+	}
+	e.caret.xoff = fixed.I(lineLen) - caret.x
 	e.updateSelection(selAct)
 	e.clampCursorToGraphemes()
 }
@@ -709,6 +787,29 @@ func (e *textView) MoveWord(distance int, selAct selectionAction) {
 
 func (e *textView) ScrollToCaret() {
 	caret := e.closestToRune(e.caret.start)
+	if e.vertical() {
+		if e.SingleLine {
+			var dist int
+			if d := caret.x.Floor() - e.scrollOff.Y; d < 0 {
+				dist = d
+			} else if d := caret.x.Ceil() - (e.scrollOff.Y + e.viewSize.Y); d > 0 {
+				dist = d
+			}
+			e.ScrollRel(0, dist)
+		} else {
+			// Columns are to the left of their predecessors.
+			minx := -caret.y - caret.descent.Ceil()
+			maxx := -caret.y + caret.ascent.Ceil()
+			var dist int
+			if d := minx - e.scrollOff.X; d < 0 {
+				dist = d
+			} else if d := maxx - (e.scrollOff.X + e.viewSize.X); d > 0 {
+				dist = d
+			}
+			e.ScrollRel(dist, 0)
+		}
+		return
+	}
 	if e.SingleLine {
 		var dist int
 		if d := caret.x.Floor() - e.scrollOff.X; d < 0 {
@@ -143,6 +143,9 @@ type Callbacks interface {
 	SetEditorSnippet(r key.Range)
 	ClickFocus()
 	ActionAt(p f32.Point) (system.Action, bool)
+	// DropTarget returns the first of the MIME types of a drop from
+	// another application that the window accepts at pos.
+	DropTarget(pos f32.Point, types []string) (string, bool)
 }
 
 type Window interface {
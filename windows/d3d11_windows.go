@@ -156,3 +156,11 @@ func (c *d3d11Context) SwapBuffers() error {
 func (c *d3d11Context) SwapInterval(interval int) {
 	fmt.Println("not implamented")
 }
+
+func (c *d3d11Context) GetProcAddress(procname string) unsafe.Pointer {
+	return nil
+}
+
+func (c *d3d11Context) ExtensionSupported(extension string) bool {
+	return false
+}
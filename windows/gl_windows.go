@@ -378,6 +378,19 @@ func (c *glContext) SwapInterval(interval int) {
 	}
 }
 
+func (c *glContext) GetProcAddress(procname string) unsafe.Pointer {
+	proc := gl.GetProcAddressWGL(procname)
+	return *(*unsafe.Pointer)(unsafe.Pointer(&proc))
+}
+
+func (c *glContext) ExtensionSupported(extension string) bool {
+	ok, err := c.extensionSupported(extension)
+	if err != nil {
+		return false
+	}
+	return ok
+}
+
 func (c *glContext) RenderTarget() (gpu.RenderTarget, error) {
 	return gpu.OpenGLRenderTarget{}, nil
 }
@@ -679,7 +692,7 @@ func (c *glContext) refreshContextAttribs(ctxconfig *mado.CtxConfig) (ferr error
 			if flags&GL_CONTEXT_FLAG_DEBUG_BIT != 0 {
 				c.context.Debug = true
 			} else {
-				ok, err := c.ExtensionSupported("GL_ARB_debug_output")
+				ok, err := c.extensionSupported("GL_ARB_debug_output")
 				if err != nil {
 					return err
 				}
@@ -706,7 +719,7 @@ func (c *glContext) refreshContextAttribs(ctxconfig *mado.CtxConfig) (ferr error
 			} else if mask&GL_CONTEXT_CORE_PROFILE_BIT != 0 {
 				c.context.Profile = mado.OpenGLCoreProfile
 			} else {
-				ok, err := c.ExtensionSupported("GL_ARB_compatibility")
+				ok, err := c.extensionSupported("GL_ARB_compatibility")
 				if err != nil {
 					return err
 				}
@@ -721,7 +734,7 @@ func (c *glContext) refreshContextAttribs(ctxconfig *mado.CtxConfig) (ferr error
 		}
 
 		// Read back robustness strategy
-		ok, err := c.ExtensionSupported("GL_ARB_robustness")
+		ok, err := c.extensionSupported("GL_ARB_robustness")
 		if err != nil {
 			return err
 		}
@@ -740,7 +753,7 @@ func (c *glContext) refreshContextAttribs(ctxconfig *mado.CtxConfig) (ferr error
 		}
 	} else {
 		// Read back robustness strategy
-		ok, err := c.ExtensionSupported("GL_EXT_robustness")
+		ok, err := c.extensionSupported("GL_EXT_robustness")
 		if err != nil {
 			return err
 		}
@@ -759,7 +772,7 @@ func (c *glContext) refreshContextAttribs(ctxconfig *mado.CtxConfig) (ferr error
 		}
 	}
 
-	ok, err := c.ExtensionSupported("GL_KHR_context_flush_control")
+	ok, err := c.extensionSupported("GL_KHR_context_flush_control")
 	if err != nil {
 		return err
 	}
@@ -776,8 +789,9 @@ func (c *glContext) refreshContextAttribs(ctxconfig *mado.CtxConfig) (ferr error
 
 	// Clearing the front buffer to black to avoid garbage pixels left over from
 	// previous uses of our bit of VRAM
-	glClear := gl.GetProcAddressWGL("glClear")
-	_, _, _ = syscall.Syscall(glClear, GL_COLOR_BUFFER_BIT, 0, 0, 0)
+	if glClear := gl.GetProcAddressWGL("glClear"); glClear != 0 {
+		_, _, _ = syscall.Syscall(glClear, GL_COLOR_BUFFER_BIT, 0, 0, 0)
+	}
 
 	c.Unlock()
 	return nil
@@ -1128,7 +1142,7 @@ func checkValidContextConfig(ctxconfig *mado.CtxConfig) error {
 	return nil
 }
 
-func (c *glContext) ExtensionSupported(extension string) (bool, error) {
+func (c *glContext) extensionSupported(extension string) (bool, error) {
 	const (
 		GL_EXTENSIONS     = 0x1F03
 		GL_NUM_EXTENSIONS = 0x821D
@@ -1151,10 +1165,13 @@ func (c *glContext) ExtensionSupported(extension string) (bool, error) {
 		// Check if extension is in the modern OpenGL extensions string list
 
 		glGetIntegerv := gl.GetProcAddressWGL("glGetIntegerv")
+		glGetStringi := gl.GetProcAddressWGL("glGetStringi")
+		if glGetIntegerv == 0 || glGetStringi == 0 {
+			return false, fmt.Errorf("glfw: entry point retrieval is broken: %w", PlatformError)
+		}
 		var count int32
 		_, _, _ = syscall.Syscall(glGetIntegerv, GL_NUM_EXTENSIONS, uintptr(unsafe.Pointer(&count)), 0, 0)
 
-		glGetStringi := gl.GetProcAddressWGL("glGetStringi")
 		for i := 0; i < int(count); i++ {
 			r, _, _ := syscall.Syscall(glGetStringi, GL_EXTENSIONS, uintptr(i), 0, 0)
 			if r == 0 {
@@ -1170,6 +1187,9 @@ func (c *glContext) ExtensionSupported(extension string) (bool, error) {
 		// Check if extension is in the old style OpenGL extensions string
 
 		glGetString := gl.GetProcAddressWGL("glGetString")
+		if glGetString == 0 {
+			return false, fmt.Errorf("glfw: entry point retrieval is broken: %w", PlatformError)
+		}
 		r, _, _ := syscall.Syscall(glGetString, GL_EXTENSIONS, 0, 0, 0)
 		if r == 0 {
 			return false, fmt.Errorf("glfw: extension string retrieval is broken: %w", PlatformError)
@@ -1184,6 +1204,9 @@ func (c *glContext) ExtensionSupported(extension string) (bool, error) {
 	}
 
 	// Check if extension is in the platform-specific string
+	if c.context.ExtensionSupported == nil {
+		return ExtensionSupportedWGL(extension), nil
+	}
 	return c.context.ExtensionSupported(extension), nil
 }
 
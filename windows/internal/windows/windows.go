@@ -139,6 +139,49 @@ type MonitorInfo struct {
 	Flags    uint32
 }
 
+// RawInputDevice is RAWINPUTDEVICE.
+type RawInputDevice struct {
+	UsagePage uint16
+	Usage     uint16
+	Flags     uint32
+	Target    syscall.Handle
+}
+
+// RawInputHeader is RAWINPUTHEADER.
+type RawInputHeader struct {
+	Type   uint32
+	Size   uint32
+	Device syscall.Handle
+	WParam uintptr
+}
+
+// RawMouse is RAWMOUSE.
+type RawMouse struct {
+	Flags            uint16
+	_                uint16
+	ButtonFlags      uint16
+	ButtonData       uint16
+	RawButtons       uint32
+	LastX            int32
+	LastY            int32
+	ExtraInformation uint32
+}
+
+// RawInput is RAWINPUT for mouse devices.
+type RawInput struct {
+	Header RawInputHeader
+	Mouse  RawMouse
+}
+
+// IconInfo is ICONINFO.
+type IconInfo struct {
+	Icon     int32
+	XHotspot uint32
+	YHotspot uint32
+	Mask     syscall.Handle
+	Color    syscall.Handle
+}
+
 type TrackMouseEventStruct struct {
 	cbSize      uint32
 	CbFlags     uint32
@@ -207,8 +250,16 @@ const (
 
 	MONITOR_DEFAULTTOPRIMARY = 1
 
+	MOUSE_MOVE_ABSOLUTE = 0x01
+
 	NI_COMPOSITIONSTR = 0x0015
 
+	RID_INPUT = 0x10000003
+
+	RIDEV_REMOVE = 0x00000001
+
+	RIM_TYPEMOUSE = 0
+
 	SIZE_MAXIMIZED = 2
 	SIZE_MINIMIZED = 1
 	SIZE_RESTORED  = 0
@@ -234,7 +285,9 @@ const (
 
 	USER_TIMER_MINIMUM = 0x0000000A
 
+	VK_CAPITAL = 0x14
 	VK_CONTROL = 0x11
+	VK_NUMLOCK = 0x90
 	VK_LWIN    = 0x5B
 	VK_MENU    = 0x12
 	VK_RWIN    = 0x5C
@@ -291,6 +344,7 @@ const (
 	WM_DESTROY              = 0x0002
 	WM_ERASEBKGND           = 0x0014
 	WM_GETMINMAXINFO        = 0x0024
+	WM_INPUT                = 0x00FF
 	WM_IME_COMPOSITION      = 0x010F
 	WM_IME_ENDCOMPOSITION   = 0x010E
 	WM_IME_STARTCOMPOSITION = 0x010D
@@ -387,21 +441,27 @@ var (
 	user32                       = syscall.NewLazySystemDLL("user32.dll")
 	_AdjustWindowRectEx          = user32.NewProc("AdjustWindowRectEx")
 	_CallMsgFilter               = user32.NewProc("CallMsgFilterW")
+	_ClientToScreen              = user32.NewProc("ClientToScreen")
+	_ClipCursor                  = user32.NewProc("ClipCursor")
 	_CloseClipboard              = user32.NewProc("CloseClipboard")
+	_CreateIconIndirect          = user32.NewProc("CreateIconIndirect")
 	_CreateWindowEx              = user32.NewProc("CreateWindowExW")
 	_DefWindowProc               = user32.NewProc("DefWindowProcW")
+	_DestroyIcon                 = user32.NewProc("DestroyIcon")
 	_DestroyWindow               = user32.NewProc("DestroyWindow")
 	_DispatchMessage             = user32.NewProc("DispatchMessageW")
 	_EmptyClipboard              = user32.NewProc("EmptyClipboard")
 	_GetWindowRect               = user32.NewProc("GetWindowRect")
 	_GetClientRect               = user32.NewProc("GetClientRect")
 	_GetClipboardData            = user32.NewProc("GetClipboardData")
+	_GetCursorPos                = user32.NewProc("GetCursorPos")
 	_GetDC                       = user32.NewProc("GetDC")
 	_GetDpiForWindow             = user32.NewProc("GetDpiForWindow")
 	_GetKeyState                 = user32.NewProc("GetKeyState")
 	_GetMessage                  = user32.NewProc("GetMessageW")
 	_GetMessageTime              = user32.NewProc("GetMessageTime")
 	_GetMonitorInfo              = user32.NewProc("GetMonitorInfoW")
+	_GetRawInputData             = user32.NewProc("GetRawInputData")
 	_GetSystemMetrics            = user32.NewProc("GetSystemMetrics")
 	_GetWindowLong               = user32.NewProc("GetWindowLongPtrW")
 	_GetWindowLong32             = user32.NewProc("GetWindowLongW")
@@ -419,11 +479,13 @@ var (
 	_PostQuitMessage             = user32.NewProc("PostQuitMessage")
 	_ReleaseCapture              = user32.NewProc("ReleaseCapture")
 	_RegisterClassExW            = user32.NewProc("RegisterClassExW")
+	_RegisterRawInputDevices     = user32.NewProc("RegisterRawInputDevices")
 	_ReleaseDC                   = user32.NewProc("ReleaseDC")
 	_ScreenToClient              = user32.NewProc("ScreenToClient")
 	_ShowWindow                  = user32.NewProc("ShowWindow")
 	_SetCapture                  = user32.NewProc("SetCapture")
 	_SetCursor                   = user32.NewProc("SetCursor")
+	_SetCursorPos                = user32.NewProc("SetCursorPos")
 	_SetClipboardData            = user32.NewProc("SetClipboardData")
 	_SetForegroundWindow         = user32.NewProc("SetForegroundWindow")
 	_SetFocus                    = user32.NewProc("SetFocus")
@@ -444,6 +506,8 @@ var (
 
 	gdi32                = syscall.NewLazySystemDLL("gdi32")
 	_ChoosePixelFormat   = gdi32.NewProc("ChoosePixelFormat")
+	_CreateBitmap        = gdi32.NewProc("CreateBitmap")
+	_DeleteObject        = gdi32.NewProc("DeleteObject")
 	_DescribePixelFormat = gdi32.NewProc("DescribePixelFormat")
 	_GetDeviceCaps       = gdi32.NewProc("GetDeviceCaps")
 	_SetPixelFormat      = gdi32.NewProc("SetPixelFormat")
@@ -470,6 +534,46 @@ func CallMsgFilter(m *Msg, nCode uintptr) bool {
 	return r != 0
 }
 
+func ClientToScreen(hwnd syscall.Handle, p *Point) {
+	_ClientToScreen.Call(uintptr(hwnd), uintptr(unsafe.Pointer(p)))
+}
+
+// ClipCursor confines the cursor to r, in screen coordinates. A nil r
+// releases the cursor.
+func ClipCursor(r *Rect) {
+	_ClipCursor.Call(uintptr(unsafe.Pointer(r)))
+}
+
+// CreateBitmap creates a device dependent bitmap from bits, which may
+// be nil for an uninitialized bitmap.
+func CreateBitmap(width, height int32, bitsPerPixel uint32, bits []byte) (syscall.Handle, error) {
+	var p unsafe.Pointer
+	if len(bits) > 0 {
+		p = unsafe.Pointer(&bits[0])
+	}
+	h, _, err := _CreateBitmap.Call(uintptr(width), uintptr(height), 1, uintptr(bitsPerPixel), uintptr(p))
+	if h == 0 {
+		return 0, fmt.Errorf("CreateBitmap failed: %v", err)
+	}
+	return syscall.Handle(h), nil
+}
+
+func CreateIconIndirect(info *IconInfo) (syscall.Handle, error) {
+	h, _, err := _CreateIconIndirect.Call(uintptr(unsafe.Pointer(info)))
+	if h == 0 {
+		return 0, fmt.Errorf("CreateIconIndirect failed: %v", err)
+	}
+	return syscall.Handle(h), nil
+}
+
+func DeleteObject(h syscall.Handle) {
+	_DeleteObject.Call(uintptr(h))
+}
+
+func DestroyIcon(h syscall.Handle) {
+	_DestroyIcon.Call(uintptr(h))
+}
+
 func ChoosePixelFormat(hdc syscall.Handle, ppfd *PIXELFORMATDESCRIPTOR) (int32, error) {
 	r, _, e := _ChoosePixelFormat.Call(uintptr(hdc), uintptr(unsafe.Pointer(ppfd)))
 	if int32(r) == 0 && !errors.Is(e, windows.ERROR_SUCCESS) {
@@ -562,6 +666,12 @@ func GetClipboardData(format uint32) (syscall.Handle, error) {
 	return syscall.Handle(r), nil
 }
 
+func GetCursorPos() Point {
+	var p Point
+	_GetCursorPos.Call(uintptr(unsafe.Pointer(&p)))
+	return p
+}
+
 func GetDC(hwnd syscall.Handle) (syscall.Handle, error) {
 	hdc, _, err := _GetDC.Call(uintptr(hwnd))
 	if hdc == 0 {
@@ -624,6 +734,17 @@ func GetMessageTime() time.Duration {
 	return time.Duration(r) * time.Millisecond
 }
 
+// GetRawInput returns the raw input of a WM_INPUT message.
+func GetRawInput(lParam uintptr) (RawInput, bool) {
+	var ri RawInput
+	size := uint32(unsafe.Sizeof(ri))
+	r, _, _ := _GetRawInputData.Call(lParam, RID_INPUT, uintptr(unsafe.Pointer(&ri)), uintptr(unsafe.Pointer(&size)), unsafe.Sizeof(ri.Header))
+	if int32(r) <= 0 {
+		return RawInput{}, false
+	}
+	return ri, true
+}
+
 func GetSystemMetrics(nIndex int) int {
 	r, _, _ := _GetSystemMetrics.Call(uintptr(nIndex))
 	return int(r)
@@ -863,6 +984,14 @@ func RegisterClassEx(cls *WndClassEx) (uint16, error) {
 	return uint16(a), nil
 }
 
+func RegisterRawInputDevices(devs ...RawInputDevice) error {
+	r, _, err := _RegisterRawInputDevices.Call(uintptr(unsafe.Pointer(&devs[0])), uintptr(len(devs)), unsafe.Sizeof(devs[0]))
+	if r == 0 {
+		return fmt.Errorf("RegisterRawInputDevices failed: %v", err)
+	}
+	return nil
+}
+
 func ReleaseDC(hdc syscall.Handle) {
 	_ReleaseDC.Call(uintptr(hdc))
 }
@@ -904,6 +1033,10 @@ func SetCursor(h syscall.Handle) {
 	_SetCursor.Call(uintptr(h))
 }
 
+func SetCursorPos(x, y int32) {
+	_SetCursorPos.Call(uintptr(x), uintptr(y))
+}
+
 func SetTimer(hwnd syscall.Handle, nIDEvent uintptr, uElapse uint32, timerProc uintptr) error {
 	r, _, err := _SetTimer.Call(uintptr(hwnd), uintptr(nIDEvent), uintptr(uElapse), timerProc)
 	if r == 0 {
@@ -27,6 +27,7 @@ import (
 	gowindows "golang.org/x/sys/windows"
 
 	"github.com/kanryu/mado/f32"
+	"github.com/kanryu/mado/io/input"
 	"github.com/kanryu/mado/io/key"
 	"github.com/kanryu/mado/io/pointer"
 	"github.com/kanryu/mado/io/system"
@@ -54,6 +55,10 @@ type window struct {
 	cursorIn      bool
 	cursor        syscall.Handle
 	cursorTracked bool
+	// customCursor is the cursor set by SetCustomCursor, or 0.
+	customCursor syscall.Handle
+	// customCursors caches the cursors created by SetCustomCursor.
+	customCursors map[*pointer.CustomCursor]syscall.Handle
 
 	// placement saves the previous window position when in full screen mode.
 	placement *windows.WindowPlacement
@@ -61,6 +66,16 @@ type window struct {
 	animating bool
 	focused   bool
 
+	// inputMode is the cursor mode set by SetInputMode.
+	inputMode mado.InputMode
+	// virtualPos is the cursor position reported while the cursor is
+	// disabled.
+	virtualPos f32.Point
+	// rawInput tracks whether raw mouse input is registered for the window.
+	rawInput bool
+	// rawPos is the last position reported by an absolute raw input device.
+	rawPos image.Point
+
 	borderSize image.Point
 	config     mado.Config
 }
@@ -104,6 +119,13 @@ func InitWindows() {
 	mado.PollEvents = PollEvents
 	mado.GetTimerValue = GetTimerValue
 	mado.GetTimerFrequency = GetTimerFrequency
+	mado.RawMouseMotionSupported = rawMouseMotionSupported
+}
+
+// rawMouseMotionSupported reports true; raw input is available on all
+// supported versions of Windows.
+func rawMouseMotionSupported() bool {
+	return true
 }
 
 var withPollEvents bool
@@ -290,7 +312,7 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 			e := key.Event{
 				Name:      n,
 				KeyCode:   key.KeyCode(wParam),
-				Modifiers: getModifiers(),
+				Modifiers: w.modifiers(),
 				State:     key.Press,
 			}
 			if msg == windows.WM_KEYUP || msg == windows.WM_SYSKEYUP {
@@ -306,26 +328,28 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 			}
 		}
 	case windows.WM_LBUTTONDOWN:
-		w.pointerButton(pointer.ButtonPrimary, true, lParam, getModifiers())
+		w.pointerButton(pointer.ButtonPrimary, true, lParam, w.modifiers())
 	case windows.WM_LBUTTONUP:
-		w.pointerButton(pointer.ButtonPrimary, false, lParam, getModifiers())
+		w.pointerButton(pointer.ButtonPrimary, false, lParam, w.modifiers())
 	case windows.WM_RBUTTONDOWN:
-		w.pointerButton(pointer.ButtonSecondary, true, lParam, getModifiers())
+		w.pointerButton(pointer.ButtonSecondary, true, lParam, w.modifiers())
 	case windows.WM_RBUTTONUP:
-		w.pointerButton(pointer.ButtonSecondary, false, lParam, getModifiers())
+		w.pointerButton(pointer.ButtonSecondary, false, lParam, w.modifiers())
 	case windows.WM_MBUTTONDOWN:
-		w.pointerButton(pointer.ButtonTertiary, true, lParam, getModifiers())
+		w.pointerButton(pointer.ButtonTertiary, true, lParam, w.modifiers())
 	case windows.WM_MBUTTONUP:
-		w.pointerButton(pointer.ButtonTertiary, false, lParam, getModifiers())
+		w.pointerButton(pointer.ButtonTertiary, false, lParam, w.modifiers())
 	case windows.WM_CANCELMODE:
 		w.w.Event(pointer.Event{
 			Kind: pointer.Cancel,
 		})
 	case windows.WM_SETFOCUS:
 		w.focused = true
+		w.applyInputMode()
 		w.w.Event(key.FocusEvent{Focus: true})
 	case windows.WM_KILLFOCUS:
 		w.focused = false
+		w.releaseInputMode()
 		w.w.Event(key.FocusEvent{Focus: false})
 	case windows.WM_NCACTIVATE:
 		if w.stage >= mado.StageInactive {
@@ -345,8 +369,11 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 		windows.ScreenToClient(w.hwnd, &np)
 		return w.hitTest(int(np.X), int(np.Y))
 	case windows.WM_MOVE:
+		w.updateClip()
 		x, y := coordsFromlParam(lParam)
-		w.w.Event(iowindow.MoveEvent{Pos: image.Point{X: x, Y: y}})
+		w.config.Pos = image.Point{X: x, Y: y}
+		w.w.Event(iowindow.MoveEvent{Pos: w.config.Pos})
+		w.w.Event(mado.ConfigEvent{Config: w.config})
 	case windows.WM_CLOSE:
 		w.w.Event(iowindow.CloseEvent{})
 	case windows.WM_MOUSEMOVE:
@@ -361,22 +388,54 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 			w.w.Event(pointer.CursorEnterEvent{Entered: true})
 		}
 		x, y := coordsFromlParam(lParam)
-		p := f32.Point{X: float32(x), Y: float32(y)}
-		w.w.Event(pointer.Event{
-			Kind:      pointer.Move,
-			Source:    pointer.Mouse,
-			Position:  p,
-			Buttons:   w.pointerBtns,
-			Time:      windows.GetMessageTime(),
-			Modifiers: getModifiers(),
-		})
+		if w.inputMode.Cursor == mado.CursorModeDisabled {
+			if w.rawInput {
+				// Motion is reported by WM_INPUT.
+				break
+			}
+			// Accumulate the motion away from the center and move the
+			// cursor back.
+			c := w.clientCenter()
+			d := f32.Pt(float32(x-int(c.X)), float32(y-int(c.Y)))
+			if d == (f32.Point{}) {
+				break
+			}
+			w.centerCursor()
+			w.virtualPos = w.virtualPos.Add(d)
+			w.pointerMove(w.virtualPos)
+			break
+		}
+		w.pointerMove(f32.Point{X: float32(x), Y: float32(y)})
+	case windows.WM_INPUT:
+		if !w.rawInput || w.inputMode.Cursor != mado.CursorModeDisabled {
+			break
+		}
+		ri, ok := windows.GetRawInput(lParam)
+		if !ok || ri.Header.Type != windows.RIM_TYPEMOUSE {
+			break
+		}
+		var d f32.Point
+		pos := image.Pt(int(ri.Mouse.LastX), int(ri.Mouse.LastY))
+		if ri.Mouse.Flags&windows.MOUSE_MOVE_ABSOLUTE != 0 {
+			// Absolute devices such as remote desktop sessions report
+			// positions, not motion.
+			d = f32.Pt(float32(pos.X-w.rawPos.X), float32(pos.Y-w.rawPos.Y))
+			w.rawPos = pos
+		} else {
+			d = f32.Pt(float32(pos.X), float32(pos.Y))
+		}
+		if d == (f32.Point{}) {
+			break
+		}
+		w.virtualPos = w.virtualPos.Add(d)
+		w.pointerMove(w.virtualPos)
 	case windows.WM_MOUSELEAVE:
 		w.cursorTracked = false
 		w.w.Event(pointer.CursorEnterEvent{Entered: false})
 	case windows.WM_MOUSEWHEEL:
-		w.scrollEvent(wParam, lParam, false, getModifiers())
+		w.scrollEvent(wParam, lParam, false, w.modifiers())
 	case windows.WM_MOUSEHWHEEL:
-		w.scrollEvent(wParam, lParam, true, getModifiers())
+		w.scrollEvent(wParam, lParam, true, w.modifiers())
 	case windows.WM_DESTROY:
 		w.w.Event(ViewEvent{})
 		w.w.Event(mado.DestroyEvent{})
@@ -384,6 +443,13 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 			windows.ReleaseDC(w.hdc)
 			w.hdc = 0
 		}
+		for _, h := range w.customCursors {
+			if h != 0 {
+				windows.DestroyIcon(h)
+			}
+		}
+		w.customCursors = nil
+		w.customCursor = 0
 		// The system destroys the HWND for us.
 		w.hwnd = 0
 		windows.PostQuitMessage(0)
@@ -412,6 +478,7 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 	case windows.WM_PAINT:
 		w.draw(true)
 	case windows.WM_SIZE:
+		w.updateClip()
 		x, y := coordsFromlParam(lParam)
 		w.w.Event(iowindow.SizeEvent{Size: image.Point{X: x, Y: y}})
 		w.w.Event(iowindow.FramebufferSizeEvent{Size: image.Point{X: x, Y: y}})
@@ -456,7 +523,7 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 	case windows.WM_SETCURSOR:
 		w.cursorIn = (lParam & 0xffff) == windows.HTCLIENT
 		if w.cursorIn {
-			windows.SetCursor(w.cursor)
+			windows.SetCursor(w.currentCursor())
 			return windows.TRUE
 		}
 	case _WM_WAKEUP:
@@ -522,6 +589,21 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 	return windows.DefWindowProc(hwnd, msg, wParam, lParam)
 }
 
+// modifiers returns the current modifiers, including the lock modifiers
+// if requested by the input mode.
+func (w *window) modifiers() key.Modifiers {
+	kmods := getModifiers()
+	if w.inputMode.LockKeyMods {
+		if windows.GetKeyState(windows.VK_CAPITAL)&1 != 0 {
+			kmods |= key.ModCapsLock
+		}
+		if windows.GetKeyState(windows.VK_NUMLOCK)&1 != 0 {
+			kmods |= key.ModNumLock
+		}
+	}
+	return kmods
+}
+
 func getModifiers() key.Modifiers {
 	var kmods key.Modifiers
 	if windows.GetKeyState(windows.VK_LWIN)&0x1000 != 0 || windows.GetKeyState(windows.VK_RWIN)&0x1000 != 0 {
@@ -602,6 +684,9 @@ func (w *window) pointerButton(btn pointer.Buttons, press bool, lParam uintptr,
 	}
 	x, y := coordsFromlParam(lParam)
 	p := f32.Point{X: float32(x), Y: float32(y)}
+	if w.inputMode.Cursor == mado.CursorModeDisabled {
+		p = w.virtualPos
+	}
 	w.w.Event(pointer.Event{
 		Kind:      kind,
 		Source:    pointer.Mouse,
@@ -612,6 +697,17 @@ func (w *window) pointerButton(btn pointer.Buttons, press bool, lParam uintptr,
 	})
 }
 
+func (w *window) pointerMove(p f32.Point) {
+	w.w.Event(pointer.Event{
+		Kind:      pointer.Move,
+		Source:    pointer.Mouse,
+		Position:  p,
+		Buttons:   w.pointerBtns,
+		Time:      windows.GetMessageTime(),
+		Modifiers: w.modifiers(),
+	})
+}
+
 func coordsFromlParam(lParam uintptr) (int, int) {
 	x := int(int16(lParam & 0xffff))
 	y := int(int16((lParam >> 16) & 0xffff))
@@ -625,13 +721,16 @@ func (w *window) scrollEvent(wParam, lParam uintptr, horizontal bool, kmods key.
 	np := windows.Point{X: int32(x), Y: int32(y)}
 	windows.ScreenToClient(w.hwnd, &np)
 	p := f32.Point{X: float32(np.X), Y: float32(np.Y)}
+	if w.inputMode.Cursor == mado.CursorModeDisabled {
+		p = w.virtualPos
+	}
 	dist := float32(int16(wParam >> 16))
 	var sp f32.Point
 	if horizontal {
 		sp.X = dist
 	} else {
 		// support horizontal scroll (shift + mousewheel)
-		if kmods == key.ModShift {
+		if kmods.Held() == key.ModShift {
 			sp.X = -dist
 		} else {
 			sp.Y = -dist
@@ -718,6 +817,16 @@ func (w *window) GetFrameBufferSize() image.Point {
 	return image.Pt(int(rect.Right-rect.Left), int(rect.Bottom-rect.Top))
 }
 
+func (w *window) GetFrameExtents() (left, top, right, bottom int) {
+	if !w.config.Decorated || w.config.Mode == mado.Fullscreen {
+		return 0, 0, 0, 0
+	}
+	style := windows.GetWindowLong(w.hwnd, windows.GWL_STYLE)
+	var r windows.Rect
+	windows.AdjustWindowRectEx(&r, uint32(style), 0, dwExStyle)
+	return int(-r.Left), int(-r.Top), int(r.Right), int(r.Bottom)
+}
+
 func (w *window) NewContext() (mado.Context, error) {
 	sort.Slice(drivers, func(i, j int) bool {
 		return drivers[i].priority < drivers[j].priority
@@ -739,8 +848,22 @@ func (w *window) NewContext() (mado.Context, error) {
 	return nil, errors.New("NewContext: no available GPU drivers")
 }
 
-func (w *window) ReadClipboard() {
-	w.readClipboard()
+func (w *window) NewFramebuffer() (mado.Framebuffer, error) {
+	return mado.NewContextFramebuffer(w)
+}
+
+func (w *window) ReadClipboard(mime string, primary bool) {
+	// Only text is supported, and there is no primary selection. Send
+	// empty responses on unsupported types and unavailable clipboards.
+	if mime != "application/text" || primary || w.readClipboard() != nil {
+		w.w.Event(transfer.DataEvent{
+			Type: mime,
+			Open: func() io.ReadCloser {
+				return io.NopCloser(strings.NewReader(""))
+			},
+			Primary: primary,
+		})
+	}
 }
 
 func (w *window) readClipboard() error {
@@ -770,6 +893,7 @@ func (w *window) readClipboard() error {
 func (w *window) Configure(options []mado.Option) {
 	dpi := windows.GetSystemDPI()
 	metric := configForDPI(dpi)
+	prev := w.config
 	w.config.Apply(metric, options)
 	windows.SetWindowText(w.hwnd, w.config.Title)
 
@@ -800,6 +924,11 @@ func (w *window) Configure(options []mado.Option) {
 		wr := windows.GetWindowRect(w.hwnd)
 		x = wr.Left
 		y = wr.Top
+		if w.config.Pos != prev.Pos {
+			// Pos is the position of the client area.
+			x = int32(w.config.Pos.X)
+			y = int32(w.config.Pos.Y)
+		}
 		if w.config.Decorated {
 			// Compute client size and position. Note that the client size is
 			// equal to the window size when we are in control of decorations.
@@ -810,6 +939,10 @@ func (w *window) Configure(options []mado.Option) {
 			windows.AdjustWindowRectEx(&r, uint32(style), 0, dwExStyle)
 			width = r.Right - r.Left
 			height = r.Bottom - r.Top
+			if w.config.Pos != prev.Pos {
+				x += r.Left
+				y += r.Top
+			}
 		}
 		if !w.config.Decorated {
 			// Enable drop shadows when we draw decorations.
@@ -831,10 +964,21 @@ func (w *window) Configure(options []mado.Option) {
 	w.update()
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
-	w.writeClipboard(string(s))
+func (w *window) WriteClipboard(content []input.ClipboardData) {
+	for _, c := range content {
+		if c.Type == "application/text" {
+			w.writeClipboard(string(c.Data))
+			return
+		}
+	}
+}
+
+func (w *window) SetDragSource(types []string) {
+	// Dragging to other applications is not supported.
 }
 
+func (w *window) OfferDragData(mime string, data []byte) {}
+
 func (w *window) writeClipboard(s string) error {
 	if err := windows.OpenClipboard(w.hwnd); err != nil {
 		return err
@@ -874,8 +1018,196 @@ func (w *window) SetCursor(cursor pointer.Cursor) {
 	}
 	w.cursor = c
 	if w.cursorIn {
-		windows.SetCursor(w.cursor)
+		windows.SetCursor(w.currentCursor())
+	}
+}
+
+// currentCursor returns the cursor to show in the client area.
+func (w *window) currentCursor() syscall.Handle {
+	switch w.inputMode.Cursor {
+	case mado.CursorModeHidden, mado.CursorModeDisabled:
+		return 0
+	}
+	if w.customCursor != 0 {
+		return w.customCursor
+	}
+	return w.cursor
+}
+
+func (w *window) SetCustomCursor(c *pointer.CustomCursor) {
+	w.customCursor = 0
+	if c != nil {
+		w.customCursor = w.loadCustomCursor(c)
+	}
+	if w.cursorIn {
+		windows.SetCursor(w.currentCursor())
+	}
+}
+
+func (w *window) ReleaseCustomCursor(c *pointer.CustomCursor) {
+	h, ok := w.customCursors[c]
+	if !ok {
+		return
+	}
+	delete(w.customCursors, c)
+	if h == 0 {
+		return
+	}
+	if h == w.customCursor {
+		w.SetCustomCursor(nil)
+	}
+	windows.DestroyIcon(h)
+}
+
+// loadCustomCursor returns the cursor for the image of c, creating it
+// on first use.
+func (w *window) loadCustomCursor(c *pointer.CustomCursor) syscall.Handle {
+	if h, ok := w.customCursors[c]; ok {
+		return h
+	}
+	// A failed cursor is cached as 0, the default cursor.
+	h, _ := createCursor(c)
+	if w.customCursors == nil {
+		w.customCursors = make(map[*pointer.CustomCursor]syscall.Handle)
+	}
+	w.customCursors[c] = h
+	return h
+}
+
+// createCursor creates an alpha blended cursor from the image of c.
+func createCursor(c *pointer.CustomCursor) (syscall.Handle, error) {
+	size := c.Size()
+	img := c.Image
+	bgra := make([]byte, 0, size.X*size.Y*4)
+	for y := 0; y < size.Y; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+size.X*4]
+		for x := 0; x < len(row); x += 4 {
+			bgra = append(bgra, row[x+2], row[x+1], row[x], row[x+3])
+		}
+	}
+	color, err := windows.CreateBitmap(int32(size.X), int32(size.Y), 32, bgra)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.DeleteObject(color)
+	// The mask is ignored for bitmaps with alpha, but must be present.
+	// Its rows are aligned to 16 bits.
+	mask := make([]byte, (size.X+15)/16*2*size.Y)
+	for i := range mask {
+		mask[i] = 0xff
+	}
+	maskBmp, err := windows.CreateBitmap(int32(size.X), int32(size.Y), 1, mask)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.DeleteObject(maskBmp)
+	return windows.CreateIconIndirect(&windows.IconInfo{
+		XHotspot: uint32(c.Hotspot.X),
+		YHotspot: uint32(c.Hotspot.Y),
+		Mask:     maskBmp,
+		Color:    color,
+	})
+}
+
+func (w *window) SetInputMode(mode mado.InputMode) {
+	if mode.Cursor == mado.CursorModeDisabled && w.inputMode.Cursor != mado.CursorModeDisabled {
+		// Start the virtual cursor where the real cursor is.
+		p := windows.GetCursorPos()
+		windows.ScreenToClient(w.hwnd, &p)
+		w.virtualPos = f32.Pt(float32(p.X), float32(p.Y))
+		w.rawPos = image.Point{}
+	}
+	w.inputMode = mode
+	if w.focused {
+		w.applyInputMode()
+	}
+	if w.cursorIn {
+		windows.SetCursor(w.currentCursor())
+	}
+}
+
+func (w *window) SetCursorPos(pos f32.Point) {
+	if w.inputMode.Cursor == mado.CursorModeDisabled {
+		w.virtualPos = pos
+		return
+	}
+	if !w.focused {
+		// Don't steal the cursor from other windows.
+		return
+	}
+	p := windows.Point{X: int32(pos.X), Y: int32(pos.Y)}
+	windows.ClientToScreen(w.hwnd, &p)
+	windows.SetCursorPos(p.X, p.Y)
+}
+
+// applyInputMode confines and hides the cursor according to the input
+// mode. It must only be called while the window has focus.
+func (w *window) applyInputMode() {
+	disabled := w.inputMode.Cursor == mado.CursorModeDisabled
+	w.updateClip()
+	if disabled {
+		w.centerCursor()
+	}
+	w.setRawInput(disabled && w.inputMode.RawMouseMotion)
+}
+
+// releaseInputMode gives the cursor back to the system.
+func (w *window) releaseInputMode() {
+	windows.ClipCursor(nil)
+	w.setRawInput(false)
+}
+
+// updateClip confines the cursor to the client area in the captured and
+// disabled modes.
+func (w *window) updateClip() {
+	if !w.focused {
+		return
+	}
+	switch w.inputMode.Cursor {
+	case mado.CursorModeCaptured, mado.CursorModeDisabled:
+	default:
+		windows.ClipCursor(nil)
+		return
+	}
+	cr := windows.GetClientRect(w.hwnd)
+	tl := windows.Point{X: cr.Left, Y: cr.Top}
+	br := windows.Point{X: cr.Right, Y: cr.Bottom}
+	windows.ClientToScreen(w.hwnd, &tl)
+	windows.ClientToScreen(w.hwnd, &br)
+	windows.ClipCursor(&windows.Rect{Left: tl.X, Top: tl.Y, Right: br.X, Bottom: br.Y})
+}
+
+func (w *window) clientCenter() windows.Point {
+	cr := windows.GetClientRect(w.hwnd)
+	return windows.Point{X: (cr.Right - cr.Left) / 2, Y: (cr.Bottom - cr.Top) / 2}
+}
+
+func (w *window) centerCursor() {
+	c := w.clientCenter()
+	windows.ClientToScreen(w.hwnd, &c)
+	windows.SetCursorPos(c.X, c.Y)
+}
+
+// setRawInput registers or removes the window as the receiver of raw
+// mouse input.
+func (w *window) setRawInput(enable bool) {
+	if enable == w.rawInput {
+		return
+	}
+	dev := windows.RawInputDevice{
+		UsagePage: 0x01, // HID_USAGE_PAGE_GENERIC
+		Usage:     0x02, // HID_USAGE_GENERIC_MOUSE
+	}
+	if enable {
+		dev.Target = w.hwnd
+	} else {
+		dev.Flags = windows.RIDEV_REMOVE
+	}
+	if err := windows.RegisterRawInputDevices(dev); err != nil {
+		// Fall back to cursor motion.
+		return
 	}
+	w.rawInput = enable
 }
 
 // windowsCursor contains mapping from pointer.Cursor to an IDC.